@@ -0,0 +1,257 @@
+package main
+
+import (
+	"codeClient/connection"
+	"codeClient/protocol"
+	"codeClient/resource"
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	assetTimeout   = 15 * time.Second // Время ожидания индекса и скачивания ресурсов
+	assetQueueSize = 32               // Задач, ожидающих скачивания, до блокировки обработки сообщений
+)
+
+// Часть файла ресурса от сервера
+type AssetChunk struct {
+	Hash   string `msgpack:"h"`
+	Offset int    `msgpack:"o"`
+	Total  int    `msgpack:"t"` // Полный размер файла
+	Data   []byte `msgpack:"d"`
+}
+
+// Скачивание одного файла, done закрывается по завершении
+type assetDownload struct {
+	data []byte
+	err  error
+	done chan struct{}
+}
+
+func (dl *assetDownload) finish(err error) {
+	dl.err = err
+	close(dl.done)
+}
+
+// Скачивает с сервера ресурсы, которых нет в клиенте, и кладёт их в кэш.
+// Ответы сервера перехватываются в listenServer, поэтому ожидать скачивания можно из любой горутины, кроме неё.
+// Обработка сообщений тоже не ждёт скачивания, иначе listenServer не передаст ей следующее сообщение:
+// сообщения, которым нужны ресурсы, обрабатываются задачами Queue
+type AssetLoader struct {
+	conn       net.Conn
+	manifestCh chan map[string]string
+	downloads  map[string]*assetDownload
+	mutex      sync.Mutex
+	jobs       chan func()
+}
+
+func CreateAssetLoader(conn net.Conn) *AssetLoader {
+	a := &AssetLoader{
+		conn:       conn,
+		manifestCh: make(chan map[string]string, 1),
+		downloads:  make(map[string]*assetDownload),
+		jobs:       make(chan func(), assetQueueSize),
+	}
+	go a.runJobs()
+	return a
+}
+
+// Выполняет задачу в горутине скачивания. Задачи выполняются по одной в порядке постановки
+func (a *AssetLoader) Queue(job func()) {
+	a.jobs <- job
+}
+
+func (a *AssetLoader) runJobs() {
+	for job := range a.jobs {
+		job()
+	}
+}
+
+// Скачивает ресурсы в горутине скачивания. Канал закрывается по завершении,
+// nil - все ресурсы уже есть и ждать нечего
+func (a *AssetLoader) EnsureAsync(ids ...string) <-chan struct{} {
+	if len(resource.Missing(ids...)) == 0 {
+		return nil
+	}
+	done := make(chan struct{})
+	a.Queue(func() {
+		a.EnsureOrLog(ids...)
+		close(done)
+	})
+	return done
+}
+
+// Обрабатывает сообщения скачивания ресурсов, возвращает false для остальных сообщений
+func (a *AssetLoader) HandleMessage(msg protocol.Message) bool {
+	switch msg.Type {
+	case protocol.MsgAssetManifest:
+		var manifest map[string]string
+		err := msgpack.Unmarshal(msg.Data, &manifest)
+		if err != nil {
+			log.Printf("Ошибка при десериализации индекса ресурсов: %v", err)
+			return true
+		}
+		resource.SetManifest(manifest)
+		select {
+		case a.manifestCh <- manifest:
+		default:
+		}
+		return true
+
+	case protocol.MsgAssetChunk:
+		var chunk AssetChunk
+		err := msgpack.Unmarshal(msg.Data, &chunk)
+		if err != nil {
+			log.Printf("Ошибка при десериализации части ресурса: %v", err)
+			return true
+		}
+		a.addChunk(chunk)
+		return true
+
+	case protocol.MsgError:
+		// Отсутствующий на сервере ресурс завершает скачивание сразу, без ожидания таймаута
+		gameErr := protocol.DecodeError(msg.Data)
+		if gameErr.Code != protocol.ErrCodeAssetNotFound {
+			return false
+		}
+		hash := gameErr.Details["hash"]
+		a.mutex.Lock()
+		dl, ok := a.downloads[hash]
+		delete(a.downloads, hash)
+		a.mutex.Unlock()
+		if ok {
+			dl.finish(fmt.Errorf("ресурс %s: %w", hash, gameErr))
+		}
+		return true
+	}
+	return false
+}
+
+// Добавляет часть файла, по получении последней части сохраняет файл в кэш
+func (a *AssetLoader) addChunk(chunk AssetChunk) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	dl, ok := a.downloads[chunk.Hash]
+	if !ok {
+		return
+	}
+	if chunk.Offset != len(dl.data) {
+		delete(a.downloads, chunk.Hash)
+		dl.finish(fmt.Errorf("ресурс %s: нарушен порядок частей", chunk.Hash))
+		return
+	}
+	dl.data = append(dl.data, chunk.Data...)
+	if len(dl.data) < chunk.Total {
+		return
+	}
+
+	delete(a.downloads, chunk.Hash)
+	dl.finish(resource.StoreCached(chunk.Hash, dl.data))
+}
+
+// Запрашивает у сервера актуальный индекс ресурсов
+func (a *AssetLoader) RefreshManifest() error {
+	if _, err := connection.SendRequest(a.conn, protocol.MsgAssetManifest, nil); err != nil {
+		return err
+	}
+
+	select {
+	case <-a.manifestCh:
+		return nil
+	case <-time.After(assetTimeout):
+		return fmt.Errorf("сервер не прислал индекс ресурсов")
+	}
+}
+
+// Скачивает ресурсы, которых нет локально или версия которых устарела
+func (a *AssetLoader) Ensure(ids ...string) error {
+	hashes := resource.Missing(ids...)
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	waits := make([]*assetDownload, 0, len(hashes))
+	for _, hash := range hashes {
+		a.mutex.Lock()
+		dl, ok := a.downloads[hash]
+		if !ok {
+			dl = &assetDownload{done: make(chan struct{})}
+			a.downloads[hash] = dl
+		}
+		a.mutex.Unlock()
+		waits = append(waits, dl)
+		if ok {
+			continue // Уже скачивается по другому запросу
+		}
+
+		_, err := connection.SendRequest(a.conn, protocol.MsgAssetRequest, hash)
+		if err != nil {
+			a.forget(hash, dl)
+			dl.finish(err)
+			return err
+		}
+	}
+
+	timeout := time.After(assetTimeout)
+	for i, dl := range waits {
+		select {
+		case <-dl.done:
+			if dl.err != nil {
+				return dl.err
+			}
+		case <-timeout:
+			// Незавершённые скачивания будут запрошены заново при следующем обращении
+			for j := i; j < len(waits); j++ {
+				a.forget(hashes[j], waits[j])
+			}
+			return fmt.Errorf("не удалось скачать ресурсы за %v", assetTimeout)
+		}
+	}
+	log.Printf("Скачано ресурсов с сервера: %d", len(waits))
+	return nil
+}
+
+func (a *AssetLoader) forget(hash string, dl *assetDownload) {
+	a.mutex.Lock()
+	if a.downloads[hash] == dl {
+		delete(a.downloads, hash)
+	}
+	a.mutex.Unlock()
+}
+
+// Скачивает ресурсы и пишет ошибку в лог, при ошибке используются локальные ресурсы
+func (a *AssetLoader) EnsureOrLog(ids ...string) {
+	if err := a.Ensure(ids...); err != nil {
+		log.Println("Ошибка скачивания ресурсов: ", err)
+	}
+}
+
+// Идентификаторы изображений персонажа, которые загружает клиент
+func characterAssetIDs(data protocol.CharacterData) []string {
+	ids := make([]string, 0, len(data.Assets))
+	for _, as := range data.Assets {
+		ids = append(ids, as.AssetPath)
+	}
+	return ids
+}
+
+// Идентификаторы превью товаров магазина
+func (d *ShopData) assetIDs() []string {
+	var ids []string
+	for _, items := range [][]ShopBackgroundItem{d.PurchasedBackgrounds, d.AvailableBackgrounds} {
+		for _, item := range items {
+			ids = append(ids, item.AssetPath)
+		}
+	}
+	for _, items := range [][]ShopCharacterItem{d.PurchasedCharacters, d.AvailableCharacters} {
+		for _, item := range items {
+			ids = append(ids, item.AssetPath)
+		}
+	}
+	return ids
+}
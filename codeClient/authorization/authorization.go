@@ -2,13 +2,13 @@ package authorization
 
 import (
 	"codeClient/connection"
+	"codeClient/protocol"
 	"codeClient/registration"
+	"codeClient/resource"
 	"fmt"
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"math"
 	"net"
-	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -21,7 +21,7 @@ type authData struct {
 }
 
 // Authorization выполняет процесс авторизации и возвращает результат
-func Authorization() (net.Conn, *connection.UserData, error) {
+func Authorization() (net.Conn, *protocol.UserData, error) {
 	rl.SetWindowTitle("Авторизация")
 
 	// Базовые размеры окна относительно которых масштабируется
@@ -29,32 +29,26 @@ func Authorization() (net.Conn, *connection.UserData, error) {
 	const baseHeight = 540
 	const baseFontSize = 20
 
-	// Путь к текущей директории
-	currentDir, err := os.Getwd()
-	if err != nil {
-		fmt.Println("Ошибка при получении текущей директории:", err)
-		return nil, nil, fmt.Errorf("Ошибка при получении текущей директории:", err)
-	}
-
-	// УБРАТЬ ЕСЛИ EXE ТАМ ЖЕ ГДЕ И ПАПКА RESOURCES
-	currentDir = filepath.Dir(currentDir)
-
 	// Загрузка текстур
-	backgroundTexture := rl.LoadTexture(currentDir + "\\resources\\UI\\Authorization\\Background\\LoginRegBack.png")
-	backgroundLoginTexture := rl.LoadTexture(currentDir + "\\resources\\UI\\Authorization\\Background\\LoginTexture.png")
-	entryReleasedTexture := rl.LoadTexture(currentDir + "\\resources\\UI\\Authorization\\Button\\Entry\\EntryReleased.png")
-	entryPressedTexture := rl.LoadTexture(currentDir + "\\resources\\UI\\Authorization\\Button\\Entry\\EntryPressed.png")
-	regPressedTexture := rl.LoadTexture(currentDir + "\\resources\\UI\\Authorization\\Button\\Registration\\RegistrationPressed.png")
+	backgroundTexture := resource.LoadRequiredTexture("UI/Authorization/Background/LoginRegBack.png")
+	backgroundLoginTexture := resource.LoadRequiredTexture("UI/Authorization/Background/LoginTexture.png")
+	entryReleasedTexture := resource.LoadRequiredTexture("UI/Authorization/Button/Entry/EntryReleased.png")
+	entryPressedTexture := resource.LoadRequiredTexture("UI/Authorization/Button/Entry/EntryPressed.png")
+	regPressedTexture := resource.LoadRequiredTexture("UI/Authorization/Button/Registration/RegistrationPressed.png")
 	defer rl.UnloadTexture(backgroundTexture)
 	defer rl.UnloadTexture(backgroundLoginTexture)
 	defer rl.UnloadTexture(entryReleasedTexture)
 	defer rl.UnloadTexture(entryPressedTexture)
 	defer rl.UnloadTexture(regPressedTexture)
+	if err := resource.CheckRequired(); err != nil {
+		fmt.Println("Ошибка загрузки ресурсов: ", err)
+		return nil, nil, err
+	}
 
 	// Путь к шрифту
-	fontPath := currentDir + "\\resources\\fonts\\PressStart2P-Regular.ttf"
+	fontPath := "Fonts/PressStart2P-Regular.ttf"
 	runes := []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyzАБВГДЕЁЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯабвгдеёжзийклмнопрстуфхцчшщъыьэюя0123456789!@#№$%`~^&*()-=_+[]{};:'\",.<>?/|\\ ")
-	font := rl.LoadFontEx(fontPath, 32, runes, int32(len(runes)))
+	font := resource.LoadFont(fontPath, 32, runes)
 	if font.Texture.ID == 0 {
 		fmt.Println("Ошибка загрузки шрифта!")
 		return nil, nil, fmt.Errorf("Ошибка загрузки шрифта!")
@@ -157,7 +151,7 @@ func Authorization() (net.Conn, *connection.UserData, error) {
 			entryBtPressed = false
 			if !isProcessing {
 				isProcessing = true
-				authMes, err := connection.CreateMessage(connection.MsgAuthorization, authData{loginText, passwordText})
+				authMes, err := protocol.CreateMessage(protocol.MsgAuthorization, authData{loginText, passwordText})
 				if err == nil {
 					errorText = "Получение ответа..."
 					go func(authMes []byte) {
@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"codeClient/protocol"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Бой бота от начала до результата
+type Battle struct {
+	bot       *Bot
+	Info      StartBattleInfo
+	Errors    []*protocol.GameError // Ошибки сервера во время боя, например действие вне времени боя
+	ActionRTT []time.Duration       // Задержка ответов сервера на команды, заполняется в Wait
+
+	offset time.Duration // Время сервера минус местное время
+
+	sentMutex sync.Mutex
+	nextID    int               // Номер следующей команды, в каждом бою считается с нуля
+	sent      map[int]time.Time // Время отправки команд без ответа
+}
+
+// Шаг сценария: пауза после предыдущего шага и команда персонажа
+type Step struct {
+	Delay   time.Duration
+	Command Cmd
+}
+
+// Встаёт в очередь на обычный или рейтинговый бой, ждёт соперника и подтверждает готовность.
+// При отмене контекста выходит из очереди
+func (b *Bot) QueueBattle(ctx context.Context, ranked bool) (*Battle, error) {
+	mesType := protocol.MsgBattle
+	if ranked {
+		mesType = protocol.MsgBattleRanked
+	}
+	if err := b.Send(mesType, nil); err != nil {
+		return nil, err
+	}
+
+	msg, err := b.WaitFor(ctx, protocol.MsgStartBattleInfo)
+	if err != nil {
+		if ctx.Err() != nil {
+			b.Send(protocol.MsgExitBattle, nil)
+		}
+		return nil, err
+	}
+	battle := &Battle{bot: b, sent: make(map[int]time.Time)}
+	if err = msg.Decode(&battle.Info); err != nil {
+		return nil, err
+	}
+	battle.offset = time.Duration(battle.Info.Timestamp-time.Now().UnixMilli()) * time.Millisecond
+
+	if err = b.Send(protocol.MsgReadyBattle, nil); err != nil {
+		return nil, err
+	}
+	return battle, nil
+}
+
+// Местное время начала и конца боя
+func (bt *Battle) StartTime() time.Time {
+	return time.UnixMilli(bt.Info.StartTime).Add(-bt.offset)
+}
+
+func (bt *Battle) EndTime() time.Time {
+	return time.UnixMilli(bt.Info.EndTime).Add(-bt.offset)
+}
+
+// Ждёт начала боя, до него сервер действия не принимает
+func (bt *Battle) WaitStart(ctx context.Context) error {
+	timer := time.NewTimer(time.Until(bt.StartTime()))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Отправляет команду персонажа
+func (bt *Battle) Act(command Cmd) error {
+	bt.sentMutex.Lock()
+	id := bt.nextID
+	bt.nextID++
+	now := time.Now()
+	bt.sent[id] = now
+	bt.sentMutex.Unlock()
+	return bt.bot.Send(protocol.MsgActionCharacter, Action{Id: id, Command: command, Time: now.UnixMilli()})
+}
+
+// Ждёт начала боя и выполняет сценарий, пока он не кончится или не кончится время боя
+func (bt *Battle) Run(ctx context.Context, script []Step) error {
+	if err := bt.WaitStart(ctx); err != nil {
+		return err
+	}
+	end := bt.EndTime()
+	for _, step := range script {
+		if step.Delay > 0 {
+			timer := time.NewTimer(step.Delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		}
+		if time.Now().After(end) {
+			return nil
+		}
+		if err := bt.Act(step.Command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ждёт результата боя. Ошибки сервера во время боя сохраняются в Errors,
+// ответы на команды - в ActionRTT. На команду внутри атаки сервер не отвечает
+func (bt *Battle) Wait(ctx context.Context) (*EndBattleInfo, error) {
+	for {
+		msg, err := bt.bot.WaitFor(ctx, protocol.MsgEndBattle, protocol.MsgActionCharacter)
+		var gameErr *protocol.GameError
+		if errors.As(err, &gameErr) {
+			bt.Errors = append(bt.Errors, gameErr)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if msg.Type == protocol.MsgActionCharacter {
+			bt.observeAction(msg)
+			continue
+		}
+		info := new(EndBattleInfo)
+		if err = msg.Decode(info); err != nil {
+			return nil, err
+		}
+		return info, nil
+	}
+}
+
+// Досрочно выходит из боя, результат приходит как обычно
+func (bt *Battle) Leave() error {
+	return bt.bot.Send(protocol.MsgExitBattle, nil)
+}
+
+func (bt *Battle) observeAction(msg Message) {
+	var result ActionResult
+	if msg.Decode(&result) != nil || result.CommandID < 0 {
+		return
+	}
+	bt.sentMutex.Lock()
+	defer bt.sentMutex.Unlock()
+	if sent, ok := bt.sent[result.CommandID]; ok {
+		bt.ActionRTT = append(bt.ActionRTT, msg.Received.Sub(sent))
+		delete(bt.sent, result.CommandID)
+	}
+}
@@ -0,0 +1,409 @@
+// Пакет bot - клиент игры без графики для нагрузочного и интеграционного тестирования.
+// Бот говорит на том же протоколе, что и игровой клиент: рукопожатие, регистрация и вход,
+// друзья, магазин, поиск боя и действия персонажа.
+//
+// В отличие от пакета connection состояние соединения хранится в самом боте,
+// поэтому в одном процессе можно запустить сотни ботов одновременно.
+package bot
+
+import (
+	"codeClient/protocol"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Настройки подключения бота
+type Config struct {
+	Host      string        // Адрес сервера без порта, порт зависит от транспорта
+	Port      int           // Порт сервера, 0 - порт транспорта или сетевого профиля по умолчанию
+	Transport string        // kcp, tcp или ws
+	Profile   string        // Сетевой профиль KCP: lan, normal или lossy
+	Key       string        // Общий ключ шифрования, как GAME_KEY у клиента
+	Timeout   time.Duration // Время ожидания ответа на запрос
+}
+
+// Настройки по умолчанию, хост и ключ задаются отдельно
+var DefaultConfig = Config{
+	Host:      "127.0.0.1",
+	Transport: "kcp",
+	Profile:   "normal",
+	Timeout:   10 * time.Second,
+}
+
+var ErrClosed = errors.New("соединение с сервером закрыто")
+
+// Бот - одно подключение к серверу
+type Bot struct {
+	cfg  Config
+	conn net.Conn
+	User *protocol.UserData // Данные игрока после входа
+
+	writeMutex sync.Mutex
+	sendSeq    uint32 // Номер последнего отправленного кадра, под writeMutex
+
+	pendingMutex sync.Mutex
+	pending      map[uint32]chan Message // Запросы, ожидающие ответа: ID -> канал
+
+	eventsMutex sync.Mutex
+	events      []Message     // Сообщения сервера, не относящиеся к ожидаемым ответам
+	eventNotify chan struct{} // Сигнал о новом сообщении в events
+
+	done      chan struct{} // Закрывается, когда чтение соединения завершено
+	closeOnce sync.Once
+	err       error // Причина завершения, доступна после закрытия done
+}
+
+// Подключается к серверу и выполняет рукопожатие
+func Dial(cfg Config) (*Bot, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultConfig.Timeout
+	}
+	key, err := protocol.DeriveKey(cfg.Key)
+	if err != nil {
+		return nil, err
+	}
+	conn, session, err := dial(cfg, key)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bot{
+		cfg:         cfg,
+		conn:        conn,
+		pending:     make(map[uint32]chan Message),
+		eventNotify: make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+	tuning, err := b.handshake(cfg.Transport, key)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// Параметры сервера важнее локальных, чтобы обе стороны работали одинаково
+	if session != nil && tuning != nil {
+		tuning.Apply(session)
+	}
+	go b.readLoop()
+	return b, nil
+}
+
+// Рукопожатие до запуска чтения. Пакеты KCP с чужим ключом сервер отбрасывает молча.
+// После ответа сервера соединение переходит на ключ сессии
+func (b *Bot) handshake(profile string, key []byte) (*protocol.KCPTuning, error) {
+	if profile == "kcp" {
+		profile = b.cfg.Profile
+	}
+	private, err := protocol.NewHandshakeKey()
+	if err != nil {
+		return nil, err
+	}
+	hello := protocol.Handshake{Version: protocol.ProtocolVersion, Profile: profile, Public: private.PublicKey().Bytes()}
+	reqID, err := b.send(protocol.MsgHandshake, hello, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	b.conn.SetReadDeadline(time.Now().Add(protocol.HandshakeTimeout))
+	defer b.conn.SetReadDeadline(time.Time{})
+	for {
+		msg, err := b.readMessage()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil, protocol.ErrNoResponse
+			}
+			return nil, err
+		}
+		if msg.ReqID != 0 && msg.ReqID != reqID {
+			continue
+		}
+		switch msg.Type {
+		case protocol.MsgPing, protocol.MsgPong:
+		case protocol.MsgHandshake:
+			var hs protocol.Handshake
+			if err = msg.Decode(&hs); err != nil {
+				return nil, err
+			}
+			sessionKey, err := protocol.SessionKey(key, private, hs.Public)
+			if err != nil {
+				return nil, err
+			}
+			if b.conn, err = protocol.SecureSession(b.conn, sessionKey); err != nil {
+				return nil, err
+			}
+			return hs.Tuning, nil
+		case protocol.MsgError:
+			return nil, protocol.DecodeError(msg.Data)
+		default:
+			return nil, fmt.Errorf("неожиданный ответ сервера при рукопожатии: %d", msg.Type)
+		}
+	}
+}
+
+// Отправляет сообщение, ID запроса совпадает с порядковым номером кадра.
+// Канал reply регистрируется до записи, ответ может прийти раньше, чем send вернёт ID
+func (b *Bot) send(mesType protocol.MessageType, data interface{}, reply chan Message) (uint32, error) {
+	dataBytes, err := msgpack.Marshal(data)
+	if err != nil {
+		return 0, err
+	}
+	body, err := msgpack.Marshal(Message{Type: mesType, Data: dataBytes})
+	if err != nil {
+		return 0, err
+	}
+
+	b.writeMutex.Lock()
+	defer b.writeMutex.Unlock()
+	frame, err := protocol.EncodeFrame(b.sendSeq+1, body)
+	if err != nil {
+		return 0, err
+	}
+	b.sendSeq++
+	if reply != nil {
+		b.pendingMutex.Lock()
+		b.pending[b.sendSeq] = reply
+		b.pendingMutex.Unlock()
+	}
+	_, err = b.conn.Write(frame)
+	return b.sendSeq, err
+}
+
+// Отправляет сообщение без ожидания ответа
+func (b *Bot) Send(mesType protocol.MessageType, data interface{}) error {
+	_, err := b.send(mesType, data, nil)
+	return err
+}
+
+func (b *Bot) readMessage() (Message, error) {
+	seq, reqID, body, err := protocol.ReadFrame(b.conn)
+	if err != nil {
+		return Message{}, err
+	}
+	var msg Message
+	if err = msgpack.Unmarshal(body, &msg); err != nil {
+		return Message{}, err
+	}
+	msg.Seq = seq
+	msg.ReqID = reqID
+	msg.Received = time.Now()
+	return msg, nil
+}
+
+// Читает сообщения сервера: отвечает на пинг, ответы передаёт ожидающим запросам, остальное в события
+func (b *Bot) readLoop() {
+	var err error
+	defer func() { b.finish(err) }()
+
+	for {
+		var msg Message
+		msg, err = b.readMessage()
+		if err != nil {
+			return
+		}
+		switch msg.Type {
+		case protocol.MsgPing:
+			if err = b.Send(protocol.MsgPong, nil); err != nil {
+				return
+			}
+			continue
+		case protocol.MsgPong:
+			continue
+		case protocol.MsgExit: // Сервер закрывает сессию и сообщает причину
+			err = protocol.DecodeError(msg.Data)
+			return
+		}
+
+		b.pendingMutex.Lock()
+		ch, ok := b.pending[msg.ReqID]
+		if ok {
+			delete(b.pending, msg.ReqID)
+		}
+		b.pendingMutex.Unlock()
+		if ok {
+			ch <- msg
+			continue
+		}
+
+		b.eventsMutex.Lock()
+		b.events = append(b.events, msg)
+		b.eventsMutex.Unlock()
+		select {
+		case b.eventNotify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Запоминает причину завершения и закрывает соединение
+func (b *Bot) finish(err error) {
+	b.closeOnce.Do(func() {
+		if err == nil || errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+			err = ErrClosed
+		}
+		b.err = err
+		b.conn.Close()
+		close(b.done)
+	})
+}
+
+// Причина завершения соединения или nil, пока оно открыто
+func (b *Bot) Err() error {
+	select {
+	case <-b.done:
+		return b.err
+	default:
+		return nil
+	}
+}
+
+// Отправляет запрос и ждёт ответа на него. protocol.MsgError возвращается как *protocol.GameError,
+// остальные ответы разбираются в resp, если он задан
+func (b *Bot) Request(ctx context.Context, mesType protocol.MessageType, data, resp interface{}) (Message, error) {
+	ch := make(chan Message, 1)
+	reqID, err := b.send(mesType, data, ch)
+	defer func() {
+		b.pendingMutex.Lock()
+		delete(b.pending, reqID)
+		b.pendingMutex.Unlock()
+	}()
+	if err != nil {
+		return Message{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.Timeout)
+	defer cancel()
+	select {
+	case msg := <-ch:
+		if msg.Type == protocol.MsgError {
+			return msg, protocol.DecodeError(msg.Data)
+		}
+		if resp != nil {
+			if err = msg.Decode(resp); err != nil {
+				return msg, err
+			}
+		}
+		return msg, nil
+	case <-b.done:
+		return Message{}, b.err
+	case <-ctx.Done():
+		return Message{}, fmt.Errorf("нет ответа на запрос %d: %w", mesType, ctx.Err())
+	}
+}
+
+// Ждёт сообщение одного из типов, остальные события пропускаются.
+// protocol.MsgError среди событий возвращается как *protocol.GameError
+func (b *Bot) WaitFor(ctx context.Context, types ...protocol.MessageType) (Message, error) {
+	for {
+		b.eventsMutex.Lock()
+		for len(b.events) > 0 {
+			msg := b.events[0]
+			b.events[0] = Message{}
+			b.events = b.events[1:]
+			if msg.Type == protocol.MsgError {
+				b.eventsMutex.Unlock()
+				return msg, protocol.DecodeError(msg.Data)
+			}
+			for _, t := range types {
+				if msg.Type == t {
+					b.eventsMutex.Unlock()
+					return msg, nil
+				}
+			}
+		}
+		b.eventsMutex.Unlock()
+
+		select {
+		case <-b.eventNotify:
+		case <-b.done:
+			return Message{}, b.err
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		}
+	}
+}
+
+// Регистрирует пользователя и входит под ним
+func (b *Bot) Register(ctx context.Context, login, name, password string) (*protocol.UserData, error) {
+	user := new(protocol.UserData)
+	if _, err := b.Request(ctx, protocol.MsgRegistration, regData{Login: login, Name: name, Password: password, ConfirmPassword: password}, user); err != nil {
+		return nil, err
+	}
+	b.User = user
+	return user, nil
+}
+
+// Вход по логину и паролю
+func (b *Bot) Login(ctx context.Context, login, password string) (*protocol.UserData, error) {
+	user := new(protocol.UserData)
+	if _, err := b.Request(ctx, protocol.MsgAuthorization, authData{Login: login, Password: password}, user); err != nil {
+		return nil, err
+	}
+	b.User = user
+	return user, nil
+}
+
+// Список друзей и заявок
+func (b *Bot) Friends(ctx context.Context) (*FriendsData, error) {
+	data := new(FriendsData)
+	if _, err := b.Request(ctx, protocol.MsgFriendsData, nil, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Товары магазина
+func (b *Bot) Shop(ctx context.Context) (*ShopData, error) {
+	data := new(ShopData)
+	if _, err := b.Request(ctx, protocol.MsgShopData, nil, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Покупает товар. Чек приходит без ID запроса, поэтому ожидается как событие
+func (b *Bot) Buy(ctx context.Context, productType ProductType, productID int) (*PurchaseReceipt, error) {
+	if err := b.Send(protocol.MsgShopAction, ShopAction{Action: ShopBuy, ProductType: productType, ProductID: productID}); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.Timeout)
+	defer cancel()
+	msg, err := b.WaitFor(ctx, protocol.MsgPurchaseReceipt)
+	if err != nil {
+		return nil, err
+	}
+	receipt := new(PurchaseReceipt)
+	if err = msg.Decode(receipt); err != nil {
+		return nil, err
+	}
+	if b.User != nil {
+		b.User.Money = receipt.RemainingMoney
+	}
+	return receipt, nil
+}
+
+// Запрашивает ежедневную награду
+func (b *Bot) DailyReward(ctx context.Context) (*DailyRewardInfo, error) {
+	info := new(DailyRewardInfo)
+	if _, err := b.Request(ctx, protocol.MsgDailyReward, nil, info); err != nil {
+		return nil, err
+	}
+	if b.User != nil {
+		b.User.Money = info.TotalMoney
+	}
+	return info, nil
+}
+
+// Отправляет серверу protocol.MsgExit и закрывает соединение
+func (b *Bot) Close() error {
+	if b.Err() == nil {
+		b.Send(protocol.MsgExit, nil)
+	}
+	b.finish(ErrClosed)
+	return nil
+}
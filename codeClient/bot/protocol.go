@@ -0,0 +1,170 @@
+package bot
+
+import (
+	"codeClient/protocol"
+	"github.com/vmihailenco/msgpack/v5"
+	"time"
+)
+
+// Команда персонажа в бою
+type Cmd uint8
+
+const (
+	CmdRunRight Cmd = iota + 1
+	CmdRunLeft
+	CmdStopRun
+	CmdStartJump
+	CmdStopJump
+	CmdAttack
+	CmdHeavyAttack
+)
+
+type BattleResult int8
+
+const (
+	Cancelled BattleResult = -3 // Бой отменён при остановке сервера
+	NoBattle  BattleResult = -2 // Боя не было
+	Defeat    BattleResult = -1 // Поражение
+	Draw      BattleResult = 0  // Ничья
+	Victory   BattleResult = 1  // Победа
+)
+
+// Сообщение сервера, как protocol.Message, Seq и ReqID берутся из заголовка кадра
+type Message struct {
+	Type     protocol.MessageType `msgpack:"t"`
+	Data     []byte               `msgpack:"d"`
+	Seq      uint32               `msgpack:"-"`
+	ReqID    uint32               `msgpack:"-"`
+	Received time.Time            `msgpack:"-"` // Время чтения кадра, для замера задержки
+}
+
+// Разбирает данные сообщения
+func (m Message) Decode(v interface{}) error {
+	return msgpack.Unmarshal(m.Data, v)
+}
+
+type authData struct {
+	Login    string `msgpack:"l"`
+	Password string `msgpack:"p"`
+}
+
+type regData struct {
+	Login           string `msgpack:"l"`
+	Name            string `msgpack:"n"`
+	Password        string `msgpack:"p"`
+	ConfirmPassword string `msgpack:"cp"`
+}
+
+type FriendEntry struct {
+	Name     string
+	PublicID string
+}
+
+type FriendsData struct {
+	Friends  []FriendEntry `msgpack:"f"`
+	Incoming []FriendEntry `msgpack:"i"`
+	Outgoing []FriendEntry `msgpack:"o"`
+}
+
+type ShopBackgroundItem struct {
+	ID          int
+	Name        string
+	Description string
+	Cost        int
+	AssetPath   string
+}
+
+type ShopCharacterItem struct {
+	ID          int
+	Name        string
+	Description string
+	Health      int
+	Damage      int
+	Cost        int
+	AssetPath   string
+}
+
+type ShopActionType int
+
+type ProductType int
+
+const (
+	ShopBuy ShopActionType = iota
+	ShopSelect
+)
+
+const (
+	ProductBackground ProductType = iota
+	ProductCharacter
+)
+
+type ShopAction struct {
+	Action      ShopActionType `msgpack:"a"`
+	ProductType ProductType    `msgpack:"t"`
+	ProductID   int            `msgpack:"p"`
+}
+
+type PurchaseReceipt struct {
+	ProductType    ProductType `msgpack:"t"`
+	ProductID      int         `msgpack:"p"`
+	RemainingMoney int         `msgpack:"r"`
+}
+
+type ShopData struct {
+	PurchasedBackgrounds []ShopBackgroundItem `msgpack:"pb"`
+	AvailableBackgrounds []ShopBackgroundItem `msgpack:"ab"`
+	PurchasedCharacters  []ShopCharacterItem  `msgpack:"pc"`
+	AvailableCharacters  []ShopCharacterItem  `msgpack:"ac"`
+}
+
+// Ответ на запрос ежедневной награды
+type DailyRewardInfo struct {
+	Claimed    bool   `msgpack:"c"` // Награда получена этим запросом, иначе она уже получена сегодня
+	Streak     int    `msgpack:"s"`
+	Day        int    `msgpack:"d"`
+	Money      int    `msgpack:"m,omitempty"`
+	Background string `msgpack:"b,omitempty"`
+	TotalMoney int    `msgpack:"tm"`
+	NextClaim  int64  `msgpack:"n"`
+}
+
+// Действие персонажа
+type Action struct {
+	Id      int   `msgpack:"i"`
+	Command Cmd   `msgpack:"c"`
+	Time    int64 `msgpack:"t"` // Время отправки, мс по UTC
+}
+
+// Состояние персонажа после действия
+type ActionResult struct {
+	Timestamp   int64   `msgpack:"t"`
+	CommandID   int     `msgpack:"id"`
+	Health      int     `msgpack:"h"`
+	Direction   float32 `msgpack:"di"`
+	X           float32 `msgpack:"x"`
+	Y           float32 `msgpack:"y"`
+	IsDying     bool    `msgpack:"dy"`
+	IsAttacking bool    `msgpack:"a"`
+	TypeAttack  int8    `msgpack:"ta"`
+	IsJumping   bool    `msgpack:"j"`
+	IsRunning   bool    `msgpack:"r"`
+}
+
+type StartBattleInfo struct {
+	Timestamp         int64                  `msgpack:"tt"` // Время на сервере, когда событие было обработано
+	StartTime         int64                  `msgpack:"st"`
+	EndTime           int64                  `msgpack:"et"`
+	OpponentPublicID  string                 `msgpack:"oi"`
+	OpponentName      string                 `msgpack:"on"`
+	OpponentRank      int                    `msgpack:"or"`
+	OpponentLevel     int                    `msgpack:"ol"`
+	OpponentCharacter protocol.CharacterData `msgpack:"oc"`
+}
+
+type EndBattleInfo struct {
+	Result       BattleResult `msgpack:"w,omitempty"`
+	TotalMoney   int          `msgpack:"m"`
+	CurrentRank  int          `msgpack:"r"`
+	CurrentLevel int          `msgpack:"l"`
+	UpdatedStats ActionResult `msgpack:"u"`
+}
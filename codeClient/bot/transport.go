@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"codeClient/protocol"
+	"fmt"
+	"github.com/xtaci/kcp-go/v5"
+	"net"
+)
+
+// Открывает соединение выбранным транспортом. Для KCP возвращает сессию, чтобы применить параметры сервера
+func dial(cfg Config, key []byte) (net.Conn, *kcp.UDPSession, error) {
+	switch cfg.Transport {
+	case "kcp":
+		profile, err := protocol.LookupNetProfile(cfg.Profile)
+		if err != nil {
+			return nil, nil, err
+		}
+		block, err := kcp.NewAESBlockCrypt(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		session, err := kcp.DialWithOptions(cfg.addr(profile.Port), block, profile.DataShards, profile.ParityShards)
+		if err != nil {
+			return nil, nil, fmt.Errorf("не удалось подключиться к KCP серверу: %v", err)
+		}
+		profile.Tuning.Apply(session)
+		return session, session, nil
+	case "tcp":
+		raw, err := net.DialTimeout("tcp", cfg.addr(protocol.TCPPort), protocol.HandshakeTimeout)
+		if err != nil {
+			return nil, nil, fmt.Errorf("не удалось подключиться к серверу (tcp): %v", err)
+		}
+		return wrapStream(raw, key)
+	case "ws":
+		raw, err := protocol.DialWebSocket(cfg.addr(protocol.WSPort))
+		if err != nil {
+			return nil, nil, fmt.Errorf("не удалось подключиться к серверу (ws): %v", err)
+		}
+		return wrapStream(raw, key)
+	}
+	return nil, nil, fmt.Errorf("неизвестный транспорт %s, доступны: kcp, tcp, ws", cfg.Transport)
+}
+
+// Адрес сервера с портом из настроек или портом по умолчанию
+func (cfg Config) addr(port int) string {
+	if cfg.Port != 0 {
+		port = cfg.Port
+	}
+	return fmt.Sprintf("%s:%d", cfg.Host, port)
+}
+
+func wrapStream(raw net.Conn, key []byte) (net.Conn, *kcp.UDPSession, error) {
+	conn, err := protocol.NewSecureConn(raw, key)
+	if err != nil {
+		raw.Close()
+		return nil, nil, err
+	}
+	return conn, nil, nil
+}
@@ -1,7 +1,8 @@
 package main
 
 import (
-	"codeClient/connection"
+	"codeClient/protocol"
+	"codeClient/resource"
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"net"
 	"time"
@@ -56,8 +57,9 @@ type PendingCommand struct {
 
 // Команды персонажа отправленные на сервер
 type Action struct {
-	Id      int `msgpack:"i"`
-	Command Cmd `msgpack:"c"`
+	Id      int   `msgpack:"i"`
+	Command Cmd   `msgpack:"c"`
+	Time    int64 `msgpack:"t"` // Время ввода, сервер сверяет его со временем получения команды
 }
 
 // Подверждение команды от сервера
@@ -75,6 +77,12 @@ type ActionResult struct {
 	IsRunning   bool    `msgpack:"r"`
 }
 
+// Анимация персонажа с загруженной текстурой, текстура в протокол не входит
+type characterAsset struct {
+	protocol.AssetsCharacter
+	Texture rl.Texture2D
+}
+
 type Character struct {
 	name                                                 string
 	description                                          string
@@ -91,12 +99,12 @@ type Character struct {
 	direction, directionRun                              float32
 	animationTicker                                      *time.Ticker
 	physicsTicker                                        *time.Ticker
-	assets                                               map[string]*connection.AssetsCharacter
+	assets                                               map[string]*characterAsset
 	totalNumberCommands                                  int
 	pendingCommands                                      map[int]*PendingCommand
 }
 
-func CreateCharacter(data connection.CharacterData, direction float32) *Character {
+func CreateCharacter(data protocol.CharacterData, direction float32) *Character {
 	var character Character
 	character.name = data.Name
 	character.description = data.Description
@@ -111,7 +119,7 @@ func CreateCharacter(data connection.CharacterData, direction float32) *Characte
 	character.currentState = Idle
 	character.currentFrame = 0
 	character.direction, character.directionRun = direction, direction
-	character.assets = make(map[string]*connection.AssetsCharacter)
+	character.assets = make(map[string]*characterAsset)
 	character.LoadTextures(data.Assets)
 	character.StartAnimation()
 	character.totalNumberCommands = 0
@@ -120,7 +128,7 @@ func CreateCharacter(data connection.CharacterData, direction float32) *Characte
 	return &character
 }
 
-func (ch *Character) UpdateCharacter(data connection.CharacterData, direction float32) {
+func (ch *Character) UpdateCharacter(data protocol.CharacterData, direction float32) {
 	ch.UnloadTextures()
 	ch.StopAnimation()
 
@@ -136,7 +144,7 @@ func (ch *Character) UpdateCharacter(data connection.CharacterData, direction fl
 	ch.currentState = Idle
 	ch.currentFrame = 0
 	ch.direction, ch.directionRun = direction, direction
-	ch.assets = make(map[string]*connection.AssetsCharacter)
+	ch.assets = make(map[string]*characterAsset)
 	ch.LoadTextures(data.Assets)
 
 	ch.StartAnimation()
@@ -161,6 +169,11 @@ func (ch *Character) StopPhysics() {
 	}
 }
 
+// Команда для отправки на сервер
+func (ch *Character) NewAction(c Cmd) Action {
+	return Action{Id: ch.totalNumberCommands, Command: c, Time: time.Now().UnixMilli()}
+}
+
 func (ch *Character) AddCommand(c Cmd) {
 	cmd := PendingCommand{
 		command:     c,
@@ -202,14 +215,14 @@ func (ch *Character) Control(conn net.Conn, gameState *string) {
 
 	if rl.IsKeyPressed(rl.KeyD) || rl.IsKeyPressed(rl.KeyA) {
 		if ch.directionRun != ch.direction && ch.isRunning { // отправлять стоп при переключении направления
-			sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdStopRun})
+			sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdStopRun))
 			ch.AddCommand(cmdStopRun)
 		}
 		if rl.IsKeyPressed(rl.KeyD) {
-			sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdRunRight})
+			sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdRunRight))
 			ch.AddCommand(cmdRunRight)
 		} else {
-			sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdRunLeft})
+			sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdRunLeft))
 			ch.AddCommand(cmdRunLeft)
 		}
 		ch.isRunning = true
@@ -221,7 +234,7 @@ func (ch *Character) Control(conn net.Conn, gameState *string) {
 	}
 
 	if rl.IsKeyReleased(rl.KeyD) && ch.direction == Right || rl.IsKeyReleased(rl.KeyA) && ch.direction == Left {
-		sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdStopRun})
+		sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdStopRun))
 		ch.AddCommand(cmdStopRun)
 		if !ch.isJumping && ch.currentState != Idle {
 			if ch.currentState != Idle {
@@ -241,7 +254,7 @@ func (ch *Character) Control(conn net.Conn, gameState *string) {
 
 	if rl.IsKeyPressed(rl.KeySpace) && !ch.isJumping {
 		if ch.currentState != Jump {
-			sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdStartJump})
+			sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdStartJump))
 			ch.AddCommand(cmdStartJump)
 			ch.isJumping = true
 			ch.currentState = Jump
@@ -253,11 +266,11 @@ func (ch *Character) Control(conn net.Conn, gameState *string) {
 	if rl.IsMouseButtonPressed(rl.MouseLeftButton) {
 		if ch.currentState != Attack {
 			if ch.isRunning {
-				sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdStopRun})
+				sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdStopRun))
 				ch.AddCommand(cmdStopRun)
 				ch.isRunning = false
 			}
-			sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdAttack})
+			sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdAttack))
 			ch.AddCommand(cmdAttack)
 			ch.isAttacking = true
 			ch.currentState = Attack
@@ -266,11 +279,11 @@ func (ch *Character) Control(conn net.Conn, gameState *string) {
 	} else if rl.IsMouseButtonPressed(rl.MouseRightButton) {
 		if ch.currentState != HeavyAttack {
 			if ch.isRunning {
-				sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdStopRun})
+				sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdStopRun))
 				ch.AddCommand(cmdStopRun)
 				ch.isRunning = false
 			}
-			sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdHeavyAttack})
+			sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdHeavyAttack))
 			ch.AddCommand(cmdHeavyAttack)
 			ch.isAttacking = true
 			ch.currentState = HeavyAttack
@@ -284,10 +297,10 @@ func (ch *Character) NextAnimationAfterAttack(conn net.Conn) {
 	if ch.isJumping {
 		if rl.IsKeyDown(rl.KeyD) && ch.directionRun == Right || rl.IsKeyDown(rl.KeyA) && ch.directionRun == Left {
 			if ch.directionRun == Right {
-				sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdRunRight})
+				sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdRunRight))
 				ch.AddCommand(cmdRunRight)
 			} else {
-				sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdRunLeft})
+				sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdRunLeft))
 				ch.AddCommand(cmdRunLeft)
 			}
 			ch.isRunning = true
@@ -297,10 +310,10 @@ func (ch *Character) NextAnimationAfterAttack(conn net.Conn) {
 	} else {
 		if rl.IsKeyDown(rl.KeyD) && ch.directionRun == Right || rl.IsKeyDown(rl.KeyA) && ch.directionRun == Left {
 			if ch.directionRun == Right {
-				sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdRunRight})
+				sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdRunRight))
 				ch.AddCommand(cmdRunRight)
 			} else {
-				sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdRunLeft})
+				sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdRunLeft))
 				ch.AddCommand(cmdRunLeft)
 			}
 			ch.currentState = Run
@@ -359,7 +372,7 @@ func (ch *Character) Update(conn net.Conn) {
 
 			if ch.yFrame >= ch.yStart {
 				if conn != nil {
-					sendInput(conn, connection.MsgActionCharacter, Action{ch.totalNumberCommands, cmdStopJump})
+					sendInput(conn, protocol.MsgActionCharacter, ch.NewAction(cmdStopJump))
 					ch.AddCommand(cmdStopJump)
 				}
 
@@ -619,17 +632,23 @@ func (ch *Character) Draw(scaleX, scaleY float32) {
 	)
 }
 
-func (ch *Character) LoadTextures(data map[string]connection.AssetsCharacter) {
+func (ch *Character) LoadTextures(data map[string]protocol.AssetsCharacter) {
 	for typeAs, as := range data {
-		as.Texture = rl.LoadTexture(currentDirectory + as.AssetPath)
-		ch.assets[typeAs] = &as
+		ch.assets[typeAs] = &characterAsset{AssetsCharacter: as, Texture: resource.LoadTexture(as.AssetPath)}
+	}
+}
+
+// Загружает текстуры заново, например после скачивания ресурсов
+func (ch *Character) ReloadTextures() {
+	for _, asset := range ch.assets {
+		rl.UnloadTexture(asset.Texture)
+		asset.Texture = resource.LoadTexture(asset.AssetPath)
 	}
 }
 
 func (ch *Character) UnloadTextures() {
-	for typeAs, asset := range ch.assets {
+	for _, asset := range ch.assets {
 		rl.UnloadTexture(asset.Texture)
 		asset.Texture = rl.Texture2D{}
-		ch.assets[typeAs] = asset
 	}
 }
@@ -0,0 +1,124 @@
+// Нагрузочное тестирование сервера ботами без графики. Боты входят с заданной частотой
+// и выполняют сценарий до конца теста, в конце выводится отчёт: задержки по типам сообщений,
+// ошибки, ожидание подбора соперника и метрики сервера.
+//
+//	loadtest -host 10.0.0.5 -scenario battle -bots 200 -rate 20 -duration 5m
+//	loadtest -scenario mixed -metrics http://10.0.0.5:9100/metrics
+//
+// Все боты подключаются с одного IP, поэтому на сервере нужно поднять лимит сессий,
+// например -sessionrate 100/500
+package main
+
+import (
+	"codeClient/bot"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const progressInterval = 10 * time.Second // Как часто выводить ход теста
+
+func main() {
+	cfg := bot.DefaultConfig
+	flag.StringVar(&cfg.Host, "host", cfg.Host, "адрес сервера без порта")
+	flag.StringVar(&cfg.Transport, "transport", cfg.Transport, "транспорт: kcp, tcp или ws")
+	flag.StringVar(&cfg.Profile, "profile", cfg.Profile, "сетевой профиль KCP: lan, normal или lossy")
+	flag.StringVar(&cfg.Key, "key", os.Getenv("GAME_KEY"), "общий ключ шифрования, такой же как у сервера")
+	flag.DurationVar(&cfg.Timeout, "timeout", cfg.Timeout, "время ожидания ответа на запрос")
+	scenarioName := flag.String("scenario", "battle", "сценарий: login, queue, battle, shop или mixed")
+	bots := flag.Int("bots", 100, "число одновременных игроков")
+	rate := flag.Float64("rate", 10, "входов в секунду на все боты")
+	duration := flag.Duration("duration", time.Minute, "длительность теста")
+	prefix := flag.String("prefix", "loadbot", "префикс логинов ботов, аккаунты создаются при первом запуске")
+	password := flag.String("password", "loadtest", "пароль ботов")
+	ranked := flag.Bool("ranked", false, "рейтинговые бои вместо обычных")
+	metricsURL := flag.String("metrics", "", "адрес /metrics сервера для отчёта, пустой отключает")
+	flag.Parse()
+
+	scenarios, err := pickScenarios(*scenarioName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *bots < 1 || *rate <= 0 {
+		log.Fatal("число ботов и частота входов должны быть положительными")
+	}
+	if len(*prefix)+6 >= 24 {
+		log.Fatal("префикс логинов слишком длинный, логин должен быть короче 24 символов")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		log.Println("Остановка теста...")
+		cancel()
+	}()
+
+	var before map[string]float64
+	if *metricsURL != "" {
+		if before, err = scrapeMetrics(*metricsURL); err != nil {
+			log.Printf("Метрики сервера недоступны: %v", err)
+		}
+	}
+
+	r := &runner{
+		cfg:      cfg,
+		password: *password,
+		ranked:   *ranked,
+		stats:    newStats(),
+		logins:   newLoginLimiter(*rate),
+	}
+	defer r.logins.Stop()
+
+	started := time.Now()
+	var active int32
+	var wg sync.WaitGroup
+	for i := 0; i < *bots; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			atomic.AddInt32(&active, 1)
+			defer atomic.AddInt32(&active, -1)
+			p := &player{runner: r, login: fmt.Sprintf("%s_%05d", *prefix, i), seed: int64(i)}
+			scenarios[i%len(scenarios)](ctx, p)
+			p.logout()
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+progress:
+	for {
+		select {
+		case <-ticker.C:
+			log.Printf("Прошло %v: ботов в работе %d, запросов %d, ошибок %d",
+				time.Since(started).Round(time.Second), atomic.LoadInt32(&active), r.stats.total(), r.stats.failed())
+		case <-done:
+			break progress
+		}
+	}
+
+	r.stats.report(os.Stdout, time.Since(started))
+	if *metricsURL != "" {
+		after, err := scrapeMetrics(*metricsURL)
+		if err != nil {
+			log.Printf("Метрики сервера недоступны: %v", err)
+			return
+		}
+		reportMetrics(os.Stdout, before, after)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"codeClient/bot"
+	"codeClient/protocol"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const metricsTimeout = 5 * time.Second
+
+// Статистика всех ботов
+type stats struct {
+	mutex     sync.Mutex
+	latencies map[string][]time.Duration // Операция -> задержки удачных запросов
+	failures  map[string]map[string]int  // Операция -> причина -> число ошибок
+	waits     []time.Duration            // Ожидание подбора соперника
+	results   map[bot.BattleResult]int   // Итоги боёв
+}
+
+func newStats() *stats {
+	return &stats{
+		latencies: make(map[string][]time.Duration),
+		failures:  make(map[string]map[string]int),
+		results:   make(map[bot.BattleResult]int),
+	}
+}
+
+// Учитывает операцию: задержку удачной или причину ошибки
+func (s *stats) observe(op string, d time.Duration, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err == nil {
+		s.latencies[op] = append(s.latencies[op], d)
+		return
+	}
+	if s.failures[op] == nil {
+		s.failures[op] = make(map[string]int)
+	}
+	s.failures[op][failureReason(err)]++
+}
+
+func (s *stats) matched(wait time.Duration) {
+	s.mutex.Lock()
+	s.waits = append(s.waits, wait)
+	s.mutex.Unlock()
+}
+
+func (s *stats) battleResult(result bot.BattleResult) {
+	s.mutex.Lock()
+	s.results[result]++
+	s.mutex.Unlock()
+}
+
+func (s *stats) total() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	n := 0
+	for _, l := range s.latencies {
+		n += len(l)
+	}
+	for _, f := range s.failures {
+		for _, count := range f {
+			n += count
+		}
+	}
+	return n
+}
+
+func (s *stats) failed() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	n := 0
+	for _, f := range s.failures {
+		for _, count := range f {
+			n += count
+		}
+	}
+	return n
+}
+
+// Ключ ошибки сервера или текст прочих ошибок
+func failureReason(err error) string {
+	var gameErr *protocol.GameError
+	if errors.As(err, &gameErr) {
+		return fmt.Sprintf("%d %s", gameErr.Code, gameErr.Key)
+	}
+	return err.Error()
+}
+
+// Перцентиль отсортированных задержек
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i]
+}
+
+func formatDuration(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 1, 64) + "ms"
+}
+
+// Выводит отчёт: задержки и ошибки по операциям, ожидание соперника, итоги боёв
+func (s *stats) report(w io.Writer, elapsed time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ops := make(map[string]bool)
+	for op := range s.latencies {
+		ops[op] = true
+	}
+	for op := range s.failures {
+		ops[op] = true
+	}
+	names := make([]string, 0, len(ops))
+	for op := range ops {
+		names = append(names, op)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "\nДлительность теста: %v\n\n", elapsed.Round(time.Second))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "операция\tуспешно\tошибок\tв секунду\tp50\tp90\tp99\tmax\t")
+	for _, op := range names {
+		l := s.latencies[op]
+		sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+		failed := 0
+		for _, count := range s.failures[op] {
+			failed += count
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n", op, len(l), failed,
+			float64(len(l)+failed)/elapsed.Seconds(),
+			formatDuration(percentile(l, 0.5)), formatDuration(percentile(l, 0.9)),
+			formatDuration(percentile(l, 0.99)), formatDuration(percentile(l, 1)))
+	}
+	tw.Flush()
+
+	if len(s.failures) > 0 {
+		fmt.Fprintln(w, "\nОшибки:")
+		for _, op := range names {
+			reasons := make([]string, 0, len(s.failures[op]))
+			for reason := range s.failures[op] {
+				reasons = append(reasons, reason)
+			}
+			sort.Strings(reasons)
+			for _, reason := range reasons {
+				fmt.Fprintf(w, "  %s: %s - %d\n", op, reason, s.failures[op][reason])
+			}
+		}
+	}
+
+	if len(s.waits) > 0 {
+		sort.Slice(s.waits, func(i, j int) bool { return s.waits[i] < s.waits[j] })
+		fmt.Fprintf(w, "\nОжидание соперника: боёв %d, p50 %v, p90 %v, p99 %v, max %v\n", len(s.waits),
+			percentile(s.waits, 0.5).Round(time.Millisecond), percentile(s.waits, 0.9).Round(time.Millisecond),
+			percentile(s.waits, 0.99).Round(time.Millisecond), percentile(s.waits, 1).Round(time.Millisecond))
+	}
+	if len(s.results) > 0 {
+		fmt.Fprintf(w, "Итоги боёв: побед %d, поражений %d, ничьих %d, прервано %d\n",
+			s.results[bot.Victory], s.results[bot.Defeat], s.results[bot.Draw], s.results[bot.Cancelled]+s.results[bot.NoBattle])
+	}
+}
+
+// Читает метрики сервера в текстовом формате Prometheus: серия с метками -> значение
+func scrapeMetrics(url string) (map[string]float64, error) {
+	client := http.Client{Timeout: metricsTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("сервер метрик ответил %s", resp.Status)
+	}
+
+	metrics := make(map[string]float64)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "game_") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			continue
+		}
+		metrics[line[:i]] = value
+	}
+	return metrics, scanner.Err()
+}
+
+// Выводит метрики сервера после теста: значения, прирост счётчиков за тест
+// и среднее гистограмм за тест
+func reportMetrics(w io.Writer, before, after map[string]float64) {
+	names := make([]string, 0, len(after))
+	for name := range after {
+		if !strings.Contains(name, "_bucket") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "\nМетрики сервера:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "серия\tзначение\tза тест\t")
+	for _, name := range names {
+		if sumName := strings.Replace(name, "_count", "_sum", 1); sumName != name {
+			count := after[name] - before[name]
+			if count > 0 {
+				avg := (after[sumName] - before[sumName]) / count
+				fmt.Fprintf(tw, "%s\t%g\t%g, среднее %s\t\n", name, after[name], count, formatDuration(time.Duration(avg*float64(time.Second))))
+				continue
+			}
+		}
+		if strings.Contains(name, "_sum") {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%g\t%g\t\n", name, after[name], after[name]-before[name])
+	}
+	tw.Flush()
+}
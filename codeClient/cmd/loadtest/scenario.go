@@ -0,0 +1,327 @@
+package main
+
+import (
+	"codeClient/bot"
+	"codeClient/protocol"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	reconnectDelay = time.Second      // Пауза перед повторным подключением после ошибки
+	endBattleWait  = 15 * time.Second // Запас на подведение итогов после конца боя
+)
+
+// Сценарий одного бота, выполняется до отмены контекста
+type scenario func(ctx context.Context, p *player)
+
+var scenarios = map[string]scenario{
+	"login":  loginScenario,
+	"queue":  queueScenario,
+	"battle": battleScenario,
+	"shop":   shopScenario,
+}
+
+// В смешанном сценарии боты распределяются по кругу: двое в бой, остальные по одному
+var mixedScenario = []scenario{battleScenario, battleScenario, shopScenario, queueScenario, loginScenario}
+
+func pickScenarios(name string) ([]scenario, error) {
+	if name == "mixed" {
+		return mixedScenario, nil
+	}
+	s, ok := scenarios[name]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный сценарий %s, доступны: login, queue, battle, shop, mixed", name)
+	}
+	return []scenario{s}, nil
+}
+
+// Общие настройки и статистика всех ботов
+type runner struct {
+	cfg      bot.Config
+	password string
+	ranked   bool
+	stats    *stats
+	logins   *loginLimiter
+}
+
+// Ограничивает частоту входов всех ботов вместе
+type loginLimiter struct {
+	ticker *time.Ticker
+}
+
+func newLoginLimiter(rate float64) *loginLimiter {
+	return &loginLimiter{ticker: time.NewTicker(time.Duration(float64(time.Second) / rate))}
+}
+
+func (l *loginLimiter) wait(ctx context.Context) error {
+	select {
+	case <-l.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *loginLimiter) Stop() {
+	l.ticker.Stop()
+}
+
+// Один симулируемый игрок
+type player struct {
+	*runner
+	login      string
+	seed       int64
+	registered bool // Аккаунт уже есть, дальше только вход
+	bot        *bot.Bot
+	rnd        *rand.Rand
+}
+
+// Записывает результат операции. Ошибки из-за окончания теста не считаются
+func (p *player) observe(ctx context.Context, op string, start time.Time, err error) {
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	p.stats.observe(op, time.Since(start), err)
+}
+
+// Подключается и входит, при первом запуске создаёт аккаунт
+func (p *player) connect(ctx context.Context) error {
+	if p.rnd == nil {
+		p.rnd = rand.New(rand.NewSource(time.Now().UnixNano() + p.seed))
+	}
+	if err := p.logins.wait(ctx); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	b, err := bot.Dial(p.cfg)
+	p.observe(ctx, "handshake", start, err)
+	if err != nil {
+		return err
+	}
+
+	if !p.registered {
+		start = time.Now()
+		_, err = b.Register(ctx, p.login, p.login, p.password)
+		var gameErr *protocol.GameError
+		if errors.As(err, &gameErr) && gameErr.Code == protocol.ErrCodeLoginExists {
+			p.observe(ctx, "registration", start, nil)
+			p.registered = true
+			// После отказа в регистрации сервер закрывает сессию, вход - в новом подключении
+			b.Close()
+			start = time.Now()
+			b, err = bot.Dial(p.cfg)
+			p.observe(ctx, "handshake", start, err)
+			if err != nil {
+				return err
+			}
+		} else {
+			p.observe(ctx, "registration", start, err)
+			p.registered = err == nil
+		}
+	}
+	if p.registered && b.User == nil {
+		start = time.Now()
+		_, err = b.Login(ctx, p.login, p.password)
+		p.observe(ctx, "authorization", start, err)
+	}
+	if err != nil {
+		b.Close()
+		return err
+	}
+	p.bot = b
+	return nil
+}
+
+// Проверяет подключение и при необходимости подключается заново. false - тест окончен
+func (p *player) ensure(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if p.bot != nil && p.bot.Err() == nil {
+		return true
+	}
+	p.bot = nil
+	for ctx.Err() == nil {
+		if p.connect(ctx) == nil {
+			return true
+		}
+		p.pause(ctx, reconnectDelay, reconnectDelay)
+	}
+	return false
+}
+
+func (p *player) logout() {
+	if p.bot != nil {
+		p.bot.Close()
+		p.bot = nil
+	}
+}
+
+// Случайная пауза от min до max
+func (p *player) pause(ctx context.Context, min, max time.Duration) {
+	d := min
+	if max > min {
+		d += time.Duration(p.rnd.Int63n(int64(max - min)))
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// Вход, загрузка друзей и магазина, выход
+func loginScenario(ctx context.Context, p *player) {
+	for p.ensure(ctx) {
+		start := time.Now()
+		_, err := p.bot.Friends(ctx)
+		p.observe(ctx, "friends", start, err)
+
+		start = time.Now()
+		_, err = p.bot.Shop(ctx)
+		p.observe(ctx, "shop", start, err)
+
+		p.logout()
+		p.pause(ctx, time.Second, 3*time.Second)
+	}
+}
+
+// Вход в очередь и выход из неё, нагрузка на подбор соперников
+func queueScenario(ctx context.Context, p *player) {
+	mesType := protocol.MsgBattle
+	if p.ranked {
+		mesType = protocol.MsgBattleRanked
+	}
+	for p.ensure(ctx) {
+		start := time.Now()
+		err := p.bot.Send(mesType, nil)
+		if err == nil {
+			_, err = p.waitFor(ctx, protocol.MsgWaitingBattle)
+		}
+		p.observe(ctx, "queue_join", start, err)
+		if err != nil {
+			continue
+		}
+		p.pause(ctx, 500*time.Millisecond, 3*time.Second)
+
+		start = time.Now()
+		err = p.bot.Send(protocol.MsgExitBattle, nil)
+		var msg bot.Message
+		if err == nil {
+			msg, err = p.waitFor(ctx, protocol.MsgExitBattle, protocol.MsgStartBattleInfo)
+		}
+		p.observe(ctx, "queue_leave", start, err)
+		// Соперник нашёлся раньше выхода: уходим из боя и ждём итогов
+		if err == nil && msg.Type == protocol.MsgStartBattleInfo {
+			p.bot.Send(protocol.MsgExitBattle, nil)
+			p.bot.WaitFor(ctx, protocol.MsgEndBattle)
+		}
+		p.pause(ctx, 500*time.Millisecond, 2*time.Second)
+	}
+}
+
+// Полные бои по случайному сценарию
+func battleScenario(ctx context.Context, p *player) {
+	for p.ensure(ctx) {
+		start := time.Now()
+		battle, err := p.bot.QueueBattle(ctx, p.ranked)
+		if err != nil {
+			p.observe(ctx, "matchmaking", start, err)
+			continue
+		}
+		p.stats.matched(time.Since(start))
+
+		script := battleScript(p.rnd, battle.EndTime().Sub(battle.StartTime()))
+		if err = battle.Run(ctx, script); err != nil {
+			p.observe(ctx, "action", time.Now(), err)
+			continue
+		}
+
+		waitCtx, cancel := context.WithDeadline(context.Background(), battle.EndTime().Add(endBattleWait))
+		info, err := battle.Wait(waitCtx)
+		cancel()
+		for _, rtt := range battle.ActionRTT {
+			p.stats.observe("action", rtt, nil)
+		}
+		for _, gameErr := range battle.Errors {
+			p.stats.observe("action", 0, gameErr)
+		}
+		if err != nil {
+			p.stats.observe("end_battle", 0, err)
+			continue
+		}
+		p.stats.battleResult(info.Result)
+		p.pause(ctx, time.Second, 3*time.Second)
+	}
+}
+
+// Просмотр магазина и покупка самого дешёвого доступного товара
+func shopScenario(ctx context.Context, p *player) {
+	for p.ensure(ctx) {
+		start := time.Now()
+		shop, err := p.bot.Shop(ctx)
+		p.observe(ctx, "shop", start, err)
+		if err != nil {
+			continue
+		}
+
+		productType, productID, cost := bot.ProductBackground, -1, 0
+		for _, item := range shop.AvailableBackgrounds {
+			if item.Cost <= p.bot.User.Money && (productID < 0 || item.Cost < cost) {
+				productID, cost = item.ID, item.Cost
+			}
+		}
+		for _, item := range shop.AvailableCharacters {
+			if item.Cost <= p.bot.User.Money && (productID < 0 || item.Cost < cost) {
+				productType, productID, cost = bot.ProductCharacter, item.ID, item.Cost
+			}
+		}
+		if productID >= 0 {
+			start = time.Now()
+			_, err = p.bot.Buy(ctx, productType, productID)
+			p.observe(ctx, "purchase", start, err)
+		}
+		p.pause(ctx, 2*time.Second, 5*time.Second)
+	}
+}
+
+// Ждёт событие не дольше таймаута бота
+func (p *player) waitFor(ctx context.Context, types ...protocol.MessageType) (bot.Message, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+	return p.bot.WaitFor(ctx, types...)
+}
+
+// Случайный сценарий боя: бег, прыжки и атаки с паузой не короче анимации атаки
+func battleScript(rnd *rand.Rand, length time.Duration) []bot.Step {
+	var script []bot.Step
+	for total := time.Duration(0); total < length; {
+		delay := time.Duration(200+rnd.Intn(600)) * time.Millisecond
+		var steps []bot.Step
+		switch rnd.Intn(4) {
+		case 0:
+			cmd := bot.CmdRunRight
+			if rnd.Intn(2) == 0 {
+				cmd = bot.CmdRunLeft
+			}
+			steps = []bot.Step{{Delay: delay, Command: cmd}, {Delay: time.Duration(300+rnd.Intn(700)) * time.Millisecond, Command: bot.CmdStopRun}}
+		case 1:
+			steps = []bot.Step{{Delay: delay, Command: bot.CmdStartJump}, {Delay: 400 * time.Millisecond, Command: bot.CmdStopJump}}
+		case 2:
+			steps = []bot.Step{{Delay: delay, Command: bot.CmdAttack}, {Delay: time.Second, Command: bot.CmdStopRun}}
+		default:
+			steps = []bot.Step{{Delay: delay, Command: bot.CmdHeavyAttack}, {Delay: 1500 * time.Millisecond, Command: bot.CmdStopRun}}
+		}
+		for _, step := range steps {
+			total += step.Delay
+		}
+		script = append(script, steps...)
+	}
+	return script
+}
@@ -0,0 +1,60 @@
+// Утилита сборки архива ресурсов клиента: упаковывает папку resources в один файл
+// с индексом и хешами содержимого и проверяет готовый архив.
+//
+//	pack -dir resources -out resources.pak
+//	pack -verify resources.pak
+package main
+
+import (
+	"codeClient/resource/pack"
+	"flag"
+	"log"
+	"os"
+)
+
+func main() {
+	dir := flag.String("dir", "resources", "папка ресурсов")
+	out := flag.String("out", "resources.pak", "файл архива")
+	verify := flag.String("verify", "", "только проверить готовый архив")
+	flag.Parse()
+
+	if *verify != "" {
+		if err := verifyPack(*verify); err != nil {
+			log.Fatalf("Архив не прошёл проверку: %v", err)
+		}
+		return
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("Ошибка создания архива: %v", err)
+	}
+	count, err := pack.Build(*dir, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*out)
+		log.Fatalf("Ошибка сборки архива: %v", err)
+	}
+	log.Printf("Архив %s собран, ресурсов: %d", *out, count)
+
+	if err = verifyPack(*out); err != nil {
+		log.Fatalf("Архив не прошёл проверку: %v", err)
+	}
+}
+
+// Открывает архив и проверяет хеши всех ресурсов
+func verifyPack(name string) error {
+	p, err := pack.Open(name)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	if err = p.Verify(); err != nil {
+		return err
+	}
+	log.Printf("Архив %s проверен, ресурсов: %d", name, len(p.IDs()))
+	return nil
+}
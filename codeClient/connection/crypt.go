@@ -0,0 +1,74 @@
+package connection
+
+import (
+	"codeClient/protocol"
+	"errors"
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+	"net"
+	"time"
+)
+
+// Общий ключ шифрования трафика. Для сборки дистрибутива задаётся через
+// -ldflags "-X codeClient/connection.SharedKey=..."
+// Ключ одинаков во всех копиях клиента и извлекается из сборки, поэтому защищает только
+// от посторонних, которые просто слушают сеть. Сообщения после рукопожатия шифруются ключом сессии
+// из обмена X25519: записанный трафик без одноразовых ключей не расшифровать, а кадры одной сессии
+// не подходят к другой. От подмены сервера владельцем общего ключа обмен не защищает
+var SharedKey string
+
+// Рукопожатие после подключения. Пакеты KCP с чужим ключом сервер отбрасывает,
+// поэтому отсутствие ответа может означать несовпадение ключей. Возвращает соединение на ключе сессии
+// и параметры KCP сервера
+func handshake(conn net.Conn, profile string, key []byte) (net.Conn, *protocol.KCPTuning, error) {
+	private, err := protocol.NewHandshakeKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	hello := protocol.Handshake{Version: protocol.ProtocolVersion, Profile: profile, Public: private.PublicKey().Bytes()}
+	reqID, err := SendRequest(conn, protocol.MsgHandshake, hello)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(protocol.HandshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		resp, err := GetMessage(conn, nil)
+		if errors.Is(err, protocol.ErrBadFrame) {
+			continue
+		}
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil, nil, protocol.ErrNoResponse
+			}
+			return nil, nil, err
+		}
+		if resp.ReqID != 0 && resp.ReqID != reqID {
+			continue
+		}
+		switch resp.Type {
+		case protocol.MsgPing, protocol.MsgPong:
+		case protocol.MsgHandshake:
+			var hs protocol.Handshake
+			if err = msgpack.Unmarshal(resp.Data, &hs); err != nil {
+				return nil, nil, err
+			}
+			// Следующие кадры сервер шифрует уже ключом сессии
+			sessionKey, err := protocol.SessionKey(key, private, hs.Public)
+			if err != nil {
+				return nil, nil, err
+			}
+			secure, err := protocol.SecureSession(conn, sessionKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			return secure, hs.Tuning, nil
+		case protocol.MsgError:
+			return nil, nil, protocol.DecodeError(resp.Data)
+		default:
+			return nil, nil, fmt.Errorf("неожиданный ответ сервера при рукопожатии: %d", resp.Type)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package connection
+
+import (
+	"codeClient/protocol"
+	"net"
+	"sync"
+)
+
+const maxPending = 256 // Сколько последних запросов помнить для сопоставления ответов
+
+var (
+	sendSeq         uint32                                  // Номер последнего отправленного кадра, под connMutex
+	recvSeq         uint32                                  // Номер последнего принятого кадра
+	pendingRequests = make(map[uint32]protocol.MessageType) // Отправленные запросы: ID -> тип
+	pendingMutex    sync.Mutex
+)
+
+// Сбрасывает номера кадров перед новым подключением, сервер считает их для каждого соединения заново
+func resetFrames() {
+	connMutex.Lock()
+	sendSeq = 0
+	recvSeq = 0
+	connMutex.Unlock()
+
+	pendingMutex.Lock()
+	pendingRequests = make(map[uint32]protocol.MessageType)
+	pendingMutex.Unlock()
+}
+
+// Отправляет кадр со следующим порядковым номером. Возвращает ID запроса
+func writeFrame(conn net.Conn, body []byte) (uint32, error) {
+	connMutex.Lock()
+	defer connMutex.Unlock()
+
+	frame, err := protocol.EncodeFrame(sendSeq+1, body)
+	if err != nil {
+		return 0, err
+	}
+	sendSeq++
+	_, err = conn.Write(frame)
+	return sendSeq, err
+}
+
+// Отправляет запрос и запоминает его тип, чтобы сопоставить с ним ответ сервера
+func SendRequest(conn net.Conn, mesType protocol.MessageType, data interface{}) (uint32, error) {
+	msg, err := protocol.CreateMessage(mesType, data)
+	if err != nil {
+		return 0, err
+	}
+	reqID, err := writeFrame(conn, msg)
+	if err != nil {
+		return 0, err
+	}
+
+	pendingMutex.Lock()
+	pendingRequests[reqID] = mesType
+	delete(pendingRequests, reqID-maxPending)
+	pendingMutex.Unlock()
+	return reqID, nil
+}
+
+// Возвращает тип запроса, на который ответил сервер, и забывает запрос
+func RequestType(reqID uint32) (protocol.MessageType, bool) {
+	if reqID == 0 {
+		return protocol.MsgNone, false
+	}
+	pendingMutex.Lock()
+	defer pendingMutex.Unlock()
+	mesType, ok := pendingRequests[reqID]
+	delete(pendingRequests, reqID)
+	return mesType, ok
+}
@@ -0,0 +1,73 @@
+package connection
+
+import (
+	"codeClient/protocol"
+	"fmt"
+	"net"
+)
+
+// Транспорт: auto (KCP, при недоступности UDP - TCP, затем WebSocket), kcp, tcp или ws
+var Transport = "auto"
+
+// Выбранный сетевой профиль: lan, normal или lossy
+var NetProfileName = "normal"
+
+var workingTransport string // Транспорт последнего удачного подключения, в режиме auto пробуется первым
+
+// Порядок перебора транспортов
+func transportOrder() []string {
+	if Transport != "auto" {
+		return []string{Transport}
+	}
+	order := []string{"kcp", "tcp", "ws"}
+	if workingTransport != "" {
+		result := []string{workingTransport}
+		for _, t := range order {
+			if t != workingTransport {
+				result = append(result, t)
+			}
+		}
+		return result
+	}
+	return order
+}
+
+func dialTransport(transport string) (net.Conn, error) {
+	resetFrames()
+	switch transport {
+	case "kcp":
+		return dialKCP()
+	case "tcp":
+		return dialStream(transport, func() (net.Conn, error) {
+			return net.DialTimeout("tcp", fmt.Sprintf("%s:%d", serverHost, protocol.TCPPort), protocol.HandshakeTimeout)
+		})
+	case "ws":
+		return dialStream(transport, func() (net.Conn, error) {
+			return protocol.DialWebSocket(fmt.Sprintf("%s:%d", serverHost, protocol.WSPort))
+		})
+	}
+	return nil, fmt.Errorf("неизвестный транспорт %s, доступны: auto, kcp, tcp, ws", transport)
+}
+
+// Подключение по TCP или WebSocket с шифрованием потока
+func dialStream(transport string, dial func() (net.Conn, error)) (net.Conn, error) {
+	key, err := protocol.DeriveKey(SharedKey)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := dial()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к серверу (%s): %v", transport, err)
+	}
+	conn, err := protocol.NewSecureConn(raw, key)
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+	secure, _, err := handshake(conn, transport, key)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return secure, nil
+}
@@ -1,8 +1,9 @@
 package connection
 
 import (
+	"codeClient/protocol"
+	"errors"
 	"fmt"
-	rl "github.com/gen2brain/raylib-go/raylib"
 	"log"
 	"net"
 	"sync"
@@ -10,7 +11,7 @@ import (
 	"time"
 )
 
-const serverAddr = "...:7777"
+const serverHost = "..." // Порт зависит от сетевого профиля
 
 var (
 	connMutex      sync.Mutex
@@ -19,143 +20,55 @@ var (
 	waitingForPong int32 = 0
 )
 
-type MessageType uint8
-
-const (
-	MsgNone MessageType = iota
-	MsgPing
-	MsgPong
-	MsgExit
-	MsgSuccess
-	MsgError
-	MsgAuthorization
-	MsgRegistration
-	MsgActionCharacter
-	MsgActionOpponent
-	MsgHealthUpdate
-	MsgFriendsData
-	MsgAddFriend
-	MsgAcceptFriendship
-	MsgDeclineFriendship
-	MsgChallengeToFight
-	MsgAcceptChallengeToFight
-	MsgRefuseChallengeToFight
-	MsgRemoveFriend
-	MsgBattle
-	MsgBattleRanked
-	MsgExitBattle
-	MsgWaitingBattle
-	MsgStartBattleInfo
-	MsgReadyBattle
-	MsgEndBattle
-	MsgListBattles
-	MsgShopData
-	MsgShopAction
-	MsgMoneyUpdate
-	MsgSelectBackground
-	MsgSelectCharacter
-	MsgPurchaseReceipt
-)
-
 // Для получения данных о регистрации и авторизации
 type AuthRegResult struct {
 	Conn net.Conn
-	Data *UserData
+	Data *protocol.UserData
 	Err  error
 }
 
-// Содержит данные авторизованного пользователя
-type UserData struct {
-	Login                string        `msgpack:"l"`
-	PublicID             string        `msgpack:"id"`
-	Name                 string        `msgpack:"n"`
-	Level                int           `msgpack:"lv"`
-	Money                int           `msgpack:"m"`
-	Rank                 int           `msgpack:"r"`
-	ActiveBackgroundPath string        `msgpack:"b"`
-	ActiveCharacter      CharacterData `msgpack:"c"`
-}
-
-// Содержит данные персонажа
-type CharacterData struct {
-	Name        string                     `msgpack:"n"`
-	Description string                     `msgpack:"d"`
-	Health      int                        `msgpack:"h"`
-	Damage      int                        `msgpack:"dm"`
-	Cost        int                        `msgpack:"c"`
-	HCharacter  int                        `msgpack:"hc"` // Высота персонажа без оружия
-	XStart      int                        `msgpack:"xs"`
-	YStart      int                        `msgpack:"ys"`
-	Assets      map[string]AssetsCharacter `msgpack:"as"`
-}
-
-// Содержит данные изображений персонажа
-type AssetsCharacter struct {
-	AnimationType string       `msgpack:"at"`
-	FrameCount    int          `msgpack:"fc"`
-	BaseHeight    int          `msgpack:"bh"`
-	BaseWidth     int          `msgpack:"bw"`
-	FrameRate     float32      `msgpack:"fr"`
-	AssetPath     string       `msgpack:"ap"`
-	Texture       rl.Texture2D `msgpack:"t"`
-}
-
-// Содержит данные об изменении здоровья
-type HealthUpdate struct {
-	Who    MessageType `msgpack:"w"`
-	Health int         `msgpack:"h"`
-}
-
-// Универсальное сообщения для связи с сервером
-type Message struct {
-	Type MessageType `msgpack:"t"`
-	Data []byte      `msgpack:"d"`
-}
-
-// Функция для превращения сообщения в массив байт
-func CreateMessage(mesType MessageType, data interface{}) ([]byte, error) {
-	dataBytes, err := msgpack.Marshal(data)
-	if err != nil {
-		return nil, err
-	}
-	message := Message{Type: mesType, Data: dataBytes}
-	return msgpack.Marshal(message)
-}
-
 // Функция отправки сообщений на сервер
 func SendMessage(conn net.Conn, data []byte) error {
-	connMutex.Lock()
-	defer connMutex.Unlock()
-	_, err := conn.Write(data)
+	_, err := writeFrame(conn, data)
 	return err
 }
 
-// Функция полученяи сообщений от сервера
-func GetMessage(conn net.Conn, data interface{}) (Message, error) {
-	decoder := msgpack.NewDecoder(conn)
+// Функция полученяи сообщений от сервера. Повреждённый кадр пропускается с ошибкой protocol.ErrBadFrame
+func GetMessage(conn net.Conn, data interface{}) (protocol.Message, error) {
+	seq, reqID, body, err := protocol.ReadFrame(conn)
+	if err != nil {
+		log.Println("GetMessage: ошибка при чтении кадра:", err)
+		return protocol.Message{}, err
+	}
+	if seq != recvSeq+1 {
+		log.Printf("GetMessage: ожидался кадр %d, получен %d", recvSeq+1, seq)
+	}
+	recvSeq = seq
 
-	var msg Message
-	err := decoder.Decode(&msg)
+	var msg protocol.Message
+	err = msgpack.Unmarshal(body, &msg)
 	if err != nil {
 		log.Println("GetMessage: ошибка при десериализации сообщения:", err)
-		return Message{}, err
+		return protocol.Message{}, protocol.ErrBadFrame
 	}
+	msg.Seq = seq
+	msg.ReqID = reqID
 
 	if data != nil && msg.Data != nil {
 		err = msgpack.Unmarshal(msg.Data, data)
 		if err != nil {
 			log.Println("GetMessage: ошибка при десериализации msg.Data:", err)
-			return Message{}, err
+			return protocol.Message{}, err
 		}
 	}
 
-	if msg.Type == MsgPing {
-		pong := Message{Type: MsgPong}
+	if msg.Type == protocol.MsgPing {
+		pong := protocol.Message{Type: protocol.MsgPong}
 		raw, _ := msgpack.Marshal(pong)
 		SendMessage(conn, raw)
 	}
 
-	if msg.Type == MsgPong {
+	if msg.Type == protocol.MsgPong {
 		now := time.Now().UnixMilli()
 		sentTime := atomic.LoadInt64(&lastPingTime)
 		RTT := now - sentTime
@@ -169,24 +82,65 @@ func GetMessage(conn net.Conn, data interface{}) (Message, error) {
 	return msg, nil
 }
 
-// Функция подключения к серверу
+// Функция подключения к серверу. В режиме auto пробует KCP, затем TCP и WebSocket
 func ConnectToServer() (net.Conn, error) {
-	conn, err := kcp.Dial(serverAddr)
-	if err != nil {
-		return nil, fmt.Errorf("не удалось подключиться к KCP серверу: %v", err)
+	var resultErr error
+	for _, transport := range transportOrder() {
+		conn, err := dialTransport(transport)
+		if err == nil {
+			if workingTransport != transport {
+				log.Printf("Подключение к серверу через %s", transport)
+			}
+			workingTransport = transport
+			go startPingRoutine(conn)
+			return conn, nil
+		}
+		log.Printf("Транспорт %s недоступен: %v", transport, err)
+
+		// Несовпадение ключа и отказ сервера важнее остальных ошибок, о них нужно сообщить игроку
+		var gameErr *protocol.GameError
+		switch {
+		case errors.Is(err, protocol.ErrKeyMismatch), errors.As(err, &gameErr):
+			return nil, err
+		case resultErr == nil || errors.Is(err, protocol.ErrNoResponse):
+			resultErr = err
+		}
 	}
+	return nil, resultErr
+}
 
-	// Приводим тип и настраиваем параметры KCP
-	if session, ok := conn.(*kcp.UDPSession); ok {
-		session.SetNoDelay(1, 10, 2, 1) // Fast mode
-		session.SetWindowSize(128, 128) // Увеличенные окна
-		session.SetACKNoDelay(true)     // Без отложенных ACK
-	} else {
-		return nil, fmt.Errorf("соединение не является *kcp.UDPSession")
+// Подключение по KCP с шифрованием и FEC сетевого профиля
+func dialKCP() (net.Conn, error) {
+	profile, err := protocol.LookupNetProfile(NetProfileName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := protocol.DeriveKey(SharedKey)
+	if err != nil {
+		return nil, err
+	}
+	block, err := kcp.NewAESBlockCrypt(key)
+	if err != nil {
+		return nil, err
+	}
+	serverAddr := fmt.Sprintf("%s:%d", serverHost, profile.Port)
+	session, err := kcp.DialWithOptions(serverAddr, block, profile.DataShards, profile.ParityShards)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к KCP серверу: %v", err)
 	}
 
-	go startPingRoutine(conn)
+	// Настраиваем параметры KCP
+	profile.Tuning.Apply(session)
 
+	conn, tuning, err := handshake(session, profile.Name, key)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	// Параметры сервера важнее локальных, чтобы обе стороны работали одинаково
+	if tuning != nil {
+		tuning.Apply(session)
+	}
 	return conn, nil
 }
 
@@ -204,7 +158,7 @@ func startPingRoutine(conn net.Conn) {
 		atomic.StoreInt64(&lastPingTime, now)
 		atomic.StoreInt32(&waitingForPong, 1)
 
-		ping := Message{Type: MsgPing}
+		ping := protocol.Message{Type: protocol.MsgPing}
 
 		data, err := msgpack.Marshal(ping)
 		if err != nil {
@@ -223,7 +177,7 @@ func startPingRoutine(conn net.Conn) {
 
 // Функция завершеняи соединения
 func CloseConnection(conn net.Conn) {
-	exitMsg, _ := CreateMessage(MsgExit, nil)
+	exitMsg, _ := protocol.CreateMessage(protocol.MsgExit, nil)
 	SendMessage(conn, exitMsg)
 
 	connMutex.Lock()
@@ -232,11 +186,18 @@ func CloseConnection(conn net.Conn) {
 }
 
 // Функция для устанволеняи соединения при регистрации и авторизации
-func AuthorizationRegistrationToServer(data []byte) (net.Conn, *UserData, error) {
+func AuthorizationRegistrationToServer(data []byte) (net.Conn, *protocol.UserData, error) {
 
 	conn, err := ConnectToServer()
 	if err != nil {
 		log.Println("Не удалось подключиться к серверу: ", err)
+		if errors.Is(err, protocol.ErrKeyMismatch) {
+			return nil, nil, fmt.Errorf("ключ шифрования не совпадает с сервером, обновите игру")
+		}
+		var gameErr *protocol.GameError
+		if errors.As(err, &gameErr) {
+			return nil, nil, gameErr
+		}
 		return nil, nil, fmt.Errorf("не удалось подключиться к серверу, попробуйте позже")
 	}
 
@@ -244,7 +205,7 @@ func AuthorizationRegistrationToServer(data []byte) (net.Conn, *UserData, error)
 	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
 
 	// Отправка данных
-	err = SendMessage(conn, data)
+	reqID, err := writeFrame(conn, data)
 	if err != nil {
 		CloseConnection(conn)
 		fmt.Println("Не удалось отправить данные на сервер: ", err)
@@ -255,20 +216,26 @@ func AuthorizationRegistrationToServer(data []byte) (net.Conn, *UserData, error)
 		// Установка таймаута для чтения ответа
 		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
 		msg, err := GetMessage(conn, nil)
+		if errors.Is(err, protocol.ErrBadFrame) {
+			continue
+		}
 		if err != nil {
 			return nil, nil, err
 		}
+		// Ответы на другие запросы к авторизации не относятся
+		if msg.ReqID != 0 && msg.ReqID != reqID {
+			continue
+		}
 		switch msg.Type {
-		case MsgPong:
-		case MsgPing:
-		case MsgError:
-			var erDt string
-			err = msgpack.Unmarshal(msg.Data, &erDt)
+		case protocol.MsgPong:
+		case protocol.MsgPing:
+		case protocol.MsgError:
+			gameErr := protocol.DecodeError(msg.Data)
 			CloseConnection(conn)
-			log.Println("Ошибка: ", erDt)
-			return nil, nil, fmt.Errorf(erDt)
-		case MsgSuccess:
-			usDt := new(UserData)
+			log.Printf("Ошибка %d: %s", gameErr.Code, gameErr.Message)
+			return nil, nil, gameErr
+		case protocol.MsgSuccess:
+			usDt := new(protocol.UserData)
 			// Десериализация данных внутри Data в структуру
 			err = msgpack.Unmarshal(msg.Data, usDt)
 			if err != nil {
@@ -2,15 +2,17 @@ package main
 
 import (
 	"codeClient/connection"
+	"codeClient/protocol"
+	"codeClient/resource"
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"math"
 	"net"
 	"os"
-	"path/filepath"
 	"sync/atomic"
 )
 
@@ -18,7 +20,7 @@ const (
 	// Базовые размеры окна и текста относительно которых масштабируется. Их менять нельзя, иначе всё поедет
 	baseWidth             = 1280
 	baseHeight            = 720
-	fontPath              = "\\resources\\fonts\\PressStart2P-Regular.ttf"
+	fontPath              = "Fonts/PressStart2P-Regular.ttf"
 	stateMenu             = "Menu"
 	stateListFriends      = "ListFriends"
 	stateShop             = "Shop"
@@ -29,16 +31,17 @@ const (
 
 // Глобальные переменные
 var (
-	menuUI           *MenuUI
-	friendsUI        *FriendsUI
-	friendlyFightUI  *FriendlyFightUI
-	battleUI         *BattleUI
-	shopUI           *ShopUI
-	listBattlesUI    *ListBattlesUI
-	messageServerCh  = make(chan connection.Message)
-	isConnected      bool
-	currentDirectory string
-	font             rl.Font
+	menuUI          *MenuUI
+	friendsUI       *FriendsUI
+	friendlyFightUI *FriendlyFightUI
+	battleUI        *BattleUI
+	shopUI          *ShopUI
+	listBattlesUI   *ListBattlesUI
+	dailyRewardUI   *DailyRewardUI
+	assetLoader     *AssetLoader
+	messageServerCh = make(chan protocol.Message)
+	isConnected     bool
+	font            rl.Font
 )
 
 // Структура для управления автоматическим обработчиком сообщений
@@ -55,20 +58,30 @@ func main() {
 	rl.SetWindowMinSize(640, 360)
 	rl.SetExitKey(rl.KeyNull) // Клавиша завершения работы
 	defer rl.CloseWindow()
-	// Путь к текущей директории
-	var err error
-	currentDirectory, err = os.Getwd()
+	// Ресурсы: флаг -resources, переменная GAME_RESOURCES или поиск resources.pak и папки resources рядом с рабочей папкой и exe
+	resourcesRoot := flag.String("resources", "", "путь к архиву resources.pak или папке resources")
+	// Ключ шифрования: флаг -key, переменная GAME_KEY или ключ, заданный при сборке
+	if key := os.Getenv(protocol.KeyEnvName); key != "" {
+		connection.SharedKey = key
+	}
+	flag.StringVar(&connection.SharedKey, "key", connection.SharedKey, "общий ключ шифрования трафика, такой же как у сервера")
+	flag.StringVar(&connection.NetProfileName, "net", connection.NetProfileName, "сетевой профиль: lan, normal или lossy (мобильная сеть с потерями)")
+	flag.StringVar(&connection.Transport, "transport", connection.Transport, "транспорт: auto, kcp, tcp или ws")
+	flag.Parse()
+	err := resource.Init(*resourcesRoot)
 	if err != nil {
-		fmt.Println("Ошибка при получении текущей директории: ", err)
+		fmt.Println("Ошибка загрузки ресурсов: ", err)
+		return
+	}
+	err = resource.Require(fontPath)
+	if err != nil {
+		fmt.Println("Ошибка загрузки ресурсов: ", err)
 		return
 	}
-
-	// УБРАТЬ ЕСЛИ EXE ТАМ ЖЕ ГДЕ И ПАПКА RESOURCES
-	currentDirectory = filepath.Dir(currentDirectory)
 
 	// Путь к шрифту
 	runes := []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyzАБВГДЕЁЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯабвгдеёжзийклмнопрстуфхцчшщъыьэюя0123456789…!@#№$%`~^&*()-=_+[]{};:'\",.<>?/|\\ ")
-	font = rl.LoadFontEx(currentDirectory+fontPath, 32, runes, int32(len(runes)))
+	font = resource.LoadFont(fontPath, 32, runes)
 	if font.Texture.ID == 0 {
 		fmt.Println("Ошибка загрузки шрифта: ", err)
 		return
@@ -85,6 +98,16 @@ func main() {
 	defer connection.CloseConnection(conn)
 	rl.SetWindowTitle("Дуэль клинков")
 
+	// Автоматическая обработка входящих сообщений, необходима в случае управления персонажем
+	assetLoader = CreateAssetLoader(conn)
+	go listenServer(conn)
+
+	// Скачивание ресурсов, которых нет в клиенте, до загрузки текстур
+	if err = assetLoader.RefreshManifest(); err != nil {
+		log.Println("Ошибка получения индекса ресурсов: ", err)
+	}
+	assetLoader.EnsureOrLog(append(characterAssetIDs(data.ActiveCharacter), data.ActiveBackgroundPath)...)
+
 	player := CreatePlayer(data)
 	defer player.UnloadBackground()
 
@@ -117,17 +140,28 @@ func main() {
 	listBattlesUI = CreateListBattlesUI()
 	defer listBattlesUI.Unload()
 
+	// Ежедневная награда
+	dailyRewardUI = CreateDailyRewardUI()
+	defer dailyRewardUI.Unload()
+
+	// Без текстур интерфейса игра не запускается, иначе вместо них пустые места
+	if err = resource.CheckRequired(); err != nil {
+		fmt.Println("Ошибка загрузки ресурсов: ", err)
+		return
+	}
+
 	currentWidth := float32(baseWidth)
 	currentHeight := float32(baseHeight)
 
 	gameState := stateMenu
 
-	// Автоматическая обработка входящих сообщений, необходима в случае управления персонажем
-	go listenServer(conn)
 	autoMesHandle := &AutoMessageHandler{}
 	autoMesHandle.Start(player)
 	defer autoMesHandle.Stop()
 
+	// Награда за вход, окно покажется в меню, если она получена
+	sendInput(conn, protocol.MsgDailyReward, nil)
+
 	// Основной цикл игры
 	rl.SetTargetFPS(360)
 
@@ -158,7 +192,7 @@ func main() {
 		offsetY := (currentHeight - baseHeight*scaleY) / 2
 
 		// Обработка нажатий кнопок
-		if friendlyFightUI.state == waitingInvitation {
+		if friendlyFightUI.state == waitingInvitation && !dailyRewardUI.shown {
 			switch gameState {
 			case stateMenu:
 				exit := menuUI.HandleInput(conn, &gameState)
@@ -178,6 +212,9 @@ func main() {
 			}
 		}
 
+		if friendlyFightUI.state == waitingInvitation {
+			dailyRewardUI.HandleInput(&gameState)
+		}
 		friendlyFightUI.HandleInput(conn, player, &gameState)
 
 		// Отрисовка
@@ -200,6 +237,7 @@ func main() {
 			listBattlesUI.Draw(scaleX, scaleY)
 		}
 
+		dailyRewardUI.Draw(scaleX, scaleY)
 		friendlyFightUI.Draw(scaleX, scaleY)
 
 		player.character.Draw(scaleX, scaleY)
@@ -210,11 +248,10 @@ func main() {
 }
 
 // Функция безопасной отправки на сервер при параллельной обработке
-func sendInput(conn net.Conn, dataType connection.MessageType, data interface{}) {
+func sendInput(conn net.Conn, dataType protocol.MessageType, data interface{}) {
 	if isConnected {
 		var netErr net.Error
-		msg, err := connection.CreateMessage(dataType, data)
-		err = connection.SendMessage(conn, msg)
+		_, err := connection.SendRequest(conn, dataType, data)
 		if errors.Is(err, io.EOF) || errors.As(err, &netErr) {
 			log.Println("Соединение неожиданно закрыто:", err)
 			isConnected = false
@@ -230,6 +267,9 @@ func listenServer(conn net.Conn) {
 	var netErr net.Error
 	for {
 		msg, err := connection.GetMessage(conn, nil)
+		if errors.Is(err, protocol.ErrBadFrame) {
+			continue
+		}
 		if err != nil {
 			// EOF: клиент явно закрыл соединение
 			if errors.Is(err, io.EOF) {
@@ -256,7 +296,16 @@ func listenServer(conn net.Conn) {
 			}
 		}
 
-		if msg.Type == connection.MsgPing || msg.Type == connection.MsgPong {
+		if msg.Type == protocol.MsgPing || msg.Type == protocol.MsgPong {
+			continue
+		}
+		// Сервер закрывает сессию и сообщает причину
+		if msg.Type == protocol.MsgExit {
+			log.Println("Сервер закрыл соединение:", protocol.DecodeError(msg.Data).Error())
+			isConnected = false
+			return
+		}
+		if assetLoader.HandleMessage(msg) {
 			continue
 		}
 
@@ -318,8 +367,9 @@ func (h *AutoMessageHandler) handleServerMessages(player *Player) {
 				log.Println("Канал сообщений закрыт, завершение горутины")
 				return
 			}
+			reqType, isReply := connection.RequestType(msg.ReqID) // Запрос, на который ответил сервер
 			switch msg.Type {
-			case connection.MsgActionCharacter:
+			case protocol.MsgActionCharacter:
 				var response ActionResult
 				err := msgpack.Unmarshal(msg.Data, &response)
 				if err != nil {
@@ -329,7 +379,7 @@ func (h *AutoMessageHandler) handleServerMessages(player *Player) {
 				// Обновляем положение персонажа
 				player.character.ReplayCommands(response)
 
-			case connection.MsgFriendsData:
+			case protocol.MsgFriendsData:
 				var response FriendsData
 				err := msgpack.Unmarshal(msg.Data, &response)
 				if err != nil {
@@ -344,16 +394,16 @@ func (h *AutoMessageHandler) handleServerMessages(player *Player) {
 					log.Println("Данные друзей отброшены, нет получателя.")
 				}
 
-			case connection.MsgAddFriend:
-				var response string
+			case protocol.MsgAddFriend:
+				var response FriendRequestStatus
 				err := msgpack.Unmarshal(msg.Data, &response)
 				if err != nil {
 					log.Printf("Ошибка при десериализации данных на добавление в друзья: %v", err)
 					break
 				}
-				friendsUI.addFriendResponse = response
+				friendsUI.addFriendResponse = friendRequestTexts[response]
 
-			case connection.MsgChallengeToFight:
+			case protocol.MsgChallengeToFight:
 				var response FriendEntry
 				err := msgpack.Unmarshal(msg.Data, &response)
 				if err != nil {
@@ -368,7 +418,7 @@ func (h *AutoMessageHandler) handleServerMessages(player *Player) {
 					log.Println("Данные друга на участие в дружеской схватке отброшены, нет получателя.")
 				}
 
-			case connection.MsgRefuseChallengeToFight:
+			case protocol.MsgRefuseChallengeToFight:
 				var response string
 				err := msgpack.Unmarshal(msg.Data, &response)
 				if err != nil {
@@ -383,7 +433,7 @@ func (h *AutoMessageHandler) handleServerMessages(player *Player) {
 					log.Println("Отказ друга на участие в дружеской схватке отброшен, нет получателя.")
 				}
 
-			case connection.MsgWaitingBattle:
+			case protocol.MsgWaitingBattle:
 				var response ActionResult
 				err := msgpack.Unmarshal(msg.Data, &response)
 				if err != nil {
@@ -393,7 +443,7 @@ func (h *AutoMessageHandler) handleServerMessages(player *Player) {
 
 				battleUI.currentBattle.waiting <- struct{}{}
 
-			case connection.MsgExitBattle:
+			case protocol.MsgExitBattle:
 				select {
 				case battleUI.currentBattle.exit <- "":
 					log.Println("Выход из боя доставлен.")
@@ -401,17 +451,19 @@ func (h *AutoMessageHandler) handleServerMessages(player *Player) {
 					log.Println("Выход из боя отброшен, нет получателя.")
 				}
 
-			case connection.MsgStartBattleInfo:
+			case protocol.MsgStartBattleInfo:
 				var response StartBattleInfo
 				err := msgpack.Unmarshal(msg.Data, &response)
 				if err != nil {
 					log.Printf("Ошибка при десериализации данных начала боя и оппонента: %v", err)
 					break
 				}
+				// Бой начинается сразу, текстуры соперника перезагружаются после скачивания
+				response.assetsReady = assetLoader.EnsureAsync(characterAssetIDs(response.OpponentCharacter)...)
 
 				battleUI.currentBattle.start <- response
 
-			case connection.MsgEndBattle:
+			case protocol.MsgEndBattle:
 				var response EndBattleInfo
 				err := msgpack.Unmarshal(msg.Data, &response)
 				if err != nil {
@@ -421,21 +473,28 @@ func (h *AutoMessageHandler) handleServerMessages(player *Player) {
 
 				battleUI.currentBattle.end <- response
 
-			case connection.MsgShopData:
+			case protocol.MsgShopData:
 				var response ShopData
 				err := msgpack.Unmarshal(msg.Data, &response)
 				if err != nil {
 					log.Printf("Ошибка при десериализации данных магазина: %v", err)
 					break
 				}
-				select {
-				case shopUI.shopDataCh <- response:
-					log.Println("Данные магазина доставлены.")
-				default:
-					log.Println("Данные магазина отброшены, нет получателя.")
-				}
-
-			case connection.MsgMoneyUpdate:
+				assetLoader.Queue(func() {
+					// Индекс обновляется, так как на сервере могли появиться новые товары
+					if err := assetLoader.RefreshManifest(); err != nil {
+						log.Println("Ошибка получения индекса ресурсов: ", err)
+					}
+					assetLoader.EnsureOrLog(response.assetIDs()...)
+					select {
+					case shopUI.shopDataCh <- response:
+						log.Println("Данные магазина доставлены.")
+					default:
+						log.Println("Данные магазина отброшены, нет получателя.")
+					}
+				})
+
+			case protocol.MsgMoneyUpdate:
 				var money int
 				err := msgpack.Unmarshal(msg.Data, &money)
 				if err != nil {
@@ -444,7 +503,26 @@ func (h *AutoMessageHandler) handleServerMessages(player *Player) {
 				}
 				player.money = money
 
-			case connection.MsgPurchaseReceipt:
+			case protocol.MsgDailyReward:
+				var response DailyRewardInfo
+				err := msgpack.Unmarshal(msg.Data, &response)
+				if err != nil {
+					log.Printf("Ошибка при десериализации данных ежедневной награды: %v", err)
+					break
+				}
+				player.money = response.TotalMoney
+				if !response.Claimed {
+					log.Println("Ежедневная награда уже получена сегодня.")
+					break
+				}
+				select {
+				case dailyRewardUI.rewardCh <- response:
+					log.Println("Данные ежедневной награды доставлены.")
+				default:
+					log.Println("Данные ежедневной награды отброшены, нет получателя.")
+				}
+
+			case protocol.MsgPurchaseReceipt:
 				var purchaseReceipt PurchaseReceipt
 				err := msgpack.Unmarshal(msg.Data, &purchaseReceipt)
 				if err != nil {
@@ -458,35 +536,41 @@ func (h *AutoMessageHandler) handleServerMessages(player *Player) {
 					log.Println("Данные чека покупки отброшены, нет получателя.")
 				}
 
-			case connection.MsgSelectBackground:
+			case protocol.MsgSelectBackground:
 				var assetPath string
 				err := msgpack.Unmarshal(msg.Data, &assetPath)
 				if err != nil {
 					log.Printf("Ошибка при десериализации данных выбора фона: %v", err)
 					break
 				}
-				select {
-				case shopUI.updateBackgroundCh <- assetPath:
-					log.Println("Данные обновления фона доставлены.")
-				default:
-					log.Println("Данные обновления фона отброшены, нет получателя.")
-				}
-
-			case connection.MsgSelectCharacter:
-				var characterData connection.CharacterData
+				assetLoader.Queue(func() {
+					assetLoader.EnsureOrLog(assetPath)
+					select {
+					case shopUI.updateBackgroundCh <- assetPath:
+						log.Println("Данные обновления фона доставлены.")
+					default:
+						log.Println("Данные обновления фона отброшены, нет получателя.")
+					}
+				})
+
+			case protocol.MsgSelectCharacter:
+				var characterData protocol.CharacterData
 				err := msgpack.Unmarshal(msg.Data, &characterData)
 				if err != nil {
 					log.Printf("Ошибка при десериализации данных выбора персонажа: %v", err)
 					break
 				}
-				select {
-				case shopUI.updateCharacterCh <- characterData:
-					log.Println("Данные обновления персонажа доставлены.")
-				default:
-					log.Println("Данные обновления персонажа отброшены, нет получателя.")
-				}
-
-			case connection.MsgListBattles:
+				assetLoader.Queue(func() {
+					assetLoader.EnsureOrLog(characterAssetIDs(characterData)...)
+					select {
+					case shopUI.updateCharacterCh <- characterData:
+						log.Println("Данные обновления персонажа доставлены.")
+					default:
+						log.Println("Данные обновления персонажа отброшены, нет получателя.")
+					}
+				})
+
+			case protocol.MsgListBattles:
 				var response BattleData
 				err := msgpack.Unmarshal(msg.Data, &response)
 				if err != nil {
@@ -500,7 +584,7 @@ func (h *AutoMessageHandler) handleServerMessages(player *Player) {
 					log.Println("Данные списка сражений отброшены, нет получателя.")
 				}
 
-			case connection.MsgActionOpponent:
+			case protocol.MsgActionOpponent:
 				var response ActionResult
 				err := msgpack.Unmarshal(msg.Data, &response)
 				if err != nil {
@@ -511,14 +595,14 @@ func (h *AutoMessageHandler) handleServerMessages(player *Player) {
 					battleUI.currentBattle.opponent.character.ChangeState(response)
 				}
 
-			case connection.MsgHealthUpdate:
-				var response connection.HealthUpdate
+			case protocol.MsgHealthUpdate:
+				var response protocol.HealthUpdate
 				err := msgpack.Unmarshal(msg.Data, &response)
 				if err != nil {
 					log.Printf("Ошибка при десериализации обновления здоровья: %v", err)
 					break
 				}
-				if response.Who == connection.MsgActionCharacter {
+				if response.Who == protocol.MsgActionCharacter {
 					player.character.HealthUpdate(response.Health)
 					fmt.Println("player.character ", player.character.health)
 				} else {
@@ -526,14 +610,17 @@ func (h *AutoMessageHandler) handleServerMessages(player *Player) {
 					fmt.Println("opponent.character ", battleUI.currentBattle.opponent.character.health)
 				}
 
-			case connection.MsgError:
-				var errInf string
-				err := msgpack.Unmarshal(msg.Data, &errInf)
-				if err != nil {
-					log.Printf("Ошибка при десериализации данных ошибки: %v", err)
+			case protocol.MsgError:
+				gameErr := protocol.DecodeError(msg.Data)
+				if !isReply {
+					log.Printf("Ошибка %d: %s", gameErr.Code, gameErr.Message)
 					break
 				}
-				log.Println("Ошибка: ", errInf)
+				log.Printf("Ошибка %d в ответ на запрос %d (тип %d): %s", gameErr.Code, msg.ReqID, reqType, gameErr.Message)
+				// Ошибки заявки в друзья показываются под полем ввода
+				if reqType == protocol.MsgAddFriend {
+					friendsUI.addFriendResponse = gameErr.Error()
+				}
 			default:
 				log.Println("Неизвестный код типа сообщения: ", msg.Type)
 			}
@@ -1,7 +1,8 @@
 package main
 
 import (
-	"codeClient/connection"
+	"codeClient/protocol"
+	"codeClient/resource"
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
@@ -24,7 +25,7 @@ type Opponent struct {
 	character *Character
 }
 
-func CreatePlayer(data *connection.UserData) *Player {
+func CreatePlayer(data *protocol.UserData) *Player {
 	var player Player
 	player.login = data.Login
 	player.publicID = data.PublicID
@@ -41,7 +42,7 @@ func (p *Player) LoadBackground(backgroundPath string) {
 	if p.background.ID > 0 {
 		p.UnloadBackground()
 	}
-	p.background = rl.LoadTexture(currentDirectory + backgroundPath)
+	p.background = resource.LoadTexture(backgroundPath)
 }
 
 func (p *Player) UnloadBackground() {
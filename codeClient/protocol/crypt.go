@@ -0,0 +1,67 @@
+package protocol
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+	"io"
+	"time"
+)
+
+const (
+	ProtocolVersion  = 2                         // Версия протокола, должна совпадать с сервером
+	KeyEnvName       = "GAME_KEY"                // Переменная окружения с общим ключом шифрования
+	keySalt          = "duel-of-blades-kcp-salt" // Соль для получения ключа AES, как на сервере
+	keyIterations    = 4096
+	sessionKeyInfo   = "duel-of-blades-session" // Назначение ключа сессии для HKDF, как на сервере
+	HandshakeTimeout = 5 * time.Second
+)
+
+var (
+	ErrKeyMismatch = errors.New("ключ шифрования не совпадает с сервером")
+	ErrNoResponse  = errors.New("сервер не ответил на рукопожатие: UDP заблокирован, сервер недоступен или ключ шифрования не совпадает")
+)
+
+// Данные рукопожатия
+type Handshake struct {
+	Version int        `msgpack:"v"`
+	Profile string     `msgpack:"p"`           // Сетевой профиль клиента
+	Tuning  *KCPTuning `msgpack:"k,omitempty"` // Параметры KCP, которые применил сервер
+	Public  []byte     `msgpack:"pk"`          // Одноразовый открытый ключ X25519 для ключа сессии
+}
+
+// Получает ключ AES-256 из общего ключа
+func DeriveKey(sharedKey string) ([]byte, error) {
+	if sharedKey == "" {
+		return nil, errors.New("не задан ключ шифрования: укажите флаг -key или переменную " + KeyEnvName)
+	}
+	return pbkdf2.Key([]byte(sharedKey), []byte(keySalt), keyIterations, 32, sha256.New), nil
+}
+
+// Одноразовый ключ X25519 клиента для рукопожатия
+func NewHandshakeKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// Ключ сессии AES-256 из обмена X25519 с сервером. Ключ из DeriveKey служит солью, поэтому
+// ключ сессии зависит и от общего ключа, и от одноразовых ключей обеих сторон
+func SessionKey(key []byte, private *ecdh.PrivateKey, serverPublic []byte) ([]byte, error) {
+	peer, err := ecdh.X25519().NewPublicKey(serverPublic)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := private.ECDH(peer)
+	if err != nil {
+		return nil, err
+	}
+	info := append([]byte(sessionKeyInfo), private.PublicKey().Bytes()...)
+	info = append(info, serverPublic...)
+	sessionKey := make([]byte, 32)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, secret, key, info), sessionKey); err != nil {
+		return nil, err
+	}
+	return sessionKey, nil
+}
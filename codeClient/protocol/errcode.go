@@ -0,0 +1,178 @@
+package protocol
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+	"log"
+	"strings"
+)
+
+// Код ошибки протокола, совпадает с сервером
+type ErrorCode uint16
+
+// Общие ошибки и ошибки протокола
+const (
+	ErrCodeInternal          ErrorCode = 100
+	ErrCodeBadRequest        ErrorCode = 101
+	ErrCodeUnknownMessage    ErrorCode = 102
+	ErrCodeNotAuthorized     ErrorCode = 103
+	ErrCodeAlreadyAuthorized ErrorCode = 104
+	ErrCodeInBattle          ErrorCode = 105
+	ErrCodeVersion           ErrorCode = 106
+	ErrCodeNetProfile        ErrorCode = 107
+	ErrCodeBadFrame          ErrorCode = 108
+	ErrCodeTooManyBadFrames  ErrorCode = 109
+	ErrCodeRateLimited       ErrorCode = 110
+	ErrCodeFlood             ErrorCode = 111
+	ErrCodeServerShutdown    ErrorCode = 112
+	ErrCodeKicked            ErrorCode = 113
+)
+
+// Регистрация и авторизация
+const (
+	ErrCodeEmptyFields        ErrorCode = 200
+	ErrCodeLoginTooLong       ErrorCode = 201
+	ErrCodeNameTooLong        ErrorCode = 202
+	ErrCodePasswordTooLong    ErrorCode = 203
+	ErrCodeLoginChars         ErrorCode = 204
+	ErrCodeNamePasswordChars  ErrorCode = 205
+	ErrCodePasswordsMismatch  ErrorCode = 206
+	ErrCodeLoginExists        ErrorCode = 207
+	ErrCodeUserNotFound       ErrorCode = 208 // Не отправляется, вместо неё ErrCodeBadCredentials
+	ErrCodeUserBlocked        ErrorCode = 209
+	ErrCodeWrongPassword      ErrorCode = 210 // Не отправляется, вместо неё ErrCodeBadCredentials
+	ErrCodePlayerNotFound     ErrorCode = 211
+	ErrCodeCreateUserFailed   ErrorCode = 212
+	ErrCodeCreatePlayerFailed ErrorCode = 213
+	ErrCodeBadCredentials     ErrorCode = 214
+	ErrCodeLoginLocked        ErrorCode = 215
+	ErrCodeUserBannedUntil    ErrorCode = 216
+)
+
+// Друзья и дружеские бои
+const (
+	ErrCodeFriendCodeInvalid   ErrorCode = 300
+	ErrCodeFriendRepeatRequest ErrorCode = 301
+	ErrCodeFriendOffline       ErrorCode = 302
+	ErrCodeFriendInBattle      ErrorCode = 303
+	ErrCodeChallengeNotSent    ErrorCode = 304
+	ErrCodeChallengeCancelled  ErrorCode = 305
+	ErrCodeChallengeInactive   ErrorCode = 306
+	ErrCodeFriendLeft          ErrorCode = 307
+)
+
+// Бой
+const (
+	ErrCodeAlreadyWaiting   ErrorCode = 400
+	ErrCodeBattleNotActive  ErrorCode = 401
+	ErrCodeUnknownCommand   ErrorCode = 402
+	ErrCodeCharacterMissing ErrorCode = 403
+	ErrCodeCheatDetected    ErrorCode = 404
+)
+
+// Магазин и ресурсы
+const (
+	ErrCodeUnknownShopAction       ErrorCode = 500
+	ErrCodeUnknownProduct          ErrorCode = 501
+	ErrCodeBackgroundNotFound      ErrorCode = 502
+	ErrCodeBackgroundAlreadyBought ErrorCode = 503
+	ErrCodeBackgroundNotBought     ErrorCode = 504
+	ErrCodeCharacterNotFound       ErrorCode = 505
+	ErrCodeCharacterAlreadyBought  ErrorCode = 506
+	ErrCodeCharacterNotBought      ErrorCode = 507
+	ErrCodeNotEnoughMoney          ErrorCode = 508
+	ErrCodeAssetNotFound           ErrorCode = 509
+)
+
+// Тексты сообщений по ключу. {имя} заменяется значением из Details.
+// Для неизвестного ключа показывается текст сервера
+var Messages = map[string]string{
+	"error.internal":            "Внутренняя ошибка сервера",
+	"error.bad_request":         "Сервер не смог разобрать запрос",
+	"error.unknown_message":     "Сервер не поддерживает запрос {type}",
+	"error.not_authorized":      "Вы не авторизованы!",
+	"error.already_authorized":  "Вы уже авторизованы!",
+	"error.in_battle":           "Вы уже в поиске боя!",
+	"error.version":             "Версия клиента не поддерживается, обновите игру!",
+	"error.net_profile":         "Профиль сети {profile} не совпадает с портом сервера ({expected})!",
+	"error.bad_frame":           "Сообщение повреждено и пропущено сервером",
+	"error.too_many_bad_frames": "Слишком много повреждённых сообщений, соединение закрыто",
+	"error.rate_limited":        "Слишком много запросов, подождите немного",
+	"error.flood":               "Соединение закрыто из-за слишком частых запросов",
+	"error.server_shutdown":     "Сервер остановлен на обслуживание, подключитесь позже",
+	"error.kicked":              "Вы отключены администратором: {reason}",
+
+	"auth.empty_fields":         "Поля не могут быть пустыми",
+	"auth.login_too_long":       "Превышена длина логина",
+	"auth.name_too_long":        "Превышена длина имени",
+	"auth.password_too_long":    "Превышена длина пароля",
+	"auth.login_chars":          "Логин должен содержать символы: {allowed}",
+	"auth.name_password_chars":  "Допустимые символы для имени и пароля: {allowed}",
+	"auth.passwords_mismatch":   "Пароли не совпадают",
+	"auth.login_exists":         "Логин уже существует",
+	"auth.user_not_found":       "Пользователь '{login}' не найден",
+	"auth.user_blocked":         "Пользователь '{login}' заблокирован: {reason}",
+	"auth.wrong_password":       "Неверно введен пароль",
+	"auth.player_not_found":     "Игровые данные '{login}' не найдены",
+	"auth.create_user_failed":   "Не удалось создать пользователя",
+	"auth.create_player_failed": "Не удалось создать профиль игрока",
+	"auth.bad_credentials":      "Неверный логин или пароль",
+	"auth.login_locked":         "Слишком много неудачных попыток входа, повторите через {seconds} с",
+	"auth.user_banned_until":    "Пользователь '{login}' заблокирован до {until}: {reason}",
+
+	"friends.code_invalid":        "Неверный код",
+	"friends.repeat_request":      "Повторная заявка",
+	"friends.offline":             "Игрок {friend} не в сети",
+	"friends.in_battle":           "Игрок {friend} уже в бою, попробуйте позже",
+	"friends.challenge_not_sent":  "Не удалось отправить приглашение",
+	"friends.challenge_cancelled": "Друг отменил приглашение на бой, попробуйте снова",
+	"friends.challenge_inactive":  "Приглашение на бой больше не активно, игрок уже в бою",
+	"friends.left":                "Игрок вышел из игры",
+
+	"battle.already_waiting":   "Вы в ожидании боя",
+	"battle.not_active":        "Бой еще не начался или уже закончился!",
+	"battle.unknown_command":   "Неизвестная команда: {command}",
+	"battle.character_missing": "Персонаж не найден",
+	"battle.cheat_detected":    "Соединение закрыто: обнаружены недопустимые действия",
+
+	"shop.unknown_action":            "Неизвестное действие магазина",
+	"shop.unknown_product":           "Неизвестный товар",
+	"shop.background_not_found":      "Фон не найден",
+	"shop.background_already_bought": "Фон уже куплен",
+	"shop.background_not_bought":     "Фон не куплен",
+	"shop.character_not_found":       "Персонаж не найден",
+	"shop.character_already_bought":  "Персонаж уже куплен",
+	"shop.character_not_bought":      "Персонаж не куплен",
+	"shop.not_enough_money":          "Недостаточно денег для покупки",
+	"content.asset_not_found":        "Ресурс не найден!",
+}
+
+// Ошибка от сервера из MsgError
+type GameError struct {
+	Code    ErrorCode         `msgpack:"c"`
+	Key     string            `msgpack:"k"`           // Ключ сообщения для локализации
+	Details map[string]string `msgpack:"d,omitempty"` // Значения для подстановки в сообщение
+	Message string            `msgpack:"m"`           // Текст сервера на случай неизвестного ключа
+}
+
+// Текст для игрока на языке клиента
+func (e *GameError) Error() string {
+	text, ok := Messages[e.Key]
+	if !ok {
+		return e.Message
+	}
+	replace := make([]string, 0, 2*len(e.Details))
+	for name, value := range e.Details {
+		replace = append(replace, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(replace...).Replace(text)
+}
+
+// Разбирает данные MsgError. Неразборчивый ответ считается внутренней ошибкой сервера
+func DecodeError(data []byte) *GameError {
+	gameErr := new(GameError)
+	if err := msgpack.Unmarshal(data, gameErr); err != nil {
+		log.Printf("Ошибка при десериализации данных ошибки: %v", err)
+		return &GameError{Code: ErrCodeInternal, Key: "error.internal", Message: "внутренняя ошибка сервера"}
+	}
+	return gameErr
+}
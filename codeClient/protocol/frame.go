@@ -0,0 +1,48 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Кадр сообщения, как на сервере: длина тела, порядковый номер, ID запроса (uint32, big endian), тело - Message в msgpack
+const (
+	frameHeaderSize = 12
+	maxFrameSize    = 1 << 20
+)
+
+var ErrBadFrame = errors.New("повреждённое сообщение пропущено")
+
+// Читает кадр целиком
+func ReadFrame(r io.Reader) (seq, reqID uint32, body []byte, err error) {
+	var buf [frameHeaderSize]byte
+	if _, err = io.ReadFull(r, buf[:]); err != nil {
+		return 0, 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(buf[0:4])
+	seq = binary.BigEndian.Uint32(buf[4:8])
+	reqID = binary.BigEndian.Uint32(buf[8:12])
+	if size > maxFrameSize {
+		return 0, 0, nil, fmt.Errorf("размер кадра %d превышает допустимый", size)
+	}
+
+	body = make([]byte, size)
+	if _, err = io.ReadFull(r, body); err != nil {
+		return 0, 0, nil, err
+	}
+	return seq, reqID, body, nil
+}
+
+// Собирает кадр, ID запроса совпадает с порядковым номером
+func EncodeFrame(seq uint32, body []byte) ([]byte, error) {
+	if len(body) > maxFrameSize {
+		return nil, fmt.Errorf("размер кадра %d превышает допустимый", len(body))
+	}
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+len(body))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(frame[4:8], seq)
+	binary.BigEndian.PutUint32(frame[8:12], seq)
+	return append(frame, body...), nil
+}
@@ -0,0 +1,107 @@
+package protocol
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type MessageType uint8
+
+const (
+	MsgNone MessageType = iota
+	MsgPing
+	MsgPong
+	MsgExit
+	MsgSuccess
+	MsgError
+	MsgAuthorization
+	MsgRegistration
+	MsgActionCharacter
+	MsgActionOpponent
+	MsgHealthUpdate
+	MsgFriendsData
+	MsgAddFriend
+	MsgAcceptFriendship
+	MsgDeclineFriendship
+	MsgChallengeToFight
+	MsgAcceptChallengeToFight
+	MsgRefuseChallengeToFight
+	MsgRemoveFriend
+	MsgBattle
+	MsgBattleRanked
+	MsgExitBattle
+	MsgWaitingBattle
+	MsgStartBattleInfo
+	MsgReadyBattle
+	MsgEndBattle
+	MsgListBattles
+	MsgShopData
+	MsgShopAction
+	MsgMoneyUpdate
+	MsgSelectBackground
+	MsgSelectCharacter
+	MsgPurchaseReceipt
+	MsgAssetManifest
+	MsgAssetRequest
+	MsgAssetChunk
+	MsgHandshake
+	MsgDailyReward
+)
+
+// Содержит данные авторизованного пользователя
+type UserData struct {
+	Login                string        `msgpack:"l"`
+	PublicID             string        `msgpack:"id"`
+	Name                 string        `msgpack:"n"`
+	Level                int           `msgpack:"lv"`
+	Money                int           `msgpack:"m"`
+	Rank                 int           `msgpack:"r"`
+	ActiveBackgroundPath string        `msgpack:"b"`
+	ActiveCharacter      CharacterData `msgpack:"c"`
+}
+
+// Содержит данные персонажа
+type CharacterData struct {
+	Name        string                     `msgpack:"n"`
+	Description string                     `msgpack:"d"`
+	Health      int                        `msgpack:"h"`
+	Damage      int                        `msgpack:"dm"`
+	Cost        int                        `msgpack:"c"`
+	HCharacter  int                        `msgpack:"hc"` // Высота персонажа без оружия
+	XStart      int                        `msgpack:"xs"`
+	YStart      int                        `msgpack:"ys"`
+	Assets      map[string]AssetsCharacter `msgpack:"as"`
+}
+
+// Содержит данные изображений персонажа
+type AssetsCharacter struct {
+	AnimationType string  `msgpack:"at"`
+	FrameCount    int     `msgpack:"fc"`
+	BaseHeight    int     `msgpack:"bh"`
+	BaseWidth     int     `msgpack:"bw"`
+	FrameRate     float32 `msgpack:"fr"`
+	AssetPath     string  `msgpack:"ap"`
+}
+
+// Содержит данные об изменении здоровья
+type HealthUpdate struct {
+	Who    MessageType `msgpack:"w"`
+	Health int         `msgpack:"h"`
+}
+
+// Универсальное сообщения для связи с сервером
+type Message struct {
+	Type  MessageType `msgpack:"t"`
+	Data  []byte      `msgpack:"d"`
+	Seq   uint32      `msgpack:"-"` // Из заголовка кадра
+	ReqID uint32      `msgpack:"-"` // ID запроса клиента, на который отвечает сервер
+}
+
+// Функция для превращения сообщения в массив байт
+func CreateMessage(mesType MessageType, data interface{}) ([]byte, error) {
+	dataBytes, err := msgpack.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	message := Message{Type: mesType, Data: dataBytes}
+	return msgpack.Marshal(message)
+}
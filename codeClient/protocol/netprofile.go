@@ -0,0 +1,47 @@
+package protocol
+
+import (
+	"fmt"
+	"github.com/xtaci/kcp-go/v5"
+)
+
+// Параметры KCP, сервер присылает свои значения при рукопожатии
+type KCPTuning struct {
+	NoDelay      int `msgpack:"nd"`
+	Interval     int `msgpack:"i"` // Интервал внутреннего цикла KCP в мс
+	Resend       int `msgpack:"r"`
+	NoCongestion int `msgpack:"nc"`
+	SndWnd       int `msgpack:"sw"`
+	RcvWnd       int `msgpack:"rw"`
+}
+
+// Сетевой профиль, Port и FEC должны совпадать с сервером
+type NetProfile struct {
+	Name         string
+	Port         int
+	DataShards   int
+	ParityShards int
+	Tuning       KCPTuning // Начальные значения до ответа сервера
+}
+
+var NetProfiles = map[string]NetProfile{
+	"lan":    {Name: "lan", Port: 7778, Tuning: KCPTuning{NoDelay: 1, Interval: 10, Resend: 2, NoCongestion: 1, SndWnd: 256, RcvWnd: 256}},
+	"normal": {Name: "normal", Port: 7777, DataShards: 10, ParityShards: 3, Tuning: KCPTuning{NoDelay: 1, Interval: 10, Resend: 2, NoCongestion: 1, SndWnd: 128, RcvWnd: 128}},
+	"lossy":  {Name: "lossy", Port: 7779, DataShards: 10, ParityShards: 6, Tuning: KCPTuning{NoDelay: 1, Interval: 20, Resend: 1, NoCongestion: 1, SndWnd: 512, RcvWnd: 512}},
+}
+
+// Ищет сетевой профиль по имени
+func LookupNetProfile(name string) (NetProfile, error) {
+	p, ok := NetProfiles[name]
+	if !ok {
+		return NetProfile{}, fmt.Errorf("неизвестный сетевой профиль %s, доступны: lan, normal, lossy", name)
+	}
+	return p, nil
+}
+
+// Применяет параметры KCP к сессии
+func (t KCPTuning) Apply(session *kcp.UDPSession) {
+	session.SetNoDelay(t.NoDelay, t.Interval, t.Resend, t.NoCongestion)
+	session.SetWindowSize(t.SndWnd, t.RcvWnd)
+	session.SetACKNoDelay(true) // Без отложенных ACK
+}
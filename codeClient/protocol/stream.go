@@ -0,0 +1,115 @@
+package protocol
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"golang.org/x/net/websocket"
+	"io"
+	"net"
+)
+
+const (
+	TCPPort        = 7780
+	WSPort         = 7781
+	maxSecureFrame = 1 << 20 // Наибольший размер зашифрованного кадра, как на сервере
+)
+
+var errSecureFrame = fmt.Errorf("ошибка расшифровки кадра: %w", ErrKeyMismatch)
+
+// Открывает WebSocket сервера по адресу host:port без шифрования, данные передаются двоичными кадрами
+func DialWebSocket(addr string) (net.Conn, error) {
+	config, err := websocket.NewConfig("ws://"+addr+"/ws", "http://"+addr+"/")
+	if err != nil {
+		return nil, err
+	}
+	config.Dialer = &net.Dialer{Timeout: HandshakeTimeout}
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	ws.PayloadType = websocket.BinaryFrame
+	return ws, nil
+}
+
+// Зашифрованный поток поверх TCP и WebSocket, после рукопожатия и поверх KCP. Формат кадра как на сервере:
+// длина uint32, nonce, данные AES-GCM. Запись выполняется под мьютексом соединения
+type secureConn struct {
+	net.Conn
+	aead    cipher.AEAD
+	readBuf []byte
+}
+
+// Оборачивает поток шифрованием AES-GCM с ключом из DeriveKey
+func NewSecureConn(conn net.Conn, key []byte) (net.Conn, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &secureConn{Conn: conn, aead: aead}, nil
+}
+
+// Переводит соединение на ключ сессии после рукопожатия: поток TCP и WebSocket шифруется
+// новым ключом вместо общего, сессия KCP дополнительно оборачивается шифрованием потока
+func SecureSession(conn net.Conn, sessionKey []byte) (net.Conn, error) {
+	if secure, ok := conn.(*secureConn); ok {
+		conn = secure.Conn
+	}
+	return NewSecureConn(conn, sessionKey)
+}
+
+func (c *secureConn) Read(p []byte) (int, error) {
+	if len(c.readBuf) == 0 {
+		var header [4]byte
+		if _, err := io.ReadFull(c.Conn, header[:]); err != nil {
+			return 0, err
+		}
+		size := int(binary.BigEndian.Uint32(header[:]))
+		if size < c.aead.NonceSize()+c.aead.Overhead() || size > maxSecureFrame {
+			return 0, errSecureFrame
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(c.Conn, frame); err != nil {
+			return 0, err
+		}
+		nonce, data := frame[:c.aead.NonceSize()], frame[c.aead.NonceSize():]
+		plain, err := c.aead.Open(data[:0], nonce, data, header[:])
+		if err != nil {
+			return 0, errSecureFrame
+		}
+		c.readBuf = plain
+	}
+	n := copy(p, c.readBuf)
+	c.readBuf = c.readBuf[n:]
+	return n, nil
+}
+
+func (c *secureConn) Write(p []byte) (int, error) {
+	maxPlain := maxSecureFrame - c.aead.NonceSize() - c.aead.Overhead()
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxPlain {
+			chunk = chunk[:maxPlain]
+		}
+		nonceSize := c.aead.NonceSize()
+		frame := make([]byte, 4+nonceSize, 4+nonceSize+len(chunk)+c.aead.Overhead())
+		binary.BigEndian.PutUint32(frame, uint32(nonceSize+len(chunk)+c.aead.Overhead()))
+		if _, err := rand.Read(frame[4:]); err != nil {
+			return written, err
+		}
+		frame = c.aead.Seal(frame, frame[4:4+nonceSize], chunk, frame[:4])
+		if _, err := c.Conn.Write(frame); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
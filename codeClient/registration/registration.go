@@ -2,12 +2,12 @@ package registration
 
 import (
 	"codeClient/connection"
+	"codeClient/protocol"
+	"codeClient/resource"
 	"fmt"
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"math"
 	"net"
-	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -21,7 +21,7 @@ type regData struct {
 	ConfirmPassword string `msgpack:"cp"`
 }
 
-func Registration() (net.Conn, *connection.UserData, error) {
+func Registration() (net.Conn, *protocol.UserData, error) {
 	rl.SetWindowTitle("Регистрация")
 
 	// Базовые размеры окна относительно которых масштабируется
@@ -29,32 +29,26 @@ func Registration() (net.Conn, *connection.UserData, error) {
 	const baseHeight = 540
 	const baseFontSize = 20
 
-	// Путь к текущей директории
-	currentDir, err := os.Getwd()
-	if err != nil {
-		fmt.Println("Ошибка при получении текущей директории:", err)
-		return nil, nil, fmt.Errorf("Ошибка при получении текущей директории:", err)
-	}
-
-	// УБРАТЬ ЕСЛИ EXE ТАМ ЖЕ ГДЕ И ПАПКА RESOURCES
-	currentDir = filepath.Dir(currentDir)
-
 	// Изображение фона
-	backgroundTexture := rl.LoadTexture(currentDir + "\\resources\\UI\\Registration\\Background\\LoginRegBack.png")
-	backgroundRegTexture := rl.LoadTexture(currentDir + "\\resources\\UI\\Registration\\Background\\RegTexture.png")
-	regReleasedTexture := rl.LoadTexture(currentDir + "\\resources\\UI\\Registration\\Button\\Registration\\RegistrationReleased.png")
-	regPressedTexture := rl.LoadTexture(currentDir + "\\resources\\UI\\Registration\\Button\\Registration\\RegistrationPressed.png")
-	entryPressedTexture := rl.LoadTexture(currentDir + "\\resources\\UI\\Registration\\Button\\Entry\\EntryPressed.png")
+	backgroundTexture := resource.LoadRequiredTexture("UI/Registration/Background/LoginRegBack.png")
+	backgroundRegTexture := resource.LoadRequiredTexture("UI/Registration/Background/RegTexture.png")
+	regReleasedTexture := resource.LoadRequiredTexture("UI/Registration/Button/Registration/RegistrationReleased.png")
+	regPressedTexture := resource.LoadRequiredTexture("UI/Registration/Button/Registration/RegistrationPressed.png")
+	entryPressedTexture := resource.LoadRequiredTexture("UI/Registration/Button/Entry/EntryPressed.png")
 	defer rl.UnloadTexture(backgroundTexture)
 	defer rl.UnloadTexture(backgroundRegTexture)
 	defer rl.UnloadTexture(regReleasedTexture)
 	defer rl.UnloadTexture(regPressedTexture)
 	defer rl.UnloadTexture(entryPressedTexture)
+	if err := resource.CheckRequired(); err != nil {
+		fmt.Println("Ошибка загрузки ресурсов: ", err)
+		return nil, nil, err
+	}
 
 	// Путь к шрифту
-	fontPath := currentDir + "\\resources\\fonts\\PressStart2P-Regular.ttf"
+	fontPath := "Fonts/PressStart2P-Regular.ttf"
 	runes := []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyzАБВГДЕЁЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯабвгдеёжзийклмнопрстуфхцчшщъыьэюя0123456789!@#№$%`~^&*()-=_+[]{};:'\",.<>?/|\\ ")
-	font := rl.LoadFontEx(fontPath, 32, runes, int32(len(runes)))
+	font := resource.LoadFont(fontPath, 32, runes)
 	if font.Texture.ID == 0 {
 		fmt.Println("Ошибка загрузки шрифта!")
 		return nil, nil, fmt.Errorf("Ошибка загрузки шрифта!")
@@ -167,7 +161,7 @@ func Registration() (net.Conn, *connection.UserData, error) {
 					errorText = "Ошибка: Пароли не совпадают!"
 				} else {
 					isProcessing = true
-					regMes, err := connection.CreateMessage(connection.MsgRegistration, regData{loginText, nameText, passwordText, confirmPasswordText})
+					regMes, err := protocol.CreateMessage(protocol.MsgRegistration, regData{loginText, nameText, passwordText, confirmPasswordText})
 					if err == nil {
 						errorText = "Получение ответа..."
 						go func(regMes []byte) {
@@ -0,0 +1,159 @@
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const cacheDirName = "DuelOfBlades" // Папка кэша скачанных ресурсов в пользовательском кэше ОС
+
+// Ресурсы сервера и кэш скачанных файлов.
+// Скачанные файлы хранятся под именем хеша, поэтому разные версии одного ресурса не пересекаются.
+var (
+	remote      = make(map[string]string) // Идентификатор ресурса -> хеш версии на сервере
+	localHashes = make(map[string]string) // Хеши локальных ресурсов, считаются один раз
+	cacheDir    string
+	cacheMutex  sync.RWMutex
+)
+
+// Папка кэша скачанных ресурсов, создаётся при первом обращении
+func getCacheDir() (string, error) {
+	if cacheDir != "" {
+		return cacheDir, nil
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, cacheDirName, "assets")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	cacheDir = dir
+	return cacheDir, nil
+}
+
+// Запоминает список ресурсов сервера с хешами
+func SetManifest(manifest map[string]string) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	remote = make(map[string]string, len(manifest))
+	for id, hash := range manifest {
+		if id = Normalize(id); id != "" {
+			remote[id] = hash
+		}
+	}
+}
+
+// Хеш в кэше - это SHA-256 в нижнем регистре. Другие строки от сервера
+// в путь не подставляются, чтобы не выйти за папку кэша
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Хеш локального ресурса с запоминанием
+func localHash(id string) (string, bool) {
+	if hash, ok := localHashes[id]; ok {
+		return hash, hash != ""
+	}
+	hash, ok := defaultLocator.Hash(id)
+	localHashes[id] = hash
+	return hash, ok
+}
+
+// Путь к скачанной версии ресурса, если она отличается от локальной и уже есть в кэше
+func cachedPath(id string) string {
+	id = Normalize(id)
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	hash, ok := remote[id]
+	if !ok || !validHash(hash) {
+		return ""
+	}
+	if local, ok := localHash(id); ok && local == hash {
+		return ""
+	}
+	dir, err := getCacheDir()
+	if err != nil {
+		return ""
+	}
+	name := filepath.Join(dir, hash)
+	if _, err = os.Stat(name); err != nil {
+		return ""
+	}
+	return name
+}
+
+// Возвращает хеши ресурсов, которые нужно скачать с сервера: их нет локально, в кэше или версия устарела
+func Missing(ids ...string) []string {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	dir, _ := getCacheDir()
+	seen := make(map[string]bool)
+	var hashes []string
+	for _, id := range ids {
+		id = Normalize(id)
+		hash, ok := remote[id]
+		if !ok || !validHash(hash) || seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		if local, ok := localHash(id); ok && local == hash {
+			continue
+		}
+		if dir != "" {
+			if _, err := os.Stat(filepath.Join(dir, hash)); err == nil {
+				continue
+			}
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+// Проверяет хеш скачанного файла и сохраняет его в кэш
+func StoreCached(hash string, data []byte) error {
+	if !validHash(hash) {
+		return fmt.Errorf("ресурс %q: некорректный хеш", hash)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != hash {
+		return fmt.Errorf("ресурс %s: содержимое не совпадает с хешем", hash)
+	}
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	dir, err := getCacheDir()
+	if err != nil {
+		return fmt.Errorf("папка кэша недоступна: %v", err)
+	}
+
+	// Запись через временный файл, чтобы оборванная запись не попала в кэш
+	tmp, err := os.CreateTemp(dir, hash+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, hash))
+}
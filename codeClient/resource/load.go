@@ -0,0 +1,66 @@
+package resource
+
+import (
+	"fmt"
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"path"
+	"strings"
+)
+
+// Загружает текстуру из кэша скачанных ресурсов, папки ресурсов или архива
+func LoadTexture(id string) rl.Texture2D {
+	if name := cachedPath(id); name != "" {
+		return rl.LoadTexture(name)
+	}
+	if !defaultLocator.Packed() {
+		return rl.LoadTexture(defaultLocator.Path(id))
+	}
+
+	data, err := ReadFile(id)
+	if err != nil {
+		fmt.Println("Ошибка загрузки текстуры: ", err)
+		return rl.Texture2D{}
+	}
+	image := rl.LoadImageFromMemory(fileType(id), data, int32(len(data)))
+	defer rl.UnloadImage(image)
+	return rl.LoadTextureFromImage(image)
+}
+
+// Текстуры интерфейса, которые не удалось загрузить
+var failedRequired []string
+
+// Загружает текстуру интерфейса. Без неё клиент не запускается, ошибки проверяет CheckRequired
+func LoadRequiredTexture(id string) rl.Texture2D {
+	texture := LoadTexture(id)
+	if texture.ID == 0 {
+		failedRequired = append(failedRequired, id)
+	}
+	return texture
+}
+
+// Возвращает ошибку со списком текстур интерфейса, которые не удалось загрузить
+func CheckRequired() error {
+	if len(failedRequired) > 0 {
+		return fmt.Errorf("не удалось загрузить ресурсы: %s", strings.Join(failedRequired, ", "))
+	}
+	return nil
+}
+
+// Загружает шрифт из папки ресурсов или из архива
+func LoadFont(id string, fontSize int32, runes []rune) rl.Font {
+	if !defaultLocator.Packed() {
+		return rl.LoadFontEx(defaultLocator.Path(id), fontSize, runes, int32(len(runes)))
+	}
+
+	data, err := defaultLocator.ReadFile(id)
+	if err != nil {
+		fmt.Println("Ошибка загрузки шрифта: ", err)
+		return rl.Font{}
+	}
+	return rl.LoadFontFromMemory(fileType(id), data, fontSize, runes)
+}
+
+// Расширение файла в формате raylib: ".png"
+func fileType(id string) string {
+	return strings.ToLower(path.Ext(id))
+}
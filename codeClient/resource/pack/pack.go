@@ -0,0 +1,238 @@
+// Формат архива ресурсов клиента.
+//
+// Архив состоит из заголовка, индекса и данных:
+//
+//	magic     [8]byte  "DKPACK01"
+//	indexLen  uint32   длина индекса (little endian)
+//	indexHash [32]byte SHA-256 индекса
+//	index     []Entry  в msgpack
+//	data      содержимое файлов подряд
+//
+// Для каждого файла в индексе хранится SHA-256 содержимого,
+// поэтому повреждённые и подменённые файлы обнаруживаются при проверке.
+package pack
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const magic = "DKPACK01"
+
+const headerSize = len(magic) + 4 + sha256.Size
+
+var (
+	ErrNotFound  = errors.New("ресурс отсутствует в архиве")
+	ErrCorrupted = errors.New("содержимое ресурса не совпадает с хешем")
+)
+
+// Запись индекса архива
+type Entry struct {
+	ID     string `msgpack:"i"` // Идентификатор ресурса, например "UI/Menu/Background/Menu.png"
+	Offset int64  `msgpack:"o"` // Смещение относительно начала данных
+	Size   int64  `msgpack:"s"`
+	Hash   []byte `msgpack:"h"` // SHA-256 содержимого
+}
+
+// Открытый архив ресурсов
+type Pack struct {
+	file       *os.File
+	dataOffset int64
+	entries    map[string]*Entry
+}
+
+// Открывает архив и проверяет целостность индекса
+func Open(name string) (*Pack, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := readIndex(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return p, nil
+}
+
+func readIndex(f *os.File) (*Pack, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, fmt.Errorf("ошибка чтения заголовка: %v", err)
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, errors.New("файл не является архивом ресурсов")
+	}
+	indexLen := binary.LittleEndian.Uint32(header[len(magic):])
+	indexHash := header[len(magic)+4:]
+
+	index := make([]byte, indexLen)
+	if _, err := io.ReadFull(f, index); err != nil {
+		return nil, fmt.Errorf("ошибка чтения индекса: %v", err)
+	}
+	sum := sha256.Sum256(index)
+	if !bytes.Equal(sum[:], indexHash) {
+		return nil, errors.New("индекс архива повреждён")
+	}
+
+	var entries []Entry
+	if err := msgpack.Unmarshal(index, &entries); err != nil {
+		return nil, fmt.Errorf("ошибка разбора индекса: %v", err)
+	}
+
+	p := &Pack{
+		file:       f,
+		dataOffset: int64(headerSize) + int64(indexLen),
+		entries:    make(map[string]*Entry, len(entries)),
+	}
+	for i := range entries {
+		p.entries[entries[i].ID] = &entries[i]
+	}
+	return p, nil
+}
+
+// Закрывает архив
+func (p *Pack) Close() error {
+	return p.file.Close()
+}
+
+// Список идентификаторов ресурсов в архиве
+func (p *Pack) IDs() []string {
+	ids := make([]string, 0, len(p.entries))
+	for id := range p.entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Проверяет наличие ресурса
+func (p *Pack) Has(id string) bool {
+	_, ok := p.entries[id]
+	return ok
+}
+
+// SHA-256 ресурса в hex
+func (p *Pack) Hash(id string) (string, bool) {
+	entry, ok := p.entries[id]
+	if !ok {
+		return "", false
+	}
+	return hex.EncodeToString(entry.Hash), true
+}
+
+// Читает ресурс и проверяет его хеш
+func (p *Pack) ReadFile(id string) ([]byte, error) {
+	entry, ok := p.entries[id]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", id, ErrNotFound)
+	}
+
+	data := make([]byte, entry.Size)
+	if _, err := p.file.ReadAt(data, p.dataOffset+entry.Offset); err != nil {
+		return nil, fmt.Errorf("%s: %w", id, ErrNotFound)
+	}
+	sum := sha256.Sum256(data)
+	if !bytes.Equal(sum[:], entry.Hash) {
+		return nil, fmt.Errorf("%s: %w", id, ErrCorrupted)
+	}
+	return data, nil
+}
+
+// Проверяет все ресурсы архива, возвращает ошибку со списком повреждённых
+func (p *Pack) Verify() error {
+	var broken []string
+	for _, id := range p.IDs() {
+		if _, err := p.ReadFile(id); err != nil {
+			broken = append(broken, err.Error())
+		}
+	}
+	if len(broken) > 0 {
+		return fmt.Errorf("повреждено ресурсов: %d\n%s", len(broken), strings.Join(broken, "\n"))
+	}
+	return nil
+}
+
+// Собирает архив из папки ресурсов
+func Build(root string, w io.Writer) (int, error) {
+	var files []string
+	err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(files)
+
+	// Первый проход: смещения и хеши для индекса
+	entries := make([]Entry, 0, len(files))
+	var offset int64
+	for _, name := range files {
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return 0, err
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return 0, err
+		}
+		sum := sha256.Sum256(data)
+		entries = append(entries, Entry{
+			ID:     path.Clean(filepath.ToSlash(rel)),
+			Offset: offset,
+			Size:   int64(len(data)),
+			Hash:   sum[:],
+		})
+		offset += int64(len(data))
+	}
+
+	index, err := msgpack.Marshal(entries)
+	if err != nil {
+		return 0, err
+	}
+	indexHash := sha256.Sum256(index)
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(index)))
+	header = append(header, indexHash[:]...)
+	if _, err = w.Write(header); err != nil {
+		return 0, err
+	}
+	if _, err = w.Write(index); err != nil {
+		return 0, err
+	}
+
+	// Второй проход: содержимое файлов
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return 0, err
+		}
+		_, err = io.Copy(w, f)
+		f.Close()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(entries), nil
+}
@@ -0,0 +1,218 @@
+package resource
+
+import (
+	"codeClient/resource/pack"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	rootDirName = "resources"      // Имя папки с ресурсами игры
+	packName    = "resources.pak"  // Имя архива ресурсов, собирается утилитой cmd/pack
+	rootEnvName = "GAME_RESOURCES" // Переменная окружения с путём к папке ресурсов
+	maxLevelsUp = 3                // Сколько родительских папок проверять при поиске ресурсов
+)
+
+var defaultLocator *Locator
+
+// Преобразует логические идентификаторы ресурсов в пути операционной системы.
+// Идентификатор ресурса - путь относительно папки resources через "/", например "UI/Menu/Background/Menu.png".
+// Ресурсы берутся из папки или из архива resources.pak.
+type Locator struct {
+	root string
+	pack *pack.Pack // nil, если ресурсы лежат в папке
+}
+
+// Создаёт локатор. Если root пустой, архив или папка берутся из GAME_RESOURCES
+// или ищутся рядом с рабочей папкой и исполняемым файлом
+func NewLocator(root string) (*Locator, error) {
+	if root == "" {
+		root = os.Getenv(rootEnvName)
+	}
+	if root == "" {
+		var err error
+		root, err = findRoot()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("ресурсы недоступны: %v", err)
+	}
+	if !info.IsDir() {
+		p, err := pack.Open(root)
+		if err != nil {
+			return nil, err
+		}
+		return &Locator{root: root, pack: p}, nil
+	}
+	return &Locator{root: root}, nil
+}
+
+// Ищет архив resources.pak или папку resources в рабочей папке, рядом с исполняемым файлом и в их родителях
+func findRoot() (string, error) {
+	var starts []string
+	if wd, err := os.Getwd(); err == nil {
+		starts = append(starts, wd)
+	}
+	if exe, err := os.Executable(); err == nil {
+		starts = append(starts, filepath.Dir(exe))
+	}
+
+	for _, dir := range starts {
+		for level := 0; level <= maxLevelsUp; level++ {
+			if info, err := os.Stat(filepath.Join(dir, packName)); err == nil && !info.IsDir() {
+				return filepath.Join(dir, packName), nil
+			}
+			candidate := filepath.Join(dir, rootDirName)
+			if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+				return candidate, nil
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return "", fmt.Errorf("%s и папка %s не найдены, укажите путь явно или через %s", packName, rootDirName, rootEnvName)
+}
+
+// Папка ресурсов или путь к архиву
+func (l *Locator) Root() string {
+	return l.root
+}
+
+// Путь к ресурсу в файловой системе, имеет смысл только для ресурсов из папки
+func (l *Locator) Path(id string) string {
+	id = Normalize(id)
+	if l.pack != nil || id == "" {
+		return ""
+	}
+	return filepath.Join(l.root, filepath.FromSlash(id))
+}
+
+// Загружены ли ресурсы из архива
+func (l *Locator) Packed() bool {
+	return l.pack != nil
+}
+
+// Читает содержимое ресурса. Ресурсы из архива проверяются по хешу
+func (l *Locator) ReadFile(id string) ([]byte, error) {
+	if l.pack != nil {
+		return l.pack.ReadFile(Normalize(id))
+	}
+	return os.ReadFile(l.Path(id))
+}
+
+// SHA-256 локального ресурса в hex
+func (l *Locator) Hash(id string) (string, bool) {
+	if l.pack != nil {
+		return l.pack.Hash(Normalize(id))
+	}
+	data, err := os.ReadFile(l.Path(id))
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// Проверяет целостность всех ресурсов архива. Для папки проверка не выполняется
+func (l *Locator) Verify() error {
+	if l.pack == nil {
+		return nil
+	}
+	return l.pack.Verify()
+}
+
+// Проверяет наличие ресурсов, без которых клиент не может запуститься
+func (l *Locator) Require(ids ...string) error {
+	var missing []string
+	for _, id := range ids {
+		if l.pack != nil {
+			if !l.pack.Has(Normalize(id)) {
+				missing = append(missing, id)
+			}
+			continue
+		}
+		if _, err := os.Stat(l.Path(id)); err != nil {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("отсутствуют ресурсы: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Приводит путь ресурса к идентификатору. Поддерживает старый формат "\resources\Asset\..." из базы данных.
+// Для пути, выходящего за папку ресурсов, возвращает пустую строку: такой ресурс считается отсутствующим
+func Normalize(id string) string {
+	id = strings.ReplaceAll(id, "\\", "/")
+	id = strings.TrimLeft(id, "/")
+	if len(id) > len(rootDirName) && strings.EqualFold(id[:len(rootDirName)+1], rootDirName+"/") {
+		id = id[len(rootDirName)+1:]
+	}
+	id = path.Clean(id)
+	if id == "." || id == ".." || strings.HasPrefix(id, "../") {
+		return ""
+	}
+	return id
+}
+
+// Идентификатор варианта ресурса из подпапки: ("Asset/Knight girl/Idle.png", "Character") -> "Asset/Knight girl/Character/Idle.png"
+func Variant(id, subDir string) string {
+	id = Normalize(id)
+	if id == "" {
+		return ""
+	}
+	return path.Join(path.Dir(id), subDir, path.Base(id))
+}
+
+// Инициализирует локатор по умолчанию и проверяет целостность архива
+func Init(root string) error {
+	l, err := NewLocator(root)
+	if err != nil {
+		return err
+	}
+	if err = l.Verify(); err != nil {
+		return fmt.Errorf("%s: %v", l.root, err)
+	}
+	defaultLocator = l
+	return nil
+}
+
+// Папка ресурсов или путь к архиву локатора по умолчанию
+func Root() string {
+	return defaultLocator.Root()
+}
+
+// Путь к ресурсу через локатор по умолчанию
+func Path(id string) string {
+	return defaultLocator.Path(id)
+}
+
+// Читает ресурс из кэша скачанных ресурсов или через локатор по умолчанию
+func ReadFile(id string) ([]byte, error) {
+	if name := cachedPath(id); name != "" {
+		return os.ReadFile(name)
+	}
+	return defaultLocator.ReadFile(id)
+}
+
+// Проверяет наличие ресурсов через локатор по умолчанию
+func Require(ids ...string) error {
+	return defaultLocator.Require(ids...)
+}
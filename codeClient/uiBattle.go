@@ -2,6 +2,8 @@ package main
 
 import (
 	"codeClient/connection"
+	"codeClient/protocol"
+	"codeClient/resource"
 	"fmt"
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"log"
@@ -22,10 +24,11 @@ const (
 )
 
 const (
-	NoBattle BattleResult = -2 // Боя не было
-	Defeat   BattleResult = -1 // Поражение
-	Draw     BattleResult = 0  // Ничья
-	Victory  BattleResult = 1  // Победа
+	Cancelled BattleResult = -3 // Бой отменён при остановке сервера
+	NoBattle  BattleResult = -2 // Боя не было
+	Defeat    BattleResult = -1 // Поражение
+	Draw      BattleResult = 0  // Ничья
+	Victory   BattleResult = 1  // Победа
 )
 
 const (
@@ -36,14 +39,16 @@ const (
 )
 
 type StartBattleInfo struct {
-	Timestamp         int64                    `msgpack:"tt"` // Время на сервере, когда событие было обработано
-	StartTime         int64                    `msgpack:"st"`
-	EndTime           int64                    `msgpack:"et"`
-	OpponentPublicID  string                   `msgpack:"oi"`
-	OpponentName      string                   `msgpack:"on"`
-	OpponentRank      int                      `msgpack:"or"`
-	OpponentLevel     int                      `msgpack:"ol"`
-	OpponentCharacter connection.CharacterData `msgpack:"oc"`
+	Timestamp         int64                  `msgpack:"tt"` // Время на сервере, когда событие было обработано
+	StartTime         int64                  `msgpack:"st"`
+	EndTime           int64                  `msgpack:"et"`
+	OpponentPublicID  string                 `msgpack:"oi"`
+	OpponentName      string                 `msgpack:"on"`
+	OpponentRank      int                    `msgpack:"or"`
+	OpponentLevel     int                    `msgpack:"ol"`
+	OpponentCharacter protocol.CharacterData `msgpack:"oc"`
+
+	assetsReady <-chan struct{} // Закрывается после скачивания ресурсов соперника, nil - качать нечего
 }
 
 type EndBattleInfo struct {
@@ -82,6 +87,7 @@ type Battle struct {
 	timeToStart time.Time
 	timeToEnd   time.Time
 	opponent    *Opponent
+	assetsReady <-chan struct{} // Ресурсы соперника, которые ещё скачиваются
 
 	waiting chan struct{}
 	start   chan StartBattleInfo
@@ -130,18 +136,18 @@ type BattleUI struct {
 
 func CreateFriendlyFightUI() *FriendlyFightUI {
 	const (
-		friendlyFightBGPath = "\\resources\\UI\\Battle\\Background\\FriendlyFight.png"
+		friendlyFightBGPath = "UI/Battle/Background/FriendlyFight.png"
 
-		btnAcceptPressedPath  = "\\resources\\UI\\Battle\\Button\\Accept\\AcceptPressed.png"
-		btnAcceptReleasedPath = "\\resources\\UI\\Battle\\Button\\Accept\\AcceptReleased.png"
-		btnRefusePressedPath  = "\\resources\\UI\\Battle\\Button\\Refuse\\RefusePressed.png"
-		btnRefuseReleasedPath = "\\resources\\UI\\Battle\\Button\\Refuse\\RefuseReleased.png"
+		btnAcceptPressedPath  = "UI/Battle/Button/Accept/AcceptPressed.png"
+		btnAcceptReleasedPath = "UI/Battle/Button/Accept/AcceptReleased.png"
+		btnRefusePressedPath  = "UI/Battle/Button/Refuse/RefusePressed.png"
+		btnRefuseReleasedPath = "UI/Battle/Button/Refuse/RefuseReleased.png"
 	)
 	backgroundRect := rl.Rectangle{0, 0, baseWidth, baseHeight}
 
-	friendlyFightBG := rl.LoadTexture(currentDirectory + friendlyFightBGPath)
-	acceptBtn := CreateButton(currentDirectory+btnAcceptPressedPath, currentDirectory+btnAcceptReleasedPath, backgroundRect, rl.Rectangle{666, 333, 137, 36})
-	refuseBtn := CreateButton(currentDirectory+btnRefusePressedPath, currentDirectory+btnRefuseReleasedPath, backgroundRect, rl.Rectangle{477, 333, 137, 36})
+	friendlyFightBG := resource.LoadRequiredTexture(friendlyFightBGPath)
+	acceptBtn := CreateButton(btnAcceptPressedPath, btnAcceptReleasedPath, backgroundRect, rl.Rectangle{666, 333, 137, 36})
+	refuseBtn := CreateButton(btnRefusePressedPath, btnRefuseReleasedPath, backgroundRect, rl.Rectangle{477, 333, 137, 36})
 
 	return &FriendlyFightUI{
 		state:           waitingInvitation,
@@ -166,44 +172,44 @@ func CreateBattle() *Battle {
 
 func CreateBattleUI() *BattleUI {
 	const (
-		battleModeBGPath         = "\\resources\\UI\\Battle\\Background\\BattleMode.png"
-		battleSearchBGPath       = "\\resources\\UI\\Battle\\Background\\BattleSearch.png"
-		inBattleBGPath           = "\\resources\\UI\\Battle\\Background\\InBattleGold.png"
-		postBattlePlayerBGPath   = "\\resources\\UI\\Battle\\Background\\PostBattlePlayer.png"
-		postBattleOpponentBGPath = "\\resources\\UI\\Battle\\Background\\PostBattleOpponent.png"
-		exitDialogBGPath         = "\\resources\\UI\\Battle\\Background\\Exit.png"
-
-		btnLevelPressedPath   = "\\resources\\UI\\Battle\\Button\\LevelMatch\\LevelMatchPressed.png"
-		btnLevelReleasedPath  = "\\resources\\UI\\Battle\\Button\\LevelMatch\\LevelMatchReleased.png"
-		btnRankedPressedPath  = "\\resources\\UI\\Battle\\Button\\RankedMatch\\RankedMatchPressed.png"
-		btnRankedReleasedPath = "\\resources\\UI\\Battle\\Button\\RankedMatch\\RankedMatchReleased.png"
-		btnLeftPressedPath    = "\\resources\\UI\\Battle\\Button\\Left\\LeftPressed.png"
-		btnLeftReleasedPath   = "\\resources\\UI\\Battle\\Button\\Left\\LeftReleased.png"
-		btnRightPressedPath   = "\\resources\\UI\\Battle\\Button\\Right\\RightPressed.png"
-		btnRightReleasedPath  = "\\resources\\UI\\Battle\\Button\\Right\\RightReleased.png"
-		btnOkPressedPath      = "\\resources\\UI\\Battle\\Button\\Ok\\OkPressed.png"
-		btnOkReleasedPath     = "\\resources\\UI\\Battle\\Button\\Ok\\OkReleased.png"
-		btnYesPressedPath     = "\\resources\\UI\\Battle\\Button\\Yes\\YesPressed.png"
-		btnYesReleasedPath    = "\\resources\\UI\\Battle\\Button\\Yes\\YesReleased.png"
-		btnNoPressedPath      = "\\resources\\UI\\Battle\\Button\\No\\NoPressed.png"
-		btnNoReleasedPath     = "\\resources\\UI\\Battle\\Button\\No\\NoReleased.png"
+		battleModeBGPath         = "UI/Battle/Background/BattleMode.png"
+		battleSearchBGPath       = "UI/Battle/Background/BattleSearch.png"
+		inBattleBGPath           = "UI/Battle/Background/InBattleGold.png"
+		postBattlePlayerBGPath   = "UI/Battle/Background/PostBattlePlayer.png"
+		postBattleOpponentBGPath = "UI/Battle/Background/PostBattleOpponent.png"
+		exitDialogBGPath         = "UI/Battle/Background/Exit.png"
+
+		btnLevelPressedPath   = "UI/Battle/Button/LevelMatch/LevelMatchPressed.png"
+		btnLevelReleasedPath  = "UI/Battle/Button/LevelMatch/LevelMatchReleased.png"
+		btnRankedPressedPath  = "UI/Battle/Button/RankedMatch/RankedMatchPressed.png"
+		btnRankedReleasedPath = "UI/Battle/Button/RankedMatch/RankedMatchReleased.png"
+		btnLeftPressedPath    = "UI/Battle/Button/Left/LeftPressed.png"
+		btnLeftReleasedPath   = "UI/Battle/Button/Left/LeftReleased.png"
+		btnRightPressedPath   = "UI/Battle/Button/Right/RightPressed.png"
+		btnRightReleasedPath  = "UI/Battle/Button/Right/RightReleased.png"
+		btnOkPressedPath      = "UI/Battle/Button/Ok/OkPressed.png"
+		btnOkReleasedPath     = "UI/Battle/Button/Ok/OkReleased.png"
+		btnYesPressedPath     = "UI/Battle/Button/Yes/YesPressed.png"
+		btnYesReleasedPath    = "UI/Battle/Button/Yes/YesReleased.png"
+		btnNoPressedPath      = "UI/Battle/Button/No/NoPressed.png"
+		btnNoReleasedPath     = "UI/Battle/Button/No/NoReleased.png"
 	)
-	battleModeBG := rl.LoadTexture(currentDirectory + battleModeBGPath)
-	battleSearchBG := rl.LoadTexture(currentDirectory + battleSearchBGPath)
-	inBattleBG := rl.LoadTexture(currentDirectory + inBattleBGPath)
-	postBattlePlayerBG := rl.LoadTexture(currentDirectory + postBattlePlayerBGPath)
-	postBattleOpponentBG := rl.LoadTexture(currentDirectory + postBattleOpponentBGPath)
-	exitDialogBG := rl.LoadTexture(currentDirectory + exitDialogBGPath)
+	battleModeBG := resource.LoadRequiredTexture(battleModeBGPath)
+	battleSearchBG := resource.LoadRequiredTexture(battleSearchBGPath)
+	inBattleBG := resource.LoadRequiredTexture(inBattleBGPath)
+	postBattlePlayerBG := resource.LoadRequiredTexture(postBattlePlayerBGPath)
+	postBattleOpponentBG := resource.LoadRequiredTexture(postBattleOpponentBGPath)
+	exitDialogBG := resource.LoadRequiredTexture(exitDialogBGPath)
 
 	textureBounds := rl.Rectangle{0, 0, baseWidth, baseHeight}
 
-	levelMatchBtn := CreateButton(currentDirectory+btnLevelPressedPath, currentDirectory+btnLevelReleasedPath, textureBounds, rl.Rectangle{373, 25, 215, 54})
-	rankedMatchBtn := CreateButton(currentDirectory+btnRankedPressedPath, currentDirectory+btnRankedReleasedPath, textureBounds, rl.Rectangle{692, 25, 215, 54})
-	leftBtn := CreateButton(currentDirectory+btnLeftPressedPath, currentDirectory+btnLeftReleasedPath, textureBounds, rl.Rectangle{518, 463, 21, 31})
-	rightBtn := CreateButton(currentDirectory+btnRightPressedPath, currentDirectory+btnRightReleasedPath, textureBounds, rl.Rectangle{741, 463, 21, 31})
-	okBtn := CreateButton(currentDirectory+btnOkPressedPath, currentDirectory+btnOkReleasedPath, textureBounds, rl.Rectangle{573, 461, 134, 36})
-	yesBtn := CreateButton(currentDirectory+btnYesPressedPath, currentDirectory+btnYesReleasedPath, textureBounds, rl.Rectangle{655, 324, 103, 36})
-	noBtn := CreateButton(currentDirectory+btnNoPressedPath, currentDirectory+btnNoReleasedPath, textureBounds, rl.Rectangle{522, 324, 103, 36})
+	levelMatchBtn := CreateButton(btnLevelPressedPath, btnLevelReleasedPath, textureBounds, rl.Rectangle{373, 25, 215, 54})
+	rankedMatchBtn := CreateButton(btnRankedPressedPath, btnRankedReleasedPath, textureBounds, rl.Rectangle{692, 25, 215, 54})
+	leftBtn := CreateButton(btnLeftPressedPath, btnLeftReleasedPath, textureBounds, rl.Rectangle{518, 463, 21, 31})
+	rightBtn := CreateButton(btnRightPressedPath, btnRightReleasedPath, textureBounds, rl.Rectangle{741, 463, 21, 31})
+	okBtn := CreateButton(btnOkPressedPath, btnOkReleasedPath, textureBounds, rl.Rectangle{573, 461, 134, 36})
+	yesBtn := CreateButton(btnYesPressedPath, btnYesReleasedPath, textureBounds, rl.Rectangle{655, 324, 103, 36})
+	noBtn := CreateButton(btnNoPressedPath, btnNoReleasedPath, textureBounds, rl.Rectangle{522, 324, 103, 36})
 
 	return &BattleUI{
 		currentBattle:        CreateBattle(),
@@ -296,7 +302,7 @@ func (f *FriendlyFightUI) HandleInput(conn net.Conn, player *Player, gameState *
 				f.state = waitingInvitation
 				f.resetToDefaultState(gameState)
 				*gameState = stateBattle
-				go battleUI.waitingBattleSearch(conn, player, connection.MsgAcceptChallengeToFight, f.friend.PublicID, gameState)
+				go battleUI.waitingBattleSearch(conn, player, protocol.MsgAcceptChallengeToFight, f.friend.PublicID, gameState)
 				return
 			}
 		} else if !f.acceptBtn.IsHovered() || !rl.IsMouseButtonDown(rl.MouseButtonLeft) {
@@ -309,7 +315,7 @@ func (f *FriendlyFightUI) HandleInput(conn net.Conn, player *Player, gameState *
 			} else if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
 				f.refuseBtn.Released()
 				f.state = waitingInvitation
-				sendInput(conn, connection.MsgRefuseChallengeToFight, f.friend.PublicID)
+				sendInput(conn, protocol.MsgRefuseChallengeToFight, f.friend.PublicID)
 				fmt.Println(gameState, friendsUI.state)
 				return
 			}
@@ -529,6 +535,8 @@ func (b *BattleUI) drawPagePlayer(player *Player, fontSize, fontSpacing, scaleX,
 	switch b.resultBattle.result {
 	case NoBattle:
 		textResult = "Бой отменён"
+	case Cancelled:
+		textResult = "Бой прерван"
 	case Victory:
 		textResult = "Победа"
 	case Draw:
@@ -632,7 +640,7 @@ func (b *BattleUI) stateBattleMode(conn net.Conn, player *Player, gameState *str
 
 			//b.timeSearch = time.Now()
 			//b.state = BattleSearch
-			typeBattle := connection.MsgBattle
+			typeBattle := protocol.MsgBattle
 			go b.waitingBattleSearch(conn, player, typeBattle, "", gameState)
 		}
 	} else if !b.levelMatchBtn.IsHovered() || !rl.IsMouseButtonDown(rl.MouseButtonLeft) {
@@ -646,7 +654,7 @@ func (b *BattleUI) stateBattleMode(conn net.Conn, player *Player, gameState *str
 			b.rankedMatchBtn.Released()
 			//b.timeSearch = time.Now()
 			//b.state = BattleSearch
-			typeBattle := connection.MsgBattleRanked
+			typeBattle := protocol.MsgBattleRanked
 			go b.waitingBattleSearch(conn, player, typeBattle, "", gameState)
 		}
 	} else if !b.rankedMatchBtn.IsHovered() || !rl.IsMouseButtonDown(rl.MouseButtonLeft) {
@@ -654,7 +662,7 @@ func (b *BattleUI) stateBattleMode(conn net.Conn, player *Player, gameState *str
 	}
 }
 
-func (b *BattleUI) waitingBattleSearch(conn net.Conn, player *Player, typeBattle connection.MessageType, friendID string, gameState *string) {
+func (b *BattleUI) waitingBattleSearch(conn net.Conn, player *Player, typeBattle protocol.MessageType, friendID string, gameState *string) {
 	select {
 	case <-b.currentBattle.exit:
 	default:
@@ -667,7 +675,7 @@ func (b *BattleUI) waitingBattleSearch(conn net.Conn, player *Player, typeBattle
 	case <-time.After(5 * time.Second):
 		log.Println(time.Now())
 		log.Println("Ожидание боя истекло, выход в меню")
-		sendInput(conn, connection.MsgExitBattle, nil)
+		sendInput(conn, protocol.MsgExitBattle, nil)
 		*gameState = stateMenu
 		b.state = BattleMode
 		return
@@ -678,7 +686,7 @@ func (b *BattleUI) waitingBattleSearch(conn net.Conn, player *Player, typeBattle
 
 func (b *BattleUI) stateBattleSearch(conn net.Conn, player *Player, gameState *string) {
 	if rl.IsKeyReleased(rl.KeyEscape) {
-		sendInput(conn, connection.MsgExitBattle, nil)
+		sendInput(conn, protocol.MsgExitBattle, nil)
 	}
 	select {
 	case response := <-b.currentBattle.start:
@@ -691,7 +699,7 @@ func (b *BattleUI) stateBattleSearch(conn net.Conn, player *Player, gameState *s
 			b.state = BattleMode
 			return
 		} else if b.friendID == friendID { // в случае отказа от приглашения на бой
-			sendInput(conn, connection.MsgExitBattle, nil)
+			sendInput(conn, protocol.MsgExitBattle, nil)
 			b.friendID = ""
 		}
 
@@ -703,15 +711,22 @@ func (b *BattleUI) handleBattleStart(conn net.Conn, player *Player, response Sta
 	timeNow := time.Now().UnixMilli()
 	offset := timeNow + connection.ServerLag - response.Timestamp
 
-	sendInput(conn, connection.MsgReadyBattle, nil)
+	sendInput(conn, protocol.MsgReadyBattle, nil)
 	opponent := CreateOpponent(response)
 	b.currentBattle.opponent = opponent
+	b.currentBattle.assetsReady = response.assetsReady
 	b.currentBattle.timeToStart = time.UnixMilli(response.StartTime + offset).Local()
 	b.currentBattle.timeToEnd = time.UnixMilli(response.EndTime + offset).Local()
 }
 
 func (b *BattleUI) stateInBattle(conn net.Conn, player *Player, gameState *string) {
 	timeNow := time.Now()
+	select {
+	case <-b.currentBattle.assetsReady:
+		b.currentBattle.opponent.character.ReloadTextures()
+		b.currentBattle.assetsReady = nil
+	default:
+	}
 	switch {
 	case len(b.currentBattle.end) > 0:
 		if player.character.isDying || b.currentBattle.opponent.character.isDying {
@@ -743,7 +758,7 @@ func (b *BattleUI) exit(conn net.Conn) {
 			b.yesBtn.Pressed()
 		} else if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
 			b.yesBtn.Released()
-			sendInput(conn, connection.MsgExitBattle, nil)
+			sendInput(conn, protocol.MsgExitBattle, nil)
 		}
 	} else if !b.yesBtn.IsHovered() || !rl.IsMouseButtonDown(rl.MouseButtonLeft) {
 		b.yesBtn.Released()
@@ -770,7 +785,7 @@ func (b *BattleUI) exit(conn net.Conn) {
 		b.yesBtn.Pressed()
 	} else if rl.IsKeyReleased(rl.KeyEnter) {
 		b.yesBtn.Released()
-		sendInput(conn, connection.MsgExitBattle, nil)
+		sendInput(conn, protocol.MsgExitBattle, nil)
 	}
 }
 
@@ -1,6 +1,9 @@
 package main
 
-import rl "github.com/gen2brain/raylib-go/raylib"
+import (
+	"codeClient/resource"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
 
 type Button struct {
 	isPressed       bool
@@ -15,9 +18,9 @@ type ClickBounds struct {
 	bounds     rl.Rectangle
 }
 
-func CreateButton(pressedPath, releasedPath string, visualBounds, clickBounds rl.Rectangle) *Button {
-	texturePressed := rl.LoadTexture(pressedPath)
-	textureReleased := rl.LoadTexture(releasedPath)
+func CreateButton(pressedID, releasedID string, visualBounds, clickBounds rl.Rectangle) *Button {
+	texturePressed := resource.LoadRequiredTexture(pressedID)
+	textureReleased := resource.LoadRequiredTexture(releasedID)
 
 	return &Button{
 		isPressed:       false,
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"codeClient/resource"
+	"fmt"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Ответ сервера на запрос ежедневной награды
+type DailyRewardInfo struct {
+	Claimed    bool   `msgpack:"c"`           // Награда получена этим запросом, иначе она уже получена сегодня
+	Streak     int    `msgpack:"s"`           // Дней подряд с наградой
+	Day        int    `msgpack:"d"`           // День цикла наград
+	Money      int    `msgpack:"m,omitempty"` // Полученные монеты
+	Background string `msgpack:"b,omitempty"` // Название полученного фона
+	TotalMoney int    `msgpack:"tm"`          // Баланс после награды
+	NextClaim  int64  `msgpack:"n"`           // Время следующей награды, мс по UTC
+}
+
+// Окно ежедневной награды поверх меню
+type DailyRewardUI struct {
+	shown    bool
+	reward   DailyRewardInfo
+	rewardCh chan DailyRewardInfo
+
+	dailyRewardBG  rl.Texture2D
+	backgroundRect rl.Rectangle
+	titleRect      rl.Rectangle
+	line1Rect      rl.Rectangle
+	line2Rect      rl.Rectangle
+	line3Rect      rl.Rectangle
+	line4Rect      rl.Rectangle
+
+	okBtn *Button
+}
+
+func CreateDailyRewardUI() *DailyRewardUI {
+	const (
+		dailyRewardBGPath = "UI/Menu/Background/DailyReward.png"
+
+		btnOkPressedPath  = "UI/Battle/Button/Ok/OkPressed.png"
+		btnOkReleasedPath = "UI/Battle/Button/Ok/OkReleased.png"
+	)
+	backgroundRect := rl.Rectangle{0, 0, baseWidth, baseHeight}
+
+	dailyRewardBG := resource.LoadRequiredTexture(dailyRewardBGPath)
+	okBtn := CreateButton(btnOkPressedPath, btnOkReleasedPath, backgroundRect, rl.Rectangle{573, 461, 134, 36})
+
+	return &DailyRewardUI{
+		shown:          false,
+		rewardCh:       make(chan DailyRewardInfo, 1),
+		dailyRewardBG:  dailyRewardBG,
+		backgroundRect: backgroundRect,
+		titleRect:      rl.Rectangle{X: 493, Y: 210, Width: 294, Height: 36},
+		line1Rect:      rl.Rectangle{X: 493, Y: 266, Width: 294, Height: 36},
+		line2Rect:      rl.Rectangle{X: 493, Y: 316, Width: 294, Height: 32},
+		line3Rect:      rl.Rectangle{X: 493, Y: 358, Width: 294, Height: 32},
+		line4Rect:      rl.Rectangle{X: 493, Y: 399, Width: 294, Height: 32},
+		okBtn:          okBtn,
+	}
+}
+
+func (d *DailyRewardUI) Unload() {
+	rl.UnloadTexture(d.dailyRewardBG)
+	d.okBtn.Unload()
+}
+
+func (d *DailyRewardUI) Draw(scaleX, scaleY float32) {
+	if !d.shown {
+		return
+	}
+	const (
+		titleFontSize = float32(26)
+		fontSize      = float32(16)
+		fontSpacing   = 0
+	)
+	titleColor := rl.Color{R: 158, G: 147, B: 144, A: 255}
+	color := rl.Color{R: 159, G: 164, B: 197, A: 255}
+
+	drawTexture(d.dailyRewardBG, d.backgroundRect, d.backgroundRect, scaleX, scaleY)
+	drawFieldText("Ежедневная награда", d.titleRect, scaleX, scaleY, titleFontSize, fontSpacing, titleColor)
+	drawFieldText(fmt.Sprintf("Дней подряд: %d", d.reward.Streak), d.line1Rect, scaleX, scaleY, fontSize, fontSpacing, color)
+	drawFieldText(fmt.Sprintf("Монеты: +%d", d.reward.Money), d.line2Rect, scaleX, scaleY, fontSize, fontSpacing, color)
+	if d.reward.Background != "" {
+		drawFieldText("Новый фон: "+d.reward.Background, d.line3Rect, scaleX, scaleY, fontSize, fontSpacing, color)
+	}
+	drawFieldText(fmt.Sprintf("Баланс: %d", d.reward.TotalMoney), d.line4Rect, scaleX, scaleY, fontSize, fontSpacing, color)
+
+	d.okBtn.Draw(scaleX, scaleY)
+}
+
+// Показывает награду, когда игрок в меню, и закрывает окно по кнопке
+func (d *DailyRewardUI) HandleInput(gameState *string) {
+	switch {
+	case *gameState == stateMenu && len(d.rewardCh) > 0 && !d.shown:
+		d.reward = <-d.rewardCh
+		d.shown = true
+	case d.shown:
+		if d.okBtn.IsHovered() {
+			if rl.IsMouseButtonPressed(rl.MouseButtonLeft) {
+				d.okBtn.Pressed()
+			} else if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
+				d.okBtn.Released()
+				d.shown = false
+			}
+		} else if !d.okBtn.IsHovered() || !rl.IsMouseButtonDown(rl.MouseButtonLeft) {
+			d.okBtn.Released()
+		}
+	}
+}
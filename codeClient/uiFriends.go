@@ -1,7 +1,8 @@
 package main
 
 import (
-	"codeClient/connection"
+	"codeClient/protocol"
+	"codeClient/resource"
 	"fmt"
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"net"
@@ -29,6 +30,19 @@ type FriendsData struct {
 	Outgoing []FriendEntry `msgpack:"o"`
 }
 
+// Результат заявки в друзья из MsgAddFriend, ошибки приходят в MsgError
+type FriendRequestStatus uint8
+
+const (
+	friendRequestSent FriendRequestStatus = iota
+	friendRequestAccepted
+)
+
+var friendRequestTexts = map[FriendRequestStatus]string{
+	friendRequestSent:     "Заявка отправлена",
+	friendRequestAccepted: "Заявка в друзья принята",
+}
+
 type FieldsFriendsUI struct {
 	fieldBG rl.Texture2D
 
@@ -87,11 +101,11 @@ type FriendsUI struct {
 
 func createFieldsRequests() *FieldsFriendsUI {
 	const (
-		fieldBGPath            = "\\resources\\UI\\Friends\\Background\\Field.png"
-		btnAcceptPressedPath   = "\\resources\\UI\\Friends\\Button\\Accept\\AcceptPressed.png"
-		btnAcceptReleasedPath  = "\\resources\\UI\\Friends\\Button\\Accept\\AcceptReleased.png"
-		btnDeclinePressedPath  = "\\resources\\UI\\Friends\\Button\\Decline\\DeclinePressed.png"
-		btnDeclineReleasedPath = "\\resources\\UI\\Friends\\Button\\Decline\\DeclineReleased.png"
+		fieldBGPath            = "UI/Friends/Background/Field.png"
+		btnAcceptPressedPath   = "UI/Friends/Button/Accept/AcceptPressed.png"
+		btnAcceptReleasedPath  = "UI/Friends/Button/Accept/AcceptReleased.png"
+		btnDeclinePressedPath  = "UI/Friends/Button/Decline/DeclinePressed.png"
+		btnDeclineReleasedPath = "UI/Friends/Button/Decline/DeclineReleased.png"
 
 		countFields = 5
 		offset      = 14
@@ -102,11 +116,11 @@ func createFieldsRequests() *FieldsFriendsUI {
 		acceptBtnList  = make([]*Button, countFields)
 		declineBtnList = make([]*Button, countFields)
 	)
-	fieldBG := rl.LoadTexture(currentDirectory + fieldBGPath)
+	fieldBG := resource.LoadRequiredTexture(fieldBGPath)
 
 	for i := 0; i < countFields; i++ {
-		acceptBtnList[i] = CreateButton(currentDirectory+btnAcceptPressedPath, currentDirectory+btnAcceptReleasedPath, OffsetRectY(acceptBounds, float32(i), offset), OffsetRectY(acceptBounds, float32(i), offset))
-		declineBtnList[i] = CreateButton(currentDirectory+btnDeclinePressedPath, currentDirectory+btnDeclineReleasedPath, OffsetRectY(declineBounds, float32(i), offset), OffsetRectY(declineBounds, float32(i), offset))
+		acceptBtnList[i] = CreateButton(btnAcceptPressedPath, btnAcceptReleasedPath, OffsetRectY(acceptBounds, float32(i), offset), OffsetRectY(acceptBounds, float32(i), offset))
+		declineBtnList[i] = CreateButton(btnDeclinePressedPath, btnDeclineReleasedPath, OffsetRectY(declineBounds, float32(i), offset), OffsetRectY(declineBounds, float32(i), offset))
 	}
 
 	return &FieldsFriendsUI{
@@ -122,11 +136,11 @@ func createFieldsRequests() *FieldsFriendsUI {
 
 func createFieldsFriends() *FieldsFriendsUI {
 	const (
-		fieldBGPath           = "\\resources\\UI\\Friends\\Background\\Field.png"
-		btnBattlePressedPath  = "\\resources\\UI\\Friends\\Button\\Battle\\BattlePressed.png"
-		btnBattleReleasedPath = "\\resources\\UI\\Friends\\Button\\Battle\\BattleReleased.png"
-		btnRemovePressedPath  = "\\resources\\UI\\Friends\\Button\\Remove\\RemovePressed.png"
-		btnRemoveReleasedPath = "\\resources\\UI\\Friends\\Button\\Remove\\RemoveReleased.png"
+		fieldBGPath           = "UI/Friends/Background/Field.png"
+		btnBattlePressedPath  = "UI/Friends/Button/Battle/BattlePressed.png"
+		btnBattleReleasedPath = "UI/Friends/Button/Battle/BattleReleased.png"
+		btnRemovePressedPath  = "UI/Friends/Button/Remove/RemovePressed.png"
+		btnRemoveReleasedPath = "UI/Friends/Button/Remove/RemoveReleased.png"
 
 		countFields = 5
 		offset      = 14
@@ -137,10 +151,10 @@ func createFieldsFriends() *FieldsFriendsUI {
 		battleBtnList = make([]*Button, countFields)
 		removeBtnList = make([]*Button, countFields)
 	)
-	fieldBG := rl.LoadTexture(currentDirectory + fieldBGPath)
+	fieldBG := resource.LoadRequiredTexture(fieldBGPath)
 	for i := 0; i < countFields; i++ {
-		battleBtnList[i] = CreateButton(currentDirectory+btnBattlePressedPath, currentDirectory+btnBattleReleasedPath, OffsetRectY(battleBounds, float32(i), offset), OffsetRectY(battleBounds, float32(i), offset))
-		removeBtnList[i] = CreateButton(currentDirectory+btnRemovePressedPath, currentDirectory+btnRemoveReleasedPath, OffsetRectY(removeBounds, float32(i), offset), OffsetRectY(removeBounds, float32(i), offset))
+		battleBtnList[i] = CreateButton(btnBattlePressedPath, btnBattleReleasedPath, OffsetRectY(battleBounds, float32(i), offset), OffsetRectY(battleBounds, float32(i), offset))
+		removeBtnList[i] = CreateButton(btnRemovePressedPath, btnRemoveReleasedPath, OffsetRectY(removeBounds, float32(i), offset), OffsetRectY(removeBounds, float32(i), offset))
 	}
 
 	return &FieldsFriendsUI{
@@ -156,21 +170,21 @@ func createFieldsFriends() *FieldsFriendsUI {
 
 func CreateFriendsUI() *FriendsUI {
 	const (
-		friendsListBGPath    = "\\resources\\UI\\Friends\\Background\\FriendsList.png"
-		friendRequestsBGPath = "\\resources\\UI\\Friends\\Background\\FriendRequests.png"
-		searchFriendBGPath   = "\\resources\\UI\\Friends\\Background\\AddFriend.png"
-
-		btnAddPressedPath     = "\\resources\\UI\\Friends\\Button\\Add\\AddPressed.png"
-		btnAddReleasedPath    = "\\resources\\UI\\Friends\\Button\\Add\\AddReleased.png"
-		btnBattlePressedPath  = "\\resources\\UI\\Friends\\Button\\Battle\\BattlePressed.png"
-		btnBattleReleasedPath = "\\resources\\UI\\Friends\\Button\\Battle\\BattleReleased.png"
-
-		btnLoupePressedPath  = "\\resources\\UI\\Friends\\Button\\Loupe\\LoupePressed.png"
-		btnLoupeReleasedPath = "\\resources\\UI\\Friends\\Button\\Loupe\\LoupeReleased.png"
-		btnLeftPressedPath   = "\\resources\\UI\\Friends\\Button\\Left\\LeftPressed.png"
-		btnLeftReleasedPath  = "\\resources\\UI\\Friends\\Button\\Left\\LeftReleased.png"
-		btnRightPressedPath  = "\\resources\\UI\\Friends\\Button\\Right\\RightPressed.png"
-		btnRightReleasedPath = "\\resources\\UI\\Friends\\Button\\Right\\RightReleased.png"
+		friendsListBGPath    = "UI/Friends/Background/FriendsList.png"
+		friendRequestsBGPath = "UI/Friends/Background/FriendRequests.png"
+		searchFriendBGPath   = "UI/Friends/Background/AddFriend.png"
+
+		btnAddPressedPath     = "UI/Friends/Button/Add/AddPressed.png"
+		btnAddReleasedPath    = "UI/Friends/Button/Add/AddReleased.png"
+		btnBattlePressedPath  = "UI/Friends/Button/Battle/BattlePressed.png"
+		btnBattleReleasedPath = "UI/Friends/Button/Battle/BattleReleased.png"
+
+		btnLoupePressedPath  = "UI/Friends/Button/Loupe/LoupePressed.png"
+		btnLoupeReleasedPath = "UI/Friends/Button/Loupe/LoupeReleased.png"
+		btnLeftPressedPath   = "UI/Friends/Button/Left/LeftPressed.png"
+		btnLeftReleasedPath  = "UI/Friends/Button/Left/LeftReleased.png"
+		btnRightPressedPath  = "UI/Friends/Button/Right/RightPressed.png"
+		btnRightReleasedPath = "UI/Friends/Button/Right/RightReleased.png"
 	)
 	var (
 		textureBounds          = rl.Rectangle{0, 0, baseWidth, baseHeight}
@@ -182,15 +196,15 @@ func CreateFriendsUI() *FriendsUI {
 		pageRect               = rl.Rectangle{X: 575, Y: 485, Width: 133, Height: 29}
 	)
 
-	friendsListBG := rl.LoadTexture(currentDirectory + friendsListBGPath)
-	friendRequestsBG := rl.LoadTexture(currentDirectory + friendRequestsBGPath)
-	searchFriendBG := rl.LoadTexture(currentDirectory + searchFriendBGPath)
+	friendsListBG := resource.LoadRequiredTexture(friendsListBGPath)
+	friendRequestsBG := resource.LoadRequiredTexture(friendRequestsBGPath)
+	searchFriendBG := resource.LoadRequiredTexture(searchFriendBGPath)
 
-	addBtn := CreateButton(currentDirectory+btnAddPressedPath, currentDirectory+btnAddReleasedPath, textureBounds, rl.Rectangle{546, 328, 187, 24})
-	battleBtn := CreateButton(currentDirectory+btnBattlePressedPath, currentDirectory+btnBattleReleasedPath, friendManagementBounds, friendManagementBounds)
-	loupeBtn := CreateButton(currentDirectory+btnLoupePressedPath, currentDirectory+btnLoupeReleasedPath, textureBounds, rl.Rectangle{754, 240, 33, 33})
-	leftBtn := CreateButton(currentDirectory+btnLeftPressedPath, currentDirectory+btnLeftReleasedPath, textureBounds, rl.Rectangle{554, 484, 21, 31})
-	rightBtn := CreateButton(currentDirectory+btnRightPressedPath, currentDirectory+btnRightReleasedPath, textureBounds, rl.Rectangle{707, 484, 21, 31})
+	addBtn := CreateButton(btnAddPressedPath, btnAddReleasedPath, textureBounds, rl.Rectangle{546, 328, 187, 24})
+	battleBtn := CreateButton(btnBattlePressedPath, btnBattleReleasedPath, friendManagementBounds, friendManagementBounds)
+	loupeBtn := CreateButton(btnLoupePressedPath, btnLoupeReleasedPath, textureBounds, rl.Rectangle{754, 240, 33, 33})
+	leftBtn := CreateButton(btnLeftPressedPath, btnLeftReleasedPath, textureBounds, rl.Rectangle{554, 484, 21, 31})
+	rightBtn := CreateButton(btnRightPressedPath, btnRightReleasedPath, textureBounds, rl.Rectangle{707, 484, 21, 31})
 
 	return &FriendsUI{
 		fieldsRequests: createFieldsRequests(),
@@ -483,7 +497,7 @@ func (f *FriendsUI) stateFriendsList(conn net.Conn, player *Player, gameState *s
 		codeBtn, number := f.fieldsFriends.FindClickedButton(f.currentPageItems)
 		if codeBtn != -1 {
 			msgType, friendID := f.friendsProcessing(codeBtn, number)
-			if msgType == connection.MsgChallengeToFight {
+			if msgType == protocol.MsgChallengeToFight {
 				f.resetState()
 				*gameState = stateBattle
 				go battleUI.waitingBattleSearch(conn, player, msgType, friendID, gameState)
@@ -521,7 +535,7 @@ func (f *FriendsUI) searchFriend(search string) {
 	f.friendsList = append(exactMatches, partialMatches...)
 }
 
-func (f *FriendsUI) friendsProcessing(codeBtn, number int) (connection.MessageType, string) {
+func (f *FriendsUI) friendsProcessing(codeBtn, number int) (protocol.MessageType, string) {
 	const (
 		battleCode = 1
 		RemoveCode = 0
@@ -531,7 +545,7 @@ func (f *FriendsUI) friendsProcessing(codeBtn, number int) (connection.MessageTy
 
 	switch codeBtn {
 	case battleCode:
-		return connection.MsgChallengeToFight, request.PublicID
+		return protocol.MsgChallengeToFight, request.PublicID
 	default:
 		for i := 0; i < len(f.friendsData.Friends); i++ {
 			if f.friendsData.Friends[i].PublicID == request.PublicID {
@@ -540,7 +554,7 @@ func (f *FriendsUI) friendsProcessing(codeBtn, number int) (connection.MessageTy
 			}
 		}
 		f.friendsList = append(f.friendsList[:id], f.friendsList[id+1:]...)
-		return connection.MsgRemoveFriend, request.PublicID
+		return protocol.MsgRemoveFriend, request.PublicID
 	}
 }
 
@@ -582,7 +596,7 @@ func (f *FriendsUI) stateFriendRequests(conn net.Conn) {
 	}
 }
 
-func (f *FriendsUI) requestProcessing(codeBtn, number int) (connection.MessageType, string) {
+func (f *FriendsUI) requestProcessing(codeBtn, number int) (protocol.MessageType, string) {
 	const (
 		acceptCode  = 1
 		declineCode = 0
@@ -595,9 +609,9 @@ func (f *FriendsUI) requestProcessing(codeBtn, number int) (connection.MessageTy
 	case acceptCode:
 		f.friendsData.Friends = append(f.friendsData.Friends, request)
 		f.friendsList = f.friendsData.Friends // append(f.friendsList, request)
-		return connection.MsgAcceptFriendship, request.PublicID
+		return protocol.MsgAcceptFriendship, request.PublicID
 	default:
-		return connection.MsgDeclineFriendship, request.PublicID
+		return protocol.MsgDeclineFriendship, request.PublicID
 	}
 }
 
@@ -643,7 +657,7 @@ func (f *FriendsUI) stateSearchFriend(conn net.Conn) {
 			f.addBtn.Pressed()
 		} else if rl.IsMouseButtonReleased(rl.MouseButtonLeft) || rl.IsKeyReleased(rl.KeyEnter) {
 			f.addBtn.Released()
-			sendInput(conn, connection.MsgAddFriend, f.input)
+			sendInput(conn, protocol.MsgAddFriend, f.input)
 			f.input = ""
 			f.addFriendResponse = "Поиск..."
 		}
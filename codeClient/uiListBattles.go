@@ -1,6 +1,7 @@
 package main
 
 import (
+	"codeClient/resource"
 	"fmt"
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"time"
@@ -71,13 +72,13 @@ type ListBattlesUI struct {
 
 func CreateListBattlesUI() *ListBattlesUI {
 	const (
-		fieldBGPath               = "\\resources\\UI\\ListBattles\\Background\\Field.png"
-		rankedListBattlesBGPath   = "\\resources\\UI\\ListBattles\\Background\\RankedListBattle.png"
-		standardListBattlesBGPath = "\\resources\\UI\\ListBattles\\Background\\StandardListBattle.png"
-		btnLeftPressedPath        = "\\resources\\UI\\ListBattles\\Button\\Left\\LeftPressed.png"
-		btnLeftReleasedPath       = "\\resources\\UI\\ListBattles\\Button\\Left\\LeftReleased.png"
-		btnRightPressedPath       = "\\resources\\UI\\ListBattles\\Button\\Right\\RightPressed.png"
-		btnRightReleasedPath      = "\\resources\\UI\\ListBattles\\Button\\Right\\RightReleased.png"
+		fieldBGPath               = "UI/ListBattles/Background/Field.png"
+		rankedListBattlesBGPath   = "UI/ListBattles/Background/RankedListBattle.png"
+		standardListBattlesBGPath = "UI/ListBattles/Background/StandardListBattle.png"
+		btnLeftPressedPath        = "UI/ListBattles/Button/Left/LeftPressed.png"
+		btnLeftReleasedPath       = "UI/ListBattles/Button/Left/LeftReleased.png"
+		btnRightPressedPath       = "UI/ListBattles/Button/Right/RightPressed.png"
+		btnRightReleasedPath      = "UI/ListBattles/Button/Right/RightReleased.png"
 	)
 	var (
 		textureBounds      = rl.Rectangle{0, 0, baseWidth, baseHeight}
@@ -85,12 +86,12 @@ func CreateListBattlesUI() *ListBattlesUI {
 		standardListBounds = rl.Rectangle{304, 176, 147, 27}
 	)
 
-	fieldBG := rl.LoadTexture(currentDirectory + fieldBGPath)
-	rankedListBattlesBG := rl.LoadTexture(currentDirectory + rankedListBattlesBGPath)
-	standardListBattlesBG := rl.LoadTexture(currentDirectory + standardListBattlesBGPath)
+	fieldBG := resource.LoadRequiredTexture(fieldBGPath)
+	rankedListBattlesBG := resource.LoadRequiredTexture(rankedListBattlesBGPath)
+	standardListBattlesBG := resource.LoadRequiredTexture(standardListBattlesBGPath)
 
-	leftBtn := CreateButton(currentDirectory+btnLeftPressedPath, currentDirectory+btnLeftReleasedPath, textureBounds, rl.Rectangle{554, 484, 21, 31})
-	rightBtn := CreateButton(currentDirectory+btnRightPressedPath, currentDirectory+btnRightReleasedPath, textureBounds, rl.Rectangle{707, 484, 21, 31})
+	leftBtn := CreateButton(btnLeftPressedPath, btnLeftReleasedPath, textureBounds, rl.Rectangle{554, 484, 21, 31})
+	rightBtn := CreateButton(btnRightPressedPath, btnRightReleasedPath, textureBounds, rl.Rectangle{707, 484, 21, 31})
 
 	return &ListBattlesUI{
 		numberBattles: 0,
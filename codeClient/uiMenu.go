@@ -1,7 +1,8 @@
 package main
 
 import (
-	"codeClient/connection"
+	"codeClient/protocol"
+	"codeClient/resource"
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"net"
 )
@@ -22,27 +23,27 @@ type MenuUI struct {
 
 func CreateMenuUI(code string) *MenuUI {
 	const (
-		menuBGPath = "\\resources\\UI\\Menu\\Background\\Menu.png"
-
-		btnExitPressedPath         = "\\resources\\UI\\Menu\\Button\\Exit\\ExitPressed.png"
-		btnExitReleasedPath        = "\\resources\\UI\\Menu\\Button\\Exit\\ExitReleased.png"
-		btnFriendsPressedPath      = "\\resources\\UI\\Menu\\Button\\Friends\\FriendsPressed.png"
-		btnFriendsReleasedPath     = "\\resources\\UI\\Menu\\Button\\Friends\\FriendsReleased.png"
-		btnBattlePressedPath       = "\\resources\\UI\\Menu\\Button\\Battle\\BattlePressed.png"
-		btnBattleReleasedPath      = "\\resources\\UI\\Menu\\Button\\Battle\\BattleReleased.png"
-		btnShopPressedPath         = "\\resources\\UI\\Menu\\Button\\Shop\\ShopPressed.png"
-		btnShopReleasedPath        = "\\resources\\UI\\Menu\\Button\\Shop\\ShopReleased.png"
-		btnListBattlesPressedPath  = "\\resources\\UI\\Menu\\Button\\ListBattles\\ListBattlesPressed.png"
-		btnListBattlesReleasedPath = "\\resources\\UI\\Menu\\Button\\ListBattles\\ListBattlesReleased.png"
+		menuBGPath = "UI/Menu/Background/Menu.png"
+
+		btnExitPressedPath         = "UI/Menu/Button/Exit/ExitPressed.png"
+		btnExitReleasedPath        = "UI/Menu/Button/Exit/ExitReleased.png"
+		btnFriendsPressedPath      = "UI/Menu/Button/Friends/FriendsPressed.png"
+		btnFriendsReleasedPath     = "UI/Menu/Button/Friends/FriendsReleased.png"
+		btnBattlePressedPath       = "UI/Menu/Button/Battle/BattlePressed.png"
+		btnBattleReleasedPath      = "UI/Menu/Button/Battle/BattleReleased.png"
+		btnShopPressedPath         = "UI/Menu/Button/Shop/ShopPressed.png"
+		btnShopReleasedPath        = "UI/Menu/Button/Shop/ShopReleased.png"
+		btnListBattlesPressedPath  = "UI/Menu/Button/ListBattles/ListBattlesPressed.png"
+		btnListBattlesReleasedPath = "UI/Menu/Button/ListBattles/ListBattlesReleased.png"
 	)
 	textureBounds := rl.Rectangle{0, 0, baseWidth, baseHeight}
 
-	backgroundTexture := rl.LoadTexture(currentDirectory + menuBGPath)
-	exitBtn := CreateButton(currentDirectory+btnExitPressedPath, currentDirectory+btnExitReleasedPath, textureBounds, rl.Rectangle{26, 25, 53, 54})
-	friendsBtn := CreateButton(currentDirectory+btnFriendsPressedPath, currentDirectory+btnFriendsReleasedPath, textureBounds, rl.Rectangle{208, 25, 107, 54})
-	battleBtn := CreateButton(currentDirectory+btnBattlePressedPath, currentDirectory+btnBattleReleasedPath, textureBounds, rl.Rectangle{379, 25, 148, 54})
-	shopBtn := CreateButton(currentDirectory+btnShopPressedPath, currentDirectory+btnShopReleasedPath, textureBounds, rl.Rectangle{591, 25, 140, 54})
-	listBattleBtn := CreateButton(currentDirectory+btnListBattlesPressedPath, currentDirectory+btnListBattlesReleasedPath, textureBounds, rl.Rectangle{795, 25, 109, 54})
+	backgroundTexture := resource.LoadRequiredTexture(menuBGPath)
+	exitBtn := CreateButton(btnExitPressedPath, btnExitReleasedPath, textureBounds, rl.Rectangle{26, 25, 53, 54})
+	friendsBtn := CreateButton(btnFriendsPressedPath, btnFriendsReleasedPath, textureBounds, rl.Rectangle{208, 25, 107, 54})
+	battleBtn := CreateButton(btnBattlePressedPath, btnBattleReleasedPath, textureBounds, rl.Rectangle{379, 25, 148, 54})
+	shopBtn := CreateButton(btnShopPressedPath, btnShopReleasedPath, textureBounds, rl.Rectangle{591, 25, 140, 54})
+	listBattleBtn := CreateButton(btnListBattlesPressedPath, btnListBattlesReleasedPath, textureBounds, rl.Rectangle{795, 25, 109, 54})
 
 	return &MenuUI{
 		publicID:       "Код игрока: " + code,
@@ -58,7 +59,7 @@ func CreateMenuUI(code string) *MenuUI {
 }
 
 func (m *MenuUI) SetTexture(backgroundPath string) {
-	newBackground := rl.LoadTexture(backgroundPath)
+	newBackground := resource.LoadTexture(backgroundPath)
 	if m.menuBG.ID != 0 {
 		rl.UnloadTexture(m.menuBG)
 	}
@@ -136,7 +137,7 @@ func (m *MenuUI) HandleInput(conn net.Conn, gameState *string) bool {
 		} else if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
 			clearChannel(friendsUI.friendsDataCh)
 
-			sendInput(conn, connection.MsgFriendsData, nil)
+			sendInput(conn, protocol.MsgFriendsData, nil)
 			m.friendsBtn.Released()
 			*gameState = stateListFriends
 		}
@@ -164,7 +165,7 @@ func (m *MenuUI) HandleInput(conn net.Conn, gameState *string) bool {
 			clearChannel(shopUI.shopDataCh)
 			clearChannel(shopUI.purchaseReceiptCh)
 
-			sendInput(conn, connection.MsgShopData, nil)
+			sendInput(conn, protocol.MsgShopData, nil)
 			m.shopBtn.Released()
 			*gameState = stateShop
 		}
@@ -179,7 +180,7 @@ func (m *MenuUI) HandleInput(conn net.Conn, gameState *string) bool {
 		} else if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
 			clearChannel(listBattlesUI.battleDataCh)
 
-			sendInput(conn, connection.MsgListBattles, nil)
+			sendInput(conn, protocol.MsgListBattles, nil)
 			m.listBattleBtn.Released()
 			*gameState = stateListBattles
 		}
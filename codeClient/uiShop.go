@@ -1,7 +1,8 @@
 package main
 
 import (
-	"codeClient/connection"
+	"codeClient/protocol"
+	"codeClient/resource"
 	"fmt"
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"net"
@@ -98,7 +99,7 @@ type ShopUI struct {
 
 	shopDataCh         chan ShopData
 	purchaseReceiptCh  chan PurchaseReceipt
-	updateCharacterCh  chan connection.CharacterData
+	updateCharacterCh  chan protocol.CharacterData
 	updateBackgroundCh chan string
 
 	shopData               ShopData
@@ -128,10 +129,10 @@ type ShopUI struct {
 
 func createBackgroundProductCard(x, y float32) *productCard {
 	const (
-		btnBuyPressedPath     = "\\resources\\UI\\Shop\\Button\\Buy\\BuyPressed.png"
-		btnBuyReleasedPath    = "\\resources\\UI\\Shop\\Button\\Buy\\BuyReleased.png"
-		btnSelectPressedPath  = "\\resources\\UI\\Shop\\Button\\Select\\SelectPressed.png"
-		btnSelectReleasedPath = "\\resources\\UI\\Shop\\Button\\Select\\SelectReleased.png"
+		btnBuyPressedPath     = "UI/Shop/Button/Buy/BuyPressed.png"
+		btnBuyReleasedPath    = "UI/Shop/Button/Buy/BuyReleased.png"
+		btnSelectPressedPath  = "UI/Shop/Button/Select/SelectPressed.png"
+		btnSelectReleasedPath = "UI/Shop/Button/Select/SelectReleased.png"
 		widthProductCard      = 312
 		heightProductCard     = 233
 	)
@@ -145,8 +146,8 @@ func createBackgroundProductCard(x, y float32) *productCard {
 		selectBounds    = rl.Rectangle{pos.X + 60, pos.Y + 199, 192, 29}
 		imagePos        = rl.Rectangle{pos.X + 12, pos.Y + 9, 288, 162}
 	)
-	buyBtn := CreateButton(currentDirectory+btnBuyPressedPath, currentDirectory+btnBuyReleasedPath, buyVisualBounds, buyClickBounds)
-	selectBtn := CreateButton(currentDirectory+btnSelectPressedPath, currentDirectory+btnSelectReleasedPath, selectBounds, selectBounds)
+	buyBtn := CreateButton(btnBuyPressedPath, btnBuyReleasedPath, buyVisualBounds, buyClickBounds)
+	selectBtn := CreateButton(btnSelectPressedPath, btnSelectReleasedPath, selectBounds, selectBounds)
 
 	return &productCard{
 		size:             size,
@@ -160,10 +161,10 @@ func createBackgroundProductCard(x, y float32) *productCard {
 
 func createCharacterProductCard(x, y float32) *productCard {
 	const (
-		btnBuyPressedPath     = "\\resources\\UI\\Shop\\Button\\Buy\\BuyPressed.png"
-		btnBuyReleasedPath    = "\\resources\\UI\\Shop\\Button\\Buy\\BuyReleased.png"
-		btnSelectPressedPath  = "\\resources\\UI\\Shop\\Button\\Select\\SelectPressed.png"
-		btnSelectReleasedPath = "\\resources\\UI\\Shop\\Button\\Select\\SelectReleased.png"
+		btnBuyPressedPath     = "UI/Shop/Button/Buy/BuyPressed.png"
+		btnBuyReleasedPath    = "UI/Shop/Button/Buy/BuyReleased.png"
+		btnSelectPressedPath  = "UI/Shop/Button/Select/SelectPressed.png"
+		btnSelectReleasedPath = "UI/Shop/Button/Select/SelectReleased.png"
 		widthProductCard      = 416
 		heightProductCard     = 343
 	)
@@ -180,8 +181,8 @@ func createCharacterProductCard(x, y float32) *productCard {
 		selectBounds         = rl.Rectangle{pos.X + 214, pos.Y + 309, 192, 29}
 		imagePos             = rl.Rectangle{pos.X + 16, pos.Y + 65, 384, 210}
 	)
-	buyBtn := CreateButton(currentDirectory+btnBuyPressedPath, currentDirectory+btnBuyReleasedPath, buyVisualBounds, buyClickBounds)
-	selectBtn := CreateButton(currentDirectory+btnSelectPressedPath, currentDirectory+btnSelectReleasedPath, selectBounds, selectBounds)
+	buyBtn := CreateButton(btnBuyPressedPath, btnBuyReleasedPath, buyVisualBounds, buyClickBounds)
+	selectBtn := CreateButton(btnSelectPressedPath, btnSelectReleasedPath, selectBounds, selectBounds)
 
 	return &productCard{
 		size:                 size,
@@ -210,8 +211,8 @@ func (p *productCard) draw(scaleX, scaleY float32) {
 // ----------------------------------------- shopGridUI
 func createGridUI() *shopGridUI {
 	const (
-		backgroundCardBGPath  = "\\resources\\UI\\Shop\\Background\\BackgroundCard.png"
-		characterCardBGPath   = "\\resources\\UI\\Shop\\Background\\CharacterCard.png"
+		backgroundCardBGPath  = "UI/Shop/Background/BackgroundCard.png"
+		characterCardBGPath   = "UI/Shop/Background/CharacterCard.png"
 		backgroundCardOffsetX = 312 + 0
 		backgroundCardOffsetY = 233 + 5
 		characterCardOffsetX  = 416 + 0
@@ -228,8 +229,8 @@ func createGridUI() *shopGridUI {
 		characterCardList      = make([]*productCard, characterCardCols)
 	)
 
-	backgroundCardBG := rl.LoadTexture(currentDirectory + backgroundCardBGPath)
-	characterCardBG := rl.LoadTexture(currentDirectory + characterCardBGPath)
+	backgroundCardBG := resource.LoadRequiredTexture(backgroundCardBGPath)
+	characterCardBG := resource.LoadRequiredTexture(characterCardBGPath)
 
 	for row := 0; row < backgroundCardRows; row++ {
 		for col := 0; col < backgroundCardCols; col++ {
@@ -404,16 +405,16 @@ func (s *shopGridUI) handelCharacterGrid(lenCardList int) *ShopAction {
 
 func CreateShopUI() *ShopUI {
 	const (
-		backgroundShopBGPath     = "\\resources\\UI\\Shop\\Background\\BackgroundShop .png"
-		characterShopBGPath      = "\\resources\\UI\\Shop\\Background\\CharacterShop .png"
-		backgroundCardBGPath     = "\\resources\\UI\\Shop\\Background\\BackgroundCard.png"
-		characterCardBGPath      = "\\resources\\UI\\Shop\\Background\\CharacterCard.png"
-		btnPurchasesPressedPath  = "\\resources\\UI\\Shop\\Button\\Purchases\\PurchasesPressed.png"
-		btnPurchasesReleasedPath = "\\resources\\UI\\Shop\\Button\\Purchases\\PurchasesReleased.png"
-		btnLeftPressedPath       = "\\resources\\UI\\Shop\\Button\\Left\\LeftPressed.png"
-		btnLeftReleasedPath      = "\\resources\\UI\\Shop\\Button\\Left\\LeftReleased.png"
-		btnRightPressedPath      = "\\resources\\UI\\Shop\\Button\\Right\\RightPressed.png"
-		btnRightReleasedPath     = "\\resources\\UI\\Shop\\Button\\Right\\RightReleased.png"
+		backgroundShopBGPath     = "UI/Shop/Background/BackgroundShop .png"
+		characterShopBGPath      = "UI/Shop/Background/CharacterShop .png"
+		backgroundCardBGPath     = "UI/Shop/Background/BackgroundCard.png"
+		characterCardBGPath      = "UI/Shop/Background/CharacterCard.png"
+		btnPurchasesPressedPath  = "UI/Shop/Button/Purchases/PurchasesPressed.png"
+		btnPurchasesReleasedPath = "UI/Shop/Button/Purchases/PurchasesReleased.png"
+		btnLeftPressedPath       = "UI/Shop/Button/Left/LeftPressed.png"
+		btnLeftReleasedPath      = "UI/Shop/Button/Left/LeftReleased.png"
+		btnRightPressedPath      = "UI/Shop/Button/Right/RightPressed.png"
+		btnRightReleasedPath     = "UI/Shop/Button/Right/RightReleased.png"
 	)
 	var (
 		textureBounds             = rl.Rectangle{0, 0, baseWidth, baseHeight}
@@ -422,14 +423,14 @@ func CreateShopUI() *ShopUI {
 		charactersBounds          = rl.Rectangle{132, 19, 169, 33}
 	)
 
-	backgroundShopBG := rl.LoadTexture(currentDirectory + backgroundShopBGPath)
-	characterShopBG := rl.LoadTexture(currentDirectory + characterShopBGPath)
-	backgroundCardBG := rl.LoadTexture(currentDirectory + backgroundCardBGPath)
-	characterCardBG := rl.LoadTexture(currentDirectory + characterCardBGPath)
+	backgroundShopBG := resource.LoadRequiredTexture(backgroundShopBGPath)
+	characterShopBG := resource.LoadRequiredTexture(characterShopBGPath)
+	backgroundCardBG := resource.LoadRequiredTexture(backgroundCardBGPath)
+	characterCardBG := resource.LoadRequiredTexture(characterCardBGPath)
 
-	purchasesBtn := CreateButton(currentDirectory+btnPurchasesPressedPath, currentDirectory+btnPurchasesReleasedPath, purchasesManagementBounds, purchasesManagementBounds)
-	leftBtn := CreateButton(currentDirectory+btnLeftPressedPath, currentDirectory+btnLeftReleasedPath, textureBounds, rl.Rectangle{554, 544, 21, 31})
-	rightBtn := CreateButton(currentDirectory+btnRightPressedPath, currentDirectory+btnRightReleasedPath, textureBounds, rl.Rectangle{706, 544, 21, 31})
+	purchasesBtn := CreateButton(btnPurchasesPressedPath, btnPurchasesReleasedPath, purchasesManagementBounds, purchasesManagementBounds)
+	leftBtn := CreateButton(btnLeftPressedPath, btnLeftReleasedPath, textureBounds, rl.Rectangle{554, 544, 21, 31})
+	rightBtn := CreateButton(btnRightPressedPath, btnRightReleasedPath, textureBounds, rl.Rectangle{706, 544, 21, 31})
 
 	return &ShopUI{
 		state:        shopWaitingForResponse,
@@ -438,7 +439,7 @@ func CreateShopUI() *ShopUI {
 
 		shopDataCh:         make(chan ShopData, 1),
 		purchaseReceiptCh:  make(chan PurchaseReceipt, 1),
-		updateCharacterCh:  make(chan connection.CharacterData, 1),
+		updateCharacterCh:  make(chan protocol.CharacterData, 1),
 		updateBackgroundCh: make(chan string, 1),
 
 		shopGrid: createGridUI(),
@@ -614,7 +615,7 @@ func (s *ShopUI) stateShopBackgrounds(conn net.Conn) {
 		s.leftBtn.Released()
 		if action := s.shopGrid.handelBackgroundGrid(len(s.currentPageBackgrounds)); action != nil {
 			action.ProductID = s.currentPageBackgrounds[action.ProductID].ID
-			sendInput(conn, connection.MsgShopAction, action)
+			sendInput(conn, protocol.MsgShopAction, action)
 		}
 	}
 
@@ -671,7 +672,7 @@ func (s *ShopUI) stateShopCharacters(conn net.Conn) {
 		s.leftBtn.Released()
 		if action := s.shopGrid.handelCharacterGrid(len(s.currentPageCharacters)); action != nil {
 			action.ProductID = s.currentPageCharacters[action.ProductID].ID
-			sendInput(conn, connection.MsgShopAction, action)
+			sendInput(conn, protocol.MsgShopAction, action)
 		}
 	}
 }
@@ -760,28 +761,28 @@ func (s *ShopUI) loadPreview() {
 	for i := range s.shopData.AvailableBackgrounds {
 		item := &s.shopData.AvailableBackgrounds[i]
 		if item.AssetPath != "" && item.Preview.ID == 0 {
-			item.Preview = rl.LoadTexture(currentDirectory + item.AssetPath)
+			item.Preview = resource.LoadTexture(item.AssetPath)
 		}
 	}
 
 	for i := range s.shopData.PurchasedBackgrounds {
 		item := &s.shopData.PurchasedBackgrounds[i]
 		if item.AssetPath != "" && item.Preview.ID == 0 {
-			item.Preview = rl.LoadTexture(currentDirectory + item.AssetPath)
+			item.Preview = resource.LoadTexture(item.AssetPath)
 		}
 	}
 
 	for i := range s.shopData.AvailableCharacters {
 		item := &s.shopData.AvailableCharacters[i]
 		if item.AssetPath != "" && item.Preview.ID == 0 {
-			item.Preview = rl.LoadTexture(currentDirectory + item.AssetPath)
+			item.Preview = resource.LoadTexture(item.AssetPath)
 		}
 	}
 
 	for i := range s.shopData.PurchasedCharacters {
 		item := &s.shopData.PurchasedCharacters[i]
 		if item.AssetPath != "" && item.Preview.ID == 0 {
-			item.Preview = rl.LoadTexture(currentDirectory + item.AssetPath)
+			item.Preview = resource.LoadTexture(item.AssetPath)
 		}
 	}
 }
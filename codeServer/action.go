@@ -1,9 +1,8 @@
 package main
 
 import (
-	"fmt"
 	rl "github.com/gen2brain/raylib-go/raylib"
-	"log"
+	"strconv"
 	"time"
 )
 
@@ -37,6 +36,7 @@ const (
 
 // Хранит данные персонажа клиента
 type CharacterState struct {
+	character            *Character // Персонаж сессии, остаётся доступным после перезагрузки каталога
 	Health               int
 	XStart, YStart       float32
 	X, Y                 float32 // Координаты верхнего левого угла кадра с персонажем относительно экрана размером 1280 * 720
@@ -79,15 +79,17 @@ type healthUpdate struct {
 
 // Действие персонажа
 type Action struct {
-	Id      int `msgpack:"i"`
-	Command Cmd `msgpack:"c"`
+	Id      int   `msgpack:"i"`
+	Command Cmd   `msgpack:"c"`
+	Time    int64 `msgpack:"t,omitempty"` // Время ввода команды на клиенте, мс по UTC
 }
 
 // Обновляет активного персонажа
-func (ch *CharacterState) Update(idActiveCharacter int) {
-	ch.Health = activeCharacters[idActiveCharacter].Health
-	ch.XStart = float32(-activeCharacters[idActiveCharacter].XBoundary)                                 // Верхний левый угл
-	ch.YStart = float32(ScreenHeight - (GroundLevel + activeCharacters[idActiveCharacter].FrameHeight)) // Верхний левый угл
+func (ch *CharacterState) Update(character *Character) {
+	ch.character = character
+	ch.Health = character.Health
+	ch.XStart = float32(-character.XBoundary)                                 // Верхний левый угл
+	ch.YStart = float32(ScreenHeight - (GroundLevel + character.FrameHeight)) // Верхний левый угл
 	ch.X = ch.XStart
 	ch.Y = ch.YStart
 	ch.Direction = Right
@@ -99,8 +101,8 @@ func (ch *CharacterState) Update(idActiveCharacter int) {
 	ch.inBattle = false
 }
 
-// Обрабатывает команды персонажа
-func actionCharacter(action Action, client *Client, opponent *Client) {
+// Обрабатывает команды персонажа, ошибки отправляются как ответ на запрос reqID
+func actionCharacter(action Action, client *Client, opponent *Client, reqID uint32) {
 	command := action.Command
 	idCmd := action.Id
 	state := client.State
@@ -109,6 +111,11 @@ func actionCharacter(action Action, client *Client, opponent *Client) {
 		return
 	}
 
+	if !validateAction(client, action, reqID) {
+		sendCharacterState(client, opponent, idCmd, MsgActionCharacter) // Клиент вернётся к состоянию сервера
+		return
+	}
+
 	if command != cmdStopJump && state.isAttacking { // Если команда на движение пришла до того, как атака закончилась на сервере
 		if command == cmdRunRight {
 			state.isRunningAfterAttack = true
@@ -149,7 +156,7 @@ func actionCharacter(action Action, client *Client, opponent *Client) {
 		currentPositionCharacter(client)
 	case cmdAttack:
 		if state.isRunning {
-			actionCharacter(Action{idCmd - 1, cmdStopRun}, client, opponent)
+			actionCharacter(Action{Id: idCmd - 1, Command: cmdStopRun, Time: action.Time}, client, opponent, reqID)
 		}
 		state.isAttacking = true
 		state.typeAttack = int8(cmdAttack)
@@ -157,15 +164,15 @@ func actionCharacter(action Action, client *Client, opponent *Client) {
 		currentPositionCharacter(client)
 	case cmdHeavyAttack:
 		if state.isRunning {
-			actionCharacter(Action{idCmd - 1, cmdStopRun}, client, opponent)
+			actionCharacter(Action{Id: idCmd - 1, Command: cmdStopRun, Time: action.Time}, client, opponent, reqID)
 		}
 		state.isAttacking = true
 		state.typeAttack = int8(cmdHeavyAttack)
 		go attackCharacter(client, opponent, "HeavyAttack")
 		currentPositionCharacter(client)
 	default:
-		log.Printf("Неизвестная команда: %d, клиент: %s", command, client.UserID)
-		createAndSendMessage(client, MsgError, fmt.Sprintf("Неизвестная команда: %d", command))
+		client.logger().Warn("Неизвестная команда", "command", command)
+		sendReply(client, reqID, MsgError, newGameError(ErrCodeUnknownCommand, "command", strconv.Itoa(int(command))))
 		return
 	}
 	sendCharacterState(client, opponent, idCmd, MsgActionCharacter)
@@ -174,7 +181,7 @@ func actionCharacter(action Action, client *Client, opponent *Client) {
 // Обновляет позицию игрока
 func currentPositionCharacter(client *Client) {
 	state := client.State
-	ch := activeCharacters[client.ActiveCharacter]
+	ch := state.character
 	if state.isRunning {
 		elapsedRun := float32(time.Now().UnixMilli()-state.LastRunTime) / 1000.0
 		state.LastRunTime = time.Now().UnixMilli()
@@ -217,7 +224,7 @@ func attackCharacter(client, opponent *Client, typeAttack string) {
 		}
 	}
 
-	ch := activeCharacters[client.ActiveCharacter]
+	ch := client.State.character
 
 	// Задержка на время атаки
 	timer := time.NewTimer(ch.TimeAnimation[typeAttack])
@@ -244,13 +251,11 @@ func attackCharacter(client, opponent *Client, typeAttack string) {
 			damage := int(damageMultiplier * float32(ch.Damage))
 			takeHit(opponent, client, damage)
 
-			log.Printf(client.Name, " попал и нанес урон ", opponent.Name, " здровоье оппонента: ", opponent.State.Health)
+			client.logger().Debug("Попадание", "attack", typeAttack, "damage", damage, "opponent", opponent.PublicID, "opponent_health", opponent.State.Health)
 		} else {
-			log.Println(client.Name, " не попал по ", opponent.Name)
+			client.logger().Debug("Промах", "attack", typeAttack, "opponent", opponent.PublicID)
 		}
 	}
-
-	fmt.Println(time.Now())
 	currentPositionCharacter(client)
 	sendCharacterState(client, opponent, -1, MsgActionCharacter)
 }
@@ -290,8 +295,8 @@ func sendHealthUpdate(target, attacker *Client) {
 
 // Проверка пересечения кадров
 func checkBoundingBoxCollision(client, opponent *Client) (bool, rl.Rectangle) {
-	chClient := activeCharacters[client.ActiveCharacter]
-	chOpponent := activeCharacters[opponent.ActiveCharacter]
+	chClient := client.State.character
+	chOpponent := opponent.State.character
 
 	rectClient := rl.Rectangle{X: client.State.X, Y: client.State.Y, Width: float32(chClient.FrameWidth), Height: float32(chClient.FrameHeight)}
 
@@ -314,8 +319,8 @@ func checkBitMaskCollision(client, opponent *Client, typeAttack string) bool {
 		return false
 	}
 
-	chClient := activeCharacters[client.ActiveCharacter]
-	chOpponent := activeCharacters[opponent.ActiveCharacter]
+	chClient := client.State.character
+	chOpponent := opponent.State.character
 
 	maskCl := chClient.BitMaskWithWeapon[typeAttack]
 	var maskOp []uint64
@@ -405,7 +410,7 @@ func getCharacterState(client *Client, id int) ActionResult {
 func sendCharacterState(client, opponent *Client, id int, mesType MessageType) {
 	chSt := getCharacterState(client, id)
 	if opponent != nil {
-		ch := activeCharacters[client.ActiveCharacter]
+		ch := client.State.character
 		invertedX := ScreenWidth - client.State.X - float32(ch.FrameWidth)
 		sendToOpponentCharacterState(opponent, chSt, invertedX, MsgActionOpponent)
 	}
@@ -425,7 +430,7 @@ func sendToOpponentCharacterState(opponent *Client, data ActionResult, invertedX
 // Сбрасывает состояние персонажа до начального
 func restoreState(client *Client, mesType MessageType) {
 	state := client.State
-	state.Health = activeCharacters[client.ActiveCharacter].Health
+	state.Health = state.character.Health
 	state.X, state.Y = state.XStart, state.YStart
 	state.Direction = Right
 	state.VelocityY = 0
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	adminTokenEnvName = "GAME_ADMIN_TOKEN" // Переменная окружения с токеном администратора
+	maxBanReason      = 200                // Длина поля BanReason в Users
+	defaultLedgerSize = 50                 // Записей журнала денег в ответе по умолчанию
+	maxLedgerSize     = 1000
+)
+
+// Действия администратора для журнала AdminActions
+const (
+	adminBan    = "ban"
+	adminUnban  = "unban"
+	adminKick   = "kick"
+	adminAdjust = "adjust"
+)
+
+// Игрок по данным базы и состоянию на сервере
+type AdminPlayer struct {
+	UserID      int        `db:"id_User" json:"userId"`
+	PlayerID    int        `db:"id_Player" json:"playerId"`
+	PublicID    string     `db:"PublicID" json:"publicId"`
+	Login       string     `db:"Login" json:"login"`
+	Name        string     `db:"Name" json:"name"`
+	Level       int        `db:"Level" json:"level"`
+	Money       int        `db:"Money" json:"money"`
+	Rank        int        `db:"Rank" json:"rank"`
+	IsActive    bool       `db:"isActive" json:"isActive"`
+	BanReason   *string    `db:"BanReason" json:"banReason,omitempty"`
+	BannedUntil *time.Time `db:"BannedUntil" json:"bannedUntil,omitempty"`
+	Online      bool       `db:"-" json:"online"`
+	InBattle    bool       `db:"-" json:"inBattle"`
+}
+
+// Запрос блокировки. Пустой Until — блокировка без срока
+type banRequest struct {
+	PublicID string     `json:"publicId"`
+	Reason   string     `json:"reason"`
+	Until    *time.Time `json:"until"`
+}
+
+// Запрос к игроку с причиной
+type playerRequest struct {
+	PublicID string `json:"publicId"`
+	Reason   string `json:"reason"`
+}
+
+// Изменение денег, ранга и уровня на указанные величины
+type adjustRequest struct {
+	PublicID string `json:"publicId"`
+	Money    int    `json:"money"`
+	Rank     int    `json:"rank"`
+	Level    int    `json:"level"`
+	Reason   string `json:"reason"`
+}
+
+// Идущий бой для списка администратора
+type AdminBattle struct {
+	ID        uint64    `json:"id"`
+	Player1   string    `json:"player1"`
+	Player2   string    `json:"player2"`
+	IsRanked  bool      `json:"isRanked"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+}
+
+// HTTP сервер администратора, все запросы требуют токен в заголовке Authorization: Bearer
+func adminHandler(addr, token string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/player", adminAuth(token, http.MethodGet, handleAdminPlayer))
+	mux.HandleFunc("/admin/ban", adminAuth(token, http.MethodPost, handleAdminBan))
+	mux.HandleFunc("/admin/unban", adminAuth(token, http.MethodPost, handleAdminUnban))
+	mux.HandleFunc("/admin/kick", adminAuth(token, http.MethodPost, handleAdminKick))
+	mux.HandleFunc("/admin/adjust", adminAuth(token, http.MethodPost, handleAdminAdjust))
+	mux.HandleFunc("/admin/battles", adminAuth(token, http.MethodGet, handleAdminBattles))
+	mux.HandleFunc("/admin/ledger", adminAuth(token, http.MethodGet, handleAdminLedger))
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	registerListener(server)
+	slog.Info("API администратора запущено", "addr", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatal("Ошибка запуска API администратора", "err", err)
+	}
+}
+
+// Проверяет метод и токен администратора
+func adminAuth(token, method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			adminError(w, http.StatusMethodNotAllowed, "метод не поддерживается")
+			return
+		}
+		header := r.Header.Get("Authorization")
+		got := strings.TrimPrefix(header, "Bearer ")
+		if got == header || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			slog.Warn("API администратора: отказ в доступе", "component", "admin", "path", r.URL.Path, "remote", r.RemoteAddr)
+			adminError(w, http.StatusUnauthorized, "неверный токен администратора")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func adminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("API администратора: ошибка записи ответа", "component", "admin", "err", err)
+	}
+}
+
+func adminError(w http.ResponseWriter, status int, message string) {
+	adminJSON(w, status, map[string]string{"error": message})
+}
+
+// Разбирает тело запроса, при ошибке отвечает сам
+func decodeAdminRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		adminError(w, http.StatusBadRequest, "некорректное тело запроса: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// Находит игрока по PublicID, при ошибке отвечает сам
+func findAdminPlayer(w http.ResponseWriter, publicID, login string) (*AdminPlayer, bool) {
+	if publicID == "" && login == "" {
+		adminError(w, http.StatusBadRequest, "нужен publicId или login")
+		return nil, false
+	}
+	player, err := store.getAdminPlayer(publicID, login)
+	if errors.Is(err, sql.ErrNoRows) {
+		adminError(w, http.StatusNotFound, "игрок не найден")
+		return nil, false
+	}
+	if err != nil {
+		adminError(w, http.StatusInternalServerError, "ошибка базы данных")
+		return nil, false
+	}
+	if client := findOnlineClient(player.PublicID); client != nil {
+		player.Online = true
+		player.InBattle = client.State != nil && client.State.inBattle
+	}
+	return player, true
+}
+
+// Авторизованный клиент по публичному ID
+func findOnlineClient(publicID string) *Client {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	return authorizedClients[publicID]
+}
+
+// Отключает клиента, сообщив причину. Соединение закрывается после задержки в отдельной горутине,
+// чтобы запрос администратора не ждал её
+func kickClient(client *Client, reason *GameError) {
+	go exitSessions(reason, client)
+}
+
+// Сообщает обработчику клиента, что администратор изменил деньги, ранг или уровень игрока
+func notifyStatsChanged(client *Client) {
+	select {
+	case client.statsChanged <- struct{}{}:
+	default: // Обработчик ещё не прочитал прошлое уведомление, данные будут перечитаны один раз
+	}
+}
+
+// Перечитывает деньги, ранг и уровень игрока из базы. Вызывается в обработчике клиента,
+// который единственный меняет его данные в памяти
+func reloadPlayerStats(client *Client) {
+	player, err := store.getAdminPlayer(client.PublicID, "")
+	if err != nil {
+		client.logger().Error("Ошибка обновления данных игрока", "err", err)
+		return
+	}
+	moneyChanged := player.Money != client.Money
+	client.Money, client.Rank, client.Level = player.Money, player.Rank, player.Level
+	if moneyChanged {
+		createAndSendMessage(client, MsgMoneyUpdate, client.Money)
+	}
+}
+
+// GET /admin/player?publicId=...&login=...
+func handleAdminPlayer(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	player, ok := findAdminPlayer(w, query.Get("publicId"), query.Get("login"))
+	if !ok {
+		return
+	}
+	adminJSON(w, http.StatusOK, player)
+}
+
+// POST /admin/ban {"publicId", "reason", "until"}
+func handleAdminBan(w http.ResponseWriter, r *http.Request) {
+	var req banRequest
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		adminError(w, http.StatusBadRequest, "нужна причина блокировки")
+		return
+	}
+	if runes := []rune(req.Reason); len(runes) > maxBanReason {
+		req.Reason = string(runes[:maxBanReason])
+	}
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		adminError(w, http.StatusBadRequest, "срок блокировки уже прошёл")
+		return
+	}
+	player, ok := findAdminPlayer(w, req.PublicID, "")
+	if !ok {
+		return
+	}
+	if err := store.banUser(player.UserID, req.Reason, req.Until); err != nil {
+		adminError(w, http.StatusInternalServerError, "ошибка базы данных")
+		return
+	}
+	until := "без срока"
+	if req.Until != nil {
+		until = req.Until.Format(time.RFC3339)
+	}
+	auditAdminAction(r, player.PlayerID, adminBan, fmt.Sprintf("до %s: %s", until, req.Reason))
+
+	if client := findOnlineClient(player.PublicID); client != nil {
+		kickClient(client, banError(player.Login, req.Reason, req.Until))
+	}
+	if player, ok = findAdminPlayer(w, player.PublicID, ""); ok {
+		adminJSON(w, http.StatusOK, player)
+	}
+}
+
+// POST /admin/unban {"publicId"}
+func handleAdminUnban(w http.ResponseWriter, r *http.Request) {
+	var req playerRequest
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	player, ok := findAdminPlayer(w, req.PublicID, "")
+	if !ok {
+		return
+	}
+	if err := store.unbanUser(player.UserID); err != nil {
+		adminError(w, http.StatusInternalServerError, "ошибка базы данных")
+		return
+	}
+	auditAdminAction(r, player.PlayerID, adminUnban, req.Reason)
+	if player, ok = findAdminPlayer(w, player.PublicID, ""); ok {
+		adminJSON(w, http.StatusOK, player)
+	}
+}
+
+// POST /admin/kick {"publicId", "reason"}
+func handleAdminKick(w http.ResponseWriter, r *http.Request) {
+	var req playerRequest
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	client := findOnlineClient(req.PublicID)
+	if client == nil {
+		adminError(w, http.StatusNotFound, "игрок не в сети")
+		return
+	}
+	auditAdminAction(r, client.PlayerID, adminKick, req.Reason)
+	kickClient(client, newGameError(ErrCodeKicked, "reason", req.Reason))
+	adminJSON(w, http.StatusOK, map[string]string{"kicked": req.PublicID})
+}
+
+// POST /admin/adjust {"publicId", "money", "rank", "level", "reason"}, значения прибавляются к текущим
+func handleAdminAdjust(w http.ResponseWriter, r *http.Request) {
+	var req adjustRequest
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		adminError(w, http.StatusBadRequest, "нужна причина изменения")
+		return
+	}
+	if req.Money == 0 && req.Rank == 0 && req.Level == 0 {
+		adminError(w, http.StatusBadRequest, "нет изменений")
+		return
+	}
+	player, ok := findAdminPlayer(w, req.PublicID, "")
+	if !ok {
+		return
+	}
+
+	// Изменение прибавляется в базе. Данные клиента в памяти меняет только его обработчик,
+	// поэтому игрок в сети перечитывает их сам и получает новый баланс
+	if _, err := store.adjustPlayerStats(player.PlayerID, req.Money, req.Rank, req.Level); err != nil {
+		adminError(w, http.StatusInternalServerError, "ошибка базы данных")
+		return
+	}
+	if client := findOnlineClient(player.PublicID); client != nil {
+		notifyStatsChanged(client)
+	}
+	auditAdminAction(r, player.PlayerID, adminAdjust, fmt.Sprintf("money %+d, rank %+d, level %+d: %s", req.Money, req.Rank, req.Level, req.Reason))
+
+	if player, ok = findAdminPlayer(w, player.PublicID, ""); ok {
+		adminJSON(w, http.StatusOK, player)
+	}
+}
+
+// GET /admin/ledger?publicId=...&login=...&limit=...
+// Последние записи журнала денег и проверка, что их сумма совпадает с балансом в базе
+func handleAdminLedger(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := defaultLedgerSize
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxLedgerSize {
+			adminError(w, http.StatusBadRequest, fmt.Sprintf("limit должен быть от 1 до %d", maxLedgerSize))
+			return
+		}
+		limit = n
+	}
+	player, ok := findAdminPlayer(w, query.Get("publicId"), query.Get("login"))
+	if !ok {
+		return
+	}
+	entries, sum, err := store.getMoneyLedger(player.PlayerID, limit)
+	if err != nil {
+		adminError(w, http.StatusInternalServerError, "ошибка базы данных")
+		return
+	}
+	adminJSON(w, http.StatusOK, AdminLedger{
+		Player:     player,
+		Entries:    entries,
+		LedgerSum:  sum,
+		Consistent: sum == player.Money,
+	})
+}
+
+// GET /admin/battles
+func handleAdminBattles(w http.ResponseWriter, r *http.Request) {
+	battlesMutex.Lock()
+	battles := make([]AdminBattle, 0, len(activeBattles))
+	for battle := range activeBattles {
+		battles = append(battles, AdminBattle{
+			ID:        battle.ID,
+			Player1:   battle.Player1.PublicID,
+			Player2:   battle.Player2.PublicID,
+			IsRanked:  battle.IsRanked,
+			StartTime: battle.StartTime,
+			EndTime:   battle.EndTime,
+		})
+	}
+	battlesMutex.Unlock()
+	adminJSON(w, http.StatusOK, battles)
+}
+
+// Ошибка для заблокированного игрока: с причиной и, если есть, сроком блокировки
+func banError(login, reason string, until *time.Time) *GameError {
+	if reason == "" {
+		reason = "причина не указана"
+	}
+	if until == nil {
+		return newGameError(ErrCodeUserBlocked, "login", login, "reason", reason)
+	}
+	return newGameError(ErrCodeUserBannedUntil, "login", login, "reason", reason, "until", until.Format("02.01.2006 15:04"))
+}
+
+// Записывает действие администратора в журнал
+func auditAdminAction(r *http.Request, playerID int, action, details string) {
+	slog.Info("Действие администратора", "component", "admin", "action", action, "player_id", playerID, "remote", r.RemoteAddr, "details", details)
+	if runes := []rune(details); len(runes) > 500 {
+		details = string(runes[:500])
+	}
+	if err := store.insertAdminAction(playerID, action, details, hostIP(r.RemoteAddr)); err != nil {
+		dbLogger("insert_admin_action", "player_id", playerID).Error("Ошибка записи действия администратора", "err", err)
+	}
+}
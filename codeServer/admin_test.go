@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"codeClient/protocol"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Вызывает обработчик изменения баланса и возвращает игрока из ответа
+func postAdminAdjust(t *testing.T, req adjustRequest) *AdminPlayer {
+	t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	handleAdminAdjust(w, httptest.NewRequest(http.MethodPost, "/admin/adjust", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("изменение %+v: статус %d, %s", req, w.Code, w.Body.String())
+	}
+	player := new(AdminPlayer)
+	if err = json.Unmarshal(w.Body.Bytes(), player); err != nil {
+		t.Fatal(err)
+	}
+	return player
+}
+
+// После изменения администратором журнал денег сходится с балансом, в том числе при обрезке до нуля
+func TestAdminAdjustLedger(t *testing.T) {
+	s := startTestServer(t, testServerConfig{})
+	ctx := testContext(t)
+	alice := s.player("alice")
+	publicID := alice.User.PublicID
+
+	player := postAdminAdjust(t, adjustRequest{PublicID: publicID, Money: 50, Rank: 5, Level: 1, Reason: "компенсация"})
+	if player.Money != startMoney+50 || player.Rank != 5 || player.Level != 1 {
+		t.Errorf("после изменения %+v", *player)
+	}
+	// Игрок в сети получает новый баланс
+	msg, err := alice.WaitFor(ctx, protocol.MsgMoneyUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var money int
+	if err = msg.Decode(&money); err != nil || money != player.Money {
+		t.Errorf("клиент получил баланс %d, ожидался %d: %v", money, player.Money, err)
+	}
+
+	// Списание больше баланса обрезается до нуля, в журнал попадает фактическое изменение
+	player = postAdminAdjust(t, adjustRequest{PublicID: publicID, Money: -10 * startMoney, Reason: "штраф"})
+	if player.Money != 0 {
+		t.Errorf("после списания %d денег, ожидалось 0", player.Money)
+	}
+
+	entries, sum, err := s.Store.getMoneyLedger(player.PlayerID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || sum != player.Money {
+		t.Fatalf("журнал денег %+v с суммой %d, баланс %d", entries, sum, player.Money)
+	}
+	if e := entries[0]; e.Reason != ledgerAdmin || e.Amount != -(startMoney+50) {
+		t.Errorf("запись о списании %+v", e)
+	}
+	if e := entries[1]; e.Reason != ledgerAdmin || e.Amount != 50 {
+		t.Errorf("запись о начислении %+v", e)
+	}
+}
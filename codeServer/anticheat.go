@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Режим античита, задаётся флагом -anticheat
+const (
+	antiCheatOff  = "off"  // Проверки отключены
+	antiCheatFlag = "flag" // Подозрительные игроки только попадают в отчёт
+	antiCheatKick = "kick" // Нарушители отключаются от сервера
+)
+
+var antiCheatMode = antiCheatFlag
+
+const (
+	cadenceWindow          = 5 * time.Second        // Окно подсчёта команд по часам сервера
+	networkJitter          = 300 * time.Millisecond // Насколько сеть может сблизить честные команды: пачки KCP и повторы пакетов
+	attackCadenceTolerance = 150 * time.Millisecond // Запас на округление длительности анимации у клиента
+	minDirectionFlip       = 50 * time.Millisecond  // Быстрее человек сменить направление не успевает
+	maxClockLead           = time.Second            // Насколько часы клиента могут уйти вперёд часов сервера
+	airborneTolerance      = 20                     // Высота над землёй в пикселях, с которой персонаж точно в воздухе
+	cheatFlagThreshold     = 10                     // Подозрительных событий до отчёта
+	cheatKickThreshold     = 30                     // Подозрительных событий до отключения в режиме kick
+)
+
+// Тип подозрительного события
+type cheatEvent string
+
+const (
+	cheatAttackCooldown cheatEvent = "attack_cooldown" // Атака раньше окончания предыдущей
+	cheatDirectionFlip  cheatEvent = "direction_flip"  // Смена направления быстрее реакции человека
+	cheatAirJump        cheatEvent = "air_jump"        // Прыжок в воздухе
+	cheatClockLead      cheatEvent = "clock_lead"      // Часы клиента идут быстрее часов сервера
+	cheatClientCadence  cheatEvent = "client_cadence"  // По меткам клиента атаки чаще длительности анимации
+)
+
+// Проверяет допустимость значения флага -anticheat
+func parseAntiCheatMode(mode string) (string, error) {
+	switch mode {
+	case antiCheatOff, antiCheatFlag, antiCheatKick:
+		return mode, nil
+	}
+	return "", fmt.Errorf("неизвестный режим античита %s, доступны: %s, %s, %s", mode, antiCheatOff, antiCheatFlag, antiCheatKick)
+}
+
+// Команда персонажа в окне подсчёта
+type windowCommand struct {
+	at       int64         // Время получения сервером, мс
+	duration time.Duration // Сколько команда занимает персонажа
+}
+
+// Каденция команд и подозрительные события клиента. Используется только из обработки команд персонажа
+type cheatTracker struct {
+	attacks          []windowCommand // Атаки, полученные за последнее окно
+	flips            []windowCommand // Смены направления бега за последнее окно
+	runDirection     float32
+	lastClientAttack int64         // Время последней атаки по часам клиента, мс
+	attackDuration   time.Duration // Длительность анимации последней атаки
+	clockOffset      int64         // Разница часов сервера и клиента, мс
+	clockSynced      bool
+	events           map[cheatEvent]int
+	total            int
+	flagged          bool
+}
+
+// Проверяет, могла ли команда прийти от честного клиента. В режиме flag подозрительная команда
+// только учитывается, в режиме kick отбрасывается. reqID - запрос, которым пришла команда.
+// Темп команд считается по времени получения на сервере: KCP доставляет команды пачками, поэтому
+// проверяется не промежуток между соседними командами, а их число за окно с запасом на задержки сети
+func validateAction(client *Client, action Action, reqID uint32) bool {
+	if antiCheatMode == antiCheatOff {
+		return true
+	}
+	t := &client.cheat
+	state := client.State
+	now := time.Now().UnixMilli()
+	t.checkClientTime(client, action, reqID, now)
+
+	var event cheatEvent
+	switch action.Command {
+	case cmdAttack, cmdHeavyAttack:
+		typeAttack := "Attack"
+		if action.Command == cmdHeavyAttack {
+			typeAttack = "HeavyAttack"
+		}
+		// Честные атаки идут одна за другой, поэтому анимации атак за окно укладываются в окно
+		t.attacks = recentCommands(t.attacks, now)
+		var busy time.Duration
+		for _, attack := range t.attacks {
+			busy += attack.duration
+		}
+		if busy > cadenceWindow+networkJitter+attackCadenceTolerance {
+			event = cheatAttackCooldown
+		}
+		t.attacks = append(t.attacks, windowCommand{at: now, duration: state.character.TimeAnimation[typeAttack]})
+		t.attackDuration = state.character.TimeAnimation[typeAttack]
+
+	case cmdRunRight, cmdRunLeft:
+		direction := Right
+		if action.Command == cmdRunLeft {
+			direction = Left
+		}
+		if t.runDirection != 0 && direction != t.runDirection {
+			t.flips = recentCommands(t.flips, now)
+			if len(t.flips) > int((cadenceWindow+networkJitter)/minDirectionFlip) {
+				event = cheatDirectionFlip
+			}
+			t.flips = append(t.flips, windowCommand{at: now})
+		}
+		t.runDirection = direction
+
+	case cmdStartJump:
+		currentPositionCharacter(client)
+		if state.isJumping && state.Y < state.YStart-airborneTolerance {
+			event = cheatAirJump
+		}
+	}
+
+	if event == "" {
+		return true
+	}
+	reportCheat(client, reqID, event)
+	return antiCheatMode != antiCheatKick
+}
+
+// Оставляет команды, полученные за последнее окно
+func recentCommands(commands []windowCommand, now int64) []windowCommand {
+	from := now - cadenceWindow.Milliseconds()
+	i := 0
+	for i < len(commands) && commands[i].at <= from {
+		i++
+	}
+	return commands[i:]
+}
+
+// Вторичные признаки по меткам времени клиента: часы клиента уходят вперёд сервера или атаки
+// чаще длительности анимации. Метку легко подделать, поэтому такие события только учитываются,
+// а команда не отбрасывается. Клиент без времени команды не проверяется
+func (t *cheatTracker) checkClientTime(client *Client, action Action, reqID uint32, now int64) {
+	if action.Time == 0 {
+		return
+	}
+	offset := now - action.Time
+	switch {
+	case !t.clockSynced:
+		t.clockOffset, t.clockSynced = offset, true
+	case offset < t.clockOffset-maxClockLead.Milliseconds():
+		// Каждое событие - не меньше секунды выигранного времени, после него отсчёт идёт заново
+		reportCheat(client, reqID, cheatClockLead)
+		t.clockOffset = offset
+	}
+
+	if action.Command != cmdAttack && action.Command != cmdHeavyAttack {
+		return
+	}
+	if t.lastClientAttack != 0 && action.Time-t.lastClientAttack < (t.attackDuration-attackCadenceTolerance).Milliseconds() {
+		reportCheat(client, reqID, cheatClientCadence)
+	}
+	t.lastClientAttack = action.Time
+}
+
+// Учитывает подозрительное событие, при превышении порогов сохраняет отчёт и отключает клиента.
+// Ошибка об отключении отправляется как ответ на запрос reqID
+func reportCheat(client *Client, reqID uint32, event cheatEvent) {
+	t := &client.cheat
+	if t.events == nil {
+		t.events = make(map[cheatEvent]int)
+	}
+	t.events[event]++
+	t.total++
+	client.logger().Info("Античит: подозрительное событие", "event", event, "total", t.total)
+
+	if !t.flagged && t.total >= cheatFlagThreshold {
+		t.flagged = true
+		saveCheatReportDB(client, antiCheatFlag)
+	}
+	if antiCheatMode == antiCheatKick && t.total == cheatKickThreshold {
+		saveCheatReportDB(client, antiCheatKick)
+		client.logger().Warn("Античит: клиент отключён", "events", t.summary())
+		sendReply(client, reqID, MsgError, newGameError(ErrCodeCheatDetected))
+		client.cancel()
+	}
+}
+
+// События в виде "air_jump=3, direction_flip=12"
+func (t *cheatTracker) summary() string {
+	parts := make([]string, 0, len(t.events))
+	for event, count := range t.events {
+		parts = append(parts, fmt.Sprintf("%s=%d", event, count))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// Сохраняет отчёт о подозрительном игроке
+func saveCheatReportDB(client *Client, action string) {
+	if err := store.insertCheatReport(client.PlayerID, action, client.cheat.summary(), client.cheat.total); err != nil {
+		clientDBLogger(client, "insert_cheat_report").Error("Ошибка сохранения отчёта античита", "err", err)
+	}
+}
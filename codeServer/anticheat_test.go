@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Клиент в бою с атакой заданной длительности, без подключения
+func newCheatTestClient(attack time.Duration) *Client {
+	store = newMemStorage()
+	return &Client{State: &CharacterState{character: &Character{TimeAnimation: map[string]time.Duration{"Attack": attack}}}}
+}
+
+// Команды, которые KCP доставил одной пачкой, не считаются читом, а поток атак быстрее анимации
+// обнаруживается по часам сервера даже без меток времени клиента
+func TestAntiCheatAttackCadence(t *testing.T) {
+	c := newCheatTestClient(200 * time.Millisecond)
+	input := time.Now().UnixMilli() - 800
+	for i := 0; i < 5; i++ {
+		if !validateAction(c, Action{Id: i, Command: cmdAttack, Time: input + int64(i)*200}, 0) {
+			t.Fatalf("честная атака %d отброшена", i)
+		}
+	}
+	if c.cheat.total != 0 {
+		t.Fatalf("события для честной пачки команд: %s", c.cheat.summary())
+	}
+
+	for i := 5; i < 40; i++ {
+		validateAction(c, Action{Id: i, Command: cmdAttack}, 0)
+	}
+	if c.cheat.events[cheatAttackCooldown] == 0 {
+		t.Errorf("частые атаки не обнаружены: %s", c.cheat.summary())
+	}
+}
+
+func TestAntiCheatDirectionFlips(t *testing.T) {
+	c := newCheatTestClient(200 * time.Millisecond)
+	commands := []Cmd{cmdRunRight, cmdRunLeft}
+	for i := 0; i < 20; i++ {
+		validateAction(c, Action{Id: i, Command: commands[i%2]}, 0)
+	}
+	if c.cheat.total != 0 {
+		t.Fatalf("события для пачки смен направления: %s", c.cheat.summary())
+	}
+	for i := 20; i < 200; i++ {
+		validateAction(c, Action{Id: i, Command: commands[i%2]}, 0)
+	}
+	if c.cheat.events[cheatDirectionFlip] == 0 {
+		t.Errorf("частые смены направления не обнаружены: %s", c.cheat.summary())
+	}
+}
+
+// Метки клиента - только вторичный признак: событие учитывается, но команда не отбрасывается
+func TestAntiCheatClientClock(t *testing.T) {
+	antiCheatMode = antiCheatKick
+	defer func() { antiCheatMode = antiCheatFlag }()
+	c := newCheatTestClient(500 * time.Millisecond)
+	now := time.Now().UnixMilli()
+	if !validateAction(c, Action{Id: 1, Command: cmdAttack, Time: now}, 0) {
+		t.Fatal("первая атака отброшена")
+	}
+	if !validateAction(c, Action{Id: 2, Command: cmdAttack, Time: now + 100}, 0) {
+		t.Error("атака отброшена по метке клиента")
+	}
+	if !validateAction(c, Action{Id: 3, Command: cmdAttack, Time: now + 5000}, 0) {
+		t.Error("атака отброшена из-за часов клиента")
+	}
+	if c.cheat.events[cheatClientCadence] != 1 || c.cheat.events[cheatClockLead] != 1 {
+		t.Errorf("ожидались события client_cadence и clock_lead: %s", c.cheat.summary())
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"codeClient/protocol"
+	"testing"
+)
+
+// Перебор логинов через регистрацию ограничивается общим со входом счётчиком IP
+func TestRegistrationThrottled(t *testing.T) {
+	s := startTestServer(t, testServerConfig{})
+	ctx := testContext(t)
+	s.player("alice")
+
+	// После ошибки регистрации сервер закрывает соединение, поэтому каждая попытка с нового бота
+	for i := 0; i <= loginFreeAttempts; i++ {
+		if _, err := s.connect().Register(ctx, "alice", "alice", "alice"); errorCode(err) != protocol.ErrCodeLoginExists {
+			t.Fatalf("попытка %d: %v", i+1, err)
+		}
+	}
+	if _, err := s.connect().Register(ctx, "alice", "alice", "alice"); errorCode(err) != protocol.ErrCodeLoginLocked {
+		t.Errorf("регистрация после лишних попыток: %v", err)
+	}
+	if _, err := s.connect().Login(ctx, "alice", "alice"); errorCode(err) != protocol.ErrCodeLoginLocked {
+		t.Errorf("вход с того же IP: %v", err)
+	}
+	// Счётчик логина не растёт, чужой аккаунт не блокируется
+	if wait := loginGuards.waitKeys(loginKey("alice")); wait != 0 {
+		t.Errorf("логин alice заблокирован на %v", wait)
+	}
+	taken := 0
+	for _, e := range s.Store.auditEntries("FailedLogins") {
+		if e.Action == failLoginTaken {
+			taken++
+		}
+	}
+	if taken != loginFreeAttempts+1 {
+		t.Errorf("в журнале %d регистраций с занятым логином, ожидалось %d", taken, loginFreeAttempts+1)
+	}
+}
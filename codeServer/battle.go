@@ -1,11 +1,14 @@
 package main
 
 import (
-	"fmt"
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/vmihailenco/msgpack/v5"
+	"log/slog"
 	"math"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,20 +17,28 @@ const (
 	MaxLevelRange = 200 // Максимальный диапазон по уровню
 	MatchInterval = 1000 * time.Millisecond
 	ExpandTime    = 500 * time.Millisecond // Интервал увеличения диапазона
-	BattleTime    = 55 * 2 * time.Second   //time.Minute
+)
+
+var (
+	BattleTime        = 55 * 2 * time.Second // Длительность боя, в тестах короче
+	battleStartDelay  = 5 * time.Second      // Задержка начала боя после подбора соперника
+	battleSaveRetries = 3                    // Попыток записать итоги боя, повтор не начисляет награды дважды
+	battleSaveDelay   = 500 * time.Millisecond
 )
 
 type BattleResult int8
 
 const (
-	NoBattle BattleResult = -2 // Боя не было
-	Defeat   BattleResult = -1 // Поражение
-	Draw     BattleResult = 0  // Ничья
-	Victory  BattleResult = 1  // Победа
+	Cancelled BattleResult = -3 // Бой отменён при остановке сервера, без наград
+	NoBattle  BattleResult = -2 // Боя не было
+	Defeat    BattleResult = -1 // Поражение
+	Draw      BattleResult = 0  // Ничья
+	Victory   BattleResult = 1  // Победа
 )
 
 var (
 	matchmakingQueue *MatchmakingQueue // Очереди для боя
+	battleSeq        uint64            // Последний выданный ID боя
 )
 
 // Ожидающий клиент очереди на бой
@@ -50,15 +61,44 @@ type MatchmakingQueue struct {
 
 // Информация о бое для сервера
 type Battle struct {
+	ID        uint64 // Номер боя с запуска сервера, для журнала
 	Player1   *Client
 	Player2   *Client
 	IsRanked  bool
 	Winner    *Client
 	StartTime time.Time
 	EndTime   time.Time
+	Cancelled bool   // Бой прерван остановкой сервера
+	Key       string // Уникальный ключ боя, по нему итоги записываются в базу не больше одного раза
+	RecordID  int    // id_Battle в базе, задаётся при сохранении результатов
+	SkillDiff int    // Разница ранга или уровня Player1 и Player2 на начало боя
+	Reward1   BattleReward
+	Reward2   BattleReward
+
+	Readiness  chan struct{}
+	EndBattle  chan struct{}
+	cancel     chan struct{} // Закрывается при остановке сервера
+	cancelOnce sync.Once
+}
+
+// Итог боя для игрока: прибавки, которые применяет хранилище, и значения после них
+type BattleReward struct {
+	Result BattleResult
+	Money  int
+	Rank   int
+	Level  int
+
+	TotalMoney int // Задаются хранилищем вместе с RecordID
+	TotalRank  int
+	TotalLevel int
+}
 
-	Readiness chan struct{}
-	EndBattle chan struct{}
+// Итог боя для клиента
+func (b *Battle) rewardFor(client *Client) *BattleReward {
+	if client == b.Player1 {
+		return &b.Reward1
+	}
+	return &b.Reward2
 }
 
 // Информация о бое для клиента
@@ -95,7 +135,7 @@ func waitingBattle(client *Client, isRanked bool, friendID string) {
 		select {
 		case msg, ok := <-client.ReceivedMess:
 			if !ok { // Если канал закрыт, то завершить обработку
-				log.Printf("Канал сообщений для клиента %d закрыт, завершаем обработку поиска боя", client.UserID)
+				client.logger().Debug("Канал сообщений клиента закрыт, завершаем обработку поиска боя")
 				matchmakingQueue.removeFromQueue(client, isRanked)
 				return
 			}
@@ -105,15 +145,15 @@ func waitingBattle(client *Client, isRanked bool, friendID string) {
 					matchmakingQueue.removeFromQueue(client, isRanked)
 				}
 				client.State.inBattle = false
-				createAndSendMessage(client, MsgExitBattle, nil)
+				sendReply(client, msg.ReqID, MsgExitBattle, nil)
 				return
 			} else {
-				createAndSendMessage(client, MsgError, "Вы в ожидание боя")
+				// Ответ относится к этому сообщению, а не к запросу на поиск боя
+				sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeAlreadyWaiting))
 			}
 
 		case battleInfo := <-client.BattleInfo:
 			startBattle(client, battleInfo)
-			fmt.Println("END ")
 			return
 		}
 	}
@@ -127,7 +167,7 @@ func sendStartBattleInfo(client, opponent *Client, startTime, endTime time.Time)
 	battleInfo.OpponentRank = opponent.Rank
 	battleInfo.OpponentLevel = opponent.Level
 	var ch CharacterDB
-	ac := activeCharacters[opponent.ActiveCharacter]
+	ac := opponent.State.character
 	ch.Name = ac.Name
 	ch.Description = ac.Description
 	ch.Health = ac.Health
@@ -158,12 +198,17 @@ func startBattle(client *Client, battleInfo *Battle) {
 		opponent = battleInfo.Player1
 	}
 
+	// До конца боя записи журнала клиента содержат ID боя
+	reqLog := client.reqLog.Load()
+	client.reqLog.Store(client.logger().With("battle_id", battleInfo.ID))
+	defer client.reqLog.Store(reqLog)
+
 	sendStartBattleInfo(client, opponent, battleInfo.StartTime, battleInfo.EndTime)
 
 	select {
 	case msg, ok := <-client.ReceivedMess:
 		if !ok { // Если канал закрыт, то завершить обработку
-			log.Printf("Бой не начнется, т.к канал сообщений для клиента %d закрыт, завершаем обработку", client.UserID)
+			client.logger().Info("Бой не начнётся, канал сообщений клиента закрыт")
 			close(battleInfo.Readiness)
 			break
 		}
@@ -175,13 +220,6 @@ func startBattle(client *Client, battleInfo *Battle) {
 		break
 	}
 
-	var skillDiff int
-	if battleInfo.IsRanked {
-		skillDiff = client.Rank - opponent.Rank
-	} else {
-		skillDiff = client.Level - opponent.Level
-	}
-
 	for {
 		select {
 		case msg, ok := <-client.ReceivedMess:
@@ -189,34 +227,35 @@ func startBattle(client *Client, battleInfo *Battle) {
 			switch {
 			case !ok:
 				client.State.Died <- struct{}{}
-				log.Printf("Канал сообщений для клиента %d закрыт, завершаем обработку боя", client.UserID)
+				client.logger().Info("Канал сообщений клиента закрыт, завершаем обработку боя")
 				break
 			case msg.Type == MsgExitBattle:
 				client.State.Died <- struct{}{}
-				log.Printf("Клиент %d решил выйти из боя!", client.UserID)
+				client.logger().Info("Клиент вышел из боя")
 				break
 
 			case msg.Type == MsgActionCharacter && timeNow.After(battleInfo.StartTime) && timeNow.Before(battleInfo.EndTime):
 				var action Action
 				err := msgpack.Unmarshal(msg.Data, &action)
 				if err != nil {
-					log.Printf("Ошибка десериализации в startBattle: %v", err)
-					createAndSendMessage(client, MsgError, "десериализации данных Action на сервере")
+					client.logger().Warn("Ошибка десериализации", "handler", "startBattle", "err", err)
+					sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeBadRequest))
 					break
 				}
-				actionCharacter(action, client, opponent)
+				actionCharacter(action, client, opponent, msg.ReqID)
 			default:
-				createAndSendMessage(client, MsgError, "Бой еще не начался или уже закончился!")
+				sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeBattleNotActive))
 			}
 		case <-battleInfo.EndBattle:
-			finalizeBattleOutcome(client, skillDiff, battleInfo)
+			finalizeBattleOutcome(client, battleInfo)
 			return
 		}
 	}
 }
 
-// Подводит результаты боя
-func finalizeBattleOutcome(client *Client, skillDiff int, battleInfo *Battle) {
+// Подводит результаты боя. Награды уже записаны в базу в manageBattle,
+// клиент получает значения из базы
+func finalizeBattleOutcome(client *Client, battleInfo *Battle) {
 	restoreState(client, MsgNone)
 	endBattleInfo := EndBattleInfo{
 		TotalMoney:   client.Money,
@@ -232,28 +271,31 @@ func finalizeBattleOutcome(client *Client, skillDiff int, battleInfo *Battle) {
 		return
 	}
 
-	if battleInfo.Winner == nil {
-		endBattleInfo.Result = Draw
-		grantRewards(client, skillDiff, int(Draw), battleInfo.IsRanked)
-	} else if client == battleInfo.Winner {
-		endBattleInfo.Result = Victory
-		grantRewards(client, skillDiff, int(Victory), battleInfo.IsRanked)
+	if battleInfo.Cancelled {
+		endBattleInfo.Result = Cancelled
+		createAndSendMessage(client, MsgEndBattle, endBattleInfo)
+		return
+	}
+
+	reward := battleInfo.rewardFor(client)
+	endBattleInfo.Result = reward.Result
+	if battleInfo.RecordID != 0 {
+		client.Money, client.Rank, client.Level = reward.TotalMoney, reward.TotalRank, reward.TotalLevel
 	} else {
-		endBattleInfo.Result = Defeat
-		grantRewards(client, skillDiff, int(Defeat), battleInfo.IsRanked)
+		client.logger().Warn("Итоги боя не записаны, награды не начислены")
 	}
 	endBattleInfo.TotalMoney = client.Money
 	endBattleInfo.CurrentRank = client.Rank
 	endBattleInfo.CurrentLevel = client.Level
 
-	updatePlayerStats(client.PlayerID, client.Level, client.Rank, client.Money)
-
 	createAndSendMessage(client, MsgEndBattle, endBattleInfo)
 
 }
 
-// Начисляет награды за бой
-func grantRewards(client *Client, skillDiff, IsWinner int, IsRanked bool) {
+// Считает прибавки за бой. skillDiff — разница ранга или уровня игрока и соперника
+func calculateReward(skillDiff int, result BattleResult, IsRanked bool) BattleReward {
+	IsWinner := int(result)
+	reward := BattleReward{Result: result}
 	baseProgress := 25.0 // Средний прогресс ранга или уровня
 	baseCoins := 50.0    // Награда для ничьи не рангового боя
 	effectScale := 0.03  // Крутизна кривой
@@ -263,45 +305,105 @@ func grantRewards(client *Client, skillDiff, IsWinner int, IsRanked bool) {
 
 	if IsRanked {
 		baseCoins = 2 * baseCoins
-		client.Rank += IsWinner * int(rankMod*baseProgress)
-		if client.Rank < 0 {
-			client.Rank = 0
-		}
+		reward.Rank = IsWinner * int(rankMod*baseProgress) // Ранг не опускается ниже нуля при записи
 	}
 
 	levelMod := math.Pow(2, float64(IsWinner)) * (1.0 + effect) * 0.5
 	levelMod = math.Max(0.2, levelMod)
 
-	client.Level += int(levelMod * baseProgress)
+	reward.Level = int(levelMod * baseProgress)
 
 	moneyMod := math.Pow(2, float64(IsWinner)) * (1.0 + effect)
 	moneyMod = math.Max(0.2, moneyMod)
 
-	client.Money += int(moneyMod * baseCoins)
+	reward.Money = int(moneyMod * baseCoins)
+	return reward
+}
+
+// Итоги боя для обоих игроков по победителю
+func (b *Battle) calculateRewards() {
+	if b.Cancelled {
+		b.Reward1 = BattleReward{Result: Cancelled}
+		b.Reward2 = BattleReward{Result: Cancelled}
+		return
+	}
+	result1, result2 := Draw, Draw
+	switch b.Winner {
+	case b.Player1:
+		result1, result2 = Victory, Defeat
+	case b.Player2:
+		result1, result2 = Defeat, Victory
+	}
+	b.Reward1 = calculateReward(b.SkillDiff, result1, b.IsRanked)
+	b.Reward2 = calculateReward(-b.SkillDiff, result2, b.IsRanked)
+}
+
+// Записывает бой и награды, повторяя попытку при ошибке базы данных
+func saveBattle(battle *Battle, logger *slog.Logger) {
+	for attempt := 1; ; attempt++ {
+		err := store.applyBattleResults(battle)
+		if err == nil {
+			return
+		}
+		if attempt == battleSaveRetries {
+			logger.Error("Итоги боя не записаны", "attempts", attempt, "err", err)
+			return
+		}
+		logger.Warn("Ошибка записи итогов боя, повтор", "attempt", attempt, "err", err)
+		time.Sleep(battleSaveDelay)
+	}
+}
+
+// Случайный ключ боя, 32 шестнадцатеричных символа
+func newBattleKey() string {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		fatal("Ошибка генерации ключа боя", "err", err)
+	}
+	return hex.EncodeToString(key)
 }
 
 // Горутина управления и синхронизации боя
 func manageBattle(clientA, clientB *Client, isRanked bool) {
 	var battle Battle
 
-	startTime := time.Now().UTC().Add(5 * time.Second).Truncate(time.Second) // Задержка начала боя
+	startTime := time.Now().UTC().Add(battleStartDelay).Truncate(time.Second) // Задержка начала боя
 	endTime := startTime.Add(BattleTime).Truncate(time.Second)
 	Readiness := make(chan struct{})
 	chanBattleEnd := make(chan struct{})
 
+	battle.ID = atomic.AddUint64(&battleSeq, 1)
+	battle.Key = newBattleKey()
 	battle.Player1 = clientA
 	battle.Player2 = clientB
 	battle.IsRanked = isRanked
 	battle.Winner = nil
 	battle.StartTime = startTime
 	battle.EndTime = endTime
+	if isRanked {
+		battle.SkillDiff = clientA.Rank - clientB.Rank
+	} else {
+		battle.SkillDiff = clientA.Level - clientB.Level
+	}
 
 	battle.Readiness = Readiness
 	battle.EndBattle = chanBattleEnd
+	battle.cancel = make(chan struct{})
 
-	log.Println("БОЙ НАЧИНАЕТСЯ!", clientA.Name, "VS", clientB.Name)
-	log.Println("Начало: ", startTime)
-	log.Println("Конец: ", endTime)
+	if !addBattle(&battle) {
+		// Пара найдена до начала остановки, игроки ещё ждут боя
+		for _, client := range []*Client{clientA, clientB} {
+			sendError(client, newGameError(ErrCodeServerShutdown))
+			createAndSendMessage(client, MsgExitBattle, nil)
+		}
+		return
+	}
+	defer removeBattle(&battle)
+
+	logger := slog.With("battle_id", battle.ID)
+	logger.Info("Бой начинается",
+		"player1", clientA.PublicID, "player2", clientB.PublicID, "ranked", isRanked,
+		"start", startTime, "end", endTime)
 
 	// Оповещаем игроков, что бой начался
 	clientA.BattleInfo <- &battle
@@ -309,7 +411,7 @@ func manageBattle(clientA, clientB *Client, isRanked bool) {
 
 	select {
 	case <-battle.Readiness: // Один из пользователей не подтвердил готовность, бой завершен досрочно
-		fmt.Println("Один из пользователей не подтвердил готовность к бою")
+		logger.Info("Один из пользователей не подтвердил готовность к бою")
 		battle.Readiness = nil
 		close(battle.EndBattle)
 		return
@@ -321,12 +423,20 @@ func manageBattle(clientA, clientB *Client, isRanked bool) {
 
 	case <-time.After(time.Until(endTime)):
 		battle.Winner = nil
+
+	case <-battle.cancel:
+		battle.Cancelled = true
 	}
 
 	battle.EndTime = time.Now().UTC() // реальное время окончания боя
-	saveBattleResultsDB(battle)
+	battle.calculateRewards()
+	saveBattle(&battle, logger)
 
-	log.Println("Бой ", clientA.Name, " VS ", clientB.Name, " завершился, победитель: ", battle.Winner)
+	winner := ""
+	if battle.Winner != nil {
+		winner = battle.Winner.PublicID
+	}
+	logger.Info("Бой завершился", "winner", winner, "cancelled", battle.Cancelled, "duration", battle.EndTime.Sub(startTime))
 	close(battle.EndBattle)
 }
 
@@ -435,6 +545,40 @@ func removePairFromQueue(queue *[]*WaitingClient, i, j int) {
 	*queue = (*queue)[:last]
 }
 
+// Число игроков в очереди
+func (m *MatchmakingQueue) length(isRanked bool) int {
+	if m == nil {
+		return 0
+	}
+	if isRanked {
+		m.rankMu.Lock()
+		defer m.rankMu.Unlock()
+		return len(m.rankedQueue)
+	}
+	m.levelMu.Lock()
+	defer m.levelMu.Unlock()
+	return len(m.levelQueue)
+}
+
+// Очищает обе очереди и возвращает ожидавших игроков
+func (m *MatchmakingQueue) drain() []*Client {
+	var clients []*Client
+	m.rankMu.Lock()
+	for _, player := range m.rankedQueue {
+		clients = append(clients, player.Client)
+	}
+	m.rankedQueue = nil
+	m.rankMu.Unlock()
+
+	m.levelMu.Lock()
+	for _, player := range m.levelQueue {
+		clients = append(clients, player.Client)
+	}
+	m.levelQueue = nil
+	m.levelMu.Unlock()
+	return clients
+}
+
 // Удаление игрока из очереди
 func (m *MatchmakingQueue) removeFromQueue(client *Client, isRanked bool) {
 	if isRanked {
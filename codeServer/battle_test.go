@@ -0,0 +1,248 @@
+package main
+
+import (
+	"codeClient/bot"
+	"codeClient/protocol"
+	"context"
+	"testing"
+	"time"
+)
+
+// Ставит двух игроков в очередь одновременно и ждёт, пока подбор соперников сведёт их в бой
+func queueBoth(t *testing.T, ctx context.Context, ranked bool, a, b *bot.Bot) (*bot.Battle, *bot.Battle) {
+	t.Helper()
+	type queued struct {
+		battle *bot.Battle
+		err    error
+	}
+	done := make(chan queued, 1)
+	go func() {
+		battle, err := b.QueueBattle(ctx, ranked)
+		done <- queued{battle, err}
+	}()
+	battleA, err := a.QueueBattle(ctx, ranked)
+	if err != nil {
+		t.Fatalf("поиск боя %s: %v", a.User.Login, err)
+	}
+	result := <-done
+	if result.err != nil {
+		t.Fatalf("поиск боя %s: %v", b.User.Login, result.err)
+	}
+	return battleA, result.battle
+}
+
+func TestMatchmaking(t *testing.T) {
+	s := startTestServer(t, testServerConfig{})
+	ctx := testContext(t)
+	alice, bob := s.player("alice"), s.player("bob")
+
+	battleA, battleB := queueBoth(t, ctx, false, alice, bob)
+	if battleA.Info.OpponentPublicID != bob.User.PublicID || battleB.Info.OpponentPublicID != alice.User.PublicID {
+		t.Errorf("соперники %s и %s, ожидались %s и %s",
+			battleA.Info.OpponentPublicID, battleB.Info.OpponentPublicID, bob.User.PublicID, alice.User.PublicID)
+	}
+	if battleA.Info.StartTime != battleB.Info.StartTime || battleA.Info.EndTime != battleB.Info.EndTime {
+		t.Errorf("время боя не совпадает: %d-%d и %d-%d",
+			battleA.Info.StartTime, battleA.Info.EndTime, battleB.Info.StartTime, battleB.Info.EndTime)
+	}
+	if got := battleA.Info.OpponentCharacter.Name; got != bob.User.ActiveCharacter.Name {
+		t.Errorf("персонаж соперника %q, ожидался %q", got, bob.User.ActiveCharacter.Name)
+	}
+}
+
+func TestMatchmakingLeaveQueue(t *testing.T) {
+	s := startTestServer(t, testServerConfig{})
+	ctx := testContext(t)
+	alice := s.player("alice")
+
+	if err := alice.Send(protocol.MsgBattle, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.WaitFor(ctx, protocol.MsgWaitingBattle); err != nil {
+		t.Fatalf("ожидание боя: %v", err)
+	}
+	if matchmakingQueue.length(false) != 1 {
+		t.Fatalf("в очереди %d игроков, ожидался 1", matchmakingQueue.length(false))
+	}
+	if _, err := alice.Request(ctx, protocol.MsgExitBattle, nil, nil); err != nil {
+		t.Fatalf("выход из очереди: %v", err)
+	}
+	if matchmakingQueue.length(false) != 0 {
+		t.Errorf("после выхода в очереди %d игроков", matchmakingQueue.length(false))
+	}
+
+	// Вышедший игрок снова может встать в очередь и найти соперника
+	bob := s.player("bob")
+	battleA, _ := queueBoth(t, ctx, false, alice, bob)
+	if battleA.Info.OpponentPublicID != bob.User.PublicID {
+		t.Errorf("соперник %s, ожидался %s", battleA.Info.OpponentPublicID, bob.User.PublicID)
+	}
+}
+
+// Полный бой: команды персонажа во время боя, досрочный выход проигравшего и запись наград
+func TestBattle(t *testing.T) {
+	s := startTestServer(t, testServerConfig{BattleTime: 5 * time.Second})
+	ctx := testContext(t)
+	alice, bob := s.player("alice"), s.player("bob")
+	battleA, battleB := queueBoth(t, ctx, false, alice, bob)
+
+	// Первая команда с запасом: время начала боя у бота вычислено по часам сервера с точностью до мс
+	script := []bot.Step{
+		{Delay: 100 * time.Millisecond, Command: bot.CmdRunRight},
+		{Delay: 200 * time.Millisecond, Command: bot.CmdStopRun},
+		{Delay: 100 * time.Millisecond, Command: bot.CmdAttack},
+	}
+	if err := battleA.Run(ctx, script); err != nil {
+		t.Fatalf("сценарий боя: %v", err)
+	}
+	if err := battleB.Leave(); err != nil {
+		t.Fatal(err)
+	}
+
+	endA, err := battleA.Wait(ctx)
+	if err != nil {
+		t.Fatalf("итоги боя alice: %v", err)
+	}
+	endB, err := battleB.Wait(ctx)
+	if err != nil {
+		t.Fatalf("итоги боя bob: %v", err)
+	}
+	if endA.Result != bot.Victory || endB.Result != bot.Defeat {
+		t.Fatalf("результаты %d и %d, ожидались победа и поражение", endA.Result, endB.Result)
+	}
+	if len(battleA.Errors) != 0 {
+		t.Errorf("ошибки сервера во время боя: %v", battleA.Errors)
+	}
+	if len(battleA.ActionRTT) == 0 {
+		t.Error("сервер не ответил ни на одну команду")
+	}
+
+	for _, check := range []struct {
+		user *protocol.UserData
+		end  *bot.EndBattleInfo
+	}{{alice.User, endA}, {bob.User, endB}} {
+		player, err := s.Store.getAdminPlayer(check.user.PublicID, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if player.Money != check.end.TotalMoney || player.Rank != check.end.CurrentRank || player.Level != check.end.CurrentLevel {
+			t.Errorf("%s: в хранилище деньги %d, ранг %d, уровень %d, клиент получил %d, %d, %d", check.user.Login,
+				player.Money, player.Rank, player.Level, check.end.TotalMoney, check.end.CurrentRank, check.end.CurrentLevel)
+		}
+		entries, sum, err := s.Store.getMoneyLedger(player.PlayerID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum != player.Money || len(entries) == 0 || entries[0].Reason != ledgerBattle {
+			t.Errorf("%s: журнал денег %+v с суммой %d не сходится с балансом %d", check.user.Login, entries, sum, player.Money)
+		}
+	}
+	if endA.TotalMoney <= alice.User.Money {
+		t.Errorf("победитель не получил награду: было %d, стало %d", alice.User.Money, endA.TotalMoney)
+	}
+
+	player, err := s.Store.getAdminPlayer(alice.User.PublicID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, stats, err := s.Store.getBattleStats(player.PlayerID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.NumberWins != 1 || stats.NumberLosses != 0 || stats.NumberDraws != 0 {
+		t.Errorf("статистика боёв alice %+v, ожидалась одна победа", *stats)
+	}
+}
+
+// Бой без действий заканчивается ничьей по времени
+func TestBattleDraw(t *testing.T) {
+	s := startTestServer(t, testServerConfig{BattleTime: time.Second})
+	ctx := testContext(t)
+	alice, bob := s.player("alice"), s.player("bob")
+	battleA, battleB := queueBoth(t, ctx, true, alice, bob)
+
+	endA, err := battleA.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endB, err := battleB.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endA.Result != bot.Draw || endB.Result != bot.Draw {
+		t.Fatalf("результаты %d и %d, ожидалась ничья", endA.Result, endB.Result)
+	}
+	player, err := s.Store.getAdminPlayer(alice.User.PublicID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, stats, err := s.Store.getBattleStats(player.PlayerID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.NumberDraws != 1 || stats.NumberWins != 0 || stats.NumberLosses != 0 {
+		t.Errorf("статистика рейтинговых боёв alice %+v, ожидалась одна ничья", *stats)
+	}
+}
+
+// Повторная запись боя с тем же ключом не начисляет награды второй раз
+func TestApplyBattleResultsIdempotent(t *testing.T) {
+	s := newTestStore(t, testServerFixtures)
+	alice, bob := createTestPlayer(t, s, "alice"), createTestPlayer(t, s, "bob")
+	newBattle := func() *Battle {
+		return &Battle{
+			Player1:   &Client{PlayerID: alice.PlayerID},
+			Player2:   &Client{PlayerID: bob.PlayerID},
+			StartTime: time.Now().Add(-time.Minute),
+			EndTime:   time.Now(),
+			Key:       "battle-key",
+			Reward1:   BattleReward{Result: Victory, Money: 30, Rank: 10, Level: 1},
+			Reward2:   BattleReward{Result: Defeat, Money: 5, Rank: -10},
+		}
+	}
+	first := newBattle()
+	first.Winner = first.Player1
+	if err := s.applyBattleResults(first); err != nil {
+		t.Fatal(err)
+	}
+	players := func() (*AdminPlayer, *AdminPlayer) {
+		a, err := s.getAdminPlayer(alice.PublicID, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := s.getAdminPlayer(bob.PublicID, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a, b
+	}
+	aliceAfter, bobAfter := players()
+	if aliceAfter.Money != startMoney+30 || aliceAfter.Rank != 10 || aliceAfter.Level != 1 || bobAfter.Money != startMoney+5 || bobAfter.Rank != 0 {
+		t.Fatalf("после боя alice %+v, bob %+v", *aliceAfter, *bobAfter)
+	}
+
+	repeat := newBattle()
+	repeat.Winner = repeat.Player1
+	if err := s.applyBattleResults(repeat); err != nil {
+		t.Fatal(err)
+	}
+	if repeat.RecordID != first.RecordID {
+		t.Errorf("повторная запись получила номер боя %d, ожидался %d", repeat.RecordID, first.RecordID)
+	}
+	aliceRepeat, bobRepeat := players()
+	if *aliceRepeat != *aliceAfter || *bobRepeat != *bobAfter {
+		t.Errorf("повтор изменил игроков: alice %+v, bob %+v", *aliceRepeat, *bobRepeat)
+	}
+	if repeat.Reward1.TotalMoney != aliceAfter.Money || repeat.Reward2.TotalRank != bobAfter.Rank {
+		t.Errorf("повтор вернул итоги %+v и %+v, ожидались текущие значения игроков", repeat.Reward1, repeat.Reward2)
+	}
+	for _, player := range []*AdminPlayer{aliceAfter, bobAfter} {
+		entries, sum, err := s.getMoneyLedger(player.PlayerID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 || sum != player.Money {
+			t.Errorf("%s: журнал денег %+v с суммой %d, баланс %d", player.Login, entries, sum, player.Money)
+		}
+	}
+}
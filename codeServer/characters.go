@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Анимации, без которых персонаж не может участвовать в бою
+var requiredAnimations = [...]string{"Idle", "Run", "Jump", "Fall", "Attack", "HeavyAttack"}
+
+var (
+	activeCharacters    = make(map[int]*Character) // Каталог персонажей, загружается при старте сервера
+	listCharactersMutex sync.RWMutex
+)
+
+// Строка таблицы Characters вместе с идентификатором
+type characterRowDB struct {
+	ID int `db:"id_Character"`
+	CharacterDB
+}
+
+// Строка таблицы Assets_Characters вместе с идентификатором персонажа
+type assetCharacterRowDB struct {
+	CharacterID int `db:"id_Character"`
+	AssetsCharacterDB
+}
+
+// Возвращает персонажа из каталога. Сессии хранят своего персонажа сами,
+// поэтому перезагрузка каталога не затрагивает игроков онлайн
+func getActiveCharacter(id int) (*Character, error) {
+	listCharactersMutex.RLock()
+	character, ok := activeCharacters[id]
+	listCharactersMutex.RUnlock()
+	if !ok {
+		slog.Error("Персонаж отсутствует в каталоге", "character_id", id)
+		return nil, newGameError(ErrCodeCharacterMissing)
+	}
+	return character, nil
+}
+
+// Проверяет, что у персонажа есть все анимации, необходимые для боя
+func validateCharacterAssets(chDB CharacterDB) error {
+	for _, animation := range requiredAnimations {
+		asset, ok := chDB.Assets[animation]
+		if !ok {
+			return fmt.Errorf("отсутствует анимация %s", animation)
+		}
+		if asset.FrameCount <= 0 || asset.BaseWidth <= 0 || asset.BaseHeight <= 0 {
+			return fmt.Errorf("некорректные размеры анимации %s", animation)
+		}
+		if int(asset.FrameRate) <= 0 {
+			return fmt.Errorf("некорректная частота кадров анимации %s", animation)
+		}
+	}
+	return nil
+}
+
+// Загружает из хранилища всех персонажей и строит для них битовые маски
+func loadCharacterCatalog() (map[int]*Character, error) {
+	rows, assetRows, err := store.characterRows()
+	if err != nil {
+		return nil, err
+	}
+
+	assets := make(map[int]map[string]*AssetsCharacterDB)
+	for i := range assetRows {
+		as := assetRows[i].AssetsCharacterDB
+		if as.AnimationType == "Preview" { // Только для магазина
+			continue
+		}
+		if assets[assetRows[i].CharacterID] == nil {
+			assets[assetRows[i].CharacterID] = make(map[string]*AssetsCharacterDB)
+		}
+		assets[assetRows[i].CharacterID][as.AnimationType] = &as
+	}
+
+	catalog := make(map[int]*Character, len(rows))
+	for _, row := range rows {
+		chDB := row.CharacterDB
+		chDB.Assets = assets[row.ID]
+		if err = validateCharacterAssets(chDB); err != nil {
+			return nil, fmt.Errorf("персонаж %d '%s': %v", row.ID, chDB.Name, err)
+		}
+		ch, err := createCharacter(chDB)
+		if err != nil {
+			return nil, fmt.Errorf("персонаж %d '%s': %v", row.ID, chDB.Name, err)
+		}
+		catalog[row.ID] = ch
+	}
+
+	return catalog, nil
+}
+
+// Перезагружает каталог персонажей, при ошибке остаётся прежний каталог
+func reloadCharacterCatalog() error {
+	catalog, err := loadCharacterCatalog()
+	if err != nil {
+		return err
+	}
+
+	listCharactersMutex.Lock()
+	activeCharacters = catalog
+	listCharactersMutex.Unlock()
+
+	slog.Info("Каталог персонажей загружен", "count", len(catalog))
+	return nil
+}
@@ -0,0 +1,182 @@
+// Утилита для добавления персонажей: проверяет папку с анимациями персонажа
+// и формирует строки Characters и Assets_Characters в виде манифеста, SQL-скрипта
+// или сразу записывает их в базу данных.
+//
+//	assets -dir "resources/Asset/Knight girl" -frame-width 180 -name "Девушка рыцарь" -manifest knight.json
+//	assets -from knight.json -db "server=localhost;database=GAME_FQW;trusted_connection=yes"
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	_ "github.com/denisenkom/go-mssqldb"
+	"github.com/jmoiron/sqlx"
+	"log"
+	"os"
+	"strings"
+)
+
+const (
+	// Поиск персонажа по имени
+	queryFindCharacter = "SELECT id_Character FROM Characters WHERE Name = @Name"
+	// Добавление персонажа
+	queryInsertCharacter = "INSERT INTO Characters (Name, Description, Health, Damage, Cost) VALUES (@Name, @Description, @Health, @Damage, @Cost); SELECT SCOPE_IDENTITY();"
+	// Обновление характеристик персонажа
+	queryUpdateCharacter = "UPDATE Characters SET Description = @Description, Health = @Health, Damage = @Damage, Cost = @Cost WHERE id_Character = @id_Character"
+	// Удаление анимаций персонажа перед повторной записью
+	queryDeleteAssetsCharacter = "DELETE FROM Assets_Characters WHERE id_Character = @id_Character"
+	// Добавление анимации персонажа
+	queryInsertAssetCharacter = "INSERT INTO Assets_Characters (id_Character, AnimationType, FrameCount, BaseHeight, BaseWidth, FrameRate, AssetPath) VALUES (@id_Character, @AnimationType, @FrameCount, @BaseHeight, @BaseWidth, @FrameRate, @AssetPath)"
+)
+
+func main() {
+	var opt scanOptions
+	var manifest CharacterManifest
+	var from, manifestOut, sqlOut, dsn string
+
+	flag.StringVar(&opt.dir, "dir", "", "папка персонажа с подпапками Character и Weapon")
+	flag.IntVar(&opt.frameWidth, "frame-width", 0, "ширина одного кадра в исходных изображениях")
+	flag.IntVar(&opt.baseHeight, "base-height", 0, "высота кадра в игре (по умолчанию высота изображения)")
+	var frameRate float64
+	flag.Float64Var(&frameRate, "frame-rate", 10, "частота кадров анимаций")
+	flag.StringVar(&manifest.Name, "name", "", "имя персонажа")
+	flag.StringVar(&manifest.Description, "description", "", "описание персонажа")
+	flag.IntVar(&manifest.Health, "health", 1000, "здоровье")
+	flag.IntVar(&manifest.Damage, "damage", 10, "урон")
+	flag.IntVar(&manifest.Cost, "cost", 0, "стоимость в магазине")
+	flag.StringVar(&from, "from", "", "взять готовый манифест вместо сканирования папки")
+	flag.StringVar(&manifestOut, "manifest", "", "записать манифест в файл")
+	flag.StringVar(&sqlOut, "sql", "", "записать SQL-скрипт в файл")
+	flag.StringVar(&dsn, "db", "", "строка подключения для записи персонажа в базу данных")
+	flag.Parse()
+	opt.frameRate = float32(frameRate)
+
+	if from != "" {
+		data, err := os.ReadFile(from)
+		if err != nil {
+			log.Fatalf("Ошибка чтения манифеста: %v", err)
+		}
+		if err = json.Unmarshal(data, &manifest); err != nil {
+			log.Fatalf("Ошибка разбора манифеста: %v", err)
+		}
+	} else {
+		if opt.dir == "" || opt.frameWidth <= 0 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		assets, errs := scanCharacterDir(opt)
+		for _, err := range errs {
+			log.Printf("Ошибка: %v", err)
+		}
+		if len(errs) > 0 {
+			log.Fatalf("Папка %s не прошла проверку, ошибок: %d", opt.dir, len(errs))
+		}
+		manifest.Assets = assets
+	}
+
+	if err := manifest.Validate(); err != nil {
+		log.Fatalf("Манифест не прошёл проверку: %v", err)
+	}
+	for _, as := range manifest.Assets {
+		log.Printf("%-12s кадров: %2d, размер: %dx%d, %s", as.AnimationType, as.FrameCount, as.BaseWidth, as.BaseHeight, as.AssetPath)
+	}
+
+	if manifestOut != "" {
+		data, err := json.MarshalIndent(manifest, "", "\t")
+		if err != nil {
+			log.Fatalf("Ошибка сериализации манифеста: %v", err)
+		}
+		if err = os.WriteFile(manifestOut, data, 0644); err != nil {
+			log.Fatalf("Ошибка записи манифеста: %v", err)
+		}
+		log.Printf("Манифест записан в %s", manifestOut)
+	}
+
+	if sqlOut != "" {
+		if err := os.WriteFile(sqlOut, []byte(manifest.SQL()), 0644); err != nil {
+			log.Fatalf("Ошибка записи SQL-скрипта: %v", err)
+		}
+		log.Printf("SQL-скрипт записан в %s", sqlOut)
+	}
+
+	if dsn != "" {
+		id, err := upsertCharacter(dsn, manifest)
+		if err != nil {
+			log.Fatalf("Ошибка записи персонажа в базу данных: %v", err)
+		}
+		log.Printf("Персонаж '%s' записан в базу данных, id_Character = %d", manifest.Name, id)
+	}
+}
+
+// Экранирует строку для T-SQL
+func quoteSQL(s string) string {
+	return "N'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Формирует SQL-скрипт, который добавляет или обновляет персонажа
+func (m *CharacterManifest) SQL() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "DECLARE @id_Character INT = (SELECT id_Character FROM Characters WHERE Name = %s);\n\n", quoteSQL(m.Name))
+	b.WriteString("IF @id_Character IS NULL\nBEGIN\n")
+	fmt.Fprintf(&b, "\tINSERT INTO Characters (Name, Description, Health, Damage, Cost) VALUES (%s, %s, %d, %d, %d);\n", quoteSQL(m.Name), quoteSQL(m.Description), m.Health, m.Damage, m.Cost)
+	b.WriteString("\tSET @id_Character = SCOPE_IDENTITY();\nEND\nELSE\nBEGIN\n")
+	fmt.Fprintf(&b, "\tUPDATE Characters SET Description = %s, Health = %d, Damage = %d, Cost = %d WHERE id_Character = @id_Character;\n", quoteSQL(m.Description), m.Health, m.Damage, m.Cost)
+	b.WriteString("\tDELETE FROM Assets_Characters WHERE id_Character = @id_Character;\nEND\n\n")
+	for _, as := range m.Assets {
+		fmt.Fprintf(&b, "INSERT INTO Assets_Characters (id_Character, AnimationType, FrameCount, BaseHeight, BaseWidth, FrameRate, AssetPath) VALUES (@id_Character, %s, %d, %d, %d, %g, %s);\n",
+			quoteSQL(as.AnimationType), as.FrameCount, as.BaseHeight, as.BaseWidth, as.FrameRate, quoteSQL(as.AssetPath))
+	}
+	b.WriteString("\nGO\n")
+	return b.String()
+}
+
+// Добавляет или обновляет персонажа и его анимации в одной транзакции
+func upsertCharacter(dsn string, m CharacterManifest) (id int, err error) {
+	db, err := sqlx.Open("sqlserver", dsn)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err = tx.Get(&id, queryFindCharacter, sql.Named("Name", m.Name))
+	switch {
+	case err == sql.ErrNoRows:
+		err = tx.QueryRow(queryInsertCharacter, sql.Named("Name", m.Name), sql.Named("Description", m.Description), sql.Named("Health", m.Health), sql.Named("Damage", m.Damage), sql.Named("Cost", m.Cost)).Scan(&id)
+		if err != nil {
+			return 0, err
+		}
+	case err != nil:
+		return 0, err
+	default:
+		_, err = tx.Exec(queryUpdateCharacter, sql.Named("id_Character", id), sql.Named("Description", m.Description), sql.Named("Health", m.Health), sql.Named("Damage", m.Damage), sql.Named("Cost", m.Cost))
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(queryDeleteAssetsCharacter, sql.Named("id_Character", id))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, as := range m.Assets {
+		_, err = tx.Exec(queryInsertAssetCharacter, sql.Named("id_Character", id), sql.Named("AnimationType", as.AnimationType), sql.Named("FrameCount", as.FrameCount), sql.Named("BaseHeight", as.BaseHeight), sql.Named("BaseWidth", as.BaseWidth), sql.Named("FrameRate", as.FrameRate), sql.Named("AssetPath", as.AssetPath))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	return id, err
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Анимации, без которых персонаж не может участвовать в бою
+var requiredAnimations = [...]string{"Idle", "Run", "Jump", "Fall", "Attack", "HeavyAttack"}
+
+// Анимации, для которых нужна отдельная маска с оружием
+var weaponAnimations = [...]string{"Attack", "HeavyAttack"}
+
+// Необязательные анимации персонажа
+var optionalAnimations = [...]string{"Death", "TakeHit"}
+
+// Изображения из одного кадра: медальон для боя и превью для магазина
+var singleImages = [...]string{"Medallion", "Preview"}
+
+// Описание персонажа и его анимаций, соответствует строкам Characters и Assets_Characters
+type CharacterManifest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Health      int             `json:"health"`
+	Damage      int             `json:"damage"`
+	Cost        int             `json:"cost"`
+	Assets      []AssetManifest `json:"assets"`
+}
+
+type AssetManifest struct {
+	AnimationType string  `json:"animationType"`
+	FrameCount    int     `json:"frameCount"`
+	BaseHeight    int     `json:"baseHeight"`
+	BaseWidth     int     `json:"baseWidth"`
+	FrameRate     float32 `json:"frameRate"`
+	AssetPath     string  `json:"assetPath"`
+}
+
+// Параметры сканирования папки персонажа
+type scanOptions struct {
+	dir        string  // Папка персонажа, например resources/Asset/Knight girl
+	frameWidth int     // Ширина одного кадра в исходном изображении
+	baseHeight int     // Высота кадра, к которой приводятся анимации на сервере и клиенте
+	frameRate  float32 // Частота кадров всех анимаций
+}
+
+// Возвращает имя файла для типа анимации: HeavyAttack -> "Heavy Attack.png"
+func animationFileName(animationType string) string {
+	var b strings.Builder
+	for i, r := range animationType {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String() + ".png"
+}
+
+// Возвращает размеры изображения
+func imageSize(path string) (int, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %v", path, err)
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// Идентификатор ресурса ассета, который хранится в базе данных
+func dbAssetPath(dir, fileName string) string {
+	return "Asset/" + filepath.Base(dir) + "/" + fileName
+}
+
+// Проверяет, что вариант анимации в подпапке совпадает по размерам с основной
+func checkVariant(dir, subDir, fileName string, width, height int) error {
+	w, h, err := imageSize(filepath.Join(dir, subDir, fileName))
+	if err != nil {
+		return err
+	}
+	if w != width || h != height {
+		return fmt.Errorf("%s/%s: размер %dx%d не совпадает с основным %dx%d", subDir, fileName, w, h, width, height)
+	}
+	return nil
+}
+
+// Сканирует папку персонажа и собирает описание анимаций, возвращает все найденные ошибки
+func scanCharacterDir(opt scanOptions) ([]AssetManifest, []error) {
+	var assets []AssetManifest
+	var errs []error
+
+	frameHeight := 0
+	addAnimation := func(animationType string, required bool) {
+		fileName := animationFileName(animationType)
+		width, height, err := imageSize(filepath.Join(opt.dir, fileName))
+		if err != nil {
+			if required || !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("анимация %s: %v", animationType, err))
+			}
+			return
+		}
+		if width%opt.frameWidth != 0 {
+			errs = append(errs, fmt.Errorf("анимация %s: ширина %d не кратна ширине кадра %d", animationType, width, opt.frameWidth))
+			return
+		}
+		if frameHeight == 0 {
+			frameHeight = height
+		} else if height != frameHeight {
+			errs = append(errs, fmt.Errorf("анимация %s: высота %d отличается от высоты других анимаций %d", animationType, height, frameHeight))
+			return
+		}
+
+		if err = checkVariant(opt.dir, "Character", fileName, width, height); err != nil {
+			errs = append(errs, fmt.Errorf("анимация %s: %v", animationType, err))
+		}
+		for _, weapon := range weaponAnimations {
+			if weapon == animationType {
+				if err = checkVariant(opt.dir, "Weapon", fileName, width, height); err != nil {
+					errs = append(errs, fmt.Errorf("анимация %s: %v", animationType, err))
+				}
+			}
+		}
+
+		baseHeight, baseWidth := height, opt.frameWidth
+		if opt.baseHeight > 0 {
+			baseWidth = opt.frameWidth * opt.baseHeight / height
+			baseHeight = opt.baseHeight
+		}
+		assets = append(assets, AssetManifest{
+			AnimationType: animationType,
+			FrameCount:    width / opt.frameWidth,
+			BaseHeight:    baseHeight,
+			BaseWidth:     baseWidth,
+			FrameRate:     opt.frameRate,
+			AssetPath:     dbAssetPath(opt.dir, fileName),
+		})
+	}
+
+	for _, animationType := range requiredAnimations {
+		addAnimation(animationType, true)
+	}
+	for _, animationType := range optionalAnimations {
+		addAnimation(animationType, false)
+	}
+
+	for _, imageType := range singleImages {
+		fileName := animationFileName(imageType)
+		width, height, err := imageSize(filepath.Join(opt.dir, fileName))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("изображение %s: %v", imageType, err))
+			continue
+		}
+		assets = append(assets, AssetManifest{
+			AnimationType: imageType,
+			FrameCount:    1,
+			BaseHeight:    height,
+			BaseWidth:     width,
+			FrameRate:     0,
+			AssetPath:     dbAssetPath(opt.dir, fileName),
+		})
+	}
+
+	return assets, errs
+}
+
+// Проверяет описание персонажа перед записью в базу данных
+func (m *CharacterManifest) Validate() error {
+	if strings.TrimSpace(m.Name) == "" {
+		return fmt.Errorf("не указано имя персонажа")
+	}
+	if m.Health <= 0 || m.Damage <= 0 || m.Cost < 0 {
+		return fmt.Errorf("некорректные характеристики: здоровье %d, урон %d, стоимость %d", m.Health, m.Damage, m.Cost)
+	}
+
+	found := make(map[string]bool)
+	for _, as := range m.Assets {
+		found[as.AnimationType] = true
+		if as.FrameCount <= 0 || as.BaseWidth <= 0 || as.BaseHeight <= 0 {
+			return fmt.Errorf("анимация %s: некорректные размеры", as.AnimationType)
+		}
+	}
+	for _, animationType := range requiredAnimations {
+		if !found[animationType] {
+			return fmt.Errorf("отсутствует анимация %s", animationType)
+		}
+	}
+	for _, as := range m.Assets {
+		for _, animationType := range requiredAnimations {
+			if as.AnimationType == animationType && int(as.FrameRate) <= 0 {
+				return fmt.Errorf("анимация %s: некорректная частота кадров", as.AnimationType)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"codeServer/resource"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+const assetChunkSize = 16 * 1024 // Размер части файла при передаче клиенту
+
+// Папки ресурсов, которые клиент может скачать с сервера
+var contentDirs = [...]string{"Asset", "Backgrounds"}
+
+var (
+	contentIndex  = make(map[string]string) // Идентификатор ресурса -> хеш содержимого
+	contentByHash = make(map[string]string) // Хеш содержимого -> идентификатор ресурса
+	contentMutex  sync.RWMutex
+)
+
+// Часть файла ресурса
+type AssetChunk struct {
+	Hash   string `msgpack:"h"`
+	Offset int    `msgpack:"o"`
+	Total  int    `msgpack:"t"` // Полный размер файла
+	Data   []byte `msgpack:"d"`
+}
+
+// Перестраивает индекс ресурсов, которые раздаёт сервер
+func reloadContentIndex() error {
+	index, err := resource.Index(contentDirs[:]...)
+	if err != nil {
+		return fmt.Errorf("ошибка построения индекса ресурсов: %v", err)
+	}
+	byHash := make(map[string]string, len(index))
+	for id, hash := range index {
+		byHash[hash] = id
+	}
+
+	contentMutex.Lock()
+	contentIndex = index
+	contentByHash = byHash
+	contentMutex.Unlock()
+
+	slog.Info("Индекс ресурсов загружен", "files", len(index))
+	return nil
+}
+
+// Отправляет клиенту список ресурсов сервера с хешами
+func handleAssetManifest(client *Client, data []byte) {
+	// Отправка идёт без блокировки, чтобы медленный клиент не задерживал перестроение индекса
+	contentMutex.RLock()
+	index := make(map[string]string, len(contentIndex))
+	for id, hash := range contentIndex {
+		index[id] = hash
+	}
+	contentMutex.RUnlock()
+
+	if err := createAndSendMessage(client, MsgAssetManifest, index); err != nil {
+		client.logger().Warn("Ошибка отправки индекса ресурсов", "err", err)
+	}
+}
+
+// Отправляет клиенту файл ресурса по хешу частями
+func handleAssetRequest(client *Client, data []byte) {
+	var hash string
+
+	err := msgpack.Unmarshal(data, &hash)
+	if err != nil {
+		client.logger().Warn("Ошибка десериализации", "handler", "handleAssetRequest", "err", err)
+		sendError(client, newGameError(ErrCodeBadRequest))
+		return
+	}
+
+	contentMutex.RLock()
+	id, ok := contentByHash[hash]
+	contentMutex.RUnlock()
+	if !ok {
+		sendError(client, newGameError(ErrCodeAssetNotFound, "hash", hash))
+		return
+	}
+
+	content, err := os.ReadFile(resource.Path(id))
+	if err != nil {
+		client.logger().Error("Ошибка чтения ресурса", "asset", id, "err", err)
+		sendError(client, newGameError(ErrCodeInternal))
+		return
+	}
+	sum := sha256.Sum256(content)
+	if hex.EncodeToString(sum[:]) != hash {
+		client.logger().Error("Ресурс изменился после построения индекса, требуется перезагрузка (SIGHUP)", "asset", id)
+		sendError(client, newGameError(ErrCodeAssetNotFound, "hash", hash))
+		return
+	}
+
+	// Пустой файл передаётся одной пустой частью
+	offset := 0
+	for {
+		end := offset + assetChunkSize
+		if end > len(content) {
+			end = len(content)
+		}
+		chunk := AssetChunk{Hash: hash, Offset: offset, Total: len(content), Data: content[offset:end]}
+		if err = createAndSendMessage(client, MsgAssetChunk, chunk); err != nil {
+			client.logger().Warn("Ошибка отправки ресурса", "asset", id, "err", err)
+			return
+		}
+		if end == len(content) {
+			return
+		}
+		offset = end
+	}
+}
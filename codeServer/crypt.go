@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/xtaci/kcp-go/v5"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	protocolVersion = 2                         // Версия протокола, проверяется при рукопожатии
+	keyEnvName      = "GAME_KEY"                // Переменная окружения с общим ключом шифрования
+	keySalt         = "duel-of-blades-kcp-salt" // Соль для получения ключа AES из общего ключа
+	keyIterations   = 4096
+	sessionKeyInfo  = "duel-of-blades-session" // Назначение ключа сессии для HKDF
+)
+
+// Ключ из общего ключа, соль для ключей сессий. Общий ключ есть в каждой копии клиента, поэтому
+// защищает только от посторонних, которые просто слушают сеть. Сообщения после рукопожатия
+// шифруются ключом сессии из обмена X25519, записанный трафик без одноразовых ключей не расшифровать
+var trafficKey []byte
+
+// Данные рукопожатия, первое сообщение клиента после подключения
+type Handshake struct {
+	Version int        `msgpack:"v"`
+	Profile string     `msgpack:"p"`           // Сетевой профиль, выбранный клиентом
+	Tuning  *KCPTuning `msgpack:"k,omitempty"` // Параметры KCP, которые применил сервер
+	Public  []byte     `msgpack:"pk"`          // Одноразовый открытый ключ X25519 для ключа сессии
+}
+
+// Получает ключ AES-256 из общего ключа, такой же ключ должен быть у клиента
+func deriveKey(sharedKey string) ([]byte, error) {
+	if sharedKey == "" {
+		return nil, errors.New("не задан ключ шифрования: укажите флаг -key или переменную " + keyEnvName)
+	}
+	return pbkdf2.Key([]byte(sharedKey), []byte(keySalt), keyIterations, 32, sha256.New), nil
+}
+
+// Создаёт шифрование KCP
+func newBlockCrypt(key []byte) (kcp.BlockCrypt, error) {
+	return kcp.NewAESBlockCrypt(key)
+}
+
+// Создаёт шифрование для TCP и WebSocket
+func newStreamCrypt(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Ключ сессии AES-256 из обмена X25519, так же его получает клиент
+func sessionKey(secret, clientPublic, serverPublic []byte) ([]byte, error) {
+	info := append([]byte(sessionKeyInfo), clientPublic...)
+	info = append(info, serverPublic...)
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, trafficKey, info), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Рукопожатие: проверка версии протокола и обмен ключами сессии. Ответ подтверждает клиенту,
+// что общие ключи совпадают. Выполняется в горутине чтения, чтобы следующий кадр уже читался
+// с ключом сессии. Возвращает false, если соединение нужно закрыть
+func handleHandshake(client *Client, msg *Message) bool {
+	var hs Handshake
+
+	err := msgpack.Unmarshal(msg.Data, &hs)
+	if err != nil {
+		client.logger().Warn("Ошибка десериализации", "handler", "handleHandshake", "err", err)
+		sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeBadRequest))
+		return false
+	}
+	if hs.Version != protocolVersion {
+		sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeVersion, "version", strconv.Itoa(hs.Version)))
+		return false
+	}
+	if hs.Profile != client.netProfile.Name {
+		sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeNetProfile, "profile", hs.Profile, "expected", client.netProfile.Name))
+		return false
+	}
+	if client.sessionSecured {
+		sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeBadRequest))
+		return true
+	}
+
+	clientPublic, err := ecdh.X25519().NewPublicKey(hs.Public)
+	if err != nil {
+		client.logger().Warn("Неверный открытый ключ клиента", "err", err)
+		sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeBadRequest))
+		return false
+	}
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		client.logger().Error("Ошибка создания ключа сессии", "err", err)
+		sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeInternal))
+		return false
+	}
+	secret, err := private.ECDH(clientPublic)
+	if err != nil {
+		client.logger().Warn("Ошибка обмена ключами", "err", err)
+		sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeBadRequest))
+		return false
+	}
+	key, err := sessionKey(secret, hs.Public, private.PublicKey().Bytes())
+	if err != nil {
+		client.logger().Error("Ошибка создания ключа сессии", "err", err)
+		sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeInternal))
+		return false
+	}
+	aead, err := newStreamCrypt(key)
+	if err != nil {
+		client.logger().Error("Ошибка создания ключа сессии", "err", err)
+		sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeInternal))
+		return false
+	}
+
+	resp := Handshake{Version: protocolVersion, Profile: client.netProfile.Name, Public: private.PublicKey().Bytes()}
+	if _, ok := netProfiles[client.netProfile.Name]; ok { // Параметры KCP только для подключений по UDP
+		tuning := client.netProfile.Tuning
+		resp.Tuning = &tuning
+	}
+	data, err := createMessage(MsgHandshake, resp)
+	if err != nil {
+		client.logger().Error("Ошибка сериализации рукопожатия", "err", err)
+		return false
+	}
+	// Ответ уходит со старым ключом, всё после него - с ключом сессии
+	client.connMutex.Lock()
+	defer client.connMutex.Unlock()
+	if err = client.writeFrameLocked(msg.ReqID, data); err != nil {
+		return false
+	}
+	if conn, ok := client.Conn.(*secureConn); ok {
+		client.Conn = newSecureConn(conn.Conn, aead)
+	} else {
+		client.Conn = newSecureConn(client.Conn, aead)
+	}
+	client.sessionSecured = true
+	return true
+}
+
+// Пакеты с неверным ключом KCP отбрасывает молча, поэтому рост ошибок контрольной суммы выводится в лог
+func monitorCryptErrors() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	var last uint64
+	for range ticker.C {
+		current := atomic.LoadUint64(&kcp.DefaultSnmp.InCsumErrors)
+		if current > last {
+			slog.Warn("Отброшены пакеты с неверной контрольной суммой. Возможно, клиент использует другой ключ шифрования", "count", current-last)
+		}
+		last = current
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import "time"
+
+const (
+	dailyCycle      = 7   // Дней в цикле наград, после последнего дня цикл начинается заново
+	dailyBonusMoney = 100 // Монеты вместо фона, если у игрока уже есть все фоны
+)
+
+// Награда за день серии
+type DailyReward struct {
+	Money      int
+	Background bool // Самый дешёвый фон, которого у игрока ещё нет
+}
+
+// Награды по дням цикла, растут к последнему дню
+var dailyRewards = [dailyCycle]DailyReward{
+	{Money: 10},
+	{Money: 15},
+	{Money: 20},
+	{Money: 30},
+	{Money: 40},
+	{Money: 50},
+	{Money: 75, Background: true},
+}
+
+// Ответ на запрос ежедневной награды
+type DailyRewardInfo struct {
+	Claimed    bool   `msgpack:"c"`           // Награда получена этим запросом, иначе она уже получена сегодня
+	Streak     int    `msgpack:"s"`           // Дней подряд с наградой
+	Day        int    `msgpack:"d"`           // День цикла наград, от 1 до dailyCycle
+	Money      int    `msgpack:"m,omitempty"` // Полученные монеты
+	Background string `msgpack:"b,omitempty"` // Название полученного фона
+	TotalMoney int    `msgpack:"tm"`          // Баланс после награды
+	NextClaim  int64  `msgpack:"n"`           // Время следующей награды, мс по UTC
+}
+
+// День награды: дата по UTC без времени
+func dailyDate(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Серия после получения награды в день today. ok = false, если награда за этот день уже получена.
+// Пропуск дня начинает серию заново
+func nextDailyStreak(lastClaim time.Time, streak int, today time.Time) (newStreak int, ok bool) {
+	lastClaim, today = dailyDate(lastClaim), dailyDate(today)
+	switch {
+	case !today.After(lastClaim):
+		return streak, false
+	case lastClaim.AddDate(0, 0, 1).Equal(today):
+		return streak + 1, true
+	default:
+		return 1, true
+	}
+}
+
+// День цикла и награда для серии
+func dailyRewardFor(streak int) (day int, reward DailyReward) {
+	day = (streak-1)%dailyCycle + 1
+	return day, dailyRewards[day-1]
+}
+
+// Ответ без наград: серия, день цикла и время следующей награды
+func newDailyRewardInfo(streak int, today time.Time, totalMoney int) *DailyRewardInfo {
+	day, _ := dailyRewardFor(streak)
+	return &DailyRewardInfo{
+		Streak:     streak,
+		Day:        day,
+		TotalMoney: totalMoney,
+		NextClaim:  dailyDate(today).AddDate(0, 0, 1).UnixMilli(),
+	}
+}
+
+// Выдаёт ежедневную награду игроку, если она ещё не получена сегодня
+func claimDailyReward(client *Client) (*DailyRewardInfo, error) {
+	info, err := store.claimDailyReward(client.PlayerID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	client.Money = info.TotalMoney
+	if info.Claimed {
+		client.logger().Info("Получена ежедневная награда", "streak", info.Streak, "day", info.Day,
+			"money", info.Money, "background", info.Background)
+	}
+	return info, nil
+}
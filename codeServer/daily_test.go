@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextDailyStreak(t *testing.T) {
+	day := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		lastClaim time.Time
+		streak    int
+		today     time.Time
+		want      int
+		wantOK    bool
+	}{
+		{"первая награда", time.Time{}, 0, day, 1, true},
+		{"повтор в тот же день", day.Add(-10 * time.Hour), 3, day, 3, false},
+		{"следующий день", day.AddDate(0, 0, -1), 3, day, 4, true},
+		{"следующий день сразу после полуночи", day.AddDate(0, 0, -1).Add(8 * time.Hour), 3, dailyDate(day).Add(time.Minute), 4, true},
+		{"пропуск дня", day.AddDate(0, 0, -2), 5, day, 1, true},
+		{"часы назад", day.AddDate(0, 0, 1), 2, day, 2, false},
+	}
+	for _, tt := range tests {
+		got, ok := nextDailyStreak(tt.lastClaim, tt.streak, tt.today)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: серия %d, %v, ожидалось %d, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestDailyRewardFor(t *testing.T) {
+	tests := []struct {
+		streak, day int
+	}{
+		{1, 1},
+		{6, 6},
+		{dailyCycle, dailyCycle},
+		{dailyCycle + 1, 1},
+		{2*dailyCycle + 3, 3},
+	}
+	for _, tt := range tests {
+		day, reward := dailyRewardFor(tt.streak)
+		if day != tt.day || reward != dailyRewards[tt.day-1] {
+			t.Errorf("серия %d: день %d с наградой %+v, ожидался день %d", tt.streak, day, reward, tt.day)
+		}
+	}
+}
+
+// Второй запрос в тот же день не начисляет награду повторно
+func TestDailyRewardOncePerDay(t *testing.T) {
+	s := startTestServer(t, testServerConfig{})
+	ctx := testContext(t)
+	alice := s.player("alice")
+
+	first, err := alice.DailyReward(ctx)
+	if err != nil {
+		t.Fatalf("ежедневная награда: %v", err)
+	}
+	if !first.Claimed || first.Streak != 1 || first.Day != 1 || first.Money != dailyRewards[0].Money {
+		t.Fatalf("первая награда %+v", *first)
+	}
+	if want := startMoney + first.Money; first.TotalMoney != want {
+		t.Errorf("после награды %d денег, ожидалось %d", first.TotalMoney, want)
+	}
+
+	second, err := alice.DailyReward(ctx)
+	if err != nil {
+		t.Fatalf("повторная награда: %v", err)
+	}
+	if second.Claimed || second.Money != 0 || second.Streak != 1 || second.TotalMoney != first.TotalMoney {
+		t.Errorf("повторный запрос в тот же день %+v", *second)
+	}
+
+	player, err := s.Store.getAdminPlayer(alice.User.PublicID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, sum, err := s.Store.getMoneyLedger(player.PlayerID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if player.Money != first.TotalMoney || len(entries) != 2 || sum != player.Money {
+		t.Fatalf("баланс %d, журнал денег %+v с суммой %d", player.Money, entries, sum)
+	}
+	if e := entries[0]; e.Reason != ledgerDaily || e.Amount != first.Money {
+		t.Errorf("запись о награде %+v", e)
+	}
+}
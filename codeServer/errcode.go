@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// Код ошибки протокола. Значения неизменны между версиями, новые коды только добавляются
+type ErrorCode uint16
+
+// Общие ошибки и ошибки протокола
+const (
+	ErrCodeInternal          ErrorCode = 100
+	ErrCodeBadRequest        ErrorCode = 101 // Не удалось разобрать данные запроса
+	ErrCodeUnknownMessage    ErrorCode = 102
+	ErrCodeNotAuthorized     ErrorCode = 103
+	ErrCodeAlreadyAuthorized ErrorCode = 104
+	ErrCodeInBattle          ErrorCode = 105
+	ErrCodeVersion           ErrorCode = 106
+	ErrCodeNetProfile        ErrorCode = 107
+	ErrCodeBadFrame          ErrorCode = 108
+	ErrCodeTooManyBadFrames  ErrorCode = 109
+	ErrCodeRateLimited       ErrorCode = 110
+	ErrCodeFlood             ErrorCode = 111
+	ErrCodeServerShutdown    ErrorCode = 112
+	ErrCodeKicked            ErrorCode = 113
+)
+
+// Регистрация и авторизация
+const (
+	ErrCodeEmptyFields        ErrorCode = 200
+	ErrCodeLoginTooLong       ErrorCode = 201
+	ErrCodeNameTooLong        ErrorCode = 202
+	ErrCodePasswordTooLong    ErrorCode = 203
+	ErrCodeLoginChars         ErrorCode = 204
+	ErrCodeNamePasswordChars  ErrorCode = 205
+	ErrCodePasswordsMismatch  ErrorCode = 206
+	ErrCodeLoginExists        ErrorCode = 207
+	ErrCodeUserNotFound       ErrorCode = 208 // Не отправляется, вместо неё ErrCodeBadCredentials
+	ErrCodeUserBlocked        ErrorCode = 209
+	ErrCodeWrongPassword      ErrorCode = 210 // Не отправляется, вместо неё ErrCodeBadCredentials
+	ErrCodePlayerNotFound     ErrorCode = 211
+	ErrCodeCreateUserFailed   ErrorCode = 212
+	ErrCodeCreatePlayerFailed ErrorCode = 213
+	ErrCodeBadCredentials     ErrorCode = 214
+	ErrCodeLoginLocked        ErrorCode = 215
+	ErrCodeUserBannedUntil    ErrorCode = 216
+)
+
+// Друзья и дружеские бои
+const (
+	ErrCodeFriendCodeInvalid   ErrorCode = 300
+	ErrCodeFriendRepeatRequest ErrorCode = 301
+	ErrCodeFriendOffline       ErrorCode = 302
+	ErrCodeFriendInBattle      ErrorCode = 303
+	ErrCodeChallengeNotSent    ErrorCode = 304
+	ErrCodeChallengeCancelled  ErrorCode = 305
+	ErrCodeChallengeInactive   ErrorCode = 306
+	ErrCodeFriendLeft          ErrorCode = 307
+)
+
+// Бой
+const (
+	ErrCodeAlreadyWaiting   ErrorCode = 400
+	ErrCodeBattleNotActive  ErrorCode = 401
+	ErrCodeUnknownCommand   ErrorCode = 402
+	ErrCodeCharacterMissing ErrorCode = 403
+	ErrCodeCheatDetected    ErrorCode = 404
+)
+
+// Магазин и ресурсы
+const (
+	ErrCodeUnknownShopAction       ErrorCode = 500
+	ErrCodeUnknownProduct          ErrorCode = 501
+	ErrCodeBackgroundNotFound      ErrorCode = 502
+	ErrCodeBackgroundAlreadyBought ErrorCode = 503
+	ErrCodeBackgroundNotBought     ErrorCode = 504
+	ErrCodeCharacterNotFound       ErrorCode = 505
+	ErrCodeCharacterAlreadyBought  ErrorCode = 506
+	ErrCodeCharacterNotBought      ErrorCode = 507
+	ErrCodeNotEnoughMoney          ErrorCode = 508
+	ErrCodeAssetNotFound           ErrorCode = 509
+)
+
+// Ключ сообщения для локализации на клиенте и текст по умолчанию.
+// В тексте {имя} заменяется значением из Details
+type errorInfo struct {
+	key  string
+	text string
+}
+
+var errorInfos = map[ErrorCode]errorInfo{
+	ErrCodeInternal:          {"error.internal", "внутренняя ошибка сервера"},
+	ErrCodeBadRequest:        {"error.bad_request", "некорректные данные запроса"},
+	ErrCodeUnknownMessage:    {"error.unknown_message", "неизвестный тип сообщения: {type}"},
+	ErrCodeNotAuthorized:     {"error.not_authorized", "Вы не авторизованы!"},
+	ErrCodeAlreadyAuthorized: {"error.already_authorized", "Вы уже авторизованы!"},
+	ErrCodeInBattle:          {"error.in_battle", "Вы уже в поиске боя!"},
+	ErrCodeVersion:           {"error.version", "Версия клиента не поддерживается, обновите игру!"},
+	ErrCodeNetProfile:        {"error.net_profile", "Профиль сети {profile} не совпадает с портом сервера ({expected})!"},
+	ErrCodeBadFrame:          {"error.bad_frame", "ошибка при десериализации сообщения, сообщение пропущено!"},
+	ErrCodeTooManyBadFrames:  {"error.too_many_bad_frames", "слишком много повреждённых сообщений!"},
+	ErrCodeRateLimited:       {"error.rate_limited", "Слишком много запросов, подождите немного"},
+	ErrCodeFlood:             {"error.flood", "Соединение закрыто из-за слишком частых запросов"},
+	ErrCodeServerShutdown:    {"error.server_shutdown", "Сервер остановлен на обслуживание, подключитесь позже"},
+	ErrCodeKicked:            {"error.kicked", "Вы отключены администратором: {reason}"},
+
+	ErrCodeEmptyFields:        {"auth.empty_fields", "поля не могут быть пустыми"},
+	ErrCodeLoginTooLong:       {"auth.login_too_long", "превышена длина логина"},
+	ErrCodeNameTooLong:        {"auth.name_too_long", "превышена длина имени"},
+	ErrCodePasswordTooLong:    {"auth.password_too_long", "превышена длина пароля"},
+	ErrCodeLoginChars:         {"auth.login_chars", "логин должен содержать символы: {allowed}"},
+	ErrCodeNamePasswordChars:  {"auth.name_password_chars", "допустимые символы для имени и пароля: {allowed}"},
+	ErrCodePasswordsMismatch:  {"auth.passwords_mismatch", "пароли не совпадают"},
+	ErrCodeLoginExists:        {"auth.login_exists", "логин уже существует"},
+	ErrCodeUserNotFound:       {"auth.user_not_found", "пользователь '{login}' не найден"},
+	ErrCodeUserBlocked:        {"auth.user_blocked", "пользователь '{login}' заблокирован: {reason}"},
+	ErrCodeWrongPassword:      {"auth.wrong_password", "неверно введен пароль"},
+	ErrCodePlayerNotFound:     {"auth.player_not_found", "игровые данные '{login}' не найдены"},
+	ErrCodeCreateUserFailed:   {"auth.create_user_failed", "не удалось создать пользователя"},
+	ErrCodeCreatePlayerFailed: {"auth.create_player_failed", "не удалось создать профиль игрока"},
+	ErrCodeBadCredentials:     {"auth.bad_credentials", "неверный логин или пароль"},
+	ErrCodeLoginLocked:        {"auth.login_locked", "слишком много неудачных попыток входа, повторите через {seconds} с"},
+	ErrCodeUserBannedUntil:    {"auth.user_banned_until", "пользователь '{login}' заблокирован до {until}: {reason}"},
+
+	ErrCodeFriendCodeInvalid:   {"friends.code_invalid", "Неверный код"},
+	ErrCodeFriendRepeatRequest: {"friends.repeat_request", "Повторная заявка"},
+	ErrCodeFriendOffline:       {"friends.offline", "игрок: {friend}, не авторизован или ID неверно"},
+	ErrCodeFriendInBattle:      {"friends.in_battle", "игрок: {friend} уже в бою, попробуйте позже"},
+	ErrCodeChallengeNotSent:    {"friends.challenge_not_sent", "не удалось отправить приглашение"},
+	ErrCodeChallengeCancelled:  {"friends.challenge_cancelled", "друг отменил приглашение на бой, попробуйте снова"},
+	ErrCodeChallengeInactive:   {"friends.challenge_inactive", "приглашение на бой больше не активно, игрок уже в бою"},
+	ErrCodeFriendLeft:          {"friends.left", "игрок вышел из игры"},
+
+	ErrCodeAlreadyWaiting:   {"battle.already_waiting", "Вы в ожидание боя"},
+	ErrCodeBattleNotActive:  {"battle.not_active", "Бой еще не начался или уже закончился!"},
+	ErrCodeUnknownCommand:   {"battle.unknown_command", "Неизвестная команда: {command}"},
+	ErrCodeCharacterMissing: {"battle.character_missing", "персонаж не найден"},
+	ErrCodeCheatDetected:    {"battle.cheat_detected", "Соединение закрыто: обнаружены недопустимые действия"},
+
+	ErrCodeUnknownShopAction:       {"shop.unknown_action", "неизвестный тип действия магазина: {action}"},
+	ErrCodeUnknownProduct:          {"shop.unknown_product", "неизвестный тип продукта магазина: {product}"},
+	ErrCodeBackgroundNotFound:      {"shop.background_not_found", "фон не найден"},
+	ErrCodeBackgroundAlreadyBought: {"shop.background_already_bought", "фон уже куплен"},
+	ErrCodeBackgroundNotBought:     {"shop.background_not_bought", "фон не куплен"},
+	ErrCodeCharacterNotFound:       {"shop.character_not_found", "персонаж не найден"},
+	ErrCodeCharacterAlreadyBought:  {"shop.character_already_bought", "персонаж уже куплен"},
+	ErrCodeCharacterNotBought:      {"shop.character_not_bought", "персонаж не куплен"},
+	ErrCodeNotEnoughMoney:          {"shop.not_enough_money", "недостаточно денег для покупки"},
+	ErrCodeAssetNotFound:           {"content.asset_not_found", "Ресурс не найден!"},
+}
+
+// Ошибка, отправляемая клиенту в MsgError
+type GameError struct {
+	Code    ErrorCode         `msgpack:"c"`
+	Key     string            `msgpack:"k"`           // Ключ сообщения для локализации
+	Details map[string]string `msgpack:"d,omitempty"` // Значения для подстановки в сообщение
+	Message string            `msgpack:"m"`           // Текст по умолчанию на русском
+}
+
+func (e *GameError) Error() string {
+	return e.Message
+}
+
+// Создаёт ошибку по коду, details задаются парами ключ, значение
+func newGameError(code ErrorCode, details ...string) *GameError {
+	info, ok := errorInfos[code]
+	if !ok {
+		slog.Error("Код ошибки не описан", "code", code)
+		code, info = ErrCodeInternal, errorInfos[ErrCodeInternal]
+	}
+
+	e := &GameError{Code: code, Key: info.key, Message: info.text}
+	if len(details) > 1 {
+		e.Details = make(map[string]string, len(details)/2)
+		replace := make([]string, 0, len(details))
+		for i := 0; i+1 < len(details); i += 2 {
+			e.Details[details[i]] = details[i+1]
+			replace = append(replace, "{"+details[i]+"}", details[i+1])
+		}
+		e.Message = strings.NewReplacer(replace...).Replace(info.text)
+	}
+	return e
+}
+
+// Отправляет клиенту ошибку. Ошибки без кода скрываются за внутренней ошибкой сервера
+func sendError(client *Client, err error) error {
+	var gameErr *GameError
+	if !errors.As(err, &gameErr) {
+		client.logger().Error("Ошибка без кода", "err", err)
+		gameErr = newGameError(ErrCodeInternal)
+	}
+	return createAndSendMessage(client, MsgError, gameErr)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Кадр сообщения: длина тела, порядковый номер, ID запроса (uint32, big endian), тело - Message в msgpack.
+// Длина позволяет пропустить повреждённое тело, не разрывая соединение
+const (
+	frameHeaderSize = 12
+	maxFrameSize    = 1 << 20 // Наибольший размер тела кадра
+	maxBadFrames    = 10      // Сколько повреждённых кадров подряд допускается до отключения клиента
+)
+
+var errFrameTooLarge = errors.New("размер кадра превышает допустимый")
+
+// Заголовок кадра
+type frameHeader struct {
+	Size  uint32
+	Seq   uint32 // Порядковый номер сообщения у отправителя, начиная с 1
+	ReqID uint32 // ID запроса клиента, на который отвечает сообщение, 0 - без привязки
+}
+
+// Читает кадр целиком
+func readFrame(r io.Reader) (frameHeader, []byte, error) {
+	var buf [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return frameHeader{}, nil, err
+	}
+	header := frameHeader{
+		Size:  binary.BigEndian.Uint32(buf[0:4]),
+		Seq:   binary.BigEndian.Uint32(buf[4:8]),
+		ReqID: binary.BigEndian.Uint32(buf[8:12]),
+	}
+	if header.Size > maxFrameSize {
+		return header, nil, fmt.Errorf("%w: %d", errFrameTooLarge, header.Size)
+	}
+
+	body := make([]byte, header.Size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return header, nil, err
+	}
+	return header, body, nil
+}
+
+// Записывает кадр одним вызовом Write, чтобы KCP отправил его одним сообщением
+func writeFrame(w io.Writer, seq, reqID uint32, body []byte) error {
+	if len(body) > maxFrameSize {
+		return fmt.Errorf("%w: %d", errFrameTooLarge, len(body))
+	}
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+len(body))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(frame[4:8], seq)
+	binary.BigEndian.PutUint32(frame[8:12], reqID)
+	frame = append(frame, body...)
+	_, err := w.Write(frame)
+	return err
+}
+
+// Запоминает запрос, который сейчас обрабатывается, чтобы ответы на него несли его ID
+func (client *Client) beginRequest(msg *Message) {
+	client.connMutex.Lock()
+	client.reqID = msg.ReqID
+	client.reqType = msg.Type
+	client.connMutex.Unlock()
+	client.reqLog.Store(client.log.Load().With("msg_type", msg.Type, "req_id", msg.ReqID))
+}
+
+func (client *Client) endRequest() {
+	client.connMutex.Lock()
+	client.reqID = 0
+	client.reqType = MsgNone
+	client.connMutex.Unlock()
+	client.reqLog.Store(nil)
+}
+
+// ID обрабатываемого запроса
+func (client *Client) requestID() uint32 {
+	client.connMutex.Lock()
+	defer client.connMutex.Unlock()
+	return client.reqID
+}
+
+// ID запроса для ответа: ошибки, успех и ответ того же типа относятся к текущему запросу.
+// Вызывается под connMutex
+func (client *Client) replyID(mesType MessageType) uint32 {
+	if mesType == MsgError || mesType == MsgSuccess || mesType == client.reqType {
+		return client.reqID
+	}
+	return 0
+}
+
+// Отправка кадра, вызывается под connMutex
+func (client *Client) writeFrameLocked(reqID uint32, data []byte) error {
+	if client.Conn == nil {
+		client.logger().Debug("Попытка отправки сообщения отключённому клиенту")
+		return errors.New("соединение закрыто")
+	}
+	client.sendSeq++
+	return writeFrame(client.Conn, client.sendSeq, reqID, data)
+}
+
+// Отправка ответа на конкретный запрос, когда он не совпадает с обрабатываемым
+func sendReply(client *Client, reqID uint32, mesType MessageType, data interface{}) error {
+	resp, err := createMessage(mesType, data)
+	if err != nil {
+		return err
+	}
+	client.connMutex.Lock()
+	defer client.connMutex.Unlock()
+	return client.writeFrameLocked(reqID, resp)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"codeClient/bot"
+	"codeClient/protocol"
+	"context"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Публичные ID списка друзей через запятую в порядке сортировки
+func friendIDs(entries []bot.FriendEntry) string {
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.PublicID)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+// Проверяет друзей, входящие и исходящие заявки игрока
+func checkFriends(t *testing.T, ctx context.Context, b *bot.Bot, friends, incoming, outgoing []*bot.Bot) {
+	t.Helper()
+	data, err := b.Friends(ctx)
+	if err != nil {
+		t.Fatalf("список друзей %s: %v", b.User.Login, err)
+	}
+	for _, list := range []struct {
+		name string
+		got  []bot.FriendEntry
+		want []*bot.Bot
+	}{{"друзья", data.Friends, friends}, {"входящие", data.Incoming, incoming}, {"исходящие", data.Outgoing, outgoing}} {
+		ids := make([]string, 0, len(list.want))
+		for _, w := range list.want {
+			ids = append(ids, w.User.PublicID)
+		}
+		sort.Strings(ids)
+		if got, want := friendIDs(list.got), strings.Join(ids, ","); got != want {
+			t.Errorf("%s: %s [%s], ожидались [%s]", b.User.Login, list.name, got, want)
+		}
+	}
+}
+
+func TestFriends(t *testing.T) {
+	s := startTestServer(t, testServerConfig{})
+	ctx := testContext(t)
+	alice, bob, carol := s.player("alice"), s.player("bob"), s.player("carol")
+
+	var status FriendRequestStatus
+	if _, err := alice.Request(ctx, protocol.MsgAddFriend, bob.User.PublicID, &status); err != nil {
+		t.Fatalf("заявка в друзья: %v", err)
+	}
+	if status != friendRequestSent {
+		t.Errorf("статус заявки %d, ожидалась отправка", status)
+	}
+	if _, err := alice.Request(ctx, protocol.MsgAddFriend, bob.User.PublicID, &status); errorCode(err) != protocol.ErrCodeFriendRepeatRequest {
+		t.Errorf("повторная заявка: %v", err)
+	}
+	if _, err := alice.Request(ctx, protocol.MsgAddFriend, alice.User.PublicID, &status); errorCode(err) != protocol.ErrCodeFriendCodeInvalid {
+		t.Errorf("заявка самому себе: %v", err)
+	}
+	checkFriends(t, ctx, alice, nil, nil, []*bot.Bot{bob})
+	checkFriends(t, ctx, bob, nil, []*bot.Bot{alice}, nil)
+
+	// Ответа на подтверждение нет. Сообщения одного клиента сервер обрабатывает по порядку,
+	// поэтому список друзей запрашивается сначала тем же игроком
+	if err := bob.Send(protocol.MsgAcceptFriendship, alice.User.PublicID); err != nil {
+		t.Fatal(err)
+	}
+	checkFriends(t, ctx, bob, []*bot.Bot{alice}, nil, nil)
+	checkFriends(t, ctx, alice, []*bot.Bot{bob}, nil, nil)
+
+	// Встречная заявка сразу подтверждает дружбу
+	if _, err := carol.Request(ctx, protocol.MsgAddFriend, alice.User.PublicID, &status); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.Request(ctx, protocol.MsgAddFriend, carol.User.PublicID, &status); err != nil {
+		t.Fatal(err)
+	}
+	if status != friendRequestAccepted {
+		t.Errorf("статус встречной заявки %d, ожидалось подтверждение", status)
+	}
+
+	// Отклонённая заявка пропадает у обоих игроков
+	if _, err := carol.Request(ctx, protocol.MsgAddFriend, bob.User.PublicID, &status); err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Send(protocol.MsgDeclineFriendship, carol.User.PublicID); err != nil {
+		t.Fatal(err)
+	}
+	checkFriends(t, ctx, bob, []*bot.Bot{alice}, nil, nil)
+	checkFriends(t, ctx, carol, []*bot.Bot{alice}, nil, nil)
+
+	if err := alice.Send(protocol.MsgRemoveFriend, bob.User.PublicID); err != nil {
+		t.Fatal(err)
+	}
+	checkFriends(t, ctx, alice, []*bot.Bot{carol}, nil, nil)
+	checkFriends(t, ctx, bob, nil, nil, nil)
+}
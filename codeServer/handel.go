@@ -1,9 +1,7 @@
 package main
 
 import (
-	"fmt"
 	"github.com/vmihailenco/msgpack/v5"
-	"log"
 	"sync/atomic"
 	"time"
 )
@@ -18,26 +16,29 @@ var handlers = map[MessageType]messageHandler{
 	MsgAuthorization:          handleAuthorization,
 	MsgRegistration:           handleRegistration,
 	MsgActionCharacter:        requireAuth(requiresNoBattle(handleActionCharacter)),
-	MsgBattle:                 requireAuth(requiresNoBattle(handleBattle)),
-	MsgBattleRanked:           requireAuth(requiresNoBattle(handleBattleRanked)),
+	MsgBattle:                 requireAuth(requiresNoBattle(requiresNoShutdown(handleBattle))),
+	MsgBattleRanked:           requireAuth(requiresNoBattle(requiresNoShutdown(handleBattleRanked))),
 	MsgFriendsData:            requireAuth(requiresNoBattle(handelFriendsData)),
 	MsgAddFriend:              requireAuth(requiresNoBattle(handelAddFriend)),
 	MsgAcceptFriendship:       requireAuth(requiresNoBattle(handelAcceptFriendship)),
 	MsgDeclineFriendship:      requireAuth(requiresNoBattle(handelDeclineFriendship)),
-	MsgChallengeToFight:       requireAuth(requiresNoBattle(handelChallengeToFight)),
-	MsgAcceptChallengeToFight: requireAuth(requiresNoBattle(handelAcceptChallengeToFight)),
+	MsgChallengeToFight:       requireAuth(requiresNoBattle(requiresNoShutdown(handelChallengeToFight))),
+	MsgAcceptChallengeToFight: requireAuth(requiresNoBattle(requiresNoShutdown(handelAcceptChallengeToFight))),
 	MsgRefuseChallengeToFight: requireAuth(requiresNoBattle(handelRefuseChallengeToFight)),
 	MsgRemoveFriend:           requireAuth(requiresNoBattle(handelRemoveFriend)),
 	MsgListBattles:            requireAuth(requiresNoBattle(handelListBattles)),
 	MsgShopData:               requireAuth(requiresNoBattle(handelShopData)),
 	MsgShopAction:             requireAuth(requiresNoBattle(handleShopAction)),
+	MsgDailyReward:            requireAuth(requiresNoBattle(handleDailyReward)),
+	MsgAssetManifest:          requireAuth(handleAssetManifest), // Ресурсы оппонента нужны и во время боя
+	MsgAssetRequest:           requireAuth(handleAssetRequest),
 }
 
 // Для команд, требующих авторизации и отсутствия сражения
 func requireAuth(handler messageHandler) messageHandler {
 	return func(client *Client, data []byte) {
 		if !client.Authorized {
-			createAndSendMessage(client, MsgError, "Вы не авторизованы!")
+			sendError(client, newGameError(ErrCodeNotAuthorized))
 			return
 		}
 		handler(client, data)
@@ -48,7 +49,18 @@ func requireAuth(handler messageHandler) messageHandler {
 func requiresNoBattle(handler messageHandler) messageHandler {
 	return func(client *Client, data []byte) {
 		if client.State.inBattle {
-			createAndSendMessage(client, MsgError, "Вы уже в поиске боя!")
+			sendError(client, newGameError(ErrCodeInBattle))
+			return
+		}
+		handler(client, data)
+	}
+}
+
+// Для команд, начинающих бой: во время остановки сервера новые бои не создаются
+func requiresNoShutdown(handler messageHandler) messageHandler {
+	return func(client *Client, data []byte) {
+		if isShuttingDown() {
+			sendError(client, newGameError(ErrCodeServerShutdown))
 			return
 		}
 		handler(client, data)
@@ -64,45 +76,40 @@ func handlePong(client *Client) {
 	now := time.Now().UnixMilli()
 	sentTime := atomic.LoadInt64(&client.lastPingTime)
 	RTT := now - sentTime
-	log.Printf("Обновленная задержка клиента %d: %d RTT\n", client.UserID, RTT)
+	clientRTT.Observe(float64(RTT) / 1000)
 	client.Ping = RTT / 2
 	if client.Ping > 0 {
-		log.Printf("Обновленная задержка клиента %d: %d ms\n", client.UserID, client.Ping)
+		client.logger().Debug("Обновлена задержка клиента", "rtt_ms", RTT, "ping_ms", client.Ping)
 	}
 	atomic.StoreInt32(&client.waitingForPong, 0)
 }
 
 // Авторизация
 func handleAuthorization(client *Client, data []byte) {
-
-	var resp []byte
 	usDt, err := actionAuthorization(client, data)
 	if err != nil {
-		resp, _ = createMessage(MsgError, err.Error())
 		defer client.cancel()
+		sendError(client, err)
 	} else {
-		resp, _ = createMessage(MsgSuccess, usDt)
+		createAndSendMessage(client, MsgSuccess, usDt)
+		client.logger().Info("Клиент авторизован", "name", client.Name)
 	}
-	log.Println(client.Name, " авторизован")
-	sendMessage(client, resp)
 }
 
 // Регистрация
 func handleRegistration(client *Client, data []byte) {
-	var resp []byte
 	if client.Authorized {
-		resp, _ = createMessage(MsgError, "Вы уже авторизованы!")
+		sendError(client, newGameError(ErrCodeAlreadyAuthorized))
 	} else {
 		usDt, err := actionRegistration(client, data)
 		if err != nil {
-			resp, _ = createMessage(MsgError, err.Error())
 			defer client.cancel()
+			sendError(client, err)
 		} else {
-			resp, _ = createMessage(MsgSuccess, usDt)
+			createAndSendMessage(client, MsgSuccess, usDt)
+			client.logger().Info("Клиент зарегистрирован", "name", client.Name)
 		}
 	}
-	log.Println(client.Name, " зарегистрирован")
-	sendMessage(client, resp)
 }
 
 // Управление персонажем
@@ -111,11 +118,11 @@ func handleActionCharacter(client *Client, data []byte) {
 
 	err := msgpack.Unmarshal(data, &action)
 	if err != nil {
-		log.Printf("Ошибка десериализации в handleActionCharacter: %v", err)
-		createAndSendMessage(client, MsgError, "внутренняя ошибка сервера при десериализации Action")
+		client.logger().Warn("Ошибка десериализации", "handler", "handleActionCharacter", "err", err)
+		sendError(client, newGameError(ErrCodeBadRequest))
 		return
 	}
-	actionCharacter(action, client, nil)
+	actionCharacter(action, client, nil, client.requestID())
 }
 
 // Поиск обычного боя
@@ -130,14 +137,12 @@ func handleBattleRanked(client *Client, data []byte) {
 
 // Получение списка друзей и заявок в друзья.
 func handelFriendsData(client *Client, data []byte) {
-	var resp []byte
-	frDt, err := GetFriendsAndRequestsDB(client.PlayerID)
+	frDt, err := store.getFriends(client.PlayerID)
 	if err != nil {
-		resp, _ = createMessage(MsgError, err.Error())
+		sendError(client, err)
 	} else {
-		resp, _ = createMessage(MsgFriendsData, frDt)
+		createAndSendMessage(client, MsgFriendsData, frDt)
 	}
-	sendMessage(client, resp)
 }
 
 // Обрабатывает запрос на добавление друга
@@ -146,13 +151,17 @@ func handelAddFriend(client *Client, data []byte) {
 
 	err := msgpack.Unmarshal(data, &friendID)
 	if err != nil {
-		log.Printf("Ошибка десериализации в handelAddFriend: %v", err)
-		createAndSendMessage(client, MsgError, "внутренняя ошибка сервера при десериализации friendID")
+		client.logger().Warn("Ошибка десериализации", "handler", "handelAddFriend", "err", err)
+		sendError(client, newGameError(ErrCodeBadRequest))
 		return
 	}
 
-	result := addFriendDB(client.PlayerID, friendID).Error()
-	createAndSendMessage(client, MsgAddFriend, result)
+	status, err := store.addFriend(client.PlayerID, friendID)
+	if err != nil {
+		sendError(client, err)
+		return
+	}
+	createAndSendMessage(client, MsgAddFriend, status)
 }
 
 // Обрабатывает запрос на подтверждение заявки в друзья
@@ -161,14 +170,14 @@ func handelAcceptFriendship(client *Client, data []byte) {
 
 	err := msgpack.Unmarshal(data, &friendID)
 	if err != nil {
-		log.Printf("Ошибка десериализации в handelAcceptFriendship: %v", err)
-		createAndSendMessage(client, MsgError, "внутренняя ошибка сервера при десериализации friendID")
+		client.logger().Warn("Ошибка десериализации", "handler", "handelAcceptFriendship", "err", err)
+		sendError(client, newGameError(ErrCodeBadRequest))
 		return
 	}
 
-	err = acceptFriendshipDB(client.PlayerID, friendID)
+	err = store.acceptFriendship(client.PlayerID, friendID)
 	if err != nil {
-		createAndSendMessage(client, MsgError, err.Error())
+		sendError(client, err)
 		return
 	}
 
@@ -180,14 +189,14 @@ func handelDeclineFriendship(client *Client, data []byte) {
 
 	err := msgpack.Unmarshal(data, &friendID)
 	if err != nil {
-		log.Printf("Ошибка десериализации в handelDeclineFriendship: %v", err)
-		createAndSendMessage(client, MsgError, "внутренняя ошибка сервера при десериализации friendID")
+		client.logger().Warn("Ошибка десериализации", "handler", "handelDeclineFriendship", "err", err)
+		sendError(client, newGameError(ErrCodeBadRequest))
 		return
 	}
 
-	err = declineFriendshipDB(client.PlayerID, friendID)
+	err = store.declineFriendship(client.PlayerID, friendID)
 	if err != nil {
-		createAndSendMessage(client, MsgError, err.Error())
+		sendError(client, err)
 		return
 	}
 
@@ -199,26 +208,26 @@ func handelChallengeToFight(client *Client, data []byte) {
 
 	err := msgpack.Unmarshal(data, &friendID)
 	if err != nil {
-		log.Printf("Ошибка десериализации в handelRemoveFriend: %v", err)
-		createAndSendMessage(client, MsgError, "внутренняя ошибка сервера при десериализации friendID")
+		client.logger().Warn("Ошибка десериализации", "handler", "handelRemoveFriend", "err", err)
+		sendError(client, newGameError(ErrCodeBadRequest))
 		return
 	}
 
 	friend, ok := authorizedClients[friendID]
 	if !ok {
-		log.Printf("Ошибка вызова на дуэль, игрок: %v, не авторизован или ID неверно", friendID)
-		createAndSendMessage(client, MsgError, fmt.Sprintf("игрок: %v, не авторизован или ID неверно", friendID))
+		client.logger().Info("Ошибка вызова на дуэль: игрок не авторизован или ID неверно", "friend_id", friendID)
+		sendError(client, newGameError(ErrCodeFriendOffline, "friend", friendID))
 		return
 	}
 
 	if friend.State.inBattle {
-		createAndSendMessage(client, MsgError, fmt.Sprintf("игрок: %v уже в бою, попробуйте позже", friendID))
+		sendError(client, newGameError(ErrCodeFriendInBattle, "friend", friendID))
 		return
 	}
 
 	err = createAndSendMessage(friend, MsgChallengeToFight, FriendEntry{Name: client.Name, PublicID: client.PublicID})
 	if err != nil {
-		createAndSendMessage(client, MsgError, "не удалось отправить приглашение")
+		sendError(client, newGameError(ErrCodeChallengeNotSent))
 		return
 	}
 	waitingBattle(client, false, friendID)
@@ -230,27 +239,27 @@ func handelAcceptChallengeToFight(client *Client, data []byte) {
 
 	err := msgpack.Unmarshal(data, &friendID)
 	if err != nil {
-		log.Printf("Ошибка десериализации в handelAcceptChallengeToFight: %v", err)
-		createAndSendMessage(client, MsgError, "внутренняя ошибка сервера при десериализации friendID")
+		client.logger().Warn("Ошибка десериализации", "handler", "handelAcceptChallengeToFight", "err", err)
+		sendError(client, newGameError(ErrCodeBadRequest))
 		return
 	}
 
 	friend, ok := authorizedClients[friendID]
 	if !ok {
-		log.Printf("Ошибка при отправке согласии на дуэль: %v", err)
-		createAndSendMessage(client, MsgError, "игрок вышел из игры")
+		client.logger().Warn("Ошибка при отправке согласия на дуэль", "err", err)
+		sendError(client, newGameError(ErrCodeFriendLeft))
 		return
 	}
 
 	if !friend.State.inBattle {
-		log.Printf("Ошибка при отправке согласии на дуэль, игрок %v больше не ожидает оппонента", friendID)
-		createAndSendMessage(client, MsgError, "друг отменил приглашение на бой, попробуйте снова")
+		client.logger().Info("Ошибка при отправке согласия на дуэль: игрок больше не ожидает оппонента", "friend_id", friendID)
+		sendError(client, newGameError(ErrCodeChallengeCancelled))
 		return
 	}
 
 	if friend.State.inBattle && friend.friendID != client.PublicID { // friend.State.inBattle && friend.State.friendID != client.PublicID {
-		log.Printf("Ошибка при отправке согласии на дуэль, игрок %v уже в бою с другим", friendID)
-		createAndSendMessage(client, MsgError, "приглашение на бой больше не активно, игрок уже в бою")
+		client.logger().Info("Ошибка при отправке согласия на дуэль: игрок уже в бою с другим", "friend_id", friendID)
+		sendError(client, newGameError(ErrCodeChallengeInactive))
 		return
 	}
 
@@ -265,15 +274,15 @@ func handelRefuseChallengeToFight(client *Client, data []byte) {
 
 	err := msgpack.Unmarshal(data, &friendID)
 	if err != nil {
-		log.Printf("Ошибка десериализации в handelRefuseChallengeToFight: %v", err)
-		createAndSendMessage(client, MsgError, "внутренняя ошибка сервера при десериализации friendID")
+		client.logger().Warn("Ошибка десериализации", "handler", "handelRefuseChallengeToFight", "err", err)
+		sendError(client, newGameError(ErrCodeBadRequest))
 		return
 	}
 
 	friend, ok := authorizedClients[friendID]
 	if !ok {
-		log.Printf("Ошибка при отправке отказа на дуэль: %v", err)
-		createAndSendMessage(client, MsgError, "игрок вышел из игры")
+		client.logger().Warn("Ошибка при отправке отказа на дуэль", "err", err)
+		sendError(client, newGameError(ErrCodeFriendLeft))
 		return
 	}
 
@@ -286,13 +295,13 @@ func handelRemoveFriend(client *Client, data []byte) {
 
 	err := msgpack.Unmarshal(data, &friendID)
 	if err != nil {
-		log.Printf("Ошибка десериализации в handelRemoveFriend: %v", err)
-		createAndSendMessage(client, MsgError, "внутренняя ошибка сервера при десериализации friendID")
+		client.logger().Warn("Ошибка десериализации", "handler", "handelRemoveFriend", "err", err)
+		sendError(client, newGameError(ErrCodeBadRequest))
 		return
 	}
-	err = removeFriendDB(client.PlayerID, friendID)
+	err = store.removeFriend(client.PlayerID, friendID)
 	if err != nil {
-		createAndSendMessage(client, MsgError, err.Error())
+		sendError(client, err)
 		return
 	}
 }
@@ -301,10 +310,9 @@ func handelRemoveFriend(client *Client, data []byte) {
 func handelListBattles(client *Client, data []byte) {
 	listBattles, err := GetListBattles(client.PlayerID)
 	if err != nil {
-		createAndSendMessage(client, MsgError, err.Error())
+		sendError(client, err)
 		return
 	}
-	fmt.Println(listBattles)
 	createAndSendMessage(client, MsgListBattles, listBattles)
 }
 
@@ -312,11 +320,9 @@ func handelListBattles(client *Client, data []byte) {
 func handelShopData(client *Client, data []byte) {
 	shopData, err := GetShopData(client.PlayerID)
 	if err != nil {
-		createAndSendMessage(client, MsgError, err.Error())
+		sendError(client, err)
 		return
 	}
-	fmt.Println(shopData)
-
 	createAndSendMessage(client, MsgShopData, shopData)
 }
 
@@ -325,9 +331,19 @@ func handleShopAction(client *Client, data []byte) {
 	var shopAction ShopAction
 	err := msgpack.Unmarshal(data, &shopAction)
 	if err != nil {
-		log.Printf("Ошибка десериализации в handleShopAction: %v", err)
-		createAndSendMessage(client, MsgError, "внутренняя ошибка сервера при десериализации ShopAction")
+		client.logger().Warn("Ошибка десериализации", "handler", "handleShopAction", "err", err)
+		sendError(client, newGameError(ErrCodeBadRequest))
 		return
 	}
 	shopAction.Apply(client)
 }
+
+// Получение ежедневной награды, клиент запрашивает её после авторизации
+func handleDailyReward(client *Client, data []byte) {
+	info, err := claimDailyReward(client)
+	if err != nil {
+		sendError(client, err)
+		return
+	}
+	createAndSendMessage(client, MsgDailyReward, info)
+}
@@ -1,13 +1,15 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/vmihailenco/msgpack/v5"
-	"log"
+	"log/slog"
 	"math/rand"
 	"regexp"
 	"strings"
@@ -15,6 +17,12 @@ import (
 	"time"
 )
 
+const (
+	startMoney       = 100 // Деньги нового игрока
+	publicCodeChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*"
+	publicCodeLength = 6
+)
+
 // Содержит данные авторизованного пользователя
 type UserData struct {
 	PlayerID             int          `db:"PlayerID" msgpack:"-"`
@@ -39,6 +47,14 @@ type FriendsData struct {
 	Outgoing []FriendEntry `msgpack:"o"`
 }
 
+// Результат успешной заявки в друзья, отправляется в MsgAddFriend
+type FriendRequestStatus uint8
+
+const (
+	friendRequestSent FriendRequestStatus = iota
+	friendRequestAccepted
+)
+
 type BattleEntry struct {
 	StartTime        time.Time `db:"StartTime" msgpack:"st"`
 	EndTime          time.Time `db:"EndTime" msgpack:"et"`
@@ -88,10 +104,12 @@ type ShopData struct {
 }
 
 type UserDB struct {
-	IdUser       int    `db:"id_User"`
-	Login        string `db:"Login"`
-	PasswordHash string `db:"PasswordHash"`
-	IsActive     bool   `db:"isActive"`
+	IdUser       int            `db:"id_User"`
+	Login        string         `db:"Login"`
+	PasswordHash string         `db:"PasswordHash"`
+	IsActive     bool           `db:"isActive"`
+	BanReason    sql.NullString `db:"BanReason"`
+	BannedUntil  sql.NullTime   `db:"BannedUntil"`
 }
 
 type CharacterDB struct {
@@ -115,52 +133,32 @@ type AssetsCharacterDB struct {
 	AssetPath     string  `db:"AssetPath" msgpack:"ap"`
 }
 
-func getCharacterData(idActiveCharacter int) *CharacterDB {
+func getCharacterData(character *Character) *CharacterDB {
 	var ch CharacterDB
-	if _, exists := activeCharacters[idActiveCharacter]; !exists {
-		err := db.Get(&ch, queryGetCharacter, sql.Named("Id_Character", idActiveCharacter))
-		if err != nil {
-			panic(err.Error())
-		}
-		var arrAsCh []AssetsCharacterDB
-		err = db.Select(&arrAsCh, queryGetAssetsCharacter, sql.Named("Id_Character", idActiveCharacter))
-		if err != nil {
-			panic(err.Error())
-		}
-		mapAsCh := make(map[string]*AssetsCharacterDB)
-		for _, as := range arrAsCh {
-			if as.AnimationType != "Preview" { // Только для магазина
-				mapAsCh[as.AnimationType] = &as
-			}
-
-		}
-		ch.Assets = mapAsCh
-
-		addCharacterBitMask(idActiveCharacter, ch)
-	} else {
-		ch.Name = activeCharacters[idActiveCharacter].Name
-		ch.Description = activeCharacters[idActiveCharacter].Description
-		ch.Health = activeCharacters[idActiveCharacter].Health
-		ch.Damage = activeCharacters[idActiveCharacter].Damage
-		ch.Cost = activeCharacters[idActiveCharacter].Cost
-		ch.Assets = activeCharacters[idActiveCharacter].Assets
-	}
-
-	ch.HCharacter = activeCharacters[idActiveCharacter].HCharacter
-	ch.XStart = -activeCharacters[idActiveCharacter].XBoundary                                 // Верхний левый угл
-	ch.YStart = ScreenHeight - (GroundLevel + activeCharacters[idActiveCharacter].FrameHeight) // Верхний левый угл
+	ch.Name = character.Name
+	ch.Description = character.Description
+	ch.Health = character.Health
+	ch.Damage = character.Damage
+	ch.Cost = character.Cost
+	ch.Assets = character.Assets
+	ch.HCharacter = character.HCharacter
+	ch.XStart = -character.XBoundary                                 // Верхний левый угл
+	ch.YStart = ScreenHeight - (GroundLevel + character.FrameHeight) // Верхний левый угл
 	return &ch
 }
 
-func getUserData(idUser, idActiveCharacter int, client *Client) *UserData {
-	var usDt UserData
-
-	err := db.Get(&usDt, queryGetUserData, sql.Named("Id_User", idUser))
+func getUserData(idUser, idActiveCharacter int, client *Client) (*UserData, error) {
+	usDt, err := store.getPlayerData(idUser)
 	if err != nil {
-		panic(err.Error())
+		clientDBLogger(client, "get_user_data").Error("Ошибка при получении данных пользователя", "user_id", idUser, "err", err)
+		return nil, newGameError(ErrCodeInternal)
 	}
 
-	usDt.ActiveCharacter = getCharacterData(idActiveCharacter)
+	character, err := getActiveCharacter(idActiveCharacter)
+	if err != nil {
+		return nil, err
+	}
+	usDt.ActiveCharacter = getCharacterData(character)
 
 	client.UserID = idUser
 	client.PlayerID = usDt.PlayerID
@@ -175,11 +173,12 @@ func getUserData(idUser, idActiveCharacter int, client *Client) *UserData {
 	client.friendID = ""
 	client.ActiveCharacter = idActiveCharacter
 	client.State = &CharacterState{}
-	client.State.Update(idActiveCharacter)
+	client.State.Update(character)
+	client.setLogIdentity()
 
 	addClient(client)
 
-	return &usDt
+	return usDt, nil
 }
 
 func validateRegistrationData(rgDt RegisterData) error {
@@ -189,44 +188,44 @@ func validateRegistrationData(rgDt RegisterData) error {
 		namePasswordPattern = `^[a-zA-Zа-яА-Я0-9!@#№$%` + "`" + `~^&*()\-_+=\[\]{};:'",.<>?/|\\ ]+$`
 	)
 	if strings.TrimSpace(rgDt.Login) == "" || strings.TrimSpace(rgDt.Name) == "" || strings.TrimSpace(rgDt.Password) == "" {
-		log.Printf("Ошибка регистрации: поля не могут быть пустыми")
-		return fmt.Errorf("поля не могут быть пустыми")
+		slog.Info("Ошибка регистрации: поля не могут быть пустыми", "login", rgDt.Login)
+		return newGameError(ErrCodeEmptyFields)
 	}
 
 	if len(rgDt.Login) >= maxLen {
-		log.Printf("Ошибка регистрации: длина логина '%s' превышает допустимую", rgDt.Login)
-		return fmt.Errorf("превышена длина логина")
+		slog.Info("Ошибка регистрации: длина логина превышает допустимую", "login", rgDt.Login)
+		return newGameError(ErrCodeLoginTooLong)
 	}
 	if len(rgDt.Name) >= maxLen {
-		log.Printf("Ошибка регистрации: длина имени '%s' превышает допустимую", rgDt.Name)
-		return fmt.Errorf("превышена длина имени")
+		slog.Info("Ошибка регистрации: длина имени превышает допустимую", "login", rgDt.Login, "name", rgDt.Name)
+		return newGameError(ErrCodeNameTooLong)
 	}
 	if len(rgDt.Password) >= maxLen {
-		log.Printf("Ошибка регистрации: длина пароля '%s' превышает допустимую", rgDt.Password)
-		return fmt.Errorf("превышена длина пароля")
+		slog.Info("Ошибка регистрации: длина пароля превышает допустимую", "login", rgDt.Login)
+		return newGameError(ErrCodePasswordTooLong)
 	}
 
 	loginRegexp := regexp.MustCompile(loginPattern)
 	if !loginRegexp.MatchString(rgDt.Login) {
-		log.Printf("Ошибка регистрации: логин '%s' должен содержать символы: '%s'", rgDt.Login, loginPattern)
-		return fmt.Errorf("логин должен содержать символы: 'a-zA-Z0-9_@.'", loginPattern)
+		slog.Info("Ошибка регистрации: недопустимые символы в логине", "login", rgDt.Login)
+		return newGameError(ErrCodeLoginChars, "allowed", "a-zA-Z0-9_@.")
 	}
 	namePasswordRegexp := regexp.MustCompile(namePasswordPattern)
 	for _, str := range [...]string{rgDt.Name, rgDt.Password} {
 		if !namePasswordRegexp.MatchString(str) {
-			log.Printf("Ошибка регистрации: имя или пароль '%s' должны содержать символы:  %s", str, namePasswordPattern)
-			return fmt.Errorf("допустимые символы для имени и пароля: %s", `a-zA-Zа-яА-Я0-9 !@#№$%`+"`"+`~^&*()-_+=[]{};:'",.<>?/|\`)
+			slog.Info("Ошибка регистрации: недопустимые символы в имени или пароле", "login", rgDt.Login)
+			return newGameError(ErrCodeNamePasswordChars, "allowed", `a-zA-Zа-яА-Я0-9 !@#№$%`+"`"+`~^&*()-_+=[]{};:'",.<>?/|\`)
 		}
 	}
 
 	if rgDt.Password != rgDt.ConfirmPassword {
-		log.Println("Ошибка регистрации: пароли не совпадают")
-		return fmt.Errorf("пароли не совпадают")
+		slog.Info("Ошибка регистрации: пароли не совпадают", "login", rgDt.Login)
+		return newGameError(ErrCodePasswordsMismatch)
 	}
 	return nil
 }
 
-// Функция для генерации случайного 6-значного публичного кода
+// Функция для генерации случайного публичного кода
 func generateRandomCode(chars string, length int) string {
 	rand.Seed(time.Now().UnixNano())
 	code := make([]byte, length)
@@ -237,36 +236,138 @@ func generateRandomCode(chars string, length int) string {
 }
 
 func actionRegistration(client *Client, data []byte) (*UserData, error) {
-	const chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*"
-	const codeLength = 6
-
 	var rgDt RegisterData
 	err := msgpack.Unmarshal(data, &rgDt)
 	if err != nil {
-		log.Printf("Ошибка при десериализации данных: %v", err)
-		return nil, fmt.Errorf("внутренняя ошибка сервера")
+		client.logger().Warn("Ошибка при десериализации данных регистрации", "err", err)
+		return nil, newGameError(ErrCodeInternal)
 	}
 
 	err = validateRegistrationData(rgDt)
 	if err != nil {
 		return nil, err
 	}
+	if err = checkRegistrationAllowed(rgDt.Login, client.remoteIP); err != nil {
+		return nil, err
+	}
 
 	// login уникальный
-	var exists bool
-	err = db.QueryRow(queryUniqueLogin, sql.Named("login", rgDt.Login)).Scan(&exists)
+	exists, err := store.loginExists(rgDt.Login)
 	if err != nil {
 		panic(err.Error())
 	}
 	if exists {
-		log.Println("Ошибка регистрации: логин уже существует")
-		return nil, fmt.Errorf("логин уже существует")
+		client.logger().Info("Ошибка регистрации: логин уже существует", "login", rgDt.Login)
+		return nil, registrationLoginTaken(rgDt.Login, client.remoteIP)
 	}
 
-	tx, err := db.Beginx()
+	passwordHash := fmt.Sprintf("%x", sha256.Sum256([]byte(rgDt.Password)))
+	userID, activeCharacterID, err := store.createUser(rgDt.Login, passwordHash, rgDt.Name)
 	if err != nil {
-		log.Printf("Ошибка при старте транзакции: %v", err)
-		return nil, fmt.Errorf("внутренняя ошибка сервера")
+		return nil, err
+	}
+
+	return getUserData(userID, activeCharacterID, client)
+}
+
+func actionAuthorization(client *Client, data []byte) (*UserData, error) {
+	// Десериализация данных внутри Data в структуру
+	var lgDt LoginData
+	err := msgpack.Unmarshal(data, &lgDt)
+	if err != nil {
+		client.logger().Warn("Ошибка при десериализации данных авторизации", "err", err)
+		return nil, newGameError(ErrCodeInternal)
+	}
+	if err = checkLoginAllowed(lgDt.Login, client.remoteIP); err != nil {
+		return nil, err
+	}
+
+	// Хеш считается до поиска пользователя, чтобы время ответа не зависело от существования логина
+	passwordHash := fmt.Sprintf("%x", sha256.Sum256([]byte(lgDt.Password)))
+	us, err := store.getUser(lgDt.Login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, loginFailed(lgDt.Login, client.remoteIP, failUnknownLogin)
+		}
+		panic(err.Error())
+	}
+	if subtle.ConstantTimeCompare([]byte(us.PasswordHash), []byte(passwordHash)) != 1 {
+		return nil, loginFailed(lgDt.Login, client.remoteIP, failWrongPassword)
+	}
+	// Срочная блокировка снимается при первом входе после её окончания
+	if !us.IsActive && us.BannedUntil.Valid && !us.BannedUntil.Time.After(time.Now()) {
+		if err = store.unbanUser(us.IdUser); err != nil {
+			return nil, newGameError(ErrCodeInternal)
+		}
+		us.IsActive = true
+	}
+	// О блокировке сообщается только при верном пароле
+	if !us.IsActive {
+		client.logger().Info("Вход заблокированного пользователя", "user_id", us.IdUser, "login", us.Login)
+		auditFailedLogin(lgDt.Login, client.remoteIP, failBlocked)
+		var until *time.Time
+		if us.BannedUntil.Valid {
+			until = &us.BannedUntil.Time
+		}
+		return nil, banError(lgDt.Login, us.BanReason.String, until)
+	}
+	loginGuards.succeeded(lgDt.Login)
+	publicID, err := store.getPublicID(us.IdUser)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			clientDBLogger(client, "get_public_id").Error("Игровые данные пользователя не найдены", "user_id", us.IdUser, "login", lgDt.Login)
+			return nil, newGameError(ErrCodePlayerNotFound, "login", lgDt.Login)
+		}
+		panic(err.Error())
+	}
+
+	if authClient, exists := authorizedClients[publicID]; exists {
+		now := time.Now().UnixMilli()
+		elapsed := time.Duration(now-atomic.LoadInt64(&authClient.lastPingTime)) * time.Millisecond
+		if atomic.LoadInt32(&authClient.waitingForPong) == 1 && elapsed > 3*time.Second {
+			client.logger().Info("Удалено неактивное соединение пользователя", "user_id", us.IdUser)
+			removeClient(authClient.PublicID)
+		} else {
+			client.logger().Info("Пользователь уже авторизован", "user_id", us.IdUser)
+			return nil, newGameError(ErrCodeAlreadyAuthorized)
+		}
+	}
+
+	idActiveCharacter, err := store.getActiveCharacterID(us.IdUser)
+	if err != nil {
+		clientDBLogger(client, "get_active_character").Error("Ошибка при получении активного персонажа", "user_id", us.IdUser, "err", err)
+		return nil, newGameError(ErrCodeInternal)
+	}
+
+	return getUserData(us.IdUser, idActiveCharacter, client)
+}
+
+// Хранилище в базе данных SQL Server
+type sqlStorage struct {
+	db *metricsDB
+}
+
+func (s *sqlStorage) ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *sqlStorage) close() error {
+	return s.db.Close()
+}
+
+func (s *sqlStorage) loginExists(login string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(queryUniqueLogin, sql.Named("login", login)).Scan(&exists)
+	return exists, err
+}
+
+// Создаёт пользователя и игрока со стартовыми фоном и персонажем
+func (s *sqlStorage) createUser(login, passwordHash, name string) (userID, activeCharacterID int, err error) {
+	dbLog := dbLogger("registration", "login", login)
+	tx, err := s.db.Beginx()
+	if err != nil {
+		dbLog.Error("Ошибка при старте транзакции", "err", err)
+		return 0, 0, newGameError(ErrCodeInternal)
 	}
 	defer func() {
 		if p := recover(); p != nil {
@@ -277,115 +378,165 @@ func actionRegistration(client *Client, data []byte) (*UserData, error) {
 		}
 	}()
 
-	passwordHash := fmt.Sprintf("%x", sha256.Sum256([]byte(rgDt.Password)))
-	var UserID int
-	err = tx.QueryRow(queryInsertUser, sql.Named("Login", rgDt.Login), sql.Named("PasswordHash", passwordHash), sql.Named("isActive", true)).Scan(&UserID)
+	err = tx.QueryRow(queryInsertUser, sql.Named("Login", login), sql.Named("PasswordHash", passwordHash), sql.Named("isActive", true)).Scan(&userID)
 	if err != nil {
-		log.Printf("Ошибка при добавление нового пользователя: %v", err)
-		return nil, fmt.Errorf("не удалось создать пользователя")
+		dbLog.Error("Ошибка при добавлении нового пользователя", "err", err)
+		return 0, 0, newGameError(ErrCodeCreateUserFailed)
 	}
-	var ActiveBackgroundID, ActiveCharacterID int
-	err = tx.QueryRow(queryGetDefaultBackground).Scan(&ActiveBackgroundID)
-	if err != nil {
-		log.Printf("Ошибка при получении фона: %v", err)
-		return nil, fmt.Errorf("внутренняя ошибка сервера")
-	}
-	err = tx.QueryRow(queryGetDefaultCharacter).Scan(&ActiveCharacterID)
-	if err != nil {
-		log.Printf("Ошибка при получении персонажа: %v", err)
-		return nil, fmt.Errorf("внутренняя ошибка сервера")
+	var activeBackgroundID int
+	err = tx.QueryRow(queryGetStarterItems).Scan(&activeBackgroundID, &activeCharacterID)
+	if err == sql.ErrNoRows {
+		dbLog.Error("Стартовые предметы не заданы, загрузите контент командой seed")
+		return 0, 0, newGameError(ErrCodeInternal)
+	} else if err != nil {
+		dbLog.Error("Ошибка при получении стартовых предметов", "err", err)
+		return 0, 0, newGameError(ErrCodeInternal)
 	}
 
 	for attempts := 0; attempts < 100; attempts++ {
-		publicCode := generateRandomCode(chars, codeLength)
-		_, err = tx.Exec(queryInsertPlayer, sql.Named("id_User", UserID), sql.Named("PublicCode", publicCode), sql.Named("Name", rgDt.Name), sql.Named("Level", 0), sql.Named("Money", 100), sql.Named("Rank", 0), sql.Named("id_ActiveCharacter", ActiveCharacterID), sql.Named("id_ActiveBackground", ActiveBackgroundID))
-		fmt.Println(publicCode)
+		publicCode := generateRandomCode(publicCodeChars, publicCodeLength)
+		_, err = tx.Exec(queryInsertPlayer, sql.Named("id_User", userID), sql.Named("PublicCode", publicCode), sql.Named("Name", name), sql.Named("Level", 0), sql.Named("Money", startMoney), sql.Named("Rank", 0), sql.Named("id_ActiveCharacter", activeCharacterID), sql.Named("id_ActiveBackground", activeBackgroundID))
+
 		if err == nil {
 			break
 		} else if err.Error() == "UNIQUE constraint failed: Players.PublicCode" && attempts < 99 {
 			continue
 		} else {
-			log.Printf("Ошибка при создании игрока: %v", err)
-			return nil, fmt.Errorf("не удалось создать профиль игрока")
+			dbLog.Error("Ошибка при создании игрока", "err", err)
+			return 0, 0, newGameError(ErrCodeCreatePlayerFailed)
 		}
 	}
 
+	if _, err = tx.Exec(queryInsertStartMoney, sql.Named("id_User", userID), sql.Named("Reason", ledgerStart)); err != nil {
+		dbLog.Error("Ошибка при записи стартовых денег в журнал", "err", err)
+		return 0, 0, newGameError(ErrCodeInternal)
+	}
+
 	if err = tx.Commit(); err != nil {
-		log.Printf("Ошибка коммита транзакции: %v", err)
-		return nil, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка коммита транзакции", "err", err)
+		return 0, 0, newGameError(ErrCodeInternal)
 	}
+	return userID, activeCharacterID, nil
+}
 
-	usDt := getUserData(UserID, ActiveCharacterID, client)
-	return usDt, nil
+func (s *sqlStorage) getUser(login string) (*UserDB, error) {
+	var us UserDB
+	if err := s.db.Get(&us, queryAuthenticateUser, sql.Named("login", login)); err != nil {
+		return nil, err
+	}
+	return &us, nil
 }
 
-func actionAuthorization(client *Client, data []byte) (*UserData, error) {
-	// Десериализация данных внутри Data в структуру
-	var lgDt LoginData
-	err := msgpack.Unmarshal(data, &lgDt)
-	if err != nil {
-		log.Printf("Ошибка при десериализации данных: %v", err)
-		return nil, fmt.Errorf("внутренняя ошибка сервера")
+func (s *sqlStorage) getPublicID(userID int) (string, error) {
+	var publicID string
+	err := s.db.Get(&publicID, queryGetPublicIDPlayer, sql.Named("id_User", userID))
+	return publicID, err
+}
+
+func (s *sqlStorage) getActiveCharacterID(userID int) (int, error) {
+	var id int
+	err := s.db.Get(&id, queryGetActiveCharacter, sql.Named("id_User", userID))
+	return id, err
+}
+
+func (s *sqlStorage) getPlayerData(userID int) (*UserData, error) {
+	var usDt UserData
+	if err := s.db.Get(&usDt, queryGetUserData, sql.Named("Id_User", userID)); err != nil {
+		return nil, err
 	}
-	var us UserDB
-	err = db.Get(&us, queryAuthenticateUser, sql.Named("login", lgDt.Login))
+	return &usDt, nil
+}
+
+func (s *sqlStorage) characterRows() ([]characterRowDB, []assetCharacterRowDB, error) {
+	var rows []characterRowDB
+	err := s.db.Select(&rows, queryGetAllCharacters)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			log.Printf("Ошибка: пользователь с Логином '%s' не найден", lgDt.Login)
-			return nil, fmt.Errorf(fmt.Sprintf("пользователь '%s' не найден", lgDt.Login))
-		}
-		panic(err.Error())
+		return nil, nil, fmt.Errorf("ошибка при получении персонажей: %v", err)
 	}
-	if !us.IsActive {
-		log.Printf("Ошибка: пользователь с Id=%d, Логином '%s' заблокирован", us.IdUser, us.Login)
-		return nil, fmt.Errorf(fmt.Sprintf("пользователь '%s' заблокирован", lgDt.Login))
+
+	var assetRows []assetCharacterRowDB
+	err = s.db.Select(&assetRows, queryGetAllAssetsCharacters)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка при получении анимаций персонажей: %v", err)
 	}
-	passwordHash := fmt.Sprintf("%x", sha256.Sum256([]byte(lgDt.Password)))
-	if us.PasswordHash != passwordHash {
-		log.Printf("Ошибка: неверно введен пароль")
-		return nil, fmt.Errorf("неверно введен пароль")
+	return rows, assetRows, nil
+}
+
+func (s *sqlStorage) contentVersion() (int, error) {
+	var version int
+	err := s.db.Get(&version, queryGetContentVersion)
+	if err == sql.ErrNoRows {
+		return 0, nil
 	}
-	var publicID string
-	err = db.Get(&publicID, queryGetPublicIDPlayer, sql.Named("id_User", us.IdUser))
+	return version, err
+}
+
+// Добавляет или обновляет фоны, персонажей и стартовые предметы в одной транзакции
+func (s *sqlStorage) seed(sd *seedData) (err error) {
+	tx, err := s.db.Beginx()
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			log.Printf("Ошибка: игровые данные '%s' не найдены", lgDt.Login)
-			return nil, fmt.Errorf(fmt.Sprintf("игровые данные '%s' не найдены", lgDt.Login))
-		}
-		panic(err.Error())
+		return err
 	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
 
-	if authClient, exists := authorizedClients[publicID]; exists {
-		now := time.Now().UnixMilli()
-		elapsed := time.Duration(now-atomic.LoadInt64(&authClient.lastPingTime)) * time.Millisecond
-		if atomic.LoadInt32(&authClient.waitingForPong) == 1 && elapsed > 3*time.Second {
-			log.Printf("Удалено неактивное соединение для пользователя %d", us.IdUser)
-			removeClient(authClient.PublicID)
-		} else {
-			log.Printf("Ошибка: пользователь %d уже авторизован!", us.IdUser)
-			return nil, fmt.Errorf("вы уже авторизованы")
+	backgroundIDs := make(map[string]int)
+	for _, b := range sd.Backgrounds {
+		var id int
+		err = tx.Get(&id, queryFindBackground, sql.Named("Name", b.Name))
+		switch {
+		case err == sql.ErrNoRows:
+			err = tx.QueryRow(queryInsertBackground, sql.Named("Name", b.Name), sql.Named("Description", b.Description), sql.Named("Cost", b.Cost), sql.Named("AssetPath", b.AssetPath)).Scan(&id)
+		case err == nil:
+			_, err = tx.Exec(queryUpdateBackground, sql.Named("id_Background", id), sql.Named("Description", b.Description), sql.Named("Cost", b.Cost), sql.Named("AssetPath", b.AssetPath))
 		}
+		if err != nil {
+			return fmt.Errorf("фон '%s': %v", b.Name, err)
+		}
+		backgroundIDs[b.Name] = id
+	}
+
+	characterIDs := make(map[string]int)
+	for _, ch := range sd.Characters {
+		var id int
+		err = tx.Get(&id, queryFindCharacter, sql.Named("Name", ch.Name))
+		switch {
+		case err == sql.ErrNoRows:
+			err = tx.QueryRow(queryInsertCharacter, sql.Named("Name", ch.Name), sql.Named("Description", ch.Description), sql.Named("Health", ch.Health), sql.Named("Damage", ch.Damage), sql.Named("Cost", ch.Cost)).Scan(&id)
+		case err == nil:
+			_, err = tx.Exec(queryUpdateCharacter, sql.Named("id_Character", id), sql.Named("Description", ch.Description), sql.Named("Health", ch.Health), sql.Named("Damage", ch.Damage), sql.Named("Cost", ch.Cost))
+			if err == nil {
+				_, err = tx.Exec(queryDeleteAssetsCharacter, sql.Named("id_Character", id))
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("персонаж '%s': %v", ch.Name, err)
+		}
+		for _, as := range ch.Assets {
+			_, err = tx.Exec(queryInsertAssetCharacter, sql.Named("id_Character", id), sql.Named("AnimationType", as.AnimationType), sql.Named("FrameCount", as.FrameCount), sql.Named("BaseHeight", as.BaseHeight), sql.Named("BaseWidth", as.BaseWidth), sql.Named("FrameRate", as.FrameRate), sql.Named("AssetPath", as.AssetPath))
+			if err != nil {
+				return fmt.Errorf("персонаж '%s', анимация %s: %v", ch.Name, as.AnimationType, err)
+			}
+		}
+		characterIDs[ch.Name] = id
 	}
 
-	var idActiveCharacter int
-
-	err = db.Get(&idActiveCharacter, queryGetActiveCharacter, sql.Named("id_User", us.IdUser))
+	_, err = tx.Exec(querySaveGameSettings, sql.Named("Version", sd.Version), sql.Named("BackgroundID", backgroundIDs[sd.Starter.Background]), sql.Named("CharacterID", characterIDs[sd.Starter.Character]))
 	if err != nil {
-		log.Printf("Ошибка при получении идентификатора: %v", err)
-		return nil, fmt.Errorf("внутренняя ошибка сервера")
+		return fmt.Errorf("ошибка записи стартовых предметов: %v", err)
 	}
-
-	usDt := getUserData(us.IdUser, idActiveCharacter, client)
-
-	return usDt, nil
+	return tx.Commit()
 }
 
-func GetFriendsAndRequestsDB(playerID int) (*FriendsData, error) {
-
-	rows, err := db.Queryx(queryGetFriendsData, sql.Named("PlayerID", playerID))
+func (s *sqlStorage) getFriends(playerID int) (*FriendsData, error) {
+	dbLog := dbLogger("get_friends", "player_id", playerID)
+	rows, err := s.db.Queryx(queryGetFriendsData, sql.Named("PlayerID", playerID))
 	if err != nil {
-		log.Printf("ошибка при выполнении процедуры: %v", err)
-		return nil, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при выполнении процедуры", "err", err)
+		return nil, newGameError(ErrCodeInternal)
 	}
 	defer rows.Close()
 
@@ -393,16 +544,16 @@ func GetFriendsAndRequestsDB(playerID int) (*FriendsData, error) {
 
 	var friends []FriendEntry
 	if err = sqlx.StructScan(rows, &friends); err != nil {
-		log.Printf("Ошибка при чтении друзей: %v", err)
-		return nil, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при чтении друзей", "err", err)
+		return nil, newGameError(ErrCodeInternal)
 	}
 	result.Friends = friends
 
 	if rows.NextResultSet() {
 		var incoming []FriendEntry
 		if err = sqlx.StructScan(rows, &incoming); err != nil {
-			log.Printf("Ошибка при чтении входящих заявок: %v", err)
-			return nil, fmt.Errorf("внутренняя ошибка сервера")
+			dbLog.Error("Ошибка при чтении входящих заявок", "err", err)
+			return nil, newGameError(ErrCodeInternal)
 		}
 		result.Incoming = incoming
 	}
@@ -410,8 +561,8 @@ func GetFriendsAndRequestsDB(playerID int) (*FriendsData, error) {
 	if rows.NextResultSet() {
 		var outgoing []FriendEntry
 		if err = sqlx.StructScan(rows, &outgoing); err != nil {
-			log.Printf("ошибка при чтении исходящих заявок: %v", err)
-			return nil, fmt.Errorf("внутренняя ошибка сервера")
+			dbLog.Error("Ошибка при чтении исходящих заявок", "err", err)
+			return nil, newGameError(ErrCodeInternal)
 		}
 		result.Outgoing = outgoing
 	}
@@ -420,18 +571,19 @@ func GetFriendsAndRequestsDB(playerID int) (*FriendsData, error) {
 
 }
 
-func addFriendDB(requesterPlayerID int, friendPublicID string) error {
+func (s *sqlStorage) addFriend(requesterPlayerID int, friendPublicID string) (FriendRequestStatus, error) {
 	const (
 		successAccept   = 1
 		successRequest  = 0
 		invalidFriendId = -1
 		repeatRequest   = -2
 	)
+	dbLog := dbLogger("add_friend", "player_id", requesterPlayerID, "friend_id", friendPublicID)
 
-	tx, err := db.Beginx()
+	tx, err := s.db.Beginx()
 	if err != nil {
-		log.Printf("Ошибка при старте транзакции: %v", err)
-		return fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при старте транзакции", "err", err)
+		return 0, newGameError(ErrCodeInternal)
 	}
 	defer func() {
 		if p := recover(); p != nil {
@@ -442,7 +594,7 @@ func addFriendDB(requesterPlayerID int, friendPublicID string) error {
 		} else {
 			err = tx.Commit()
 			if err != nil {
-				log.Printf("Ошибка при коммите транзакции: %v", err)
+				dbLog.Error("Ошибка при коммите транзакции", "err", err)
 			}
 		}
 
@@ -452,30 +604,32 @@ func addFriendDB(requesterPlayerID int, friendPublicID string) error {
 
 	err = tx.QueryRow(queryRequestFriendship, sql.Named("RequesterPlayerID", requesterPlayerID), sql.Named("FriendPublicID", friendPublicID)).Scan(&result)
 	if err != nil {
-		log.Printf("Ошибка при запросе на дружбу: %v", err)
-		return fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при запросе на дружбу", "err", err)
+		return 0, newGameError(ErrCodeInternal)
 	}
 
 	switch result {
 	case successAccept:
-		return fmt.Errorf("Заявка в друзья принята")
+		return friendRequestAccepted, nil
 	case successRequest:
-		return fmt.Errorf("Заявка отправлена")
+		return friendRequestSent, nil
 	case invalidFriendId:
-		return fmt.Errorf("Неверный код")
+		return 0, newGameError(ErrCodeFriendCodeInvalid)
 	case repeatRequest:
-		return fmt.Errorf("Повторная заявка")
+		return 0, newGameError(ErrCodeFriendRepeatRequest)
 	default:
-		return fmt.Errorf("Неизвестный статус заявки")
+		dbLog.Error("Ошибка при запросе на дружбу: неизвестный статус заявки", "result", result)
+		return 0, newGameError(ErrCodeInternal)
 	}
 
 }
 
-func acceptFriendshipDB(playerID int, requesterPublicID string) error {
-	tx, err := db.Beginx()
+func (s *sqlStorage) acceptFriendship(playerID int, requesterPublicID string) error {
+	dbLog := dbLogger("accept_friendship", "player_id", playerID, "friend_id", requesterPublicID)
+	tx, err := s.db.Beginx()
 	if err != nil {
-		log.Printf("Ошибка при старте транзакции: %v", err)
-		return fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при старте транзакции", "err", err)
+		return newGameError(ErrCodeInternal)
 	}
 	defer func() {
 		if p := recover(); p != nil {
@@ -486,7 +640,7 @@ func acceptFriendshipDB(playerID int, requesterPublicID string) error {
 		} else {
 			err = tx.Commit()
 			if err != nil {
-				log.Printf("Ошибка при коммите транзакции: %v", err)
+				dbLog.Error("Ошибка при коммите транзакции", "err", err)
 			}
 		}
 
@@ -494,18 +648,19 @@ func acceptFriendshipDB(playerID int, requesterPublicID string) error {
 
 	_, err = tx.Exec(queryAcceptFriendship, sql.Named("PlayerID", playerID), sql.Named("RequesterPublicID", requesterPublicID))
 	if err != nil {
-		log.Printf("Ошибка при принятии заявки: %v", err)
-		return fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при принятии заявки", "err", err)
+		return newGameError(ErrCodeInternal)
 	}
 
 	return nil
 }
 
-func declineFriendshipDB(playerID int, requesterPublicID string) error {
-	tx, err := db.Beginx()
+func (s *sqlStorage) declineFriendship(playerID int, requesterPublicID string) error {
+	dbLog := dbLogger("decline_friendship", "player_id", playerID, "friend_id", requesterPublicID)
+	tx, err := s.db.Beginx()
 	if err != nil {
-		log.Printf("Ошибка при старте транзакции: %v", err)
-		return fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при старте транзакции", "err", err)
+		return newGameError(ErrCodeInternal)
 	}
 	defer func() {
 		if p := recover(); p != nil {
@@ -516,25 +671,26 @@ func declineFriendshipDB(playerID int, requesterPublicID string) error {
 		} else {
 			err = tx.Commit()
 			if err != nil {
-				log.Printf("Ошибка при коммите транзакции: %v", err)
+				dbLog.Error("Ошибка при коммите транзакции", "err", err)
 			}
 		}
 	}()
 
 	_, err = tx.Exec(queryDeclineFriendship, sql.Named("PlayerID", playerID), sql.Named("RequesterPublicID", requesterPublicID))
 	if err != nil {
-		log.Printf("Ошибка при отклонении заявки: %v", err)
-		return fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при отклонении заявки", "err", err)
+		return newGameError(ErrCodeInternal)
 	}
 
 	return nil
 }
 
-func removeFriendDB(playerID int, friendPublicID string) error {
-	tx, err := db.Beginx()
+func (s *sqlStorage) removeFriend(playerID int, friendPublicID string) error {
+	dbLog := dbLogger("remove_friend", "player_id", playerID, "friend_id", friendPublicID)
+	tx, err := s.db.Beginx()
 	if err != nil {
-		log.Printf("Ошибка при старте транзакции: %v", err)
-		return fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при старте транзакции", "err", err)
+		return newGameError(ErrCodeInternal)
 	}
 	defer func() {
 		if p := recover(); p != nil {
@@ -545,75 +701,167 @@ func removeFriendDB(playerID int, friendPublicID string) error {
 		} else {
 			err = tx.Commit()
 			if err != nil {
-				log.Printf("Ошибка при коммите транзакции: %v", err)
+				dbLog.Error("Ошибка при коммите транзакции", "err", err)
 			}
 		}
 	}()
 
 	_, err = tx.Exec(queryRemoveFriendship, sql.Named("PlayerID", playerID), sql.Named("FriendPublicID", friendPublicID))
 	if err != nil {
-		log.Printf("Ошибка при удалении друга: %v", err)
-		return fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при удалении друга", "err", err)
+		return newGameError(ErrCodeInternal)
 	}
 
 	return nil
 }
 
-func saveBattleResultsDB(battle Battle) error {
+// Записывает бой и награды одной процедурой, итоговые значения игроков берутся из базы
+func (s *sqlStorage) applyBattleResults(battle *Battle) error {
+	dbLog := dbLogger("apply_battle_results", "battle_id", battle.ID, "battle_key", battle.Key)
 	winnerID := sql.NullInt32{Valid: false}
 	if battle.Winner != nil {
 		winnerID = sql.NullInt32{Int32: int32(battle.Winner.PlayerID), Valid: true}
 	}
 
-	_, err := db.Exec(querySaveBattleResults, sql.Named("Player1ID", battle.Player1.PlayerID), sql.Named("Player2ID", battle.Player2.PlayerID), sql.Named("WinnerID", winnerID), sql.Named("StartTime", battle.StartTime), sql.Named("EndTime", battle.EndTime), sql.Named("isRanked", battle.IsRanked))
+	r1, r2 := &battle.Reward1, &battle.Reward2
+	rows, err := s.db.Queryx(queryApplyBattleResults, sql.Named("BattleKey", battle.Key),
+		sql.Named("Player1ID", battle.Player1.PlayerID), sql.Named("Player2ID", battle.Player2.PlayerID), sql.Named("WinnerID", winnerID),
+		sql.Named("StartTime", battle.StartTime), sql.Named("EndTime", battle.EndTime), sql.Named("isRanked", battle.IsRanked), sql.Named("isCancelled", battle.Cancelled),
+		sql.Named("Player1Money", r1.Money), sql.Named("Player1Rank", r1.Rank), sql.Named("Player1Level", r1.Level),
+		sql.Named("Player2Money", r2.Money), sql.Named("Player2Rank", r2.Rank), sql.Named("Player2Level", r2.Level))
+	if err != nil {
+		dbLog.Error("Ошибка при сохранении результатов боя", "err", err)
+		return err
+	}
+	defer rows.Close()
+
+	var recordID int
+	var applied bool
+	for rows.Next() {
+		var playerID, level, rank, money int
+		if err = rows.Scan(&recordID, &applied, &playerID, &level, &rank, &money); err != nil {
+			dbLog.Error("Ошибка при чтении результатов боя", "err", err)
+			return err
+		}
+		reward := r2
+		if playerID == battle.Player1.PlayerID {
+			reward = r1
+		}
+		reward.TotalLevel, reward.TotalRank, reward.TotalMoney = level, rank, money
+	}
+	if err = rows.Err(); err != nil {
+		dbLog.Error("Ошибка при чтении результатов боя", "err", err)
+		return err
+	}
+	if recordID == 0 {
+		err = errors.New("процедура не вернула номер боя")
+		dbLog.Error("Ошибка при сохранении результатов боя", "err", err)
+		return err
+	}
+	if !applied {
+		dbLog.Info("Итоги боя уже были записаны, награды не начислены повторно", "record_id", recordID)
+	}
+	battle.RecordID = recordID
+	return nil
+}
+
+// Данные игрока для API администратора по публичному ID или логину
+func (s *sqlStorage) getAdminPlayer(publicID, login string) (*AdminPlayer, error) {
+	var player AdminPlayer
+	err := s.db.Get(&player, queryGetAdminPlayer, sql.Named("PublicID", publicID), sql.Named("Login", login))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			dbLogger("get_admin_player", "public_id", publicID, "login", login).Error("Ошибка при поиске игрока для администратора", "err", err)
+		}
+		return nil, err
+	}
+	return &player, nil
+}
+
+// Блокирует пользователя. until == nil — без срока
+func (s *sqlStorage) banUser(userID int, reason string, until *time.Time) error {
+	bannedUntil := sql.NullTime{}
+	if until != nil {
+		bannedUntil = sql.NullTime{Time: *until, Valid: true}
+	}
+	_, err := s.db.Exec(queryBanUser, sql.Named("id_User", userID), sql.Named("BanReason", reason), sql.Named("BannedUntil", bannedUntil))
 	if err != nil {
-		log.Printf("Ошибка при сохранения результатов боя: %v", err)
+		dbLogger("ban_user", "user_id", userID).Error("Ошибка при блокировке пользователя", "err", err)
 	}
 	return err
 }
 
-func updatePlayerStats(playerID, newLevel, newRank, newMoney int) error {
-	_, err := db.Exec(queryUpdatePlayerStats, sql.Named("PlayerID", playerID), sql.Named("newLevel", newLevel), sql.Named("newRank", newRank), sql.Named("newMoney", newMoney))
+// Снимает блокировку пользователя
+func (s *sqlStorage) unbanUser(userID int) error {
+	_, err := s.db.Exec(queryUnbanUser, sql.Named("id_User", userID))
 	if err != nil {
-		log.Printf("Ошибка при обновлении уровня, ранга, монет после боя: %v", err)
+		dbLogger("unban_user", "user_id", userID).Error("Ошибка при снятии блокировки пользователя", "err", err)
 	}
 	return err
 }
 
-func GetBattleEntryAndStatsDB(playerID int, isRanked bool) ([]BattleEntry, *BattleStats, error) {
+// Прибавляет к деньгам, рангу и уровню игрока, значения не опускаются ниже нуля.
+// Изменение денег записывается в журнал в той же транзакции, возвращается новый баланс
+func (s *sqlStorage) adjustPlayerStats(playerID, money, rank, level int) (int, error) {
+	var totalMoney int
+	err := s.db.QueryRow(queryAdjustPlayerStats, sql.Named("PlayerID", playerID), sql.Named("Money", money), sql.Named("Rank", rank), sql.Named("Level", level), sql.Named("Reason", ledgerAdmin)).Scan(&totalMoney)
+	if err != nil {
+		dbLogger("adjust_player_stats", "player_id", playerID).Error("Ошибка при изменении статистики игрока", "err", err)
+	}
+	return totalMoney, err
+}
+
+// Последние limit записей журнала денег, новые первыми, и сумма всех записей игрока
+func (s *sqlStorage) getMoneyLedger(playerID, limit int) ([]LedgerEntry, int, error) {
+	dbLog := dbLogger("get_money_ledger", "player_id", playerID)
+	entries := []LedgerEntry{}
+	if err := s.db.Select(&entries, queryGetMoneyLedger, sql.Named("PlayerID", playerID), sql.Named("Limit", limit)); err != nil {
+		dbLog.Error("Ошибка при получении журнала денег", "err", err)
+		return nil, 0, err
+	}
+	var sum int
+	if err := s.db.Get(&sum, queryGetMoneyLedgerSum, sql.Named("PlayerID", playerID)); err != nil {
+		dbLog.Error("Ошибка при подсчёте суммы журнала денег", "err", err)
+		return nil, 0, err
+	}
+	return entries, sum, nil
+}
+
+func (s *sqlStorage) getBattleStats(playerID int, isRanked bool) ([]BattleEntry, *BattleStats, error) {
 	var battleEntry []BattleEntry
 	var battleStats BattleStats
-	rows, err := db.Queryx(queryGetPlayerBattleStats, sql.Named("PlayerID", playerID), sql.Named("isRanked", isRanked))
+	dbLog := dbLogger("get_battle_stats", "player_id", playerID, "ranked", isRanked)
+	rows, err := s.db.Queryx(queryGetPlayerBattleStats, sql.Named("PlayerID", playerID), sql.Named("isRanked", isRanked))
 	if err != nil {
-		log.Printf("Ошибка при получении из бд данных о сражениях (ранговые %t): %v ", isRanked, err)
-		return battleEntry, &battleStats, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при получении данных о сражениях", "err", err)
+		return battleEntry, &battleStats, newGameError(ErrCodeInternal)
 	}
 	defer rows.Close()
 
 	err = sqlx.StructScan(rows, &battleEntry)
 	if err != nil {
-		log.Printf("Ошибка записи в структуру списка сражений (ранговые %t): %v ", isRanked, err)
-		return battleEntry, &battleStats, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка записи в структуру списка сражений", "err", err)
+		return battleEntry, &battleStats, newGameError(ErrCodeInternal)
 	}
 	if !(rows.NextResultSet() && rows.Next()) {
-		log.Printf("Ошибка при переходе к статистике сражений (ранговые %t): %v ", isRanked, err)
-		return battleEntry, &battleStats, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при переходе к статистике сражений", "err", rows.Err())
+		return battleEntry, &battleStats, newGameError(ErrCodeInternal)
 	}
 	err = rows.StructScan(&battleStats)
 	if err != nil {
-		log.Printf("Ошибка записи статистики сражений (ранговые %t): %v ", isRanked, err)
-		return battleEntry, &battleStats, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка записи статистики сражений", "err", err)
+		return battleEntry, &battleStats, newGameError(ErrCodeInternal)
 	}
 
 	return battleEntry, &battleStats, nil
 }
 
 func GetListBattles(playerID int) (*BattleData, error) {
-	rankedBattles, rankedStats, err := GetBattleEntryAndStatsDB(playerID, true)
+	rankedBattles, rankedStats, err := store.getBattleStats(playerID, true)
 	if err != nil {
 		return nil, err
 	}
-	standardBattles, standardStats, err := GetBattleEntryAndStatsDB(playerID, false)
+	standardBattles, standardStats, err := store.getBattleStats(playerID, false)
 	if err != nil {
 		return nil, err
 	}
@@ -625,68 +873,72 @@ func GetListBattles(playerID int) (*BattleData, error) {
 	}, nil
 }
 
-func GetShopBackgroundsDB(playerID int) ([]ShopBackgroundItem, []ShopBackgroundItem, error) {
+func (s *sqlStorage) getShopBackgrounds(playerID int) ([]ShopBackgroundItem, []ShopBackgroundItem, error) {
 	var purchased, available []ShopBackgroundItem
+	dbLog := dbLogger("get_shop_backgrounds", "player_id", playerID)
 
-	rows, err := db.Queryx(queryGetShopBackgrounds, sql.Named("playerID", playerID))
+	rows, err := s.db.Queryx(queryGetShopBackgrounds, sql.Named("playerID", playerID))
 	if err != nil {
+		dbLog.Error("Ошибка при получении фонов магазина", "err", err)
 		return nil, nil, err
 	}
 	defer rows.Close()
 
 	err = sqlx.StructScan(rows, &purchased)
 	if err != nil {
-		log.Printf("Ошибка записи в структуру списка купленных фонов: %v", err)
-		return purchased, available, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка записи в структуру списка купленных фонов", "err", err)
+		return purchased, available, newGameError(ErrCodeInternal)
 	}
 	if !rows.NextResultSet() {
-		log.Printf("Ошибка при переходе к списку доступных для покупки фонов: %v ", err)
-		return purchased, available, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при переходе к списку доступных для покупки фонов", "err", rows.Err())
+		return purchased, available, newGameError(ErrCodeInternal)
 	}
 	err = sqlx.StructScan(rows, &available)
 	if err != nil {
-		log.Printf("Ошибка записи в структуру  доступных для покупки фонов: %v", err)
-		return purchased, available, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка записи в структуру доступных для покупки фонов", "err", err)
+		return purchased, available, newGameError(ErrCodeInternal)
 	}
 
 	return purchased, available, nil
 }
 
-func GetShopCharactersDB(playerID int) ([]ShopCharacterItem, []ShopCharacterItem, error) {
+func (s *sqlStorage) getShopCharacters(playerID int) ([]ShopCharacterItem, []ShopCharacterItem, error) {
 	var purchased, available []ShopCharacterItem
+	dbLog := dbLogger("get_shop_characters", "player_id", playerID)
 
-	rows, err := db.Queryx(queryGetShopCharacters, sql.Named("PlayerID", playerID))
+	rows, err := s.db.Queryx(queryGetShopCharacters, sql.Named("PlayerID", playerID))
 	if err != nil {
+		dbLog.Error("Ошибка при получении персонажей магазина", "err", err)
 		return nil, nil, err
 	}
 	defer rows.Close()
 
 	err = sqlx.StructScan(rows, &purchased)
 	if err != nil {
-		log.Printf("Ошибка записи в структуру купленных персонажей: %v", err)
-		return purchased, available, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка записи в структуру купленных персонажей", "err", err)
+		return purchased, available, newGameError(ErrCodeInternal)
 	}
 
 	if !rows.NextResultSet() {
-		log.Printf("Ошибка при переходе к списку доступных для покупки персонажей: %v ", err)
-		return purchased, available, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка при переходе к списку доступных для покупки персонажей", "err", rows.Err())
+		return purchased, available, newGameError(ErrCodeInternal)
 	}
 
 	err = sqlx.StructScan(rows, &available)
 	if err != nil {
-		log.Printf("Ошибка записи в структуру  доступных для покупки персонажей: %v", err)
-		return purchased, available, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка записи в структуру доступных для покупки персонажей", "err", err)
+		return purchased, available, newGameError(ErrCodeInternal)
 	}
 
 	return purchased, available, nil
 }
 
 func GetShopData(playerID int) (*ShopData, error) {
-	purchasedBackgrounds, availableBackgrounds, err := GetShopBackgroundsDB(playerID)
+	purchasedBackgrounds, availableBackgrounds, err := store.getShopBackgrounds(playerID)
 	if err != nil {
 		return nil, err
 	}
-	purchasedCharacters, availableCharacters, err := GetShopCharactersDB(playerID)
+	purchasedCharacters, availableCharacters, err := store.getShopCharacters(playerID)
 	if err != nil {
 		return nil, err
 	}
@@ -698,7 +950,7 @@ func GetShopData(playerID int) (*ShopData, error) {
 	}, nil
 }
 
-func buyBackgroundDB(playerID, backgroundID int) (remainingMoney int, err error) {
+func (s *sqlStorage) buyBackground(playerID, backgroundID int) (remainingMoney int, err error) {
 	const (
 		resultSuccess       = 0
 		resultNotFound      = 1
@@ -707,8 +959,9 @@ func buyBackgroundDB(playerID, backgroundID int) (remainingMoney int, err error)
 		resultError         = 4
 	)
 	var resultCode int
+	dbLog := dbLogger("buy_background", "player_id", playerID, "background_id", backgroundID)
 
-	err = db.QueryRow(queryBuyBackground,
+	err = s.db.QueryRow(queryBuyBackground,
 		sql.Named("PlayerID", playerID),
 		sql.Named("BackgroundID", backgroundID),
 		sql.Named("RemainingMoney", sql.Out{Dest: &remainingMoney}),
@@ -716,27 +969,26 @@ func buyBackgroundDB(playerID, backgroundID int) (remainingMoney int, err error)
 	).Err()
 
 	if err != nil {
-		log.Printf("Ошибка выполнения запроса BuyBackground: %v", err)
-		return 0, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка выполнения процедуры", "err", err)
+		return 0, newGameError(ErrCodeInternal)
 	}
 
 	switch resultCode {
 	case resultSuccess:
 		return remainingMoney, nil
 	case resultNotFound:
-		return 0, fmt.Errorf("фон не найден")
+		return 0, newGameError(ErrCodeBackgroundNotFound)
 	case resultAlreadyBought:
-		return 0, fmt.Errorf("фон уже куплен")
+		return 0, newGameError(ErrCodeBackgroundAlreadyBought)
 	case resultNoMoney:
-		return 0, fmt.Errorf("недостаточно денег для покупки")
-	case resultError:
-		return 0, fmt.Errorf("внутренняя ошибка при обработке запроса")
+		return 0, newGameError(ErrCodeNotEnoughMoney)
 	default:
-		return 0, fmt.Errorf("неизвестная ошибка при покупке фона")
+		dbLog.Error("Неизвестный код результата процедуры", "result", resultCode)
+		return 0, newGameError(ErrCodeInternal)
 	}
 }
 
-func selectBackgroundDB(playerID, backgroundID int) (assetPath string, err error) {
+func (s *sqlStorage) selectBackground(playerID, backgroundID int) (assetPath string, err error) {
 	const (
 		resultSuccess   = 0
 		resultNotFound  = 1
@@ -745,8 +997,9 @@ func selectBackgroundDB(playerID, backgroundID int) (assetPath string, err error
 	)
 
 	var resultCode int
+	dbLog := dbLogger("select_background", "player_id", playerID, "background_id", backgroundID)
 
-	err = db.QueryRow(querySelectBackground,
+	err = s.db.QueryRow(querySelectBackground,
 		sql.Named("PlayerID", playerID),
 		sql.Named("BackgroundID", backgroundID),
 		sql.Named("AssetPath", sql.Out{Dest: &assetPath}),
@@ -754,25 +1007,24 @@ func selectBackgroundDB(playerID, backgroundID int) (assetPath string, err error
 	).Err()
 
 	if err != nil {
-		log.Printf("Ошибка SelectBackground: %v", err)
-		return "", fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка выполнения процедуры", "err", err)
+		return "", newGameError(ErrCodeInternal)
 	}
 
 	switch resultCode {
 	case resultSuccess:
 		return assetPath, nil
 	case resultNotFound:
-		return "", fmt.Errorf("фон не найден")
+		return "", newGameError(ErrCodeBackgroundNotFound)
 	case resultNotBought:
-		return "", fmt.Errorf("фон не куплен")
-	case resultError:
-		return "", fmt.Errorf("внутренняя ошибка при обработке запроса")
+		return "", newGameError(ErrCodeBackgroundNotBought)
 	default:
-		return "", fmt.Errorf("неизвестная ошибка при выборе фона")
+		dbLog.Error("Неизвестный код результата процедуры", "result", resultCode)
+		return "", newGameError(ErrCodeInternal)
 	}
 }
 
-func buyCharacterDB(playerID, characterID int) (remainingMoney int, err error) {
+func (s *sqlStorage) buyCharacter(playerID, characterID int) (remainingMoney int, err error) {
 	const (
 		resultSuccess       = 0
 		resultNotFound      = 1
@@ -782,8 +1034,9 @@ func buyCharacterDB(playerID, characterID int) (remainingMoney int, err error) {
 	)
 
 	var resultCode int
+	dbLog := dbLogger("buy_character", "player_id", playerID, "character_id", characterID)
 
-	err = db.QueryRow(queryBuyCharacter,
+	err = s.db.QueryRow(queryBuyCharacter,
 		sql.Named("PlayerID", playerID),
 		sql.Named("CharacterID", characterID),
 		sql.Named("RemainingMoney", sql.Out{Dest: &remainingMoney}),
@@ -791,27 +1044,26 @@ func buyCharacterDB(playerID, characterID int) (remainingMoney int, err error) {
 	).Err()
 
 	if err != nil {
-		log.Printf("Ошибка выполнения запроса BuyCharacter: %v", err)
-		return 0, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка выполнения процедуры", "err", err)
+		return 0, newGameError(ErrCodeInternal)
 	}
 
 	switch resultCode {
 	case resultSuccess:
 		return remainingMoney, nil
 	case resultNotFound:
-		return 0, fmt.Errorf("персонаж не найден")
+		return 0, newGameError(ErrCodeCharacterNotFound)
 	case resultAlreadyBought:
-		return 0, fmt.Errorf("персонаж уже куплен")
+		return 0, newGameError(ErrCodeCharacterAlreadyBought)
 	case resultNoMoney:
-		return 0, fmt.Errorf("недостаточно денег для покупки")
-	case resultError:
-		return 0, fmt.Errorf("внутренняя ошибка при обработке запроса")
+		return 0, newGameError(ErrCodeNotEnoughMoney)
 	default:
-		return 0, fmt.Errorf("неизвестная ошибка при покупке персонажа")
+		dbLog.Error("Неизвестный код результата процедуры", "result", resultCode)
+		return 0, newGameError(ErrCodeInternal)
 	}
 }
 
-func selectCharacterDB(playerID, characterID int) (ch CharacterDB, err error) {
+func (s *sqlStorage) selectCharacter(playerID, characterID int) error {
 	const (
 		resultSuccess      = 0
 		resultNotFound     = 1
@@ -820,32 +1072,138 @@ func selectCharacterDB(playerID, characterID int) (ch CharacterDB, err error) {
 	)
 
 	var resultCode int
+	dbLog := dbLogger("select_character", "player_id", playerID, "character_id", characterID)
 
-	err = db.QueryRow(querySelectCharacter,
+	err := s.db.QueryRow(querySelectCharacter,
 		sql.Named("PlayerID", playerID),
 		sql.Named("CharacterID", characterID),
 		sql.Named("ResultCode", sql.Out{Dest: &resultCode}),
 	).Err()
 
 	if err != nil {
-		log.Printf("Ошибка SelectCharacter: %v", err)
-		return ch, fmt.Errorf("внутренняя ошибка сервера")
+		dbLog.Error("Ошибка выполнения процедуры", "err", err)
+		return newGameError(ErrCodeInternal)
 	}
 
 	switch resultCode {
 	case resultSuccess:
-		chPtr := getCharacterData(characterID)
-		if chPtr == nil {
-			return ch, fmt.Errorf("ошибка при загрузке данных персонажа")
-		}
-		return *chPtr, nil
+		return nil
 	case resultNotFound:
-		return ch, fmt.Errorf("персонаж не найден")
+		return newGameError(ErrCodeCharacterNotFound)
 	case resultNotPurchased:
-		return ch, fmt.Errorf("персонаж не куплен")
-	case resultError:
-		return ch, fmt.Errorf("внутренняя ошибка при обработке запроса")
+		return newGameError(ErrCodeCharacterNotBought)
 	default:
-		return ch, fmt.Errorf("неизвестная ошибка при выборе персонажа")
+		dbLog.Error("Неизвестный код результата процедуры", "result", resultCode)
+		return newGameError(ErrCodeInternal)
+	}
+}
+
+// Выдаёт ежедневную награду в одной транзакции: серия, монеты, фон и запись в журнал денег
+func (s *sqlStorage) claimDailyReward(playerID int, now time.Time) (info *DailyRewardInfo, err error) {
+	dbLog := dbLogger("claim_daily_reward", "player_id", playerID)
+	tx, err := s.db.Beginx()
+	if err != nil {
+		dbLog.Error("Ошибка при старте транзакции", "err", err)
+		return nil, newGameError(ErrCodeInternal)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil || !info.Claimed {
+			tx.Rollback()
+		}
+	}()
+
+	var lastClaim time.Time
+	var streak int
+	err = tx.QueryRow(queryGetDailyReward, sql.Named("PlayerID", playerID)).Scan(&lastClaim, &streak)
+	if err != nil && err != sql.ErrNoRows {
+		dbLog.Error("Ошибка при получении серии ежедневных наград", "err", err)
+		return nil, newGameError(ErrCodeInternal)
+	}
+	today := dailyDate(now)
+	newStreak, ok := nextDailyStreak(lastClaim, streak, today)
+	if !ok {
+		var money int
+		if err = tx.Get(&money, queryGetPlayerMoney, sql.Named("PlayerID", playerID)); err != nil {
+			dbLog.Error("Ошибка при получении баланса игрока", "err", err)
+			return nil, newGameError(ErrCodeInternal)
+		}
+		return newDailyRewardInfo(streak, today, money), nil
+	}
+
+	info = newDailyRewardInfo(newStreak, today, 0)
+	info.Claimed = true
+	_, reward := dailyRewardFor(newStreak)
+	info.Money = reward.Money
+	if reward.Background {
+		var backgroundID int
+		err = tx.QueryRow(queryGetCheapestMissingBackground, sql.Named("PlayerID", playerID)).Scan(&backgroundID, &info.Background)
+		switch {
+		case err == sql.ErrNoRows:
+			info.Money += dailyBonusMoney
+		case err != nil:
+			dbLog.Error("Ошибка при выборе фона для награды", "err", err)
+			return nil, newGameError(ErrCodeInternal)
+		default:
+			if _, err = tx.Exec(queryInsertListBackground, sql.Named("PlayerID", playerID), sql.Named("BackgroundID", backgroundID)); err != nil {
+				dbLog.Error("Ошибка при выдаче фона", "err", err)
+				return nil, newGameError(ErrCodeInternal)
+			}
+		}
+	}
+
+	if _, err = tx.Exec(querySaveDailyReward, sql.Named("PlayerID", playerID), sql.Named("Today", today), sql.Named("Streak", newStreak)); err != nil {
+		dbLog.Error("Ошибка при записи ежедневной награды", "err", err)
+		return nil, newGameError(ErrCodeInternal)
 	}
+	if err = tx.QueryRow(queryAddPlayerMoney, sql.Named("PlayerID", playerID), sql.Named("Money", info.Money)).Scan(&info.TotalMoney); err != nil {
+		dbLog.Error("Ошибка при начислении монет", "err", err)
+		return nil, newGameError(ErrCodeInternal)
+	}
+	_, err = tx.Exec(queryInsertLedgerEntry, sql.Named("PlayerID", playerID), sql.Named("Amount", info.Money), sql.Named("BalanceAfter", info.TotalMoney),
+		sql.Named("Reason", ledgerDaily), sql.Named("Reference", sql.NullInt32{}))
+	if err != nil {
+		dbLog.Error("Ошибка при записи награды в журнал денег", "err", err)
+		return nil, newGameError(ErrCodeInternal)
+	}
+
+	if err = tx.Commit(); err != nil {
+		dbLog.Error("Ошибка коммита транзакции", "err", err)
+		return nil, newGameError(ErrCodeInternal)
+	}
+	return info, nil
+}
+
+func (s *sqlStorage) insertFailedLogin(login, ip, reason string) error {
+	_, err := s.db.Exec(queryInsertFailedLogin,
+		sql.Named("Login", login),
+		sql.Named("IP", ip),
+		sql.Named("Reason", reason),
+		sql.Named("AttemptTime", time.Now()),
+	)
+	return err
+}
+
+func (s *sqlStorage) insertCheatReport(playerID int, action, events string, total int) error {
+	_, err := s.db.Exec(queryInsertCheatReport,
+		sql.Named("PlayerID", playerID),
+		sql.Named("Action", action),
+		sql.Named("Events", events),
+		sql.Named("Total", total),
+		sql.Named("ReportTime", time.Now()),
+	)
+	return err
+}
+
+func (s *sqlStorage) insertAdminAction(playerID int, action, details, adminIP string) error {
+	_, err := s.db.Exec(queryInsertAdminAction,
+		sql.Named("PlayerID", playerID),
+		sql.Named("Action", action),
+		sql.Named("Details", details),
+		sql.Named("AdminIP", adminIP),
+		sql.Named("ActionTime", time.Now()),
+	)
+	return err
 }
@@ -0,0 +1,40 @@
+package main
+
+import "time"
+
+// Причины изменения денег в журнале MoneyLedger
+const (
+	ledgerStart         = "start"          // Стартовые деньги нового игрока
+	ledgerOpening       = "opening"        // Баланс игрока на момент появления журнала
+	ledgerBattle        = "battle"         // Награда за бой, ссылка на id_Battle
+	ledgerBuyBackground = "buy_background" // Покупка фона, ссылка на id_Background
+	ledgerBuyCharacter  = "buy_character"  // Покупка персонажа, ссылка на id_Character
+	ledgerAdmin         = "admin"          // Изменение администратором
+	ledgerDaily         = "daily"          // Ежедневная награда
+)
+
+// Запись журнала денег
+type LedgerEntry struct {
+	ID           int64     `db:"id_Entry" json:"id"`
+	Amount       int       `db:"Amount" json:"amount"`
+	BalanceAfter int       `db:"BalanceAfter" json:"balanceAfter"`
+	Reason       string    `db:"Reason" json:"reason"`
+	Reference    *int      `db:"Reference" json:"reference,omitempty"`
+	EntryTime    time.Time `db:"EntryTime" json:"time"`
+}
+
+// Журнал денег игрока для API администратора
+type AdminLedger struct {
+	Player     *AdminPlayer  `json:"player"`
+	Entries    []LedgerEntry `json:"entries"`    // Последние записи, новые первыми
+	LedgerSum  int           `json:"ledgerSum"`  // Сумма всех записей игрока
+	Consistent bool          `json:"consistent"` // Сумма журнала совпадает с балансом в базе
+}
+
+// Ссылка для журнала, 0 означает её отсутствие
+func ledgerReference(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
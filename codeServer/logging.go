@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// Настраивает журнал сервера: формат text или json, уровень debug, info, warn или error.
+// Вызовы пакета log тоже попадают в журнал с уровнем info
+func setupLogger(format, level string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("неизвестный уровень журнала %s, доступны: debug, info, warn, error", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("неизвестный формат журнала %s, доступны: text, json", format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Журнал клиента: во время запроса с типом сообщения, во время боя с ID боя
+func (client *Client) logger() *slog.Logger {
+	if log := client.reqLog.Load(); log != nil {
+		return log
+	}
+	if log := client.log.Load(); log != nil {
+		return log
+	}
+	return slog.Default()
+}
+
+// Обновляет поля журнала клиента после входа
+func (client *Client) setLogIdentity() {
+	log := slog.With(
+		"user_id", client.UserID,
+		"public_id", client.PublicID,
+		"ip", client.remoteIP,
+		"transport", client.netProfile.Name,
+	)
+	client.log.Store(log)
+	if client.reqLog.Load() != nil {
+		client.reqLog.Store(log.With("msg_type", client.reqType, "req_id", client.reqID))
+	}
+}
+
+// Журнал запроса к базе данных
+func dbLogger(op string, args ...any) *slog.Logger {
+	return slog.With(append([]any{"component", "db", "op", op}, args...)...)
+}
+
+// Журнал запроса к базе данных от имени клиента
+func clientDBLogger(client *Client, op string) *slog.Logger {
+	return client.logger().With("component", "db", "op", op)
+}
+
+// Записывает ошибку и завершает сервер
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	loginFreeAttempts = 3                // Неудачных попыток без задержки
+	loginBaseDelay    = 2 * time.Second  // Задержка после первой лишней попытки, дальше удваивается
+	loginMaxDelay     = 5 * time.Minute  // Предел задержки
+	loginLockAttempts = 10               // Неудачных попыток до блокировки логина
+	ipLockAttempts    = 50               // С одного IP могут входить разные игроки, поэтому порог выше
+	loginLockDuration = 15 * time.Minute // Время временной блокировки
+	loginFailureTTL   = time.Hour        // Счётчик забывается после часа без ошибок
+	maxAuditLogin     = 50               // Длина поля Login в FailedLogins
+)
+
+// Причины неудачного входа для журнала FailedLogins
+const (
+	failUnknownLogin  = "unknown_login"
+	failWrongPassword = "wrong_password"
+	failBlocked       = "blocked"
+	failLocked        = "locked"
+	failLoginTaken    = "login_taken" // Регистрация с занятым логином
+)
+
+// Неудачные попытки входа по одному ключу: логину или IP
+type loginFailures struct {
+	count        int
+	last         time.Time
+	blockedUntil time.Time
+}
+
+// Счётчики неудачных попыток входа по логину и IP
+type loginGuard struct {
+	entries     map[string]*loginFailures
+	lastCleanup time.Time
+	mutex       sync.Mutex
+}
+
+var loginGuards = &loginGuard{entries: make(map[string]*loginFailures)}
+
+func loginKey(login string) string {
+	return "login:" + strings.ToLower(strings.TrimSpace(login))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Время до следующей разрешённой попытки входа, 0 если вход разрешён
+func (g *loginGuard) wait(login, ip string) time.Duration {
+	return g.waitKeys(loginKey(login), ipKey(ip))
+}
+
+func (g *loginGuard) waitKeys(keys ...string) time.Duration {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range keys {
+		if f, ok := g.entries[key]; ok && f.blockedUntil.After(now) {
+			if d := f.blockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
+
+// Учитывает неудачную попытку: после loginFreeAttempts растёт задержка, после порога ключ блокируется
+func (g *loginGuard) failed(login, ip string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	now := time.Now()
+	g.cleanup(now)
+	g.add(loginKey(login), loginLockAttempts, now)
+	g.add(ipKey(ip), ipLockAttempts, now)
+}
+
+// Учитывает попытку, по которой можно узнать о существовании логина, только для IP.
+// Счётчик логина не растёт, иначе чужой аккаунт можно заблокировать попытками регистрации
+func (g *loginGuard) failedIP(ip string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	now := time.Now()
+	g.cleanup(now)
+	g.add(ipKey(ip), ipLockAttempts, now)
+}
+
+func (g *loginGuard) add(key string, lockAttempts int, now time.Time) {
+	f, ok := g.entries[key]
+	if !ok || now.Sub(f.last) > loginFailureTTL {
+		f = &loginFailures{}
+		g.entries[key] = f
+	}
+	f.count++
+	f.last = now
+
+	switch {
+	case f.count >= lockAttempts:
+		f.blockedUntil = now.Add(loginLockDuration)
+		slog.Warn("Вход временно заблокирован после неудачных попыток", "key", key, "attempts", f.count)
+	case f.count > loginFreeAttempts:
+		delay := loginBaseDelay << (f.count - loginFreeAttempts - 1)
+		if delay > loginMaxDelay || delay <= 0 {
+			delay = loginMaxDelay
+		}
+		f.blockedUntil = now.Add(delay)
+	}
+}
+
+// Успешный вход сбрасывает счётчик логина. Счётчик IP не сбрасывается,
+// иначе перебор чужих паролей можно чередовать со входом в свой аккаунт
+func (g *loginGuard) succeeded(login string) {
+	g.mutex.Lock()
+	delete(g.entries, loginKey(login))
+	g.mutex.Unlock()
+}
+
+func (g *loginGuard) cleanup(now time.Time) {
+	if now.Sub(g.lastCleanup) < time.Minute {
+		return
+	}
+	for key, f := range g.entries {
+		if now.Sub(f.last) > loginFailureTTL && now.After(f.blockedUntil) {
+			delete(g.entries, key)
+		}
+	}
+	g.lastCleanup = now
+}
+
+// Проверяет, разрешена ли попытка входа, и возвращает ошибку для клиента
+func checkLoginAllowed(login, ip string) error {
+	return lockedError(login, ip, loginGuards.wait(login, ip))
+}
+
+// Проверяет, разрешена ли регистрация с IP. Занятый логин сообщает о существовании аккаунта,
+// поэтому регистрация ограничивается вместе со входом по общему счётчику IP
+func checkRegistrationAllowed(login, ip string) error {
+	return lockedError(login, ip, loginGuards.waitKeys(ipKey(ip)))
+}
+
+// Ошибка для клиента, если до следующей попытки нужно ждать wait
+func lockedError(login, ip string, wait time.Duration) error {
+	if wait <= 0 {
+		return nil
+	}
+	auditFailedLogin(login, ip, failLocked)
+	seconds := int(wait.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return newGameError(ErrCodeLoginLocked, "seconds", strconv.Itoa(seconds))
+}
+
+// Учитывает неудачный вход. Клиенту всегда возвращается одна и та же ошибка,
+// чтобы по ответу нельзя было узнать, существует ли логин
+func loginFailed(login, ip, reason string) error {
+	loginGuards.failed(login, ip)
+	auditFailedLogin(login, ip, reason)
+	return newGameError(ErrCodeBadCredentials)
+}
+
+// Учитывает регистрацию с занятым логином
+func registrationLoginTaken(login, ip string) error {
+	loginGuards.failedIP(ip)
+	auditFailedLogin(login, ip, failLoginTaken)
+	return newGameError(ErrCodeLoginExists)
+}
+
+// Записывает неудачную попытку входа в журнал
+func auditFailedLogin(login, ip, reason string) {
+	slog.Info("Неудачный вход", "login", login, "ip", ip, "reason", reason)
+	if runes := []rune(login); len(runes) > maxAuditLogin {
+		login = string(runes[:maxAuditLogin])
+	}
+	if err := store.insertFailedLogin(login, ip, reason); err != nil {
+		dbLogger("insert_failed_login", "ip", ip).Error("Ошибка записи неудачного входа", "err", err)
+	}
+}
@@ -1,22 +1,22 @@
 package main
 
 import (
+	"codeServer/resource"
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	_ "github.com/denisenkom/go-mssqldb"
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"github.com/jmoiron/sqlx"
 	"github.com/vmihailenco/msgpack/v5"
 	"github.com/xtaci/kcp-go/v5"
-	"log"
+	"log/slog"
 	"math"
-	"math/rand"
 	"net"
 	"os"
 	"os/signal"
-	"path/filepath"
-	"strings"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -63,14 +63,16 @@ const (
 	MsgSelectBackground
 	MsgSelectCharacter
 	MsgPurchaseReceipt
+	MsgAssetManifest
+	MsgAssetRequest
+	MsgAssetChunk
+	MsgHandshake
+	MsgDailyReward
 )
 
 var (
-	db                  *sqlx.DB
-	authorizedClients   = make(map[string]*Client) // Список подключённых клиентов
-	clientsMutex        sync.Mutex                 // Ограничиваем доступ к списку подключённых клиентов
-	activeCharacters    = make(map[int]*Character)
-	listCharactersMutex sync.Mutex
+	authorizedClients = make(map[string]*Client) // Список подключённых клиентов
+	clientsMutex      sync.Mutex                 // Ограничиваем доступ к списку подключённых клиентов
 )
 
 // Структура клиента
@@ -95,11 +97,23 @@ type Client struct {
 
 	lastPingTime   int64
 	Authorized     bool
+	sessionSecured bool       // Рукопожатие выполнено, соединение на ключе сессии
+	netProfile     NetProfile // Сетевой профиль слушателя, через который подключился клиент
+	remoteIP       string     // IP клиента без порта
+	cheat          cheatTracker
+	log            atomic.Pointer[slog.Logger] // Журнал с данными клиента
+	reqLog         atomic.Pointer[slog.Logger] // Журнал обрабатываемого запроса или боя, его читают и другие горутины клиента
 	Ping           int64
 	waitingForPong int32
 
 	ReceivedMess chan *Message
 	BattleInfo   chan *Battle
+	statsChanged chan struct{} // Администратор изменил деньги, ранг или уровень, буфер 1
+
+	sendSeq uint32      // Номер последнего отправленного кадра, под connMutex
+	recvSeq uint32      // Номер последнего принятого кадра
+	reqID   uint32      // ID обрабатываемого запроса, под connMutex
+	reqType MessageType // Тип обрабатываемого запроса, под connMutex
 }
 
 type Character struct {
@@ -123,8 +137,10 @@ type Character struct {
 
 // Структура для обмена сообщениями
 type Message struct {
-	Type MessageType `msgpack:"t"`
-	Data []byte      `msgpack:"d"`
+	Type  MessageType `msgpack:"t"`
+	Data  []byte      `msgpack:"d"`
+	Seq   uint32      `msgpack:"-"` // Из заголовка кадра
+	ReqID uint32      `msgpack:"-"` // Из заголовка кадра
 }
 
 // Структура для авторизации
@@ -142,16 +158,10 @@ type RegisterData struct {
 }
 
 // Создание битовой маски для боя
-func createBitMask(path string, countFrame, FrameWidth, FrameHeight int, ch *Character, sizeCharacter bool) []uint64 {
-	// Путь к текущей директории
-	currentDir, err := os.Getwd()
-	if err != nil {
-		panic(err.Error())
-	}
-	currentDir = filepath.Dir(currentDir) // УБРАТЬ ЕСЛИ EXE ТАМ ЖЕ ГДЕ И ПАПКА RESOURCES
-	assetCharacter := rl.LoadImage(currentDir + path)
+func createBitMask(assetID string, countFrame, FrameWidth, FrameHeight int, ch *Character, sizeCharacter bool) ([]uint64, error) {
+	assetCharacter := rl.LoadImage(resource.Path(assetID))
 	if assetCharacter == nil || assetCharacter.Width == 0 || assetCharacter.Height == 0 {
-		panic("Ошибка загрузки изображения: файл не найден или повреждён")
+		return nil, fmt.Errorf("ошибка загрузки изображения %s: файл не найден или повреждён", resource.Path(assetID))
 	}
 	defer rl.UnloadImage(assetCharacter)
 
@@ -191,14 +201,11 @@ func createBitMask(path string, countFrame, FrameWidth, FrameHeight int, ch *Cha
 		}
 	}
 
-	return mask
+	return mask, nil
 }
 
-// Добавить все битовые маски персонажу
-func addCharacterBitMask(id int, chDB CharacterDB) {
-	listCharactersMutex.Lock()
-	defer listCharactersMutex.Unlock()
-
+// Создаёт персонажа со всеми битовыми масками
+func createCharacter(chDB CharacterDB) (*Character, error) {
 	ch := Character{Health: chDB.Health, Damage: chDB.Damage, XCharacter: math.MaxInt, YCharacter: math.MaxInt, WCharacter: 0, HCharacter: 0, Assets: chDB.Assets, Name: chDB.Name, Description: chDB.Description, Cost: chDB.Cost}
 
 	//Считаем, что ширина и высота кадра для всех анимаций одного персонажа одинаковая
@@ -212,15 +219,20 @@ func addCharacterBitMask(id int, chDB CharacterDB) {
 		if asset.AnimationType == "Medallion" {
 			continue
 		}
-		lastSlash := strings.LastIndex(asset.AssetPath, "\\")
 		if asset.AnimationType == "Attack" || asset.AnimationType == "HeavyAttack" {
-			assPthWthWep := asset.AssetPath[:lastSlash] + "\\Weapon" + asset.AssetPath[lastSlash:]
-			BitMaskWithWeapon[asset.AnimationType] = createBitMask(assPthWthWep, asset.FrameCount, asset.BaseWidth, asset.BaseHeight, &ch, false)
+			mask, err := createBitMask(resource.Variant(asset.AssetPath, "Weapon"), asset.FrameCount, asset.BaseWidth, asset.BaseHeight, &ch, false)
+			if err != nil {
+				return nil, err
+			}
+			BitMaskWithWeapon[asset.AnimationType] = mask
 		}
 		frameDuration := time.Second / time.Duration(asset.FrameRate)                        // Время одного кадра
 		TimeAnimation[asset.AnimationType] = frameDuration * time.Duration(asset.FrameCount) // Общее время анимации
-		assPth := asset.AssetPath[:lastSlash] + "\\Character" + asset.AssetPath[lastSlash:]
-		BitMask[asset.AnimationType] = createBitMask(assPth, asset.FrameCount, asset.BaseWidth, asset.BaseHeight, &ch, true)
+		mask, err := createBitMask(resource.Variant(asset.AssetPath, "Character"), asset.FrameCount, asset.BaseWidth, asset.BaseHeight, &ch, true)
+		if err != nil {
+			return nil, err
+		}
+		BitMask[asset.AnimationType] = mask
 	}
 	ch.BitMask = BitMask
 	ch.BitMaskWithWeapon = BitMaskWithWeapon
@@ -234,7 +246,7 @@ func addCharacterBitMask(id int, chDB CharacterDB) {
 		ch.XBoundary = ch.FrameWidth - (ch.XCharacter + ch.WCharacter)
 	}
 
-	activeCharacters[id] = &ch
+	return &ch, nil
 }
 
 // Добавление клиента
@@ -264,27 +276,22 @@ func createMessage(mesType MessageType, data interface{}) ([]byte, error) {
 	return msgpack.Marshal(message)
 }
 
-// Отправка сообщения
+// Отправка сообщения без привязки к запросу
 func sendMessage(client *Client, data []byte) error {
 	client.connMutex.Lock()
 	defer client.connMutex.Unlock()
-
-	if client.Conn == nil {
-		log.Printf("Попытка отправки сообщения отключённому клиенту %d", client.UserID)
-		return errors.New("соединение закрыто")
-	}
-
-	_, err := client.Conn.Write(data)
-	return err
+	return client.writeFrameLocked(0, data)
 }
 
-// Создание и отправка байтового сообщения
+// Создание и отправка байтового сообщения. Ответы на текущий запрос клиента получают его ID
 func createAndSendMessage(client *Client, mesType MessageType, data interface{}) error {
 	resp, err := createMessage(mesType, data)
 	if err != nil {
 		return err
 	}
-	return sendMessage(client, resp)
+	client.connMutex.Lock()
+	defer client.connMutex.Unlock()
+	return client.writeFrameLocked(client.replyID(mesType), resp)
 }
 
 // Получение сообщений клиентом
@@ -299,7 +306,7 @@ func receiveClientMessages(client *Client) {
 		client.ReceivedMess = nil
 		client.Conn.Close()
 		client.Conn = nil
-		log.Printf("Клиент %d отключён", client.UserID)
+		client.logger().Info("Клиент отключён")
 	}()
 	// Пинг каждые N секунд
 	go func() {
@@ -309,7 +316,7 @@ func receiveClientMessages(client *Client) {
 		for {
 			select {
 			case <-client.ctx.Done():
-				log.Printf("Пинг понг клиента %d завершил работу по контексту", client.UserID)
+				client.logger().Debug("Пинг понг клиента завершил работу по контексту")
 				return
 
 			case <-ticker.C:
@@ -324,7 +331,7 @@ func receiveClientMessages(client *Client) {
 				pingMsg := Message{Type: MsgPing}
 				data, err := msgpack.Marshal(pingMsg)
 				if err != nil {
-					log.Printf("Ошибка сериализации Ping: %v", err)
+					slog.Error("Ошибка сериализации Ping", "err", err)
 					atomic.StoreInt32(&client.waitingForPong, 0)
 					continue
 				}
@@ -334,10 +341,10 @@ func receiveClientMessages(client *Client) {
 					client.connMutex.Unlock()
 					return
 				}
-				_, err = client.Conn.Write(data)
+				err = client.writeFrameLocked(0, data)
 				client.connMutex.Unlock()
 				if err != nil {
-					log.Printf("Ошибка отправки Ping клиенту %d: %v", client.UserID, err)
+					client.logger().Warn("Ошибка отправки Ping", "err", err)
 					return
 				}
 			}
@@ -345,29 +352,54 @@ func receiveClientMessages(client *Client) {
 	}()
 
 	var netErr net.Error
-	decoder := msgpack.NewDecoder(client.Conn)
+	var limiter rateLimiter
+	badFrames := 0
 
 	for {
 		select {
 		case <-client.ctx.Done():
-			log.Printf("Получатель сообщений клиента %d завершил работу по контексту", client.UserID)
+			client.logger().Debug("Получатель сообщений клиента завершил работу по контексту")
 			return
 
 		default:
 			client.Conn.SetReadDeadline(time.Now().Add(timeoutDuration))
 
-			var msg Message
-			err := decoder.Decode(&msg)
+			header, body, err := readFrame(client.Conn)
 			if err != nil {
 				if errors.As(err, &netErr) && netErr.Timeout() {
-					log.Printf("Клиент %d отключён по таймауту", client.UserID)
+					client.logger().Info("Клиент отключён по таймауту")
 					return
 				}
-				createAndSendMessage(client, MsgError, "ошибка при десериализации сообщения!")
-				log.Printf("Ошибка при десериализации сообщения от клиента %d: %v", client.UserID, err)
+				// Без целого кадра границу следующего сообщения не найти
+				client.logger().Info("Ошибка чтения кадра", "err", err)
 				return
 			}
 
+			if header.Seq <= client.recvSeq {
+				client.logger().Debug("Повторный кадр пропущен", "seq", header.Seq)
+				continue
+			}
+			if header.Seq != client.recvSeq+1 {
+				client.logger().Warn("Пропущены кадры", "from", client.recvSeq+1, "to", header.Seq-1)
+			}
+			client.recvSeq = header.Seq
+
+			var msg Message
+			err = msgpack.Unmarshal(body, &msg)
+			if err != nil {
+				badFrames++
+				client.logger().Warn("Ошибка при десериализации сообщения", "seq", header.Seq, "err", err)
+				if badFrames >= maxBadFrames {
+					sendReply(client, header.ReqID, MsgError, newGameError(ErrCodeTooManyBadFrames))
+					return
+				}
+				sendReply(client, header.ReqID, MsgError, newGameError(ErrCodeBadFrame))
+				continue
+			}
+			badFrames = 0
+			msg.Seq = header.Seq
+			msg.ReqID = header.ReqID
+
 			switch msg.Type {
 			case MsgExit:
 				return
@@ -376,7 +408,36 @@ func receiveClientMessages(client *Client) {
 			case MsgPong:
 				handlePong(client)
 			default:
-				client.ReceivedMess <- &msg
+				switch limiter.check(msg.Type) {
+				case rateExceeded:
+					client.logger().Warn("Клиент отключён за флуд", "msg_type", msg.Type)
+					sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeFlood))
+					return
+				case rateThrottled:
+					if limiter.firstViolation() {
+						client.logger().Warn("Клиент превысил лимит сообщений", "msg_type", msg.Type)
+						sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeRateLimited, "type", strconv.Itoa(int(msg.Type))))
+					}
+					continue
+				}
+				// Ключ сессии меняется до чтения следующего кадра, поэтому рукопожатие обрабатывается здесь
+				if msg.Type == MsgHandshake {
+					if !handleHandshake(client, &msg) {
+						return
+					}
+					continue
+				}
+				// До рукопожатия соединение на общем ключе, запросы по нему не принимаются
+				if !client.sessionSecured {
+					sendReply(client, msg.ReqID, MsgError, newGameError(ErrCodeBadRequest))
+					continue
+				}
+				// Обработчик мог завершиться по контексту, тогда сообщение некому принять
+				select {
+				case client.ReceivedMess <- &msg:
+				case <-client.ctx.Done():
+					return
+				}
 			}
 		}
 	}
@@ -385,94 +446,234 @@ func receiveClientMessages(client *Client) {
 // Обработчик сообщенйи клиента
 func handleClientMessages(client *Client) {
 	client.ReceivedMess = make(chan *Message)
+	client.statsChanged = make(chan struct{}, 1)
 	client.ctx, client.cancel = context.WithCancel(context.Background())
+	addSession(client)
+	defer removeSession(client)
 
 	go receiveClientMessages(client)
 	for {
 		select {
 
 		case <-client.ctx.Done():
-			log.Printf("Клиент %d завершил работу по контексту", client.UserID)
+			client.logger().Debug("Клиент завершил работу по контексту")
 			return
 
 		case msg, ok := <-client.ReceivedMess:
 			// Если канал закрыт, то завершить обработку
 			if !ok {
-				log.Printf("Канал сообщений для клиента %d закрыт, завершаем обработку", client.UserID)
+				client.logger().Debug("Канал сообщений клиента закрыт, завершаем обработку")
 				return
 			}
 
 			// Поиск функции обработки
+			client.beginRequest(msg)
 			if handler, ok := handlers[msg.Type]; ok {
 				handler(client, msg.Data)
 			} else {
-				createAndSendMessage(client, MsgError, fmt.Sprintf("неизвестный тип сообщения: %d", msg.Type))
+				sendError(client, newGameError(ErrCodeUnknownMessage, "type", strconv.Itoa(int(msg.Type))))
 			}
+			client.endRequest()
+
+		case <-client.statsChanged:
+			reloadPlayerStats(client)
 		}
 	}
 }
 
-// Обработчик KCP
-func kcpHandler() {
-	listener, err := kcp.Listen(":7777") // Порт для KCP
+// Обработчик KCP для сетевого профиля
+func kcpHandler(profile NetProfile, block kcp.BlockCrypt) {
+	addr := fmt.Sprintf(":%d", profile.Port)
+	listener, err := kcp.ListenWithOptions(addr, block, profile.DataShards, profile.ParityShards) // Трафик шифруется общим ключом
 	if err != nil {
-		log.Fatalf("Ошибка запуска KCP сервера: %v", err)
+		fatal("Ошибка запуска KCP сервера", "profile", profile.Name, "err", err)
 	}
-	log.Println("KCP сервер запущен на :7777")
+	registerListener(listener)
+	slog.Info("KCP сервер запущен", "addr", addr, "profile", profile.Name, "data_shards", profile.DataShards, "parity_shards", profile.ParityShards)
+	serveKCP(listener, profile)
+}
 
+// Принимает подключения KCP до закрытия слушателя
+func serveKCP(listener *kcp.Listener, profile NetProfile) {
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
-			log.Printf("Ошибка Accept KCP: %v", err)
+			if isShuttingDown() {
+				return
+			}
+			slog.Warn("Ошибка Accept KCP", "err", err)
+			continue
+		}
+
+		if !sessionLimits.allow(conn.RemoteAddr().String()) {
+			conn.Close()
 			continue
 		}
 
 		// Приводим тип и настраиваем параметры KCP
 		if session, ok := conn.(*kcp.UDPSession); ok {
-			session.SetNoDelay(1, 10, 2, 1) // Fast mode
-			session.SetWindowSize(128, 128) // Увеличенные окна
-			session.SetACKNoDelay(true)     // Без отложенных ACK
+			profile.Tuning.apply(session)
 		} else {
-			log.Printf("соединение не является *kcp.UDPSession")
+			slog.Error("Соединение не является *kcp.UDPSession")
 			continue
 		}
 
-		client := &Client{
-			UserID:     -1,
-			PlayerID:   -1,
-			Conn:       conn,
-			Authorized: false,
-		}
-		log.Printf("Новое подключение от %s", conn.RemoteAddr().String())
-		go handleClientMessages(client)
+		go handleClientMessages(newConnClient(conn, conn.RemoteAddr().String(), profile))
 	}
 }
 
 func main() {
-	var err error
-	db, err = sqlx.Open("sqlserver", "server=localhost;database=GAME_FQW;trusted_connection=yes")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := runMigrate(os.Args[2:]); err != nil {
+				fatal("Ошибка миграции", "err", err)
+			}
+			return
+		case "seed":
+			if err := runSeed(os.Args[2:]); err != nil {
+				fatal("Ошибка загрузки контента", "err", err)
+			}
+			return
+		}
+	}
+
+	// Папка ресурсов: флаг -resources, переменная GAME_RESOURCES или поиск рядом с рабочей папкой и exe
+	resourcesRoot := flag.String("resources", "", "путь к папке resources")
+	sharedKey := flag.String("key", os.Getenv(keyEnvName), "общий ключ шифрования трафика, такой же как у клиента")
+	profileList := flag.String("profiles", "lan,normal,lossy", "сетевые профили KCP, для каждого открывается свой порт")
+	tcpAddr := flag.String("tcp", ":7780", "адрес TCP сервера для клиентов без UDP, пустой отключает")
+	wsAddr := flag.String("ws", ":7781", "адрес WebSocket сервера, пустой отключает")
+	rateLimitList := flag.String("ratelimits", "", "лимиты сообщений клиента по группам: action=40/80,auth=0.5/3 (сообщений в секунду/всплеск)")
+	sessionRate := flag.String("sessionrate", "", "лимит новых сессий с одного IP: rate/burst, по умолчанию 0.5/5")
+	cheatMode := flag.String("anticheat", antiCheatFlag, "режим античита: off, flag (только отчёт) или kick (отчёт и отключение)")
+	metricsAddr := flag.String("metrics", ":9100", "адрес HTTP сервера метрик /metrics и проверки /healthz, пустой отключает")
+	adminAddr := flag.String("admin", "", "адрес HTTP API администратора, пустой отключает")
+	adminToken := flag.String("admintoken", os.Getenv(adminTokenEnvName), "токен API администратора")
+	logFormat := flag.String("logformat", "text", "формат журнала: text или json")
+	logLevel := flag.String("loglevel", "info", "уровень журнала: debug, info, warn или error")
+	shutdownWait := flag.Duration("shutdownwait", 30*time.Second, "сколько ждать окончания боёв при остановке, после чего они отменяются")
+	dsn := flag.String("db", defaultDatabase(), "строка подключения к базе данных SQL Server, по умолчанию из "+dbEnvName)
+	autoMigrate := flag.Bool("migrate", true, "применять миграции схемы при запуске, иначе только проверять версию")
+	flag.Parse()
+	if err := setupLogger(*logFormat, *logLevel); err != nil {
+		fatal("Ошибка настройки журнала", "err", err)
+	}
+	mode, err := parseAntiCheatMode(*cheatMode)
+	if err != nil {
+		fatal("Ошибка настройки античита", "err", err)
+	}
+	antiCheatMode = mode
+	if *adminAddr != "" && len(*adminToken) < 16 {
+		fatal("Для API администратора нужен токен не короче 16 символов: флаг -admintoken или переменная " + adminTokenEnvName)
+	}
+	if err = parseRateLimits(*rateLimitList); err != nil {
+		fatal("Ошибка настройки лимитов", "err", err)
+	}
+	if *sessionRate != "" {
+		limit, err := parseRateLimit(*sessionRate)
+		if err != nil {
+			fatal("Ошибка настройки лимитов", "err", err)
+		}
+		sessionRateLimit = limit
+	}
+	err = resource.Init(*resourcesRoot)
 	if err != nil {
-		log.Fatalf("Ошибка открытия соединения базы данных: %v", err)
+		fatal("Ошибка при поиске папки ресурсов", "err", err)
+	}
+
+	if *autoMigrate {
+		if err = createDatabase(*dsn); err != nil {
+			fatal("Ошибка создания базы данных", "err", err)
+		}
 	}
+	sqlDB, err := sqlx.Open("sqlserver", *dsn)
+	if err != nil {
+		fatal("Ошибка открытия соединения базы данных", "err", err)
+	}
+	store = &sqlStorage{db: &metricsDB{DB: sqlDB}}
 	// Проверка соединения
-	if err = db.Ping(); err != nil {
+	if err = sqlDB.Ping(); err != nil {
 		panic("Не удалось подключиться к базе данных: " + err.Error())
 	}
-	defer db.Close()
+	defer store.close()
+
+	// Схема базы данных
+	if err = prepareSchema(sqlDB.DB, *autoMigrate); err != nil {
+		fatal("Ошибка миграции схемы базы данных", "err", err)
+	}
+
+	// Загрузка каталога персонажей
+	if err = reloadCharacterCatalog(); err != nil {
+		fatal("Ошибка загрузки каталога персонажей", "err", err)
+	}
+
+	// Индекс ресурсов для скачивания клиентами
+	if err = reloadContentIndex(); err != nil {
+		fatal("Ошибка загрузки индекса ресурсов", "err", err)
+	}
 
 	// Инициализация очередей для матчей
 	matchmakingQueue = &MatchmakingQueue{}
 
 	// Запускаем горутину для поиска матчей
 	go matchmakingQueue.RunSearch()
-	defer matchmakingQueue.StopSearch()
 
-	go kcpHandler()
+	key, err := deriveKey(*sharedKey)
+	if err != nil {
+		fatal("Ошибка настройки шифрования", "err", err)
+	}
+	trafficKey = key
+	block, err := newBlockCrypt(key)
+	if err != nil {
+		fatal("Ошибка настройки шифрования", "err", err)
+	}
+	aead, err := newStreamCrypt(key)
+	if err != nil {
+		fatal("Ошибка настройки шифрования", "err", err)
+	}
+	profiles, err := parseNetProfiles(*profileList)
+	if err != nil {
+		fatal("Ошибка настройки сетевых профилей", "err", err)
+	}
+	for _, profile := range profiles {
+		go kcpHandler(profile, block)
+	}
+	if *tcpAddr != "" {
+		go tcpHandler(*tcpAddr, aead)
+	}
+	if *wsAddr != "" {
+		go wsHandler(*wsAddr, aead)
+	}
+	go monitorCryptErrors()
+	if *metricsAddr != "" {
+		go metricsHandler(*metricsAddr)
+	}
+	if *adminAddr != "" {
+		go adminHandler(*adminAddr, *adminToken)
+	}
+
+	// SIGHUP перезагружает каталог персонажей и индекс ресурсов без перезапуска сервера
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 
-	// Ожидание любых системных сигналов (SIGINT, SIGTERM и другие)
+	// Ожидание системных сигналов завершения (SIGINT, SIGTERM)
 	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGKILL, syscall.SIGHUP)
-	<-sig
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGKILL)
+	for running := true; running; {
+		select {
+		case <-reload:
+			if err = reloadCharacterCatalog(); err != nil {
+				slog.Error("Ошибка перезагрузки каталога персонажей, используется прежний", "err", err)
+			}
+			if err = reloadContentIndex(); err != nil {
+				slog.Error("Ошибка перезагрузки индекса ресурсов, используется прежний", "err", err)
+			}
+		case <-sig:
+			running = false
+		}
+	}
+	shutdown(*shutdownWait)
 
-	log.Println("Сервер завершил работу.")
+	slog.Info("Сервер завершил работу")
 }
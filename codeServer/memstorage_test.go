@@ -0,0 +1,732 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Фон в памяти, id назначается по порядку добавления, как IDENTITY в базе данных
+type memBackground struct {
+	id int
+	seedBackground
+}
+
+type memCharacter struct {
+	id int
+	seedCharacter
+}
+
+// Игрок в памяти
+type memPlayer struct {
+	user             UserDB
+	id               int
+	publicID         string
+	name             string
+	level            int
+	money            int
+	rank             int
+	activeCharacter  int
+	activeBackground int
+	backgrounds      map[int]bool // Купленные фоны
+	characters       map[int]bool // Купленные персонажи
+}
+
+type memBattle struct {
+	key                      string
+	player1, player2, winner int
+	startTime, endTime       time.Time
+	isRanked, isCancelled    bool
+}
+
+// Запись журнала: неудачный вход, отчёт античита или действие администратора
+type auditEntry struct {
+	PlayerID int
+	Login    string
+	IP       string
+	Action   string
+	Details  string
+}
+
+// Хранилище в памяти процесса для тестов. Повторяет поведение процедур базы данных
+type memStorage struct {
+	mutex             sync.Mutex
+	version           int // Версия загруженного контента
+	backgrounds       []*memBackground
+	characters        []*memCharacter
+	starterBackground int
+	starterCharacter  int
+	players           []*memPlayer    // Индекс = id_Player - 1
+	friends           map[[2]int]bool // (id_Player, id_Friend) -> дружба подтверждена
+	battles           []memBattle
+	audit             map[string][]auditEntry // Таблица журнала -> записи
+	ledger            map[int][]LedgerEntry   // id_Player -> журнал денег по порядку записи
+	ledgerSeq         int64
+	daily             map[int]memDaily // id_Player -> серия ежедневных наград
+}
+
+// Серия ежедневных наград в памяти
+type memDaily struct {
+	lastClaim time.Time
+	streak    int
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{
+		friends: make(map[[2]int]bool),
+		audit:   make(map[string][]auditEntry),
+		ledger:  make(map[int][]LedgerEntry),
+		daily:   make(map[int]memDaily),
+	}
+}
+
+// Добавляет запись в журнал денег, как MoneyLedger в базе данных. Нулевые изменения не пишутся
+func (s *memStorage) addLedgerEntry(p *memPlayer, amount int, reason string, reference int) {
+	if amount == 0 {
+		return
+	}
+	s.ledgerSeq++
+	s.ledger[p.id] = append(s.ledger[p.id], LedgerEntry{
+		ID:           s.ledgerSeq,
+		Amount:       amount,
+		BalanceAfter: p.money,
+		Reason:       reason,
+		Reference:    ledgerReference(reference),
+		EntryTime:    time.Now(),
+	})
+}
+
+func (s *memStorage) ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *memStorage) close() error {
+	return nil
+}
+
+// Записи журнала: FailedLogins, CheatReports или AdminActions
+func (s *memStorage) auditEntries(table string) []auditEntry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]auditEntry(nil), s.audit[table]...)
+}
+
+func (s *memStorage) playerByLogin(login string) *memPlayer {
+	for _, p := range s.players {
+		if p.user.Login == login {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *memStorage) playerByPublicID(publicID string) *memPlayer {
+	for _, p := range s.players {
+		if p.publicID == publicID {
+			return p
+		}
+	}
+	return nil
+}
+
+// Игрок по id_Player или id_User, у каждого пользователя ровно один игрок с тем же номером
+func (s *memStorage) player(id int) *memPlayer {
+	if id < 1 || id > len(s.players) {
+		return nil
+	}
+	return s.players[id-1]
+}
+
+func (s *memStorage) background(id int) *memBackground {
+	if id < 1 || id > len(s.backgrounds) {
+		return nil
+	}
+	return s.backgrounds[id-1]
+}
+
+func (s *memStorage) character(id int) *memCharacter {
+	if id < 1 || id > len(s.characters) {
+		return nil
+	}
+	return s.characters[id-1]
+}
+
+func (s *memStorage) contentVersion() (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.version, nil
+}
+
+// Добавляет или обновляет фоны и персонажей по имени, как команда seed в базе данных
+func (s *memStorage) seed(sd *seedData) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, b := range sd.Backgrounds {
+		var mb *memBackground
+		for _, existing := range s.backgrounds {
+			if existing.Name == b.Name {
+				mb = existing
+			}
+		}
+		if mb == nil {
+			mb = &memBackground{id: len(s.backgrounds) + 1}
+			s.backgrounds = append(s.backgrounds, mb)
+		}
+		mb.seedBackground = b
+		if b.Name == sd.Starter.Background {
+			s.starterBackground = mb.id
+		}
+	}
+	for _, ch := range sd.Characters {
+		var mc *memCharacter
+		for _, existing := range s.characters {
+			if existing.Name == ch.Name {
+				mc = existing
+			}
+		}
+		if mc == nil {
+			mc = &memCharacter{id: len(s.characters) + 1}
+			s.characters = append(s.characters, mc)
+		}
+		mc.seedCharacter = ch
+		if ch.Name == sd.Starter.Character {
+			s.starterCharacter = mc.id
+		}
+	}
+	s.version = sd.Version
+	return nil
+}
+
+func (s *memStorage) loginExists(login string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.playerByLogin(login) != nil, nil
+}
+
+func (s *memStorage) createUser(login, passwordHash, name string) (userID, activeCharacterID int, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.playerByLogin(login) != nil {
+		return 0, 0, newGameError(ErrCodeCreateUserFailed)
+	}
+	if s.starterBackground == 0 || s.starterCharacter == 0 {
+		return 0, 0, newGameError(ErrCodeInternal)
+	}
+	publicID := generateRandomCode(publicCodeChars, publicCodeLength)
+	for s.playerByPublicID(publicID) != nil {
+		publicID = generateRandomCode(publicCodeChars, publicCodeLength)
+	}
+	id := len(s.players) + 1
+	s.players = append(s.players, &memPlayer{
+		user:             UserDB{IdUser: id, Login: login, PasswordHash: passwordHash, IsActive: true},
+		id:               id,
+		publicID:         publicID,
+		name:             name,
+		money:            startMoney,
+		activeCharacter:  s.starterCharacter,
+		activeBackground: s.starterBackground,
+		backgrounds:      map[int]bool{s.starterBackground: true},
+		characters:       map[int]bool{s.starterCharacter: true},
+	})
+	s.addLedgerEntry(s.players[id-1], startMoney, ledgerStart, 0)
+	return id, s.starterCharacter, nil
+}
+
+func (s *memStorage) getUser(login string) (*UserDB, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p := s.playerByLogin(login)
+	if p == nil {
+		return nil, sql.ErrNoRows
+	}
+	us := p.user
+	return &us, nil
+}
+
+func (s *memStorage) getPublicID(userID int) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p := s.player(userID)
+	if p == nil {
+		return "", sql.ErrNoRows
+	}
+	return p.publicID, nil
+}
+
+func (s *memStorage) getActiveCharacterID(userID int) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p := s.player(userID)
+	if p == nil {
+		return 0, sql.ErrNoRows
+	}
+	return p.activeCharacter, nil
+}
+
+func (s *memStorage) getPlayerData(userID int) (*UserData, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p := s.player(userID)
+	if p == nil {
+		return nil, sql.ErrNoRows
+	}
+	usDt := &UserData{
+		PlayerID: p.id,
+		Login:    p.user.Login,
+		PublicID: p.publicID,
+		Name:     p.name,
+		Level:    p.level,
+		Money:    p.money,
+		Rank:     p.rank,
+	}
+	if b := s.background(p.activeBackground); b != nil {
+		usDt.ActiveBackgroundPath = b.AssetPath
+	}
+	return usDt, nil
+}
+
+func (s *memStorage) characterRows() ([]characterRowDB, []assetCharacterRowDB, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var rows []characterRowDB
+	var assetRows []assetCharacterRowDB
+	for _, ch := range s.characters {
+		rows = append(rows, characterRowDB{ID: ch.id, CharacterDB: CharacterDB{
+			Name:        ch.Name,
+			Description: ch.Description,
+			Health:      ch.Health,
+			Damage:      ch.Damage,
+			Cost:        ch.Cost,
+		}})
+		for _, as := range ch.Assets {
+			assetRows = append(assetRows, assetCharacterRowDB{CharacterID: ch.id, AssetsCharacterDB: AssetsCharacterDB(as)})
+		}
+	}
+	return rows, assetRows, nil
+}
+
+func (s *memStorage) getFriends(playerID int) (*FriendsData, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	result := &FriendsData{}
+	for pair, confirmed := range s.friends {
+		switch {
+		case confirmed && pair[0] == playerID:
+			result.Friends = append(result.Friends, s.friendEntry(pair[1]))
+		case confirmed && pair[1] == playerID:
+			result.Friends = append(result.Friends, s.friendEntry(pair[0]))
+		case pair[1] == playerID:
+			result.Incoming = append(result.Incoming, s.friendEntry(pair[0]))
+		case pair[0] == playerID:
+			result.Outgoing = append(result.Outgoing, s.friendEntry(pair[1]))
+		}
+	}
+	// Порядок записей карты случайный, тестам нужен постоянный
+	for _, list := range [][]FriendEntry{result.Friends, result.Incoming, result.Outgoing} {
+		sort.Slice(list, func(i, j int) bool { return list[i].PublicID < list[j].PublicID })
+	}
+	return result, nil
+}
+
+func (s *memStorage) friendEntry(playerID int) FriendEntry {
+	p := s.player(playerID)
+	return FriendEntry{Name: p.name, PublicID: p.publicID}
+}
+
+func (s *memStorage) addFriend(requesterPlayerID int, friendPublicID string) (FriendRequestStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	friend := s.playerByPublicID(friendPublicID)
+	if s.player(requesterPlayerID) == nil || friend == nil || friend.id == requesterPlayerID {
+		return 0, newGameError(ErrCodeFriendCodeInvalid)
+	}
+	if _, ok := s.friends[[2]int{requesterPlayerID, friend.id}]; ok {
+		return 0, newGameError(ErrCodeFriendRepeatRequest)
+	}
+	// Встречная заявка подтверждается
+	incoming := [2]int{friend.id, requesterPlayerID}
+	if confirmed, ok := s.friends[incoming]; ok && !confirmed {
+		s.friends[incoming] = true
+		return friendRequestAccepted, nil
+	}
+	s.friends[[2]int{requesterPlayerID, friend.id}] = false
+	return friendRequestSent, nil
+}
+
+func (s *memStorage) acceptFriendship(playerID int, requesterPublicID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if requester := s.playerByPublicID(requesterPublicID); requester != nil {
+		pair := [2]int{requester.id, playerID}
+		if confirmed, ok := s.friends[pair]; ok && !confirmed {
+			s.friends[pair] = true
+		}
+	}
+	return nil
+}
+
+func (s *memStorage) declineFriendship(playerID int, requesterPublicID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if requester := s.playerByPublicID(requesterPublicID); requester != nil {
+		pair := [2]int{requester.id, playerID}
+		if confirmed, ok := s.friends[pair]; ok && !confirmed {
+			delete(s.friends, pair)
+		}
+	}
+	return nil
+}
+
+func (s *memStorage) removeFriend(playerID int, friendPublicID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if friend := s.playerByPublicID(friendPublicID); friend != nil {
+		delete(s.friends, [2]int{playerID, friend.id})
+		delete(s.friends, [2]int{friend.id, playerID})
+	}
+	return nil
+}
+
+func (s *memStorage) applyBattleResults(battle *Battle) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p1, p2 := s.player(battle.Player1.PlayerID), s.player(battle.Player2.PlayerID)
+	if p1 == nil || p2 == nil {
+		return sql.ErrNoRows
+	}
+	defer func() {
+		battle.Reward1.TotalMoney, battle.Reward1.TotalRank, battle.Reward1.TotalLevel = p1.money, p1.rank, p1.level
+		battle.Reward2.TotalMoney, battle.Reward2.TotalRank, battle.Reward2.TotalLevel = p2.money, p2.rank, p2.level
+	}()
+	for i, b := range s.battles {
+		if b.key == battle.Key {
+			battle.RecordID = i + 1
+			return nil
+		}
+	}
+
+	b := memBattle{
+		key:         battle.Key,
+		player1:     battle.Player1.PlayerID,
+		player2:     battle.Player2.PlayerID,
+		startTime:   battle.StartTime,
+		endTime:     battle.EndTime,
+		isRanked:    battle.IsRanked,
+		isCancelled: battle.Cancelled,
+	}
+	if battle.Winner != nil {
+		b.winner = battle.Winner.PlayerID
+	}
+	s.battles = append(s.battles, b)
+	battle.RecordID = len(s.battles)
+	if !battle.Cancelled {
+		s.addBattleReward(p1, battle.Reward1, battle.RecordID)
+		s.addBattleReward(p2, battle.Reward2, battle.RecordID)
+	}
+	return nil
+}
+
+func (s *memStorage) addBattleReward(p *memPlayer, reward BattleReward, recordID int) {
+	p.money += reward.Money
+	p.rank = nonNegative(p.rank + reward.Rank)
+	p.level = nonNegative(p.level + reward.Level)
+	s.addLedgerEntry(p, reward.Money, ledgerBattle, recordID)
+}
+
+func (s *memStorage) getBattleStats(playerID int, isRanked bool) ([]BattleEntry, *BattleStats, error) {
+	const maxEntries = 30
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var entries []BattleEntry
+	stats := &BattleStats{}
+	for i := len(s.battles) - 1; i >= 0; i-- {
+		b := s.battles[i]
+		if b.isRanked != isRanked || b.isCancelled || (b.player1 != playerID && b.player2 != playerID) {
+			continue
+		}
+		entry := BattleEntry{StartTime: b.startTime, EndTime: b.endTime}
+		switch b.winner {
+		case 0:
+			entry.BattleResult = "Ничья"
+			stats.NumberDraws++
+		case playerID:
+			entry.BattleResult = "Победа"
+			stats.NumberWins++
+		default:
+			entry.BattleResult = "Поражение"
+			stats.NumberLosses++
+		}
+		p1, p2 := s.player(b.player1), s.player(b.player2)
+		entry.PlayerName, entry.PlayerPublicID = p1.name, p1.publicID
+		entry.OpponentName, entry.OpponentPublicID = p2.name, p2.publicID
+		if len(entries) < maxEntries {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, stats, nil
+}
+
+func (s *memStorage) getShopBackgrounds(playerID int) (purchased, available []ShopBackgroundItem, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p := s.player(playerID)
+	for _, b := range s.backgrounds {
+		item := ShopBackgroundItem{ID: b.id, Name: b.Name, Description: b.Description, Cost: b.Cost, AssetPath: b.AssetPath}
+		if p != nil && p.backgrounds[b.id] {
+			purchased = append(purchased, item)
+		} else {
+			available = append(available, item)
+		}
+	}
+	return purchased, available, nil
+}
+
+func (s *memStorage) getShopCharacters(playerID int) (purchased, available []ShopCharacterItem, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p := s.player(playerID)
+	for _, ch := range s.characters {
+		item := ShopCharacterItem{ID: ch.id, Name: ch.Name, Description: ch.Description, Health: ch.Health, Damage: ch.Damage, Cost: ch.Cost}
+		for _, as := range ch.Assets {
+			if as.AnimationType == "Preview" {
+				item.AssetPath = as.AssetPath
+				break
+			}
+		}
+		if p != nil && p.characters[ch.id] {
+			purchased = append(purchased, item)
+		} else {
+			available = append(available, item)
+		}
+	}
+	return purchased, available, nil
+}
+
+func (s *memStorage) buyBackground(playerID, backgroundID int) (remainingMoney int, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b := s.background(backgroundID)
+	p := s.player(playerID)
+	switch {
+	case b == nil || p == nil:
+		return 0, newGameError(ErrCodeBackgroundNotFound)
+	case p.backgrounds[backgroundID]:
+		return 0, newGameError(ErrCodeBackgroundAlreadyBought)
+	case p.money < b.Cost:
+		return 0, newGameError(ErrCodeNotEnoughMoney)
+	}
+	p.money -= b.Cost
+	p.backgrounds[backgroundID] = true
+	s.addLedgerEntry(p, -b.Cost, ledgerBuyBackground, backgroundID)
+	return p.money, nil
+}
+
+func (s *memStorage) selectBackground(playerID, backgroundID int) (assetPath string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b := s.background(backgroundID)
+	p := s.player(playerID)
+	switch {
+	case b == nil || p == nil:
+		return "", newGameError(ErrCodeBackgroundNotFound)
+	case !p.backgrounds[backgroundID]:
+		return "", newGameError(ErrCodeBackgroundNotBought)
+	}
+	p.activeBackground = backgroundID
+	return b.AssetPath, nil
+}
+
+func (s *memStorage) buyCharacter(playerID, characterID int) (remainingMoney int, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ch := s.character(characterID)
+	p := s.player(playerID)
+	switch {
+	case ch == nil || p == nil:
+		return 0, newGameError(ErrCodeCharacterNotFound)
+	case p.characters[characterID]:
+		return 0, newGameError(ErrCodeCharacterAlreadyBought)
+	case p.money < ch.Cost:
+		return 0, newGameError(ErrCodeNotEnoughMoney)
+	}
+	p.money -= ch.Cost
+	p.characters[characterID] = true
+	s.addLedgerEntry(p, -ch.Cost, ledgerBuyCharacter, characterID)
+	return p.money, nil
+}
+
+func (s *memStorage) selectCharacter(playerID, characterID int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ch := s.character(characterID)
+	p := s.player(playerID)
+	switch {
+	case ch == nil || p == nil:
+		return newGameError(ErrCodeCharacterNotFound)
+	case !p.characters[characterID]:
+		return newGameError(ErrCodeCharacterNotBought)
+	}
+	p.activeCharacter = characterID
+	return nil
+}
+
+func (s *memStorage) getAdminPlayer(publicID, login string) (*AdminPlayer, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var p *memPlayer
+	if publicID != "" {
+		p = s.playerByPublicID(publicID)
+	}
+	if p == nil && login != "" {
+		p = s.playerByLogin(login)
+	}
+	if p == nil {
+		return nil, sql.ErrNoRows
+	}
+	player := &AdminPlayer{
+		UserID:   p.user.IdUser,
+		PlayerID: p.id,
+		PublicID: p.publicID,
+		Login:    p.user.Login,
+		Name:     p.name,
+		Level:    p.level,
+		Money:    p.money,
+		Rank:     p.rank,
+		IsActive: p.user.IsActive,
+	}
+	if p.user.BanReason.Valid {
+		reason := p.user.BanReason.String
+		player.BanReason = &reason
+	}
+	if p.user.BannedUntil.Valid {
+		until := p.user.BannedUntil.Time
+		player.BannedUntil = &until
+	}
+	return player, nil
+}
+
+func (s *memStorage) banUser(userID int, reason string, until *time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if p := s.player(userID); p != nil {
+		p.user.IsActive = false
+		p.user.BanReason = sql.NullString{String: reason, Valid: true}
+		p.user.BannedUntil = sql.NullTime{}
+		if until != nil {
+			p.user.BannedUntil = sql.NullTime{Time: *until, Valid: true}
+		}
+	}
+	return nil
+}
+
+func (s *memStorage) unbanUser(userID int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if p := s.player(userID); p != nil {
+		p.user.IsActive = true
+		p.user.BanReason = sql.NullString{}
+		p.user.BannedUntil = sql.NullTime{}
+	}
+	return nil
+}
+
+func (s *memStorage) adjustPlayerStats(playerID, money, rank, level int) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p := s.player(playerID)
+	if p == nil {
+		return 0, sql.ErrNoRows
+	}
+	oldMoney := p.money
+	p.money = nonNegative(p.money + money)
+	p.rank = nonNegative(p.rank + rank)
+	p.level = nonNegative(p.level + level)
+	s.addLedgerEntry(p, p.money-oldMoney, ledgerAdmin, 0)
+	return p.money, nil
+}
+
+func (s *memStorage) getMoneyLedger(playerID, limit int) ([]LedgerEntry, int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	all := s.ledger[playerID]
+	entries := []LedgerEntry{}
+	sum := 0
+	for i := len(all) - 1; i >= 0; i-- {
+		if len(entries) < limit {
+			entries = append(entries, all[i])
+		}
+		sum += all[i].Amount
+	}
+	return entries, sum, nil
+}
+
+func (s *memStorage) claimDailyReward(playerID int, now time.Time) (*DailyRewardInfo, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p := s.player(playerID)
+	if p == nil {
+		return nil, newGameError(ErrCodeInternal)
+	}
+	today := dailyDate(now)
+	d := s.daily[playerID]
+	streak, ok := nextDailyStreak(d.lastClaim, d.streak, today)
+	if !ok {
+		return newDailyRewardInfo(d.streak, today, p.money), nil
+	}
+
+	info := newDailyRewardInfo(streak, today, 0)
+	info.Claimed = true
+	_, reward := dailyRewardFor(streak)
+	info.Money = reward.Money
+	if reward.Background {
+		var cheapest *memBackground
+		for _, b := range s.backgrounds {
+			if !p.backgrounds[b.id] && (cheapest == nil || b.Cost < cheapest.Cost) {
+				cheapest = b
+			}
+		}
+		if cheapest != nil {
+			p.backgrounds[cheapest.id] = true
+			info.Background = cheapest.Name
+		} else {
+			info.Money += dailyBonusMoney
+		}
+	}
+	s.daily[playerID] = memDaily{lastClaim: today, streak: streak}
+	p.money += info.Money
+	info.TotalMoney = p.money
+	s.addLedgerEntry(p, info.Money, ledgerDaily, 0)
+	return info, nil
+}
+
+func (s *memStorage) insertFailedLogin(login, ip, reason string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.audit["FailedLogins"] = append(s.audit["FailedLogins"], auditEntry{Login: login, IP: ip, Action: reason})
+	return nil
+}
+
+func (s *memStorage) insertCheatReport(playerID int, action, events string, total int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.audit["CheatReports"] = append(s.audit["CheatReports"], auditEntry{PlayerID: playerID, Action: action, Details: fmt.Sprintf("%s, total=%d", events, total)})
+	return nil
+}
+
+func (s *memStorage) insertAdminAction(playerID int, action, details, adminIP string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.audit["AdminActions"] = append(s.audit["AdminActions"], auditEntry{PlayerID: playerID, IP: adminIP, Action: action, Details: details})
+	return nil
+}
+
+func nonNegative(v int) int {
+	if v < 0 {
+		return 0
+	}
+	return v
+}
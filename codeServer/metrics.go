@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const healthzTimeout = 2 * time.Second // Время на проверку базы данных в /healthz
+
+var (
+	clientRTT = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "game_client_rtt_seconds",
+		Help:    "Время приёма-передачи пинга клиентов.",
+		Buckets: []float64{.01, .025, .05, .1, .15, .2, .3, .5, 1, 2},
+	})
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "game_db_query_duration_seconds",
+		Help:    "Время выполнения запросов к базе данных.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"op"})
+	dbErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "game_db_errors_total",
+		Help: "Ошибки запросов к базе данных.",
+	}, []string{"op"})
+)
+
+func init() {
+	prometheus.MustRegister(clientRTT, dbDuration, dbErrors)
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "game_authorized_clients",
+			Help: "Авторизованные клиенты.",
+		}, func() float64 {
+			clientsMutex.Lock()
+			defer clientsMutex.Unlock()
+			return float64(len(authorizedClients))
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "game_sessions",
+			Help: "Открытые подключения, включая неавторизованные.",
+		}, func() float64 {
+			sessionsMutex.Lock()
+			defer sessionsMutex.Unlock()
+			return float64(len(sessions))
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "game_queue_length",
+			Help:        "Игроки в очереди на бой.",
+			ConstLabels: prometheus.Labels{"queue": "ranked"},
+		}, func() float64 {
+			return float64(matchmakingQueue.length(true))
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "game_queue_length",
+			Help:        "Игроки в очереди на бой.",
+			ConstLabels: prometheus.Labels{"queue": "level"},
+		}, func() float64 {
+			return float64(matchmakingQueue.length(false))
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "game_active_battles",
+			Help: "Идущие бои.",
+		}, func() float64 {
+			battlesMutex.Lock()
+			defer battlesMutex.Unlock()
+			return float64(len(activeBattles))
+		}),
+	)
+}
+
+// База данных с учётом времени и ошибок запросов. Запросы внутри транзакций не учитываются
+type metricsDB struct {
+	*sqlx.DB
+}
+
+func observeDB(op string, start time.Time, err error) {
+	dbDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		dbErrors.WithLabelValues(op).Inc()
+	}
+}
+
+func (d *metricsDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := d.DB.Exec(query, args...)
+	observeDB("exec", start, err)
+	return res, err
+}
+
+func (d *metricsDB) Get(dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	err := d.DB.Get(dest, query, args...)
+	observeDB("get", start, err)
+	return err
+}
+
+func (d *metricsDB) Select(dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	err := d.DB.Select(dest, query, args...)
+	observeDB("select", start, err)
+	return err
+}
+
+func (d *metricsDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := d.DB.QueryRow(query, args...)
+	observeDB("query_row", start, row.Err())
+	return row
+}
+
+func (d *metricsDB) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	start := time.Now()
+	rows, err := d.DB.Queryx(query, args...)
+	observeDB("query", start, err)
+	return rows, err
+}
+
+func (d *metricsDB) Beginx() (*sqlx.Tx, error) {
+	start := time.Now()
+	tx, err := d.DB.Beginx()
+	observeDB("begin", start, err)
+	return tx, err
+}
+
+// Проверка сервера для балансировщика: база данных доступна и сервер не останавливается
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if isShuttingDown() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), healthzTimeout)
+	defer cancel()
+	if err := store.ping(ctx); err != nil {
+		dbLogger("ping").Error("Проверка /healthz: база данных недоступна", "err", err)
+		http.Error(w, "database unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+// HTTP сервер метрик Prometheus и проверки состояния
+func metricsHandler(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", handleHealthz)
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	registerListener(server)
+	slog.Info("Метрики доступны на /metrics, проверка состояния на /healthz", "addr", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatal("Ошибка запуска сервера метрик", "err", err)
+	}
+}
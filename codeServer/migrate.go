@@ -0,0 +1,147 @@
+package main
+
+import (
+	"codeServer/migrations"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	dbEnvName      = "GAME_DB" // Переменная окружения со строкой подключения к базе данных
+	defaultDSN     = "server=localhost;database=GAME_FQW;trusted_connection=yes"
+	migrateTimeout = 5 * time.Minute
+)
+
+// Строка подключения по умолчанию: переменная GAME_DB или локальный сервер
+func defaultDatabase() string {
+	if dsn := os.Getenv(dbEnvName); dsn != "" {
+		return dsn
+	}
+	return defaultDSN
+}
+
+// Подкоманда migrate: codeServer migrate [-db строка] up [версия] | down [версия] | status.
+// up без версии применяет все миграции, down без версии откатывает последнюю
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dsn := fs.String("db", defaultDatabase(), "строка подключения к базе данных SQL Server")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: codeServer migrate [-db строка] up [версия] | down [версия] | status")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	action := fs.Arg(0)
+	if action == "" {
+		action = "up"
+	}
+	target := -1
+	if fs.NArg() > 1 {
+		version, err := strconv.Atoi(fs.Arg(1))
+		if err != nil || version < 0 {
+			return fmt.Errorf("неверная версия %q", fs.Arg(1))
+		}
+		target = version
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancel()
+
+	if action == "up" {
+		if err := migrations.EnsureDatabase(ctx, *dsn); err != nil {
+			return err
+		}
+	}
+	db, err := sql.Open("sqlserver", *dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch action {
+	case "up":
+		return migrations.Migrate(ctx, db, target)
+
+	case "down":
+		applied, err := migrations.Status(ctx, db)
+		if err != nil {
+			return err
+		}
+		if target < 0 {
+			if len(applied) == 0 {
+				return errors.New("нет применённых миграций")
+			}
+			target = applied[len(applied)-1].Version - 1
+		}
+		return migrations.Migrate(ctx, db, target)
+
+	case "status":
+		return printMigrationStatus(ctx, db)
+
+	default:
+		fs.Usage()
+		return fmt.Errorf("неизвестное действие %q", action)
+	}
+}
+
+// Выводит все миграции и отметку о применении
+func printMigrationStatus(ctx context.Context, db *sql.DB) error {
+	all, err := migrations.All()
+	if err != nil {
+		return err
+	}
+	applied, err := migrations.Status(ctx, db)
+	if err != nil {
+		return err
+	}
+	appliedAt := make(map[int]time.Time, len(applied))
+	for _, a := range applied {
+		appliedAt[a.Version] = a.AppliedAt
+	}
+
+	for _, m := range all {
+		state := "не применена"
+		if at, ok := appliedAt[m.Version]; ok {
+			state = "применена " + at.Format("02.01.2006 15:04")
+		}
+		fmt.Printf("%04d %-20s %s\n", m.Version, m.Name, state)
+	}
+	fmt.Printf("Версия схемы: %d из %d\n", len(applied), len(all))
+	return nil
+}
+
+// Создаёт базу данных при первом запуске сервера
+func createDatabase(dsn string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancel()
+	return migrations.EnsureDatabase(ctx, dsn)
+}
+
+// Приводит базу к последней версии схемы при запуске сервера. Если автоматические
+// миграции выключены, только проверяет, что схема не устарела
+func prepareSchema(db *sql.DB, migrate bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancel()
+
+	if migrate {
+		return migrations.Migrate(ctx, db, -1)
+	}
+	latest, err := migrations.Latest()
+	if err != nil {
+		return err
+	}
+	applied, err := migrations.Status(ctx, db)
+	if err != nil {
+		return err
+	}
+	if len(applied) != latest {
+		return fmt.Errorf("версия схемы %d, сервер ожидает %d: выполните codeServer migrate up", len(applied), latest)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+-- Удаляет исходную схему вместе со всеми данными
+
+DROP TRIGGER IF EXISTS trg_InsertListBackgrounds;
+DROP TRIGGER IF EXISTS trg_InsertListCharacters;
+DROP TRIGGER IF EXISTS trg_HandleBattlesOnPlayerDelete;
+DROP TRIGGER IF EXISTS trg_DeleteFriendsOnDelete;
+
+GO
+
+DROP PROCEDURE IF EXISTS getUserData;
+DROP PROCEDURE IF EXISTS getAssetsCharacter;
+DROP PROCEDURE IF EXISTS getCharacter;
+DROP PROCEDURE IF EXISTS GetFriendsAndRequests;
+DROP PROCEDURE IF EXISTS RequestFriendship;
+DROP PROCEDURE IF EXISTS AcceptFriendship;
+DROP PROCEDURE IF EXISTS DeclineFriendship;
+DROP PROCEDURE IF EXISTS RemoveFriendship;
+DROP PROCEDURE IF EXISTS SaveBattleResults;
+DROP PROCEDURE IF EXISTS UpdatePlayerStats;
+DROP PROCEDURE IF EXISTS GetPlayerBattleStats;
+DROP PROCEDURE IF EXISTS GetShopCharacters;
+DROP PROCEDURE IF EXISTS GetShopBackgrounds;
+DROP PROCEDURE IF EXISTS BuyBackground;
+DROP PROCEDURE IF EXISTS SelectBackground;
+DROP PROCEDURE IF EXISTS BuyCharacter;
+DROP PROCEDURE IF EXISTS SelectCharacter;
+
+GO
+
+DROP TABLE IF EXISTS Battles;
+DROP TABLE IF EXISTS Friends;
+DROP TABLE IF EXISTS List_Characters;
+DROP TABLE IF EXISTS List_Backgrounds;
+DROP TABLE IF EXISTS Players;
+DROP TABLE IF EXISTS Assets_Characters;
+DROP TABLE IF EXISTS Characters;
+DROP TABLE IF EXISTS Backgrounds;
+DROP TABLE IF EXISTS Users;
@@ -1,12 +1,7 @@
+-- Исходная схема: таблицы, процедуры и триггеры игры.
+-- Таблицы создаются только если их нет, поэтому миграция подходит и для базы, созданной вручную из FQW.sql
 
-CREATE DATABASE GAME_FQW
-
-GO
-
-use GAME_FQW
-
-GO
-
+IF OBJECT_ID(N'Users', N'U') IS NULL
 CREATE TABLE Users
 (
 	id_User INT IDENTITY(1,1) PRIMARY KEY,
@@ -17,6 +12,7 @@ CREATE TABLE Users
 
 GO
 
+IF OBJECT_ID(N'Backgrounds', N'U') IS NULL
 CREATE TABLE Backgrounds
 (
 	id_Background INT IDENTITY(1,1) PRIMARY KEY,
@@ -28,6 +24,7 @@ CREATE TABLE Backgrounds
 
 GO
 
+IF OBJECT_ID(N'Characters', N'U') IS NULL
 CREATE TABLE Characters
 (
 	id_Character INT IDENTITY(1,1) PRIMARY KEY,
@@ -40,6 +37,7 @@ CREATE TABLE Characters
 
 GO
 
+IF OBJECT_ID(N'Assets_Characters', N'U') IS NULL
 CREATE TABLE Assets_Characters
 (
 	id_AC INT IDENTITY(1,1) PRIMARY KEY,
@@ -55,6 +53,7 @@ CREATE TABLE Assets_Characters
 
 GO
 
+IF OBJECT_ID(N'Players', N'U') IS NULL
 CREATE TABLE Players
 (
 	id_Player INT IDENTITY(1,1) PRIMARY KEY,
@@ -73,6 +72,7 @@ CREATE TABLE Players
 
 GO
 
+IF OBJECT_ID(N'List_Backgrounds', N'U') IS NULL
 CREATE TABLE List_Backgrounds
 (
 	id_LB INT IDENTITY(1,1) PRIMARY KEY,
@@ -84,6 +84,7 @@ CREATE TABLE List_Backgrounds
 
 GO
 
+IF OBJECT_ID(N'List_Characters', N'U') IS NULL
 CREATE TABLE List_Characters
 (
 	id_LC INT IDENTITY(1,1) PRIMARY KEY,
@@ -95,12 +96,12 @@ CREATE TABLE List_Characters
 
 GO
 
-
+IF OBJECT_ID(N'Friends', N'U') IS NULL
 CREATE TABLE Friends
 (
     id_Player INT NOT NULL,           
     id_Friend INT NOT NULL,           
-    IsConfirmed BIT NOT NULL,         -- 1   , 0   
+    IsConfirmed BIT NOT NULL,         -- 1 — дружба подтверждена, 0 — заявка 
     PRIMARY KEY (id_Player, id_Friend), 
     FOREIGN KEY (id_Player) REFERENCES Players(id_Player) ON DELETE NO ACTION,  
     FOREIGN KEY (id_Friend) REFERENCES Players(id_Player) ON DELETE NO ACTION   
@@ -108,6 +109,7 @@ CREATE TABLE Friends
 
 GO
 
+IF OBJECT_ID(N'Battles', N'U') IS NULL
 CREATE TABLE Battles
 (
     id_Battle INT IDENTITY(1,1) PRIMARY KEY,
@@ -124,8 +126,7 @@ CREATE TABLE Battles
 
 GO
 
-
---------------------------------
+--------------------------------Процедуры
 CREATE OR ALTER PROCEDURE getUserData
     @Id_User INT = NULL
 AS
@@ -173,7 +174,7 @@ AS
 BEGIN
     SET NOCOUNT ON;
 
-    --  
+    -- Подтверждённые друзья
     SELECT 
         p.Name AS Name,
         p.PublicID AS PublicID
@@ -186,7 +187,7 @@ BEGIN
     WHERE (f.id_Player = @PlayerID OR f.id_Friend = @PlayerID)
         AND f.IsConfirmed = 1;
 
-    --  
+    -- Входящие заявки
     SELECT 
         p.Name AS Name,
         p.PublicID AS PublicID
@@ -194,7 +195,7 @@ BEGIN
     JOIN Players p ON p.id_Player = f.id_Player
     WHERE f.id_Friend = @PlayerID AND f.IsConfirmed = 0;
 
-    --  
+    -- Исходящие заявки
     SELECT 
         p.Name AS Name,
         p.PublicID AS PublicID
@@ -218,36 +219,36 @@ BEGIN
 
     IF @RequesterPlayerID IS NULL OR @FriendPlayerID IS NULL OR @RequesterPlayerID = @FriendPlayerID
     BEGIN
-        SET @Result = -1; --      
+        SET @Result = -1; -- Неверный игрок или попытка добавить себя
         SELECT @Result AS Result;
         RETURN;
     END
 
-    -- ,     
+    -- Проверяем, есть ли уже исходящая заявка
     IF EXISTS (SELECT 1 FROM Friends WHERE id_Player = @RequesterPlayerID AND id_Friend = @FriendPlayerID)
     BEGIN
-        SET @Result = -2; --    
+        SET @Result = -2; -- Уже есть исходящая заявка
         SELECT @Result AS Result;
         RETURN;
     END
 
-    --   
+    -- Автоподтверждение входящей заявки
     IF EXISTS (SELECT 1 FROM Friends WHERE id_Player = @FriendPlayerID AND id_Friend = @RequesterPlayerID AND IsConfirmed = 0)
     BEGIN
         UPDATE Friends 
         SET IsConfirmed = 1 
         WHERE id_Player = @FriendPlayerID AND id_Friend = @RequesterPlayerID;
         
-        SET @Result = 1; --  
+        SET @Result = 1; -- Заявка подтверждена
         SELECT @Result AS Result;
         RETURN;
     END
 
-    --  
+    -- Новая заявка
     INSERT INTO Friends (id_Player, id_Friend, IsConfirmed) 
     VALUES (@RequesterPlayerID, @FriendPlayerID, 0);
 
-    SET @Result = 0; --  
+    SET @Result = 0; -- Заявка создана
     SELECT @Result AS Result;
 END;
 
@@ -290,7 +291,6 @@ BEGIN
       AND IsConfirmed = 0;
 END;
 
-
 GO
 
 CREATE OR ALTER PROCEDURE RemoveFriendship
@@ -356,14 +356,14 @@ AS
 BEGIN
     SET NOCOUNT ON;
 
-    --  30    
+    -- Последние 30 боёв с результатом игрока
     SELECT TOP 30
         B.StartTime,
         B.EndTime,
         CASE 
-            WHEN B.id_Winner IS NULL THEN ''
-            WHEN B.id_Winner = @PlayerID THEN ''
-            ELSE ''
+            WHEN B.id_Winner IS NULL THEN 'Ничья'
+            WHEN B.id_Winner = @PlayerID THEN 'Победа'
+            ELSE 'Поражение'
         END AS BattleResult,
         
         P1.Name AS PlayerName,
@@ -377,7 +377,7 @@ BEGIN
     WHERE B.isRanked = @IsRanked AND (B.id_Player = @PlayerID OR B.id_Opponent = @PlayerID)
     ORDER BY B.StartTime DESC;
 
-    -- 
+    -- Статистика
     SELECT
         ISNULL(SUM(CASE WHEN B.id_Winner = @PlayerID THEN 1 ELSE 0 END), 0) AS Wins,
         ISNULL(SUM(CASE WHEN B.id_Winner = CASE WHEN B.id_Player = @PlayerID THEN B.id_Opponent ELSE B.id_Player END THEN 1 ELSE 0 END), 0) AS Losses,
@@ -394,7 +394,7 @@ AS
 BEGIN
     SET NOCOUNT ON;
 
-    --  ,    
+    -- Получаем персонажей, которые есть у игрока
     SELECT 
         c.id_Character,
         c.Name,
@@ -412,7 +412,7 @@ BEGIN
     ) ac
     WHERE lc.id_Player = @PlayerID;
 
-    --  ,    
+    -- Получаем персонажей, которых у игрока нет
     SELECT 
         c.id_Character,
         c.Name,
@@ -442,7 +442,7 @@ AS
 BEGIN
     SET NOCOUNT ON;
 
-    -- ,    
+    -- Фоны, которые есть у игрока
     SELECT 
         b.id_Background,
         b.Name,
@@ -453,7 +453,7 @@ BEGIN
     JOIN Backgrounds b ON lb.id_Background = b.id_Background
     WHERE lb.id_Player = @PlayerID;
 
-    -- ,    
+    -- Фоны, которых нет у игрока
     SELECT 
         b.id_Background,
         b.Name,
@@ -474,7 +474,7 @@ CREATE OR ALTER PROCEDURE BuyBackground
     @PlayerID INT,
     @BackgroundID INT,
     @RemainingMoney INT OUTPUT,
-    @ResultCode INT OUTPUT -- 0 = , 1 =   , 2 =  , 3 =   , 4 = 
+    @ResultCode INT OUTPUT -- 0 = успех, 1 = фон не найден, 2 = уже куплен, 3 = не хватает денег, 4 = ошибка
 AS
 BEGIN
     SET NOCOUNT ON;
@@ -482,7 +482,7 @@ BEGIN
     BEGIN TRY
         BEGIN TRANSACTION;
 
-        -- ,   
+        -- Проверяем, что фон существует
         IF NOT EXISTS (SELECT 1 FROM Backgrounds WHERE id_Background = @BackgroundID)
         BEGIN
             SET @ResultCode = 1;
@@ -490,7 +490,7 @@ BEGIN
             RETURN;
         END
 
-        -- ,     
+        -- Проверяем, что фон еще не куплен
         IF EXISTS (
             SELECT 1 FROM List_Backgrounds 
             WHERE id_Player = @PlayerID AND id_Background = @BackgroundID
@@ -507,7 +507,7 @@ BEGIN
         DECLARE @CurrentMoney INT;
         SELECT @CurrentMoney = Money FROM Players WHERE id_Player = @PlayerID;
 
-        --    
+        -- Проверяем хватает ли денег
         IF @CurrentMoney < @Cost
         BEGIN
             SET @ResultCode = 3;
@@ -515,16 +515,16 @@ BEGIN
             RETURN;
         END
 
-        --  
+        -- Снимаем деньги
         UPDATE Players
         SET Money = Money - @Cost
         WHERE id_Player = @PlayerID;
 
-        --   
+        -- Добавляем фон игроку
         INSERT INTO List_Backgrounds (id_Player, id_Background)
         VALUES (@PlayerID, @BackgroundID);
 
-        --   
+        -- Возвращаем оставшиеся деньги
         SELECT @RemainingMoney = Money
         FROM Players
         WHERE id_Player = @PlayerID;
@@ -544,21 +544,21 @@ CREATE OR ALTER PROCEDURE SelectBackground
     @PlayerID INT,
     @BackgroundID INT,
     @AssetPath NVARCHAR(255) OUTPUT,
-    @ResultCode INT OUTPUT -- 0 = , 1 =   , 2 =   , 3 = 
+    @ResultCode INT OUTPUT -- 0 = успех, 1 = фон не найден, 2 = фон не куплен, 3 = ошибка
 AS
 BEGIN
     SET NOCOUNT ON;
     SET @AssetPath = NULL;
 
     BEGIN TRY
-        -- ,   
+        -- Проверяем, что фон существует
         IF NOT EXISTS (SELECT 1 FROM Backgrounds WHERE id_Background = @BackgroundID)
         BEGIN
             SET @ResultCode = 1;
             RETURN;
         END
 
-        -- ,    
+        -- Проверяем, что фон куплен игроком
         IF NOT EXISTS (
             SELECT 1 FROM List_Backgrounds 
             WHERE id_Player = @PlayerID AND id_Background = @BackgroundID
@@ -568,12 +568,12 @@ BEGIN
             RETURN;
         END
 
-        --    
+        -- Обновляем выбранный активный фон
         UPDATE Players
         SET id_ActiveBackground = @BackgroundID
         WHERE id_Player = @PlayerID;
 
-        --     
+        -- Возвращаем путь к ассету фона
         SELECT @AssetPath = AssetPath
         FROM Backgrounds
         WHERE id_Background = @BackgroundID;
@@ -591,7 +591,7 @@ CREATE OR ALTER PROCEDURE BuyCharacter
     @PlayerID INT,
     @CharacterID INT,
     @RemainingMoney INT OUTPUT,
-    @ResultCode INT OUTPUT -- 0 = , 1 =   , 2 =  , 3 =   , 4 = 
+    @ResultCode INT OUTPUT -- 0 = успех, 1 = персонаж не найден, 2 = уже куплен, 3 = не хватает денег, 4 = ошибка
 AS
 BEGIN
     SET NOCOUNT ON;
@@ -600,7 +600,7 @@ BEGIN
     BEGIN TRY
         BEGIN TRANSACTION;
 
-        -- ,   
+        -- Проверяем, что персонаж существует
         IF NOT EXISTS (SELECT 1 FROM Characters WHERE id_Character = @CharacterID)
         BEGIN
             SET @ResultCode = 1; 
@@ -608,7 +608,7 @@ BEGIN
             RETURN;
         END
 
-        -- ,      
+        -- Проверяем, что персонаж не куплен уже игроком
         IF EXISTS (
             SELECT 1 FROM List_Characters 
             WHERE id_Player = @PlayerID AND id_Character = @CharacterID
@@ -619,15 +619,15 @@ BEGIN
             RETURN;
         END
 
-        --   
+        -- Получаем стоимость персонажа
         DECLARE @Cost INT;
         SELECT @Cost = Cost FROM Characters WHERE id_Character = @CharacterID;
 
-        --    
+        -- Получаем текущие деньги игрока
         DECLARE @CurrentMoney INT;
         SELECT @CurrentMoney = Money FROM Players WHERE id_Player = @PlayerID;
 
-        --    
+        -- Проверяем хватает ли денег
         IF @CurrentMoney < @Cost
         BEGIN
             SET @ResultCode = 3; 
@@ -635,16 +635,16 @@ BEGIN
             RETURN;
         END
 
-        --   
+        -- Списываем деньги игрока
         UPDATE Players
         SET Money = Money - @Cost
         WHERE id_Player = @PlayerID;
 
-        --     
+        -- Добавляем персонажа в список купленных
         INSERT INTO List_Characters (id_Player, id_Character)
         VALUES (@PlayerID, @CharacterID);
 
-        --   
+        -- Возвращаем оставшиеся деньги
         SELECT @RemainingMoney = Money FROM Players WHERE id_Player = @PlayerID;
 
         SET @ResultCode = 0; 
@@ -661,20 +661,20 @@ GO
 CREATE OR ALTER PROCEDURE SelectCharacter
     @PlayerID INT,
     @CharacterID INT,
-    @ResultCode INT OUTPUT -- 0 = , 1 =   , 2 =  , 3 = 
+    @ResultCode INT OUTPUT -- 0 = успех, 1 = персонаж не найден, 2 = не куплен, 3 = ошибка
 AS
 BEGIN
     SET NOCOUNT ON;
 
     BEGIN TRY
-        -- ,   
+        -- Проверка, что персонаж существует
         IF NOT EXISTS (SELECT 1 FROM Characters WHERE id_Character = @CharacterID)
         BEGIN
             SET @ResultCode = 1;
             RETURN;
         END
 
-        -- ,    
+        -- Проверка, что персонаж куплен игроком
         IF NOT EXISTS (
             SELECT 1 FROM List_Characters 
             WHERE id_Player = @PlayerID AND id_Character = @CharacterID
@@ -684,7 +684,7 @@ BEGIN
             RETURN;
         END
 
-        --     
+        -- Установка активного персонажа для игрока
         UPDATE Players
         SET id_ActiveCharacter = @CharacterID
         WHERE id_Player = @PlayerID;
@@ -697,8 +697,8 @@ BEGIN
 END;
 
 GO
---------------------------------
-CREATE TRIGGER trg_InsertListBackgrounds
+--------------------------------Триггеры
+CREATE OR ALTER TRIGGER trg_InsertListBackgrounds
 ON Players
 AFTER INSERT
 AS
@@ -715,7 +715,7 @@ END;
 
 GO
 
-CREATE TRIGGER trg_InsertListCharacters
+CREATE OR ALTER TRIGGER trg_InsertListCharacters
 ON Players
 AFTER INSERT
 AS
@@ -732,7 +732,7 @@ END;
 
 GO
 
-CREATE TRIGGER trg_HandleBattlesOnPlayerDelete
+CREATE OR ALTER TRIGGER trg_HandleBattlesOnPlayerDelete
 ON Players
 AFTER DELETE
 AS
@@ -752,7 +752,7 @@ BEGIN
 END;
 GO
 
-CREATE TRIGGER trg_DeleteFriendsOnDelete
+CREATE OR ALTER TRIGGER trg_DeleteFriendsOnDelete
 ON Players
 AFTER DELETE
 AS
@@ -761,4 +761,3 @@ BEGIN
     WHERE id_Player IN (SELECT id_Player FROM deleted)
        OR id_Friend IN (SELECT id_Player FROM deleted);
 END
-
@@ -0,0 +1,15 @@
+-- Журнал неудачных входов
+IF OBJECT_ID(N'FailedLogins', N'U') IS NULL
+CREATE TABLE FailedLogins
+(
+    id_FailedLogin INT IDENTITY(1,1) PRIMARY KEY,
+    Login NVARCHAR(50) NOT NULL,
+    IP NVARCHAR(64) NOT NULL,
+    Reason NVARCHAR(20) NOT NULL,     -- unknown_login, wrong_password, blocked, locked
+    AttemptTime DATETIME NOT NULL DEFAULT GETDATE()
+)
+
+GO
+
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_FailedLogins_Login' AND object_id = OBJECT_ID(N'FailedLogins'))
+CREATE INDEX IX_FailedLogins_Login ON FailedLogins (Login, AttemptTime)
@@ -0,0 +1,12 @@
+-- Отчёты античита
+IF OBJECT_ID(N'CheatReports', N'U') IS NULL
+CREATE TABLE CheatReports
+(
+    id_CheatReport INT IDENTITY(1,1) PRIMARY KEY,
+    id_Player INT NOT NULL,
+    Action NVARCHAR(10) NOT NULL,     -- flag — отчёт, kick — игрок отключён
+    Events NVARCHAR(500) NOT NULL,    -- Количество событий по типам: air_jump=3, direction_flip=12
+    Total INT NOT NULL,
+    ReportTime DATETIME NOT NULL DEFAULT GETDATE(),
+    FOREIGN KEY (id_Player) REFERENCES Players(id_Player) ON DELETE CASCADE
+)
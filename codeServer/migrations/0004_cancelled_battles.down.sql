@@ -0,0 +1,70 @@
+CREATE OR ALTER PROCEDURE SaveBattleResults
+    @Player1ID INT,
+    @Player2ID INT,
+    @WinnerID INT = NULL,
+    @StartTime DATETIME,
+    @EndTime DATETIME,
+    @IsRanked BIT
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    INSERT INTO Battles (id_Player, id_Opponent, id_Winner, StartTime, EndTime, isRanked)
+    VALUES (@Player1ID, @Player2ID, @WinnerID, @StartTime, @EndTime, @IsRanked);
+END;
+
+GO
+
+CREATE OR ALTER PROCEDURE GetPlayerBattleStats
+    @PlayerID INT,
+    @IsRanked BIT
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    -- Последние 30 боёв с результатом игрока
+    SELECT TOP 30
+        B.StartTime,
+        B.EndTime,
+        CASE 
+            WHEN B.id_Winner IS NULL THEN 'Ничья'
+            WHEN B.id_Winner = @PlayerID THEN 'Победа'
+            ELSE 'Поражение'
+        END AS BattleResult,
+        
+        P1.Name AS PlayerName,
+        P1.PublicID AS PlayerPublicID,
+
+        P2.Name AS OpponentName,
+        P2.PublicID AS OpponentPublicID
+    FROM Battles B
+    LEFT JOIN Players P1 ON B.id_Player = P1.id_Player
+    LEFT JOIN Players P2 ON B.id_Opponent = P2.id_Player
+    WHERE B.isRanked = @IsRanked AND (B.id_Player = @PlayerID OR B.id_Opponent = @PlayerID)
+    ORDER BY B.StartTime DESC;
+
+    -- Статистика
+    SELECT
+        ISNULL(SUM(CASE WHEN B.id_Winner = @PlayerID THEN 1 ELSE 0 END), 0) AS Wins,
+        ISNULL(SUM(CASE WHEN B.id_Winner = CASE WHEN B.id_Player = @PlayerID THEN B.id_Opponent ELSE B.id_Player END THEN 1 ELSE 0 END), 0) AS Losses,
+        ISNULL(SUM(CASE WHEN B.id_Winner IS NULL THEN 1 ELSE 0 END), 0) AS Draws
+    FROM Battles B
+    WHERE B.isRanked = @IsRanked AND (B.id_Player = @PlayerID OR B.id_Opponent = @PlayerID);
+END;
+
+GO
+
+IF COL_LENGTH(N'Battles', N'isCancelled') IS NOT NULL
+BEGIN
+    EXEC(N'DELETE FROM Battles WHERE isCancelled = 1');
+
+    DECLARE @Constraint SYSNAME = (
+        SELECT dc.name
+        FROM sys.default_constraints dc
+        JOIN sys.columns c ON c.object_id = dc.parent_object_id AND c.column_id = dc.parent_column_id
+        WHERE dc.parent_object_id = OBJECT_ID(N'Battles') AND c.name = N'isCancelled');
+    IF @Constraint IS NOT NULL
+        EXEC(N'ALTER TABLE Battles DROP CONSTRAINT ' + QUOTENAME(@Constraint));
+
+    ALTER TABLE Battles DROP COLUMN isCancelled;
+END;
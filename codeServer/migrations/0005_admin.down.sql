@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS AdminActions;
+
+GO
+
+IF COL_LENGTH(N'Users', N'BannedUntil') IS NOT NULL
+ALTER TABLE Users DROP COLUMN BannedUntil;
+
+IF COL_LENGTH(N'Users', N'BanReason') IS NOT NULL
+ALTER TABLE Users DROP COLUMN BanReason;
@@ -0,0 +1,22 @@
+-- Блокировки с причиной и сроком, журнал действий администратора
+IF COL_LENGTH(N'Users', N'BanReason') IS NULL
+ALTER TABLE Users ADD BanReason NVARCHAR(200) NULL
+
+GO
+
+IF COL_LENGTH(N'Users', N'BannedUntil') IS NULL
+ALTER TABLE Users ADD BannedUntil DATETIME NULL -- NULL при isActive = 0 означает блокировку без срока
+
+GO
+
+IF OBJECT_ID(N'AdminActions', N'U') IS NULL
+CREATE TABLE AdminActions
+(
+    id_AdminAction INT IDENTITY(1,1) PRIMARY KEY,
+    id_Player INT NOT NULL,
+    Action NVARCHAR(10) NOT NULL,     -- ban, unban, kick, adjust
+    Details NVARCHAR(500) NOT NULL,
+    AdminIP NVARCHAR(64) NOT NULL,
+    ActionTime DATETIME NOT NULL DEFAULT GETDATE(),
+    FOREIGN KEY (id_Player) REFERENCES Players(id_Player) ON DELETE CASCADE
+)
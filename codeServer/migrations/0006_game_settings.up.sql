@@ -0,0 +1,20 @@
+-- Настройки контента: версия, загруженная командой seed, и стартовые предметы новых игроков
+IF OBJECT_ID(N'GameSettings', N'U') IS NULL
+CREATE TABLE GameSettings
+(
+	id_Settings TINYINT PRIMARY KEY CHECK (id_Settings = 1), -- Единственная строка
+	ContentVersion INT NOT NULL DEFAULT 0,
+	id_StarterBackground INT NOT NULL,
+	id_StarterCharacter INT NOT NULL,
+	SeededAt DATETIME NULL,
+	FOREIGN KEY (id_StarterBackground) REFERENCES Backgrounds(id_Background),
+	FOREIGN KEY (id_StarterCharacter) REFERENCES Characters(id_Character)
+)
+
+GO
+
+-- В базе, заполненной вручную, сохраняются прежние стартовые предметы
+INSERT INTO GameSettings (id_Settings, ContentVersion, id_StarterBackground, id_StarterCharacter)
+SELECT TOP 1 1, 0, b.id_Background, c.id_Character
+FROM Backgrounds b CROSS JOIN Characters c
+WHERE b.Name = N'Рынок' AND c.Name = N'Девушка рыцарь' AND NOT EXISTS (SELECT 1 FROM GameSettings)
@@ -0,0 +1,184 @@
+-- Прежние версии процедур без записи в журнал денег
+CREATE OR ALTER PROCEDURE SaveBattleResults
+    @Player1ID INT,
+    @Player2ID INT,
+    @WinnerID INT = NULL,
+    @StartTime DATETIME,
+    @EndTime DATETIME,
+    @IsRanked BIT,
+    @IsCancelled BIT = 0
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    INSERT INTO Battles (id_Player, id_Opponent, id_Winner, StartTime, EndTime, isRanked, isCancelled)
+    VALUES (@Player1ID, @Player2ID, @WinnerID, @StartTime, @EndTime, @IsRanked, @IsCancelled);
+END;
+
+GO
+
+CREATE OR ALTER PROCEDURE UpdatePlayerStats
+    @PlayerID INT,
+    @NewLevel INT,
+    @NewRank INT,
+    @NewMoney INT
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    UPDATE Players
+    SET
+        Level = @NewLevel,
+        Rank = @NewRank,
+        Money = @NewMoney
+    WHERE id_Player = @PlayerID;
+END;
+
+GO
+
+CREATE OR ALTER PROCEDURE BuyBackground
+    @PlayerID INT,
+    @BackgroundID INT,
+    @RemainingMoney INT OUTPUT,
+    @ResultCode INT OUTPUT -- 0 = успех, 1 = фон не найден, 2 = уже куплен, 3 = не хватает денег, 4 = ошибка
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    BEGIN TRY
+        BEGIN TRANSACTION;
+
+        -- Проверяем, что фон существует
+        IF NOT EXISTS (SELECT 1 FROM Backgrounds WHERE id_Background = @BackgroundID)
+        BEGIN
+            SET @ResultCode = 1;
+            ROLLBACK TRANSACTION;
+            RETURN;
+        END
+
+        -- Проверяем, что фон еще не куплен
+        IF EXISTS (
+            SELECT 1 FROM List_Backgrounds 
+            WHERE id_Player = @PlayerID AND id_Background = @BackgroundID
+        )
+        BEGIN
+            SET @ResultCode = 2;
+            ROLLBACK TRANSACTION;
+            RETURN;
+        END
+
+        DECLARE @Cost INT;
+        SELECT @Cost = Cost FROM Backgrounds WHERE id_Background = @BackgroundID;
+
+        DECLARE @CurrentMoney INT;
+        SELECT @CurrentMoney = Money FROM Players WHERE id_Player = @PlayerID;
+
+        -- Проверяем хватает ли денег
+        IF @CurrentMoney < @Cost
+        BEGIN
+            SET @ResultCode = 3;
+            ROLLBACK TRANSACTION;
+            RETURN;
+        END
+
+        -- Снимаем деньги
+        UPDATE Players
+        SET Money = Money - @Cost
+        WHERE id_Player = @PlayerID;
+
+        -- Добавляем фон игроку
+        INSERT INTO List_Backgrounds (id_Player, id_Background)
+        VALUES (@PlayerID, @BackgroundID);
+
+        -- Возвращаем оставшиеся деньги
+        SELECT @RemainingMoney = Money
+        FROM Players
+        WHERE id_Player = @PlayerID;
+
+        SET @ResultCode = 0;
+        COMMIT TRANSACTION;
+    END TRY
+    BEGIN CATCH
+        ROLLBACK TRANSACTION;
+        SET @ResultCode = 4;
+    END CATCH
+END;
+
+GO
+
+CREATE OR ALTER PROCEDURE BuyCharacter
+    @PlayerID INT,
+    @CharacterID INT,
+    @RemainingMoney INT OUTPUT,
+    @ResultCode INT OUTPUT -- 0 = успех, 1 = персонаж не найден, 2 = уже куплен, 3 = не хватает денег, 4 = ошибка
+AS
+BEGIN
+    SET NOCOUNT ON;
+    SET @RemainingMoney = NULL;
+
+    BEGIN TRY
+        BEGIN TRANSACTION;
+
+        -- Проверяем, что персонаж существует
+        IF NOT EXISTS (SELECT 1 FROM Characters WHERE id_Character = @CharacterID)
+        BEGIN
+            SET @ResultCode = 1; 
+            ROLLBACK TRANSACTION;
+            RETURN;
+        END
+
+        -- Проверяем, что персонаж не куплен уже игроком
+        IF EXISTS (
+            SELECT 1 FROM List_Characters 
+            WHERE id_Player = @PlayerID AND id_Character = @CharacterID
+        )
+        BEGIN
+            SET @ResultCode = 2; 
+            ROLLBACK TRANSACTION;
+            RETURN;
+        END
+
+        -- Получаем стоимость персонажа
+        DECLARE @Cost INT;
+        SELECT @Cost = Cost FROM Characters WHERE id_Character = @CharacterID;
+
+        -- Получаем текущие деньги игрока
+        DECLARE @CurrentMoney INT;
+        SELECT @CurrentMoney = Money FROM Players WHERE id_Player = @PlayerID;
+
+        -- Проверяем хватает ли денег
+        IF @CurrentMoney < @Cost
+        BEGIN
+            SET @ResultCode = 3; 
+            ROLLBACK TRANSACTION;
+            RETURN;
+        END
+
+        -- Списываем деньги игрока
+        UPDATE Players
+        SET Money = Money - @Cost
+        WHERE id_Player = @PlayerID;
+
+        -- Добавляем персонажа в список купленных
+        INSERT INTO List_Characters (id_Player, id_Character)
+        VALUES (@PlayerID, @CharacterID);
+
+        -- Возвращаем оставшиеся деньги
+        SELECT @RemainingMoney = Money FROM Players WHERE id_Player = @PlayerID;
+
+        SET @ResultCode = 0; 
+        COMMIT TRANSACTION;
+    END TRY
+    BEGIN CATCH
+        ROLLBACK TRANSACTION;
+        SET @ResultCode = 4; 
+    END CATCH
+END;
+
+GO
+
+DROP TRIGGER IF EXISTS TR_MoneyLedger_AppendOnly;
+
+GO
+
+DROP TABLE IF EXISTS MoneyLedger;
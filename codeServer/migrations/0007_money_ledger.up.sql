@@ -0,0 +1,242 @@
+-- Журнал денег: каждое изменение баланса игрока с причиной, ссылкой и балансом после изменения
+IF OBJECT_ID(N'MoneyLedger', N'U') IS NULL
+CREATE TABLE MoneyLedger
+(
+	id_Entry BIGINT IDENTITY(1,1) PRIMARY KEY,
+	id_Player INT NOT NULL,               -- Без внешнего ключа, чтобы история сохранялась после удаления игрока
+	Amount INT NOT NULL,                  -- Положительное начисление или отрицательное списание
+	BalanceAfter INT NOT NULL,
+	Reason NVARCHAR(20) NOT NULL,         -- start, opening, battle, buy_background, buy_character, admin, sync
+	Reference INT NULL,                   -- id_Battle для боя, id_Background или id_Character для покупки
+	EntryTime DATETIME NOT NULL DEFAULT GETDATE()
+)
+
+GO
+
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_MoneyLedger_Player' AND object_id = OBJECT_ID(N'MoneyLedger'))
+CREATE INDEX IX_MoneyLedger_Player ON MoneyLedger (id_Player, id_Entry)
+
+GO
+
+-- Журнал только дополняется
+CREATE OR ALTER TRIGGER TR_MoneyLedger_AppendOnly
+ON MoneyLedger
+INSTEAD OF UPDATE, DELETE
+AS
+BEGIN
+    THROW 50001, N'Журнал денег нельзя изменять', 1;
+END;
+
+GO
+
+-- Начальный баланс игроков, созданных до появления журнала
+INSERT INTO MoneyLedger (id_Player, Amount, BalanceAfter, Reason)
+SELECT p.id_Player, p.Money, p.Money, N'opening'
+FROM Players p
+WHERE p.Money <> 0 AND NOT EXISTS (SELECT 1 FROM MoneyLedger l WHERE l.id_Player = p.id_Player)
+
+GO
+
+CREATE OR ALTER PROCEDURE SaveBattleResults
+    @Player1ID INT,
+    @Player2ID INT,
+    @WinnerID INT = NULL,
+    @StartTime DATETIME,
+    @EndTime DATETIME,
+    @IsRanked BIT,
+    @IsCancelled BIT = 0
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    INSERT INTO Battles (id_Player, id_Opponent, id_Winner, StartTime, EndTime, isRanked, isCancelled)
+    VALUES (@Player1ID, @Player2ID, @WinnerID, @StartTime, @EndTime, @IsRanked, @IsCancelled);
+
+    -- Номер боя для ссылок в журнале денег
+    SELECT CAST(SCOPE_IDENTITY() AS INT) AS id_Battle;
+END;
+
+GO
+
+CREATE OR ALTER PROCEDURE UpdatePlayerStats
+    @PlayerID INT,
+    @NewLevel INT,
+    @NewRank INT,
+    @NewMoney INT,
+    @Reason NVARCHAR(20) = N'sync', -- Причина изменения денег для журнала
+    @Reference INT = NULL
+AS
+BEGIN
+    SET NOCOUNT ON;
+    SET XACT_ABORT ON;
+
+    BEGIN TRANSACTION;
+
+    DECLARE @OldMoney INT;
+    SELECT @OldMoney = Money FROM Players WITH (UPDLOCK) WHERE id_Player = @PlayerID;
+
+    UPDATE Players
+    SET
+        Level = @NewLevel,
+        Rank = @NewRank,
+        Money = @NewMoney
+    WHERE id_Player = @PlayerID;
+
+    -- Разница с прежним балансом попадает в журнал денег
+    IF @OldMoney IS NOT NULL AND @OldMoney <> @NewMoney
+        INSERT INTO MoneyLedger (id_Player, Amount, BalanceAfter, Reason, Reference)
+        VALUES (@PlayerID, @NewMoney - @OldMoney, @NewMoney, @Reason, @Reference);
+
+    COMMIT TRANSACTION;
+END;
+
+GO
+
+CREATE OR ALTER PROCEDURE BuyBackground
+    @PlayerID INT,
+    @BackgroundID INT,
+    @RemainingMoney INT OUTPUT,
+    @ResultCode INT OUTPUT -- 0 = успех, 1 = фон не найден, 2 = уже куплен, 3 = не хватает денег, 4 = ошибка
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    BEGIN TRY
+        BEGIN TRANSACTION;
+
+        -- Проверяем, что фон существует
+        IF NOT EXISTS (SELECT 1 FROM Backgrounds WHERE id_Background = @BackgroundID)
+        BEGIN
+            SET @ResultCode = 1;
+            ROLLBACK TRANSACTION;
+            RETURN;
+        END
+
+        -- Проверяем, что фон еще не куплен
+        IF EXISTS (
+            SELECT 1 FROM List_Backgrounds 
+            WHERE id_Player = @PlayerID AND id_Background = @BackgroundID
+        )
+        BEGIN
+            SET @ResultCode = 2;
+            ROLLBACK TRANSACTION;
+            RETURN;
+        END
+
+        DECLARE @Cost INT;
+        SELECT @Cost = Cost FROM Backgrounds WHERE id_Background = @BackgroundID;
+
+        DECLARE @CurrentMoney INT;
+        SELECT @CurrentMoney = Money FROM Players WHERE id_Player = @PlayerID;
+
+        -- Проверяем хватает ли денег
+        IF @CurrentMoney < @Cost
+        BEGIN
+            SET @ResultCode = 3;
+            ROLLBACK TRANSACTION;
+            RETURN;
+        END
+
+        -- Снимаем деньги
+        UPDATE Players
+        SET Money = Money - @Cost
+        WHERE id_Player = @PlayerID;
+
+        -- Добавляем фон игроку
+        INSERT INTO List_Backgrounds (id_Player, id_Background)
+        VALUES (@PlayerID, @BackgroundID);
+
+        -- Возвращаем оставшиеся деньги
+        SELECT @RemainingMoney = Money
+        FROM Players
+        WHERE id_Player = @PlayerID;
+
+        -- Записываем покупку в журнал денег
+        IF @Cost > 0
+            INSERT INTO MoneyLedger (id_Player, Amount, BalanceAfter, Reason, Reference)
+            VALUES (@PlayerID, -@Cost, @RemainingMoney, N'buy_background', @BackgroundID);
+
+        SET @ResultCode = 0;
+        COMMIT TRANSACTION;
+    END TRY
+    BEGIN CATCH
+        ROLLBACK TRANSACTION;
+        SET @ResultCode = 4;
+    END CATCH
+END;
+
+GO
+
+CREATE OR ALTER PROCEDURE BuyCharacter
+    @PlayerID INT,
+    @CharacterID INT,
+    @RemainingMoney INT OUTPUT,
+    @ResultCode INT OUTPUT -- 0 = успех, 1 = персонаж не найден, 2 = уже куплен, 3 = не хватает денег, 4 = ошибка
+AS
+BEGIN
+    SET NOCOUNT ON;
+    SET @RemainingMoney = NULL;
+
+    BEGIN TRY
+        BEGIN TRANSACTION;
+
+        -- Проверяем, что персонаж существует
+        IF NOT EXISTS (SELECT 1 FROM Characters WHERE id_Character = @CharacterID)
+        BEGIN
+            SET @ResultCode = 1; 
+            ROLLBACK TRANSACTION;
+            RETURN;
+        END
+
+        -- Проверяем, что персонаж не куплен уже игроком
+        IF EXISTS (
+            SELECT 1 FROM List_Characters 
+            WHERE id_Player = @PlayerID AND id_Character = @CharacterID
+        )
+        BEGIN
+            SET @ResultCode = 2; 
+            ROLLBACK TRANSACTION;
+            RETURN;
+        END
+
+        -- Получаем стоимость персонажа
+        DECLARE @Cost INT;
+        SELECT @Cost = Cost FROM Characters WHERE id_Character = @CharacterID;
+
+        -- Получаем текущие деньги игрока
+        DECLARE @CurrentMoney INT;
+        SELECT @CurrentMoney = Money FROM Players WHERE id_Player = @PlayerID;
+
+        -- Проверяем хватает ли денег
+        IF @CurrentMoney < @Cost
+        BEGIN
+            SET @ResultCode = 3; 
+            ROLLBACK TRANSACTION;
+            RETURN;
+        END
+
+        -- Списываем деньги игрока
+        UPDATE Players
+        SET Money = Money - @Cost
+        WHERE id_Player = @PlayerID;
+
+        -- Добавляем персонажа в список купленных
+        INSERT INTO List_Characters (id_Player, id_Character)
+        VALUES (@PlayerID, @CharacterID);
+
+        -- Возвращаем оставшиеся деньги
+        SELECT @RemainingMoney = Money FROM Players WHERE id_Player = @PlayerID;
+
+        -- Записываем покупку в журнал денег
+        IF @Cost > 0
+            INSERT INTO MoneyLedger (id_Player, Amount, BalanceAfter, Reason, Reference)
+            VALUES (@PlayerID, -@Cost, @RemainingMoney, N'buy_character', @CharacterID);
+
+        SET @ResultCode = 0; 
+        COMMIT TRANSACTION;
+    END TRY
+    BEGIN CATCH
+        ROLLBACK TRANSACTION;
+        SET @ResultCode = 4; 
+    END CATCH
+END;
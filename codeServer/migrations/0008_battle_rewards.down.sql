@@ -0,0 +1,11 @@
+DROP PROCEDURE IF EXISTS ApplyBattleResults;
+
+GO
+
+IF EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'UX_Battles_BattleKey' AND object_id = OBJECT_ID(N'Battles'))
+DROP INDEX UX_Battles_BattleKey ON Battles;
+
+GO
+
+IF COL_LENGTH(N'Battles', N'BattleKey') IS NOT NULL
+ALTER TABLE Battles DROP COLUMN BattleKey;
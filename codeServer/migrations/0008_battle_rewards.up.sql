@@ -0,0 +1,92 @@
+-- Ключ боя, по которому награды за бой начисляются не больше одного раза
+IF COL_LENGTH(N'Battles', N'BattleKey') IS NULL
+ALTER TABLE Battles ADD BattleKey CHAR(32) NULL
+
+GO
+
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'UX_Battles_BattleKey' AND object_id = OBJECT_ID(N'Battles'))
+CREATE UNIQUE INDEX UX_Battles_BattleKey ON Battles (BattleKey) WHERE BattleKey IS NOT NULL
+
+GO
+
+-- Записывает бой и прибавляет награды обоим игрокам в одной транзакции.
+-- Повторный вызов с тем же ключом ничего не меняет, возвращает Applied = 0 и текущие значения игроков
+CREATE OR ALTER PROCEDURE ApplyBattleResults
+    @BattleKey CHAR(32),
+    @Player1ID INT,
+    @Player2ID INT,
+    @WinnerID INT = NULL,
+    @StartTime DATETIME,
+    @EndTime DATETIME,
+    @IsRanked BIT,
+    @IsCancelled BIT = 0,
+    @Player1Money INT = 0,
+    @Player1Rank INT = 0,
+    @Player1Level INT = 0,
+    @Player2Money INT = 0,
+    @Player2Rank INT = 0,
+    @Player2Level INT = 0
+AS
+BEGIN
+    SET NOCOUNT ON;
+    SET XACT_ABORT ON;
+
+    BEGIN TRANSACTION;
+
+    DECLARE @BattleID INT;
+    DECLARE @Applied BIT = 0;
+
+    SELECT @BattleID = id_Battle
+    FROM Battles WITH (UPDLOCK, HOLDLOCK)
+    WHERE BattleKey = @BattleKey;
+
+    IF @BattleID IS NULL
+    BEGIN
+        INSERT INTO Battles (id_Player, id_Opponent, id_Winner, StartTime, EndTime, isRanked, isCancelled, BattleKey)
+        VALUES (@Player1ID, @Player2ID, @WinnerID, @StartTime, @EndTime, @IsRanked, @IsCancelled, @BattleKey);
+
+        SET @BattleID = CAST(SCOPE_IDENTITY() AS INT);
+        SET @Applied = 1;
+
+        -- Прибавки вместо перезаписи: покупки, сделанные во время боя, не теряются
+        IF @IsCancelled = 0
+        BEGIN
+            UPDATE Players
+            SET
+                Money = Money + @Player1Money,
+                Rank = CASE WHEN Rank + @Player1Rank < 0 THEN 0 ELSE Rank + @Player1Rank END,
+                Level = CASE WHEN Level + @Player1Level < 0 THEN 0 ELSE Level + @Player1Level END
+            WHERE id_Player = @Player1ID;
+
+            UPDATE Players
+            SET
+                Money = Money + @Player2Money,
+                Rank = CASE WHEN Rank + @Player2Rank < 0 THEN 0 ELSE Rank + @Player2Rank END,
+                Level = CASE WHEN Level + @Player2Level < 0 THEN 0 ELSE Level + @Player2Level END
+            WHERE id_Player = @Player2ID;
+
+            INSERT INTO MoneyLedger (id_Player, Amount, BalanceAfter, Reason, Reference)
+            SELECT id_Player, @Player1Money, Money, N'battle', @BattleID
+            FROM Players
+            WHERE id_Player = @Player1ID AND @Player1Money <> 0;
+
+            INSERT INTO MoneyLedger (id_Player, Amount, BalanceAfter, Reason, Reference)
+            SELECT id_Player, @Player2Money, Money, N'battle', @BattleID
+            FROM Players
+            WHERE id_Player = @Player2ID AND @Player2Money <> 0;
+        END
+    END
+
+    COMMIT TRANSACTION;
+
+    -- Номер боя, признак начисления и значения игроков после него
+    SELECT
+        @BattleID AS id_Battle,
+        @Applied AS Applied,
+        id_Player,
+        Level,
+        Rank,
+        Money
+    FROM Players
+    WHERE id_Player IN (@Player1ID, @Player2ID);
+END;
@@ -0,0 +1,10 @@
+-- Ежедневные награды: дата последней награды и серия дней подряд
+IF OBJECT_ID(N'DailyRewards', N'U') IS NULL
+CREATE TABLE DailyRewards
+(
+	id_Player INT PRIMARY KEY,
+	LastClaimDate DATE NOT NULL,          -- День по UTC
+	Streak INT NOT NULL CHECK (Streak > 0),
+	TotalClaims INT NOT NULL DEFAULT 0,
+	FOREIGN KEY (id_Player) REFERENCES Players(id_Player) ON DELETE CASCADE
+)
@@ -0,0 +1,317 @@
+// Версионные миграции схемы базы данных игры.
+// Миграция - пара файлов NNNN_имя.up.sql и NNNN_имя.down.sql, батчи внутри файла разделяются строкой GO.
+// Применённые версии хранятся в таблице schema_version, каждая миграция выполняется в своей транзакции
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	_ "github.com/denisenkom/go-mssqldb"
+	"github.com/denisenkom/go-mssqldb/batch"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+const (
+	lockResource = "game_schema_migrations" // Имя блокировки, чтобы два сервера не мигрировали одновременно
+	lockTimeout  = 60 * time.Second
+)
+
+// Миграция схемы
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Применённая версия схемы
+type Applied struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+// Все миграции по возрастанию версии
+func All() ([]Migration, error) {
+	return load(files)
+}
+
+// Читает и проверяет миграции из fsys
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("миграция %s: ожидается имя NNNN_имя.up.sql или NNNN_имя.down.sql", name)
+		}
+		number, title, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("миграция %s: неверный номер версии", name)
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		} else if m.Name != title {
+			return nil, fmt.Errorf("версия %d: разные имена %s и %s", version, m.Name, title)
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	all := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		all = append(all, *m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	for i, m := range all {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("пропущена миграция версии %d", i+1)
+		}
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("миграция %04d_%s: нужны оба файла up и down", m.Version, m.Name)
+		}
+	}
+	return all, nil
+}
+
+// Последняя версия схемы, известная серверу
+func Latest() (int, error) {
+	all, err := All()
+	if err != nil {
+		return 0, err
+	}
+	return len(all), nil
+}
+
+// Приводит схему к версии target: применяет недостающие миграции или откатывает лишние.
+// target < 0 означает последнюю версию
+func Migrate(ctx context.Context, db *sql.DB, target int) error {
+	all, err := All()
+	if err != nil {
+		return err
+	}
+	if target < 0 {
+		target = len(all)
+	}
+	if target > len(all) {
+		return fmt.Errorf("версия %d неизвестна, последняя %d", target, len(all))
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err = lock(ctx, conn); err != nil {
+		return err
+	}
+	defer unlock(conn)
+
+	if err = createVersionTable(ctx, conn); err != nil {
+		return err
+	}
+	current, err := currentVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if current > len(all) {
+		return fmt.Errorf("версия схемы %d новее сервера (%d), обновите сервер", current, len(all))
+	}
+
+	for current < target {
+		m := all[current]
+		if err = apply(ctx, conn, m, m.Up, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO schema_version (Version, Name) VALUES (@p1, @p2)", m.Version, m.Name)
+			return err
+		}); err != nil {
+			return err
+		}
+		slog.Info("Миграция применена", "version", m.Version, "name", m.Name)
+		current++
+	}
+	for current > target {
+		m := all[current-1]
+		if err = apply(ctx, conn, m, m.Down, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "DELETE FROM schema_version WHERE Version = @p1", m.Version)
+			return err
+		}); err != nil {
+			return err
+		}
+		slog.Info("Миграция отменена", "version", m.Version, "name", m.Name)
+		current--
+	}
+	return nil
+}
+
+// Применённые версии схемы по возрастанию, пустой список для новой базы
+func Status(ctx context.Context, db *sql.DB) ([]Applied, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT CAST(CASE WHEN OBJECT_ID(N'schema_version', N'U') IS NULL THEN 0 ELSE 1 END AS BIT)").Scan(&exists)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT Version, Name, AppliedAt FROM schema_version ORDER BY Version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applied []Applied
+	for rows.Next() {
+		var a Applied
+		if err = rows.Scan(&a.Version, &a.Name, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied = append(applied, a)
+	}
+	return applied, rows.Err()
+}
+
+// Выполняет батчи миграции и запись версии в одной транзакции
+func apply(ctx context.Context, conn *sql.Conn, m Migration, script string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, part := range batch.Split(script, "GO") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, part); err != nil {
+			return fmt.Errorf("миграция %04d_%s, батч %d: %w", m.Version, m.Name, i+1, err)
+		}
+	}
+	if err = record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func createVersionTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `IF OBJECT_ID(N'schema_version', N'U') IS NULL
+CREATE TABLE schema_version
+(
+	Version INT PRIMARY KEY,
+	Name NVARCHAR(100) NOT NULL,
+	AppliedAt DATETIME NOT NULL DEFAULT GETDATE()
+)`)
+	return err
+}
+
+func currentVersion(ctx context.Context, conn *sql.Conn) (int, error) {
+	var version int
+	err := conn.QueryRowContext(ctx, "SELECT ISNULL(MAX(Version), 0) FROM schema_version").Scan(&version)
+	return version, err
+}
+
+// Блокировка на время миграции, принадлежит сессии соединения
+func lock(ctx context.Context, conn *sql.Conn) error {
+	var result int
+	err := conn.QueryRowContext(ctx, `DECLARE @result INT;
+EXEC @result = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = @p2;
+SELECT @result`, lockResource, lockTimeout.Milliseconds()).Scan(&result)
+	if err != nil {
+		return err
+	}
+	if result < 0 {
+		return fmt.Errorf("не удалось получить блокировку миграций (код %d)", result)
+	}
+	return nil
+}
+
+func unlock(conn *sql.Conn) {
+	conn.ExecContext(context.Background(), "EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session'", lockResource)
+}
+
+// Создаёт базу данных из строки подключения, если её нет. Подключается к master с теми же параметрами
+func EnsureDatabase(ctx context.Context, dsn string) error {
+	masterDSN, name, err := withDatabase(dsn, "master")
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return errors.New("в строке подключения не указана база данных")
+	}
+
+	db, err := sql.Open("sqlserver", masterDSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var exists bool
+	if err = db.QueryRowContext(ctx, "SELECT CAST(CASE WHEN DB_ID(@p1) IS NULL THEN 0 ELSE 1 END AS BIT)", name).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	if _, err = db.ExecContext(ctx, "EXEC(N'CREATE DATABASE ' + QUOTENAME(@p1))", name); err != nil {
+		return err
+	}
+	slog.Info("База данных создана", "database", name)
+	return nil
+}
+
+// Заменяет базу данных в строке подключения, возвращает новую строку и прежнюю базу.
+// Поддерживаются форматы sqlserver://... и ключ=значение через ";"
+func withDatabase(dsn, database string) (string, string, error) {
+	if strings.HasPrefix(dsn, "sqlserver://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", "", err
+		}
+		query := u.Query()
+		name := query.Get("database")
+		query.Set("database", database)
+		u.RawQuery = query.Encode()
+		return u.String(), name, nil
+	}
+
+	var name string
+	parts := []string{}
+	for _, part := range strings.Split(dsn, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "database", "initial catalog":
+			name = strings.TrimSpace(value)
+		case "":
+		default:
+			parts = append(parts, part)
+		}
+	}
+	parts = append(parts, "database="+database)
+	return strings.Join(parts, ";"), name, nil
+}
@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAll(t *testing.T) {
+	all, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range all {
+		if m.Version != i+1 || m.Name == "" || m.Up == "" || m.Down == "" {
+			t.Errorf("миграция %d: версия %d, имя %q", i+1, m.Version, m.Name)
+		}
+	}
+}
+
+func TestLoadFileNames(t *testing.T) {
+	file := &fstest.MapFile{Data: []byte("SELECT 1")}
+	valid := fstest.MapFS{
+		"0001_initial.up.sql":   file,
+		"0001_initial.down.sql": file,
+		"0002_next.up.sql":      file,
+		"0002_next.down.sql":    file,
+	}
+	all, err := load(valid)
+	if err != nil {
+		t.Fatalf("правильные имена: %v", err)
+	}
+	if len(all) != 2 || all[0].Name != "initial" || all[1].Name != "next" {
+		t.Errorf("миграции %+v", all)
+	}
+
+	tests := []struct {
+		name  string
+		files []string
+		err   string
+	}{
+		{"без направления", []string{"0001_initial.sql"}, "ожидается имя"},
+		{"неизвестное направление", []string{"0001_initial.sideways.sql"}, "ожидается имя"},
+		{"без имени", []string{"0001.up.sql", "0001.down.sql"}, "неверный номер"},
+		{"номер не число", []string{"first_initial.up.sql", "first_initial.down.sql"}, "неверный номер"},
+		{"нулевая версия", []string{"0000_initial.up.sql", "0000_initial.down.sql"}, "неверный номер"},
+		{"разные имена версии", []string{"0001_initial.up.sql", "0001_other.down.sql"}, "разные имена"},
+		{"пропуск версии", []string{"0001_initial.up.sql", "0001_initial.down.sql", "0003_next.up.sql", "0003_next.down.sql"}, "пропущена"},
+		{"нет отката", []string{"0001_initial.up.sql"}, "нужны оба файла"},
+	}
+	for _, tt := range tests {
+		fsys := fstest.MapFS{}
+		for _, name := range tt.files {
+			fsys[name] = file
+		}
+		if _, err := load(fsys); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: ошибка %v, ожидалась %q", tt.name, err, tt.err)
+		}
+	}
+}
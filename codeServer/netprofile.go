@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"github.com/xtaci/kcp-go/v5"
+	"sort"
+	"strings"
+)
+
+// Параметры KCP, которые сервер сообщает клиенту при рукопожатии
+type KCPTuning struct {
+	NoDelay      int `msgpack:"nd"`
+	Interval     int `msgpack:"i"` // Интервал внутреннего цикла KCP в мс
+	Resend       int `msgpack:"r"`
+	NoCongestion int `msgpack:"nc"`
+	SndWnd       int `msgpack:"sw"`
+	RcvWnd       int `msgpack:"rw"`
+}
+
+// Сетевой профиль. FEC задаётся при создании слушателя, поэтому у каждого профиля свой порт
+type NetProfile struct {
+	Name         string
+	Port         int
+	DataShards   int
+	ParityShards int
+	Tuning       KCPTuning
+}
+
+// Профили сети, такие же значения Name, Port и FEC должны быть у клиента
+var netProfiles = map[string]NetProfile{
+	// Локальная сеть: потерь почти нет, FEC не нужен
+	"lan": {Name: "lan", Port: 7778, Tuning: KCPTuning{NoDelay: 1, Interval: 10, Resend: 2, NoCongestion: 1, SndWnd: 256, RcvWnd: 256}},
+	// Обычное подключение
+	"normal": {Name: "normal", Port: 7777, DataShards: 10, ParityShards: 3, Tuning: KCPTuning{NoDelay: 1, Interval: 10, Resend: 2, NoCongestion: 1, SndWnd: 128, RcvWnd: 128}},
+	// Мобильная сеть с потерями: больше избыточность и окна
+	"lossy": {Name: "lossy", Port: 7779, DataShards: 10, ParityShards: 6, Tuning: KCPTuning{NoDelay: 1, Interval: 20, Resend: 1, NoCongestion: 1, SndWnd: 512, RcvWnd: 512}},
+}
+
+// Разбирает список профилей из флага: "lan,normal,lossy"
+func parseNetProfiles(list string) ([]NetProfile, error) {
+	var profiles []NetProfile
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		p, ok := netProfiles[name]
+		if !ok {
+			return nil, fmt.Errorf("неизвестный сетевой профиль %s, доступны: %s", name, netProfileNames())
+		}
+		profiles = append(profiles, p)
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("не указан ни один сетевой профиль")
+	}
+	return profiles, nil
+}
+
+func netProfileNames() string {
+	names := make([]string, 0, len(netProfiles))
+	for name := range netProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Применяет параметры KCP к сессии
+func (t KCPTuning) apply(session *kcp.UDPSession) {
+	session.SetNoDelay(t.NoDelay, t.Interval, t.Resend, t.NoCongestion)
+	session.SetWindowSize(t.SndWnd, t.RcvWnd)
+	session.SetACKNoDelay(true) // Без отложенных ACK
+}
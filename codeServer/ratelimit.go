@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	violationWindow = 10 * time.Second // Окно подсчёта нарушений лимита
+	maxViolations   = 50               // Нарушений в окне до отключения клиента
+)
+
+// Лимит частоты: Rate сообщений в секунду, Burst — допустимый всплеск
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// Группы сообщений, для каждой задаётся свой лимит. Токены считаются по каждому типу отдельно
+var rateLimitGroups = map[string][]MessageType{
+	"action":  {MsgActionCharacter},
+	"auth":    {MsgHandshake, MsgAuthorization, MsgRegistration},
+	"friends": {MsgFriendsData, MsgAddFriend, MsgAcceptFriendship, MsgDeclineFriendship, MsgRemoveFriend, MsgChallengeToFight, MsgAcceptChallengeToFight, MsgRefuseChallengeToFight},
+	"battle":  {MsgBattle, MsgBattleRanked, MsgExitBattle, MsgReadyBattle, MsgListBattles},
+	"shop":    {MsgShopData, MsgShopAction, MsgDailyReward},
+	"assets":  {MsgAssetManifest, MsgAssetRequest}, // При входе клиент докачивает сразу много ресурсов
+}
+
+// Лимиты по умолчанию, меняются флагом -ratelimits
+var rateLimits = map[string]RateLimit{
+	"default": {Rate: 10, Burst: 20},
+	"action":  {Rate: 40, Burst: 80},
+	"auth":    {Rate: 0.5, Burst: 3},
+	"friends": {Rate: 2, Burst: 10},
+	"battle":  {Rate: 1, Burst: 5},
+	"shop":    {Rate: 2, Burst: 10},
+	"assets":  {Rate: 50, Burst: 200},
+}
+
+// Лимит новых сессий с одного IP, меняется флагом -sessionrate
+var sessionRateLimit = RateLimit{Rate: 0.5, Burst: 5}
+
+// Разбирает лимит вида "rate/burst", например "0.5/3"
+func parseRateLimit(s string) (RateLimit, error) {
+	rate, burst, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("лимит %q должен иметь вид rate/burst", s)
+	}
+	var limit RateLimit
+	var err error
+	if limit.Rate, err = strconv.ParseFloat(rate, 64); err != nil || limit.Rate <= 0 {
+		return RateLimit{}, fmt.Errorf("некорректная частота в лимите %q", s)
+	}
+	if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst < 1 {
+		return RateLimit{}, fmt.Errorf("некорректный всплеск в лимите %q", s)
+	}
+	return limit, nil
+}
+
+// Применяет лимиты из флага: "action=40/80,auth=0.5/3"
+func parseRateLimits(list string) error {
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("лимит %q должен иметь вид группа=rate/burst", item)
+		}
+		if _, ok = rateLimits[name]; !ok {
+			return fmt.Errorf("неизвестная группа лимитов %s, доступны: %s", name, rateLimitNames())
+		}
+		limit, err := parseRateLimit(value)
+		if err != nil {
+			return err
+		}
+		rateLimits[name] = limit
+	}
+	return nil
+}
+
+func rateLimitNames() string {
+	names := make([]string, 0, len(rateLimits))
+	for name := range rateLimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Лимит для типа сообщения
+func rateLimitFor(mesType MessageType) RateLimit {
+	for name, types := range rateLimitGroups {
+		for _, t := range types {
+			if t == mesType {
+				return rateLimits[name]
+			}
+		}
+	}
+	return rateLimits["default"]
+}
+
+// Корзина токенов
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) allow(limit RateLimit, now time.Time) bool {
+	if b.last.IsZero() {
+		b.tokens = float64(limit.Burst)
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * limit.Rate
+		if b.tokens > float64(limit.Burst) {
+			b.tokens = float64(limit.Burst)
+		}
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Корзина полна и больше не нужна
+func (b *tokenBucket) idle(limit RateLimit, now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= float64(limit.Burst)
+}
+
+// Лимиты сообщений одного клиента. Используется только в receiveClientMessages
+type rateLimiter struct {
+	buckets     map[MessageType]*tokenBucket
+	violations  int
+	windowStart time.Time
+}
+
+type rateResult int
+
+const (
+	rateAllowed   rateResult = iota
+	rateThrottled            // Сообщение отброшено
+	rateExceeded             // Клиент превысил число нарушений и отключается
+)
+
+// Проверяет сообщение. Нарушение лимита отбрасывает сообщение, частые нарушения отключают клиента
+func (l *rateLimiter) check(mesType MessageType) rateResult {
+	now := time.Now()
+	if l.buckets == nil {
+		l.buckets = make(map[MessageType]*tokenBucket)
+	}
+	bucket, ok := l.buckets[mesType]
+	if !ok {
+		bucket = &tokenBucket{}
+		l.buckets[mesType] = bucket
+	}
+	if bucket.allow(rateLimitFor(mesType), now) {
+		return rateAllowed
+	}
+
+	if now.Sub(l.windowStart) > violationWindow {
+		l.windowStart = now
+		l.violations = 0
+	}
+	l.violations++
+	if l.violations >= maxViolations {
+		return rateExceeded
+	}
+	return rateThrottled
+}
+
+// Первое нарушение в окне, о нём сообщается клиенту и в лог
+func (l *rateLimiter) firstViolation() bool {
+	return l.violations == 1
+}
+
+// Лимит новых сессий по IP для всех слушателей
+type sessionLimiter struct {
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+	mutex       sync.Mutex
+}
+
+var sessionLimits = &sessionLimiter{buckets: make(map[string]*tokenBucket)}
+
+// IP из адреса вида host:port
+func hostIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// Разрешает новую сессию с адреса
+func (s *sessionLimiter) allow(addr string) bool {
+	ip := hostIP(addr)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastCleanup) > time.Minute {
+		for key, bucket := range s.buckets {
+			if bucket.idle(sessionRateLimit, now) {
+				delete(s.buckets, key)
+			}
+		}
+		s.lastCleanup = now
+	}
+
+	bucket, ok := s.buckets[ip]
+	if !ok {
+		bucket = &tokenBucket{}
+		s.buckets[ip] = bucket
+	}
+	if bucket.allow(sessionRateLimit, now) {
+		return true
+	}
+	slog.Warn("Превышен лимит новых сессий, подключение отклонено", "ip", ip)
+	return false
+}
@@ -0,0 +1,188 @@
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	rootDirName = "resources"      // Имя папки с ресурсами игры
+	rootEnvName = "GAME_RESOURCES" // Переменная окружения с путём к папке ресурсов
+	maxLevelsUp = 3                // Сколько родительских папок проверять при поиске ресурсов
+)
+
+var defaultLocator *Locator
+
+// Преобразует логические идентификаторы ресурсов в пути операционной системы.
+// Идентификатор ресурса - путь относительно папки resources через "/", например "UI/Menu/Background/Menu.png".
+type Locator struct {
+	root string
+}
+
+// Создаёт локатор. Если root пустой, папка берётся из GAME_RESOURCES
+// или ищется рядом с рабочей папкой и исполняемым файлом
+func NewLocator(root string) (*Locator, error) {
+	if root == "" {
+		root = os.Getenv(rootEnvName)
+	}
+	if root == "" {
+		var err error
+		root, err = findRoot()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("папка ресурсов недоступна: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s не является папкой", root)
+	}
+	return &Locator{root: root}, nil
+}
+
+// Ищет папку resources в рабочей папке, рядом с исполняемым файлом и в их родителях
+func findRoot() (string, error) {
+	var starts []string
+	if wd, err := os.Getwd(); err == nil {
+		starts = append(starts, wd)
+	}
+	if exe, err := os.Executable(); err == nil {
+		starts = append(starts, filepath.Dir(exe))
+	}
+
+	for _, dir := range starts {
+		for level := 0; level <= maxLevelsUp; level++ {
+			candidate := filepath.Join(dir, rootDirName)
+			if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+				return candidate, nil
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return "", fmt.Errorf("папка %s не найдена, укажите её явно или через %s", rootDirName, rootEnvName)
+}
+
+// Папка ресурсов
+func (l *Locator) Root() string {
+	return l.root
+}
+
+// Путь к ресурсу в файловой системе
+func (l *Locator) Path(id string) string {
+	id = Normalize(id)
+	if id == "" {
+		return ""
+	}
+	return filepath.Join(l.root, filepath.FromSlash(id))
+}
+
+// Приводит путь ресурса к идентификатору. Поддерживает старый формат "\resources\Asset\..." из базы данных.
+// Для пути, выходящего за папку ресурсов, возвращает пустую строку: такой ресурс считается отсутствующим
+func Normalize(id string) string {
+	id = strings.ReplaceAll(id, "\\", "/")
+	id = strings.TrimLeft(id, "/")
+	if len(id) > len(rootDirName) && strings.EqualFold(id[:len(rootDirName)+1], rootDirName+"/") {
+		id = id[len(rootDirName)+1:]
+	}
+	id = path.Clean(id)
+	if id == "." || id == ".." || strings.HasPrefix(id, "../") {
+		return ""
+	}
+	return id
+}
+
+// Идентификатор варианта ресурса из подпапки: ("Asset/Knight girl/Idle.png", "Character") -> "Asset/Knight girl/Character/Idle.png"
+func Variant(id, subDir string) string {
+	id = Normalize(id)
+	if id == "" {
+		return ""
+	}
+	return path.Join(path.Dir(id), subDir, path.Base(id))
+}
+
+// Инициализирует локатор по умолчанию
+func Init(root string) error {
+	l, err := NewLocator(root)
+	if err != nil {
+		return err
+	}
+	defaultLocator = l
+	return nil
+}
+
+// Папка ресурсов локатора по умолчанию
+func Root() string {
+	return defaultLocator.Root()
+}
+
+// Путь к ресурсу через локатор по умолчанию
+func Path(id string) string {
+	return defaultLocator.Path(id)
+}
+
+// Строит индекс ресурсов из указанных папок: идентификатор -> SHA-256 содержимого в hex
+func (l *Locator) Index(dirs ...string) (map[string]string, error) {
+	index := make(map[string]string)
+	for _, dir := range dirs {
+		base := l.Path(dir)
+		err := filepath.Walk(base, func(name string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(l.root, name)
+			if err != nil {
+				return err
+			}
+			hash, err := FileHash(name)
+			if err != nil {
+				return err
+			}
+			index[path.Clean(filepath.ToSlash(rel))] = hash
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+// SHA-256 содержимого файла в hex
+func FileHash(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Индекс ресурсов через локатор по умолчанию
+func Index(dirs ...string) (map[string]string, error) {
+	return defaultLocator.Index(dirs...)
+}
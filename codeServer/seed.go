@@ -0,0 +1,203 @@
+package main
+
+import (
+	"codeServer/resource"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"log/slog"
+	"os"
+)
+
+// Контент игры, который поставляется вместе с сервером
+//
+//go:embed seed/content.json
+var defaultSeedData []byte
+
+// Игровой контент для команды seed: фоны, персонажи с анимациями и стартовые предметы.
+// Фоны и персонажи определяются по имени: существующие обновляются, новые добавляются
+type seedData struct {
+	Version     int              `json:"version"` // Версия контента, увеличивается при каждом изменении файла
+	Starter     seedStarter      `json:"starter"`
+	Backgrounds []seedBackground `json:"backgrounds"`
+	Characters  []seedCharacter  `json:"characters"`
+}
+
+// Предметы, которые получает новый игрок
+type seedStarter struct {
+	Background string `json:"background"` // Имя фона из backgrounds
+	Character  string `json:"character"`  // Имя персонажа из characters
+}
+
+type seedBackground struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Cost        int    `json:"cost"`
+	AssetPath   string `json:"assetPath"`
+}
+
+// Персонаж в формате манифеста утилиты assets
+type seedCharacter struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Health      int         `json:"health"`
+	Damage      int         `json:"damage"`
+	Cost        int         `json:"cost"`
+	Assets      []seedAsset `json:"assets"`
+}
+
+type seedAsset struct {
+	AnimationType string  `json:"animationType"`
+	FrameCount    int     `json:"frameCount"`
+	BaseHeight    int     `json:"baseHeight"`
+	BaseWidth     int     `json:"baseWidth"`
+	FrameRate     float32 `json:"frameRate"`
+	AssetPath     string  `json:"assetPath"`
+}
+
+// Разбирает и проверяет контент
+func parseSeedData(data []byte) (*seedData, error) {
+	sd := new(seedData)
+	if err := json.Unmarshal(data, sd); err != nil {
+		return nil, err
+	}
+	if err := sd.validate(); err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+// Читает контент из файла, пустой путь означает встроенный контент
+func loadSeedData(path string) (*seedData, error) {
+	if path == "" {
+		return parseSeedData(defaultSeedData)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sd, err := parseSeedData(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return sd, nil
+}
+
+func (sd *seedData) validate() error {
+	if sd.Version <= 0 {
+		return errors.New("не указана версия контента")
+	}
+
+	backgrounds := make(map[string]bool)
+	for _, b := range sd.Backgrounds {
+		if b.Name == "" || b.AssetPath == "" {
+			return errors.New("у фона должны быть имя и путь к изображению")
+		}
+		if backgrounds[b.Name] {
+			return fmt.Errorf("фон '%s' указан дважды", b.Name)
+		}
+		if b.Cost < 0 {
+			return fmt.Errorf("фон '%s': отрицательная стоимость", b.Name)
+		}
+		backgrounds[b.Name] = true
+	}
+
+	characters := make(map[string]bool)
+	for _, ch := range sd.Characters {
+		if ch.Name == "" {
+			return errors.New("у персонажа должно быть имя")
+		}
+		if characters[ch.Name] {
+			return fmt.Errorf("персонаж '%s' указан дважды", ch.Name)
+		}
+		if ch.Health <= 0 || ch.Damage <= 0 || ch.Cost < 0 {
+			return fmt.Errorf("персонаж '%s': некорректные здоровье, урон или стоимость", ch.Name)
+		}
+		chDB := CharacterDB{Assets: make(map[string]*AssetsCharacterDB)}
+		for _, as := range ch.Assets {
+			asset := AssetsCharacterDB(as)
+			chDB.Assets[as.AnimationType] = &asset
+		}
+		if err := validateCharacterAssets(chDB); err != nil {
+			return fmt.Errorf("персонаж '%s': %v", ch.Name, err)
+		}
+		characters[ch.Name] = true
+	}
+
+	if !backgrounds[sd.Starter.Background] {
+		return fmt.Errorf("стартовый фон '%s' не найден среди фонов", sd.Starter.Background)
+	}
+	if !characters[sd.Starter.Character] {
+		return fmt.Errorf("стартовый персонаж '%s' не найден среди персонажей", sd.Starter.Character)
+	}
+	return nil
+}
+
+// Пути изображений контента, которых нет в папке ресурсов
+func (sd *seedData) missingAssets() []string {
+	var missing []string
+	check := func(id string) {
+		if _, err := os.Stat(resource.Path(id)); err != nil {
+			missing = append(missing, id)
+		}
+	}
+	for _, b := range sd.Backgrounds {
+		check(b.AssetPath)
+	}
+	for _, ch := range sd.Characters {
+		for _, as := range ch.Assets {
+			check(as.AssetPath)
+		}
+	}
+	return missing
+}
+
+// Подкоманда seed: codeServer seed [-db строка] [-file content.json] [-force].
+// Загружает контент в базу данных, если его версия новее загруженной
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	dsn := fs.String("db", defaultDatabase(), "строка подключения к базе данных SQL Server")
+	file := fs.String("file", "", "файл контента, по умолчанию встроенный в сервер")
+	resourcesRoot := fs.String("resources", "", "путь к папке resources для проверки изображений")
+	force := fs.Bool("force", false, "загрузить контент, даже если его версия не новее загруженной")
+	fs.Parse(args)
+
+	sd, err := loadSeedData(*file)
+	if err != nil {
+		return err
+	}
+	if err = resource.Init(*resourcesRoot); err != nil {
+		slog.Warn("Изображения контента не проверены", "err", err)
+	} else if missing := sd.missingAssets(); len(missing) > 0 {
+		return fmt.Errorf("нет изображений в папке ресурсов: %v", missing)
+	}
+
+	sqlDB, err := sqlx.Open("sqlserver", *dsn)
+	if err != nil {
+		return err
+	}
+	store = &sqlStorage{db: &metricsDB{DB: sqlDB}}
+	defer store.close()
+	if err = prepareSchema(sqlDB.DB, false); err != nil {
+		return err
+	}
+
+	current, err := store.contentVersion()
+	if err != nil {
+		return err
+	}
+	if sd.Version <= current && !*force {
+		slog.Info("Контент уже загружен", "version", current, "file_version", sd.Version)
+		return nil
+	}
+	if err = store.seed(sd); err != nil {
+		return err
+	}
+	slog.Info("Контент загружен", "version", sd.Version, "previous", current,
+		"backgrounds", len(sd.Backgrounds), "characters", len(sd.Characters))
+	slog.Info("Работающий сервер перечитает каталог персонажей по сигналу SIGHUP")
+	return nil
+}
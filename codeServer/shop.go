@@ -1,6 +1,6 @@
 package main
 
-import "fmt"
+import "strconv"
 
 type ShopActionType int
 
@@ -38,7 +38,7 @@ func (a *ShopAction) Apply(client *Client) {
 	case actionSelect:
 		a.actionSelect(client)
 	default:
-		createAndSendMessage(client, MsgError, fmt.Sprintf("неизвестный тип действия магазина: %d", a.Action))
+		sendError(client, newGameError(ErrCodeUnknownShopAction, "action", strconv.Itoa(int(a.Action))))
 		return
 	}
 }
@@ -47,9 +47,9 @@ func (a *ShopAction) Apply(client *Client) {
 func (a *ShopAction) actionBuy(client *Client) {
 	switch a.ProductType {
 	case productBackground:
-		remainingMoney, err := buyBackgroundDB(client.PlayerID, a.ProductID)
+		remainingMoney, err := store.buyBackground(client.PlayerID, a.ProductID)
 		if err != nil {
-			createAndSendMessage(client, MsgError, err.Error())
+			sendError(client, err)
 			return
 		}
 		client.Money = remainingMoney
@@ -58,9 +58,9 @@ func (a *ShopAction) actionBuy(client *Client) {
 		a.selectBackground(client)
 
 	case productCharacter:
-		remainingMoney, err := buyCharacterDB(client.PlayerID, a.ProductID)
+		remainingMoney, err := store.buyCharacter(client.PlayerID, a.ProductID)
 		if err != nil {
-			createAndSendMessage(client, MsgError, err.Error())
+			sendError(client, err)
 			return
 		}
 		client.Money = remainingMoney
@@ -68,7 +68,7 @@ func (a *ShopAction) actionBuy(client *Client) {
 
 		a.selectCharacter(client)
 	default:
-		createAndSendMessage(client, MsgError, fmt.Sprintf("неизвестный тип продукта магазина: %d", a.ProductType))
+		sendError(client, newGameError(ErrCodeUnknownProduct, "product", strconv.Itoa(int(a.ProductType))))
 	}
 }
 
@@ -80,15 +80,15 @@ func (a *ShopAction) actionSelect(client *Client) {
 	case productCharacter:
 		a.selectCharacter(client)
 	default:
-		createAndSendMessage(client, MsgError, fmt.Sprintf("неизвестный тип продукта магазина: %d", a.ProductType))
+		sendError(client, newGameError(ErrCodeUnknownProduct, "product", strconv.Itoa(int(a.ProductType))))
 	}
 }
 
 // Обрабатывает выбор активного фона
 func (a *ShopAction) selectBackground(client *Client) {
-	assetPath, err := selectBackgroundDB(client.PlayerID, a.ProductID)
+	assetPath, err := store.selectBackground(client.PlayerID, a.ProductID)
 	if err != nil {
-		createAndSendMessage(client, MsgError, err.Error())
+		sendError(client, err)
 		return
 	}
 	createAndSendMessage(client, MsgSelectBackground, assetPath)
@@ -96,15 +96,19 @@ func (a *ShopAction) selectBackground(client *Client) {
 
 // Обрабатывает выбор активного персонажа
 func (a *ShopAction) selectCharacter(client *Client) {
-	character, err := selectCharacterDB(client.PlayerID, a.ProductID)
+	character, err := getActiveCharacter(a.ProductID)
 	if err != nil {
-		createAndSendMessage(client, MsgError, err.Error())
+		sendError(client, err)
+		return
+	}
+	if err = store.selectCharacter(client.PlayerID, a.ProductID); err != nil {
+		sendError(client, err)
 		return
 	}
 	client.ActiveCharacter = a.ProductID
-	client.State.Update(client.ActiveCharacter)
+	client.State.Update(character)
 
-	createAndSendMessage(client, MsgSelectCharacter, character)
+	createAndSendMessage(client, MsgSelectCharacter, getCharacterData(character))
 }
 
 // Отправляет подтверждение покупки
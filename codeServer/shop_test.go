@@ -0,0 +1,127 @@
+package main
+
+import (
+	"codeClient/bot"
+	"codeClient/protocol"
+	"testing"
+)
+
+func TestShopPurchase(t *testing.T) {
+	s := startTestServer(t, testServerConfig{})
+	ctx := testContext(t)
+	alice := s.player("alice")
+
+	shop, err := alice.Shop(ctx)
+	if err != nil {
+		t.Fatalf("магазин: %v", err)
+	}
+	items := make(map[string]bot.ShopBackgroundItem)
+	for _, item := range shop.AvailableBackgrounds {
+		items[item.Name] = item
+	}
+	coast, factory := items["Побережье"], items["Фабрика"]
+	if coast.ID == 0 || factory.ID == 0 {
+		t.Fatalf("в магазине нет фонов из testdata: %+v", shop.AvailableBackgrounds)
+	}
+
+	receipt, err := alice.Buy(ctx, bot.ProductBackground, coast.ID)
+	if err != nil {
+		t.Fatalf("покупка фона: %v", err)
+	}
+	if want := startMoney - coast.Cost; receipt.RemainingMoney != want {
+		t.Errorf("после покупки осталось %d, ожидалось %d", receipt.RemainingMoney, want)
+	}
+	// Купленный фон сразу становится активным
+	msg, err := alice.WaitFor(ctx, protocol.MsgSelectBackground)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var assetPath string
+	if err = msg.Decode(&assetPath); err != nil || assetPath != coast.AssetPath {
+		t.Errorf("активный фон %q, ожидался %q: %v", assetPath, coast.AssetPath, err)
+	}
+
+	if _, err = alice.Buy(ctx, bot.ProductBackground, coast.ID); errorCode(err) != protocol.ErrCodeBackgroundAlreadyBought {
+		t.Errorf("повторная покупка: %v", err)
+	}
+	if _, err = alice.Buy(ctx, bot.ProductBackground, factory.ID); errorCode(err) != protocol.ErrCodeNotEnoughMoney {
+		t.Errorf("покупка без денег: %v", err)
+	}
+
+	player, err := s.Store.getAdminPlayer(alice.User.PublicID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if player.Money != receipt.RemainingMoney {
+		t.Errorf("в хранилище %d денег, клиент получил %d", player.Money, receipt.RemainingMoney)
+	}
+	entries, sum, err := s.Store.getMoneyLedger(player.PlayerID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || sum != player.Money {
+		t.Fatalf("журнал денег %+v с суммой %d, баланс %d", entries, sum, player.Money)
+	}
+	if e := entries[0]; e.Reason != ledgerBuyBackground || e.Amount != -coast.Cost || e.Reference == nil || *e.Reference != coast.ID {
+		t.Errorf("запись о покупке %+v", e)
+	}
+
+	shop, err = alice.Shop(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	purchased := false
+	for _, item := range shop.PurchasedBackgrounds {
+		purchased = purchased || item.ID == coast.ID
+	}
+	if !purchased {
+		t.Errorf("купленного фона нет среди купленных: %+v", shop.PurchasedBackgrounds)
+	}
+}
+
+func TestShopCharacterPurchase(t *testing.T) {
+	s := startTestServer(t, testServerConfig{})
+	ctx := testContext(t)
+	alice := s.player("alice")
+
+	shop, err := alice.Shop(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shop.AvailableCharacters) != 1 {
+		t.Fatalf("персонажи для покупки %+v, ожидался один из testdata", shop.AvailableCharacters)
+	}
+	character := shop.AvailableCharacters[0]
+
+	receipt, err := alice.Buy(ctx, bot.ProductCharacter, character.ID)
+	if err != nil {
+		t.Fatalf("покупка персонажа: %v", err)
+	}
+	if want := startMoney - character.Cost; receipt.RemainingMoney != want {
+		t.Errorf("после покупки осталось %d, ожидалось %d", receipt.RemainingMoney, want)
+	}
+	msg, err := alice.WaitFor(ctx, protocol.MsgSelectCharacter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var selected protocol.CharacterData
+	if err = msg.Decode(&selected); err != nil {
+		t.Fatal(err)
+	}
+	if selected.Name != character.Name || selected.Health != character.Health {
+		t.Errorf("выбран персонаж %s со здоровьем %d, ожидался %s со здоровьем %d", selected.Name, selected.Health, character.Name, character.Health)
+	}
+
+	// Новый персонаж сохраняется для следующего входа
+	player, err := s.Store.getAdminPlayer(alice.User.PublicID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeID, err := s.Store.getActiveCharacterID(player.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activeID != character.ID || player.Money != receipt.RemainingMoney {
+		t.Errorf("в хранилище персонаж %d и %d денег, ожидались %d и %d", activeID, player.Money, character.ID, receipt.RemainingMoney)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	shutdownCancelWait = 5 * time.Second // Ожидание сохранения отменённых боёв
+	shutdownExitDelay  = time.Second     // Время на доставку MsgExit до закрытия соединений
+)
+
+var (
+	shuttingDown int32 // 1 после начала остановки сервера
+
+	listeners      []io.Closer // Слушатели KCP, TCP и WebSocket
+	listenersMutex sync.Mutex
+
+	sessions      = make(map[*Client]struct{}) // Все подключения, включая неавторизованные
+	sessionsMutex sync.Mutex
+
+	activeBattles = make(map[*Battle]struct{}) // Идущие бои
+	battlesMutex  sync.Mutex
+	battlesWG     sync.WaitGroup
+)
+
+// Сервер останавливается, новые бои и подключения не принимаются
+func isShuttingDown() bool {
+	return atomic.LoadInt32(&shuttingDown) == 1
+}
+
+// Запоминает слушателя, чтобы закрыть его при остановке
+func registerListener(listener io.Closer) {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+	listeners = append(listeners, listener)
+}
+
+func addSession(client *Client) {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+	sessions[client] = struct{}{}
+}
+
+func removeSession(client *Client) {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+	delete(sessions, client)
+}
+
+// Регистрирует бой, false после начала остановки сервера: новые бои не начинаются
+func addBattle(battle *Battle) bool {
+	battlesMutex.Lock()
+	defer battlesMutex.Unlock()
+	if isShuttingDown() {
+		return false
+	}
+	activeBattles[battle] = struct{}{}
+	battlesWG.Add(1)
+	return true
+}
+
+func removeBattle(battle *Battle) {
+	battlesMutex.Lock()
+	defer battlesMutex.Unlock()
+	delete(activeBattles, battle)
+	battlesWG.Done()
+}
+
+// Ждёт окончания всех боёв, false по истечении timeout
+func waitBattles(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		battlesWG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Останавливает сервер: закрывает слушателей, очищает очереди, завершает бои,
+// сохраняет статистику игроков и закрывает сессии с указанием причины
+func shutdown(battleWait time.Duration) {
+	if !atomic.CompareAndSwapInt32(&shuttingDown, 0, 1) {
+		return
+	}
+	slog.Info("Остановка сервера...")
+
+	listenersMutex.Lock()
+	for _, listener := range listeners {
+		listener.Close()
+	}
+	listeners = nil
+	listenersMutex.Unlock()
+
+	matchmakingQueue.StopSearch()
+	for _, client := range matchmakingQueue.drain() {
+		sendError(client, newGameError(ErrCodeServerShutdown))
+		createAndSendMessage(client, MsgExitBattle, nil)
+	}
+
+	battlesMutex.Lock()
+	count := len(activeBattles)
+	battlesMutex.Unlock()
+	if count > 0 {
+		slog.Info("Ожидание окончания боёв", "battles", count, "timeout", battleWait)
+	}
+	if !waitBattles(battleWait) {
+		battlesMutex.Lock()
+		for battle := range activeBattles {
+			battle.cancelOnce.Do(func() { close(battle.cancel) })
+		}
+		slog.Warn("Бои не закончились вовремя и отменены", "battles", len(activeBattles))
+		battlesMutex.Unlock()
+		if !waitBattles(shutdownCancelWait) {
+			slog.Warn("Не все отменённые бои успели сохраниться")
+		}
+	}
+
+	sessionsMutex.Lock()
+	clients := make([]*Client, 0, len(sessions))
+	for client := range sessions {
+		clients = append(clients, client)
+	}
+	sessionsMutex.Unlock()
+
+	exitSessions(newGameError(ErrCodeServerShutdown), clients...)
+	slog.Info("Сессии закрыты", "count", len(clients))
+}
+
+// Отправляет клиентам MsgExit с причиной и закрывает соединения, когда сообщение успеет дойти:
+// при закрытии сессии KCP неотправленные данные теряются
+func exitSessions(reason *GameError, clients ...*Client) {
+	for _, client := range clients {
+		createAndSendMessage(client, MsgExit, reason)
+	}
+	time.Sleep(shutdownExitDelay)
+	for _, client := range clients {
+		closeSession(client)
+	}
+}
+
+// Закрывает соединение клиента, обработчики завершаются по контексту и ошибке чтения
+func closeSession(client *Client) {
+	if client.cancel != nil {
+		client.cancel()
+	}
+	client.connMutex.Lock()
+	if client.Conn != nil {
+		client.Conn.Close()
+	}
+	client.connMutex.Unlock()
+}
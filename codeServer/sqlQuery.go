@@ -4,25 +4,80 @@ const (
 	// Проверка на уникальность логина
 	queryUniqueLogin = "SELECT CASE WHEN EXISTS (SELECT 1 FROM Users WHERE Login = @login) THEN 1 ELSE 0 END;"
 	// Добавляет запись о новом пользователе и возвращает его идентификатор
-	queryInsertUser = "INSERT INTO Users VALUES (@Login, @PasswordHash, @isActive); SELECT SCOPE_IDENTITY();"
+	queryInsertUser = "INSERT INTO Users (Login, PasswordHash, isActive) VALUES (@Login, @PasswordHash, @isActive); SELECT SCOPE_IDENTITY();"
 	// Добавляет запись о новом игроке для соответствующего пользователя
 	queryInsertPlayer = "INSERT INTO Players VALUES (@id_User,  @PublicCode, @Name, @Level, @Money, @Rank, @id_ActiveCharacter, @id_ActiveBackground)"
-	// Получение идентификатора стартового фона
-	queryGetDefaultBackground = "SELECT id_Background FROM Backgrounds WHERE Name = 'Рынок'"
-	// Получение идентификатора стартового персонажа
-	queryGetDefaultCharacter = "SELECT id_Character FROM Characters WHERE Name = 'Девушка рыцарь'"
+	// Получение стартовых фона и персонажа нового игрока
+	queryGetStarterItems = "SELECT id_StarterBackground, id_StarterCharacter FROM GameSettings WHERE id_Settings = 1"
+	// Версия загруженного контента
+	queryGetContentVersion = "SELECT ContentVersion FROM GameSettings WHERE id_Settings = 1"
+	// Поиск фона по имени
+	queryFindBackground = "SELECT id_Background FROM Backgrounds WHERE Name = @Name"
+	// Добавление фона
+	queryInsertBackground = "INSERT INTO Backgrounds (Name, Description, Cost, AssetPath) VALUES (@Name, @Description, @Cost, @AssetPath); SELECT SCOPE_IDENTITY();"
+	// Обновление фона
+	queryUpdateBackground = "UPDATE Backgrounds SET Description = @Description, Cost = @Cost, AssetPath = @AssetPath WHERE id_Background = @id_Background"
+	// Поиск персонажа по имени
+	queryFindCharacter = "SELECT id_Character FROM Characters WHERE Name = @Name"
+	// Добавление персонажа
+	queryInsertCharacter = "INSERT INTO Characters (Name, Description, Health, Damage, Cost) VALUES (@Name, @Description, @Health, @Damage, @Cost); SELECT SCOPE_IDENTITY();"
+	// Обновление характеристик персонажа
+	queryUpdateCharacter = "UPDATE Characters SET Description = @Description, Health = @Health, Damage = @Damage, Cost = @Cost WHERE id_Character = @id_Character"
+	// Удаление анимаций персонажа перед повторной записью
+	queryDeleteAssetsCharacter = "DELETE FROM Assets_Characters WHERE id_Character = @id_Character"
+	// Добавление анимации персонажа
+	queryInsertAssetCharacter = "INSERT INTO Assets_Characters (id_Character, AnimationType, FrameCount, BaseHeight, BaseWidth, FrameRate, AssetPath) VALUES (@id_Character, @AnimationType, @FrameCount, @BaseHeight, @BaseWidth, @FrameRate, @AssetPath)"
+	// Запись версии контента и стартовых предметов
+	querySaveGameSettings = `UPDATE GameSettings SET ContentVersion = @Version, id_StarterBackground = @BackgroundID, id_StarterCharacter = @CharacterID, SeededAt = GETDATE() WHERE id_Settings = 1;
+		IF @@ROWCOUNT = 0
+			INSERT INTO GameSettings (id_Settings, ContentVersion, id_StarterBackground, id_StarterCharacter, SeededAt) VALUES (1, @Version, @BackgroundID, @CharacterID, GETDATE())`
 	// Идентификация пользователя по логину с извлечением информации для аутентификации
 	queryAuthenticateUser = "SELECT * FROM Users WHERE Login = @login"
+	// Запись неудачной попытки входа
+	queryInsertFailedLogin = "INSERT INTO FailedLogins (Login, IP, Reason, AttemptTime) VALUES (@Login, @IP, @Reason, @AttemptTime)"
+	// Запись отчёта античита
+	queryInsertCheatReport = "INSERT INTO CheatReports (id_Player, Action, Events, Total, ReportTime) VALUES (@PlayerID, @Action, @Events, @Total, @ReportTime)"
+	// Поиск игрока для администратора по публичному ID или логину
+	queryGetAdminPlayer = `SELECT u.id_User, u.Login, u.isActive, u.BanReason, u.BannedUntil, p.id_Player, p.PublicID, p.Name, p.Level, p.Money, p.Rank
+		FROM Users u JOIN Players p ON p.id_User = u.id_User
+		WHERE (@PublicID <> '' AND p.PublicID = @PublicID) OR (@Login <> '' AND u.Login = @Login)`
+	// Блокировка пользователя
+	queryBanUser = "UPDATE Users SET isActive = 0, BanReason = @BanReason, BannedUntil = @BannedUntil WHERE id_User = @id_User"
+	// Снятие блокировки пользователя
+	queryUnbanUser = "UPDATE Users SET isActive = 1, BanReason = NULL, BannedUntil = NULL WHERE id_User = @id_User"
+	// Изменение денег, ранга и уровня игрока администратором, возвращает новый баланс
+	queryAdjustPlayerStats = `SET XACT_ABORT ON;
+		BEGIN TRANSACTION;
+		DECLARE @OldMoney INT = (SELECT Money FROM Players WITH (UPDLOCK) WHERE id_Player = @PlayerID);
+		UPDATE Players SET
+		Money = CASE WHEN Money + @Money < 0 THEN 0 ELSE Money + @Money END,
+		Rank = CASE WHEN Rank + @Rank < 0 THEN 0 ELSE Rank + @Rank END,
+		Level = CASE WHEN Level + @Level < 0 THEN 0 ELSE Level + @Level END
+		WHERE id_Player = @PlayerID;
+		INSERT INTO MoneyLedger (id_Player, Amount, BalanceAfter, Reason)
+		SELECT id_Player, Money - @OldMoney, Money, @Reason FROM Players WHERE id_Player = @PlayerID AND Money <> @OldMoney;
+		COMMIT TRANSACTION;
+		SELECT Money FROM Players WHERE id_Player = @PlayerID;`
+	// Последние записи журнала денег игрока
+	queryGetMoneyLedger = `SELECT TOP (@Limit) id_Entry, Amount, BalanceAfter, Reason, Reference, EntryTime
+		FROM MoneyLedger WHERE id_Player = @PlayerID ORDER BY id_Entry DESC`
+	// Сумма всех записей журнала денег игрока
+	queryGetMoneyLedgerSum = "SELECT ISNULL(SUM(CAST(Amount AS BIGINT)), 0) FROM MoneyLedger WHERE id_Player = @PlayerID"
+	// Запись стартовых денег нового игрока в журнал
+	queryInsertStartMoney = `INSERT INTO MoneyLedger (id_Player, Amount, BalanceAfter, Reason)
+		SELECT id_Player, Money, Money, @Reason FROM Players WHERE id_User = @id_User AND Money <> 0`
+	// Запись действия администратора
+	queryInsertAdminAction = "INSERT INTO AdminActions (id_Player, Action, Details, AdminIP, ActionTime) VALUES (@PlayerID, @Action, @Details, @AdminIP, @ActionTime)"
 	// Получение публичного идентификатора игрока
 	queryGetPublicIDPlayer = "SELECT PublicID FROM Players WHERE id_User = @id_User"
 	// Получение идентификатора активного персонажа пользователя
 	queryGetActiveCharacter = "SELECT id_ActiveCharacter FROM Players WHERE id_User=@id_User"
 	// Получение пользовательских данных
 	queryGetUserData = "EXEC getUserData @Id_User"
-	// Получение данных персонажа
-	queryGetCharacter = "EXEC getCharacter @Id_Character"
-	// Получение данных об анимации персонажа
-	queryGetAssetsCharacter = "EXEC getAssetsCharacter @Id_Character"
+	// Получение всех персонажей
+	queryGetAllCharacters = "SELECT id_Character, Name, Description, Health, Damage, Cost FROM Characters"
+	// Получение анимаций всех персонажей
+	queryGetAllAssetsCharacters = "SELECT id_Character, AnimationType, FrameCount, BaseHeight, BaseWidth, FrameRate, AssetPath FROM Assets_Characters"
 	// Получение информации о друзьях
 	queryGetFriendsData = "EXEC GetFriendsAndRequests @PlayerID"
 	// Запрос в друзья
@@ -33,10 +88,9 @@ const (
 	queryDeclineFriendship = "EXEC DeclineFriendship @PlayerID, @RequesterPublicID"
 	// Удалить из друзей
 	queryRemoveFriendship = "EXEC RemoveFriendship @PlayerID, @FriendPublicID"
-	// Добавляет запись о бое
-	querySaveBattleResults = "EXEC SaveBattleResults @Player1ID, @Player2ID, @WinnerID, @StartTime, @EndTime, @isRanked"
-	// Обновляет уровень, ранг, число монет после боя
-	queryUpdatePlayerStats = "EXEC UpdatePlayerStats @PlayerID, @newLevel, @newRank, @newMoney"
+	// Записывает бой и прибавляет награды игрокам, не больше одного раза для ключа боя
+	queryApplyBattleResults = `EXEC ApplyBattleResults @BattleKey, @Player1ID, @Player2ID, @WinnerID, @StartTime, @EndTime, @isRanked, @isCancelled,
+		@Player1Money, @Player1Rank, @Player1Level, @Player2Money, @Player2Rank, @Player2Level`
 	// Получение информации о сражениях игрока
 	queryGetPlayerBattleStats = "EXEC GetPlayerBattleStats @playerID, @isRanked"
 	// Получение фонов для магазина
@@ -51,4 +105,22 @@ const (
 	queryBuyCharacter = "EXEC BuyCharacter @PlayerID, @CharacterID, @RemainingMoney OUTPUT, @ResultCode OUTPUT"
 	// Сделать персонажа активным
 	querySelectCharacter = "EXEC SelectCharacter @PlayerID, @CharacterID, @ResultCode OUTPUT"
+	// Серия ежедневных наград игрока с блокировкой строки до конца транзакции
+	queryGetDailyReward = "SELECT LastClaimDate, Streak FROM DailyRewards WITH (UPDLOCK, HOLDLOCK) WHERE id_Player = @PlayerID"
+	// Запись полученной ежедневной награды
+	querySaveDailyReward = `UPDATE DailyRewards SET LastClaimDate = @Today, Streak = @Streak, TotalClaims = TotalClaims + 1 WHERE id_Player = @PlayerID;
+		IF @@ROWCOUNT = 0
+			INSERT INTO DailyRewards (id_Player, LastClaimDate, Streak, TotalClaims) VALUES (@PlayerID, @Today, @Streak, 1)`
+	// Самый дешёвый фон, которого нет у игрока
+	queryGetCheapestMissingBackground = `SELECT TOP 1 id_Background, Name FROM Backgrounds
+		WHERE id_Background NOT IN (SELECT id_Background FROM List_Backgrounds WHERE id_Player = @PlayerID)
+		ORDER BY Cost, id_Background`
+	// Добавление фона игроку
+	queryInsertListBackground = "INSERT INTO List_Backgrounds (id_Player, id_Background) VALUES (@PlayerID, @BackgroundID)"
+	// Начисление монет с возвратом нового баланса
+	queryAddPlayerMoney = "UPDATE Players SET Money = Money + @Money OUTPUT inserted.Money WHERE id_Player = @PlayerID"
+	// Получение баланса игрока
+	queryGetPlayerMoney = "SELECT Money FROM Players WHERE id_Player = @PlayerID"
+	// Запись в журнал денег
+	queryInsertLedgerEntry = "INSERT INTO MoneyLedger (id_Player, Amount, BalanceAfter, Reason, Reference) VALUES (@PlayerID, @Amount, @BalanceAfter, @Reason, @Reference)"
 )
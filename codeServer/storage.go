@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Хранилище игровых данных: база данных SQL Server или память процесса для тестов.
+// Методы магазина, ежедневных наград, друзей и регистрации сами пишут ошибки в журнал и возвращают GameError,
+// остальные возвращают ошибку как есть
+type Storage interface {
+	ping(ctx context.Context) error
+	close() error
+
+	// Пользователи
+	loginExists(login string) (bool, error)
+	createUser(login, passwordHash, name string) (userID, activeCharacterID int, err error)
+	getUser(login string) (*UserDB, error) // sql.ErrNoRows, если логина нет
+	getPublicID(userID int) (string, error)
+	getActiveCharacterID(userID int) (int, error)
+	getPlayerData(userID int) (*UserData, error)
+
+	// Каталог персонажей
+	characterRows() ([]characterRowDB, []assetCharacterRowDB, error)
+
+	// Игровой контент
+	contentVersion() (int, error) // 0, если контент не загружался
+	seed(sd *seedData) error
+
+	// Друзья
+	getFriends(playerID int) (*FriendsData, error)
+	addFriend(requesterPlayerID int, friendPublicID string) (FriendRequestStatus, error)
+	acceptFriendship(playerID int, requesterPublicID string) error
+	declineFriendship(playerID int, requesterPublicID string) error
+	removeFriend(playerID int, friendPublicID string) error
+
+	// Бои
+	// Записывает бой и прибавляет награды Reward1 и Reward2 в одной транзакции, задаёт RecordID
+	// и итоговые значения наград. Повторный вызов с тем же Key награды не начисляет,
+	// итоговыми значениями становятся текущие значения игроков
+	applyBattleResults(battle *Battle) error
+	getBattleStats(playerID int, isRanked bool) ([]BattleEntry, *BattleStats, error)
+
+	// Магазин
+	getShopBackgrounds(playerID int) (purchased, available []ShopBackgroundItem, err error)
+	getShopCharacters(playerID int) (purchased, available []ShopCharacterItem, err error)
+	buyBackground(playerID, backgroundID int) (remainingMoney int, err error)
+	selectBackground(playerID, backgroundID int) (assetPath string, err error)
+	buyCharacter(playerID, characterID int) (remainingMoney int, err error)
+	selectCharacter(playerID, characterID int) error
+
+	// Ежедневные награды
+	claimDailyReward(playerID int, now time.Time) (*DailyRewardInfo, error)
+
+	// Администрирование
+	getAdminPlayer(publicID, login string) (*AdminPlayer, error) // sql.ErrNoRows, если игрока нет
+	banUser(userID int, reason string, until *time.Time) error
+	unbanUser(userID int) error
+	adjustPlayerStats(playerID, money, rank, level int) (totalMoney int, err error)
+
+	// Журнал денег
+	getMoneyLedger(playerID, limit int) (entries []LedgerEntry, sum int, err error)
+
+	// Журналы
+	insertFailedLogin(login, ip, reason string) error
+	insertCheatReport(playerID int, action, events string, total int) error
+	insertAdminAction(playerID int, action, details, adminIP string) error
+}
+
+var store Storage // Хранилище сервера, задаётся при запуске
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"codeClient/bot"
+	"codeClient/protocol"
+	"codeServer/resource"
+	"context"
+	"errors"
+	"github.com/xtaci/kcp-go/v5"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Сервер для интеграционных тестов внутри процесса go test: KCP на случайном порту,
+// хранилище в памяти и каталог персонажей из testdata. Клиенты - боты из codeClient/bot.
+// Состояние сервера глобальное, поэтому тесты с сервером не выполняются параллельно
+const (
+	testServerKey      = "integration-test-key"
+	testServerFixtures = "testdata/fixtures.json"
+	testClientTimeout  = 5 * time.Second
+)
+
+// Настройки тестового сервера, нулевые значения заменяются значениями по умолчанию
+type testServerConfig struct {
+	Fixtures   string        // Каталог фонов и персонажей, по умолчанию testdata/fixtures.json
+	BattleTime time.Duration // Длительность боя, по умолчанию 3 с
+	StartDelay time.Duration // Задержка начала боя, по умолчанию 1 с
+}
+
+type testServer struct {
+	t     *testing.T
+	Store *memStorage // Хранилище для проверки состояния после запросов
+	bot   bot.Config  // Настройки подключения ботов
+}
+
+// Запускает сервер в текущем процессе, по окончании теста сервер останавливается
+func startTestServer(t *testing.T, cfg testServerConfig) *testServer {
+	t.Helper()
+	if cfg.Fixtures == "" {
+		cfg.Fixtures = testServerFixtures
+	}
+	if cfg.BattleTime <= 0 {
+		cfg.BattleTime = 3 * time.Second
+	}
+	if cfg.StartDelay <= 0 {
+		cfg.StartDelay = time.Second
+	}
+
+	if err := resource.Init(""); err != nil {
+		t.Fatalf("ресурсы: %v", err)
+	}
+	mem := newTestStore(t, cfg.Fixtures)
+	store = mem
+	if err := reloadCharacterCatalog(); err != nil {
+		t.Fatalf("персонажи: %v", err)
+	}
+
+	BattleTime = cfg.BattleTime
+	battleStartDelay = cfg.StartDelay
+	// Все клиенты теста подключаются с одного адреса и шлют запросы подряд
+	for group := range rateLimits {
+		rateLimits[group] = RateLimit{Rate: 1000, Burst: 1000}
+	}
+	sessionRateLimit = RateLimit{Rate: 1000, Burst: 1000}
+	loginGuards = &loginGuard{entries: make(map[string]*loginFailures)}
+	atomic.StoreInt32(&shuttingDown, 0)
+
+	matchmakingQueue = &MatchmakingQueue{stopChan: make(chan struct{})}
+	go matchmakingQueue.RunSearch()
+
+	key, err := deriveKey(testServerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trafficKey = key
+	block, err := newBlockCrypt(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := netProfiles["lan"]
+	listener, err := kcp.ListenWithOptions("127.0.0.1:0", block, profile.DataShards, profile.ParityShards)
+	if err != nil {
+		t.Fatalf("слушатель KCP: %v", err)
+	}
+	registerListener(listener)
+	go serveKCP(listener, profile)
+	t.Cleanup(func() { shutdown(0) })
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{t: t, Store: mem, bot: bot.Config{
+		Host:      "127.0.0.1",
+		Transport: "kcp",
+		Profile:   profile.Name,
+		Key:       testServerKey,
+		Timeout:   testClientTimeout,
+	}}
+	s.bot.Port, _ = strconv.Atoi(port)
+	return s
+}
+
+// Хранилище в памяти с каталогом из файла fixtures
+func newTestStore(t *testing.T, fixtures string) *memStorage {
+	t.Helper()
+	sd, err := loadSeedData(fixtures)
+	if err != nil {
+		t.Fatalf("каталог: %v", err)
+	}
+	mem := newMemStorage()
+	if err = mem.seed(sd); err != nil {
+		t.Fatalf("каталог: %v", err)
+	}
+	return mem
+}
+
+// Создаёт игрока в хранилище без подключения к серверу
+func createTestPlayer(t *testing.T, s *memStorage, login string) *AdminPlayer {
+	t.Helper()
+	if _, _, err := s.createUser(login, login, login); err != nil {
+		t.Fatalf("создание игрока %s: %v", login, err)
+	}
+	player, err := s.getAdminPlayer("", login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return player
+}
+
+// Подключает бота, соединение закрывается по окончании теста
+func (s *testServer) connect() *bot.Bot {
+	s.t.Helper()
+	b, err := bot.Dial(s.bot)
+	if err != nil {
+		s.t.Fatalf("подключение к тестовому серверу: %v", err)
+	}
+	s.t.Cleanup(func() { b.Close() })
+	return b
+}
+
+// Подключает и регистрирует нового игрока, имя и пароль совпадают с логином
+func (s *testServer) player(login string) *bot.Bot {
+	s.t.Helper()
+	b := s.connect()
+	if _, err := b.Register(testContext(s.t), login, login, login); err != nil {
+		s.t.Fatalf("регистрация %s: %v", login, err)
+	}
+	return b
+}
+
+// Контекст запросов теста, отменяется по окончании теста
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// Код ошибки сервера или 0, если ошибка другая
+func errorCode(err error) protocol.ErrorCode {
+	var gameErr *protocol.GameError
+	if errors.As(err, &gameErr) {
+		return gameErr.Code
+	}
+	return 0
+}
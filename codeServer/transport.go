@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"golang.org/x/net/websocket"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+)
+
+const maxSecureFrame = 1 << 20 // Наибольший размер зашифрованного кадра TCP и WebSocket
+
+// Профили TCP и WebSocket для рукопожатия, параметров KCP у них нет
+var (
+	tcpProfile = NetProfile{Name: "tcp"}
+	wsProfile  = NetProfile{Name: "ws"}
+)
+
+var errSecureFrame = errors.New("ошибка расшифровки кадра: ключ шифрования не совпадает или данные повреждены")
+
+// Зашифрованный поток поверх TCP и WebSocket, где нет шифрования KCP.
+// Кадр: длина uint32, nonce, данные AES-GCM. Запись выполняется под connMutex клиента
+type secureConn struct {
+	net.Conn
+	aead    cipher.AEAD
+	readBuf []byte // Расшифрованные, но ещё не прочитанные данные
+}
+
+func newSecureConn(conn net.Conn, aead cipher.AEAD) *secureConn {
+	return &secureConn{Conn: conn, aead: aead}
+}
+
+func (c *secureConn) Read(p []byte) (int, error) {
+	if len(c.readBuf) == 0 {
+		var header [4]byte
+		if _, err := io.ReadFull(c.Conn, header[:]); err != nil {
+			return 0, err
+		}
+		size := int(binary.BigEndian.Uint32(header[:]))
+		if size < c.aead.NonceSize()+c.aead.Overhead() || size > maxSecureFrame {
+			return 0, errSecureFrame
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(c.Conn, frame); err != nil {
+			return 0, err
+		}
+		nonce, data := frame[:c.aead.NonceSize()], frame[c.aead.NonceSize():]
+		plain, err := c.aead.Open(data[:0], nonce, data, header[:])
+		if err != nil {
+			return 0, errSecureFrame
+		}
+		c.readBuf = plain
+	}
+	n := copy(p, c.readBuf)
+	c.readBuf = c.readBuf[n:]
+	return n, nil
+}
+
+func (c *secureConn) Write(p []byte) (int, error) {
+	maxPlain := maxSecureFrame - c.aead.NonceSize() - c.aead.Overhead()
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxPlain {
+			chunk = chunk[:maxPlain]
+		}
+		nonceSize := c.aead.NonceSize()
+		frame := make([]byte, 4+nonceSize, 4+nonceSize+len(chunk)+c.aead.Overhead())
+		binary.BigEndian.PutUint32(frame, uint32(nonceSize+len(chunk)+c.aead.Overhead()))
+		if _, err := rand.Read(frame[4:]); err != nil {
+			return written, err
+		}
+		frame = c.aead.Seal(frame, frame[4:4+nonceSize], chunk, frame[:4])
+		if _, err := c.Conn.Write(frame); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
+
+// Создаёт клиента для нового подключения любого транспорта
+func newConnClient(conn net.Conn, addr string, profile NetProfile) *Client {
+	client := &Client{
+		UserID:     -1,
+		PlayerID:   -1,
+		Conn:       conn,
+		Authorized: false,
+		netProfile: profile,
+		remoteIP:   hostIP(addr),
+	}
+	client.setLogIdentity()
+	client.logger().Info("Новое подключение", "addr", addr)
+	return client
+}
+
+// Обработчик TCP для клиентов, у которых заблокирован UDP
+func tcpHandler(addr string, aead cipher.AEAD) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		fatal("Ошибка запуска TCP сервера", "err", err)
+	}
+	registerListener(listener)
+	slog.Info("TCP сервер запущен", "addr", addr)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if isShuttingDown() {
+				return
+			}
+			slog.Warn("Ошибка Accept TCP", "err", err)
+			continue
+		}
+		if !sessionLimits.allow(conn.RemoteAddr().String()) {
+			conn.Close()
+			continue
+		}
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			tcpConn.SetNoDelay(true)
+		}
+		go handleClientMessages(newConnClient(newSecureConn(conn, aead), conn.RemoteAddr().String(), tcpProfile))
+	}
+}
+
+// Обработчик WebSocket для сетей, где доступен только HTTP
+func wsHandler(addr string, aead cipher.AEAD) {
+	mux := http.NewServeMux()
+	mux.Handle("/ws", websocket.Server{Handler: func(ws *websocket.Conn) {
+		if !sessionLimits.allow(ws.Request().RemoteAddr) {
+			return
+		}
+		ws.PayloadType = websocket.BinaryFrame
+		handleClientMessages(newConnClient(newSecureConn(ws, aead), ws.Request().RemoteAddr, wsProfile)) // Соединение закрывается после выхода из обработчика
+	}})
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	registerListener(server)
+	slog.Info("WebSocket сервер запущен", "addr", addr+"/ws")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatal("Ошибка запуска WebSocket сервера", "err", err)
+	}
+}